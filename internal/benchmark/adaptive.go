@@ -0,0 +1,81 @@
+package benchmark
+
+import (
+	"context"
+	"sync"
+)
+
+// aimdLimiter caps the requests in flight to one provider. The cap starts at 1,
+// grows by one after each cap's worth of consecutive successes and halves when
+// the provider rate-limits (additive increase, multiplicative decrease).
+type aimdLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	max       int
+	inFlight  int
+	successes int
+	// epoch advances on every decrease, so a burst of 429s from requests that
+	// were already in flight halves the cap only once
+	epoch int
+	wake  chan struct{}
+}
+
+func newAIMDLimiter(max int) *aimdLimiter {
+	return &aimdLimiter{limit: 1, max: max, wake: make(chan struct{})}
+}
+
+// acquire waits for a free slot and returns the epoch to pass to release, or
+// false if ctx is done first
+func (l *aimdLimiter) acquire(ctx context.Context) (int, bool) {
+	for {
+		l.mu.Lock()
+		if l.inFlight < l.limit {
+			l.inFlight++
+			epoch := l.epoch
+			l.mu.Unlock()
+			return epoch, true
+		}
+		wake := l.wake
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return 0, false
+		case <-wake:
+		}
+	}
+}
+
+// release frees a slot and adjusts the cap; it reports whether the cap was halved
+func (l *aimdLimiter) release(epoch int, rateLimited bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	halved := false
+	switch {
+	case rateLimited && epoch == l.epoch:
+		l.limit = max(1, l.limit/2)
+		l.successes = 0
+		l.epoch++
+		halved = true
+	case !rateLimited && l.limit < l.max:
+		l.successes++
+		if l.successes >= l.limit {
+			l.limit++
+			l.successes = 0
+		}
+	}
+
+	// Wake waiters to recheck the cap
+	close(l.wake)
+	l.wake = make(chan struct{})
+	return halved
+}
+
+// Limit returns the current cap
+func (l *aimdLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
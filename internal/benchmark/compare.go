@@ -0,0 +1,140 @@
+package benchmark
+
+import (
+	"sort"
+	"time"
+)
+
+// Comparison holds the metric deltas between a baseline and a current run
+// for a single provider/model/prompt combination
+type Comparison struct {
+	Provider   string
+	Model      string
+	PromptFile string
+
+	BaselineP95TTFT time.Duration
+	CurrentP95TTFT  time.Duration
+	P95TTFTDeltaPct float64
+
+	BaselineTokensPerSecond float64
+	CurrentTokensPerSecond  float64
+	TokensPerSecondDeltaPct float64
+
+	BaselineAvgCost float64
+	CurrentAvgCost  float64
+	CostDeltaPct    float64
+
+	// Regressions lists the metrics that worsened beyond the threshold
+	Regressions []string
+}
+
+// IsRegression reports whether any metric worsened beyond the threshold
+func (c Comparison) IsRegression() bool {
+	return len(c.Regressions) > 0
+}
+
+// comparisonKey joins results across runs
+type comparisonKey struct {
+	provider   string
+	model      string
+	promptFile string
+}
+
+// CompareResults joins baseline and current results by provider+model+prompt and
+// computes deltas for p95 TTFT, tokens/sec and cost. A metric counts as a regression
+// when it worsens by more than thresholdPct percent. Combinations missing from
+// either side are skipped.
+func CompareResults(baseline, current []BenchmarkResult, thresholdPct float64) []Comparison {
+	baselineGroups := groupByComparisonKey(baseline)
+	currentGroups := groupByComparisonKey(current)
+
+	var comparisons []Comparison
+	for key, currentResults := range currentGroups {
+		baselineResults, ok := baselineGroups[key]
+		if !ok {
+			continue
+		}
+
+		base := CalculateSummary(baselineResults)
+		cur := CalculateSummary(currentResults)
+		if base.SuccessfulRuns == 0 || cur.SuccessfulRuns == 0 {
+			continue
+		}
+
+		c := Comparison{
+			Provider:                key.provider,
+			Model:                   key.model,
+			PromptFile:              key.promptFile,
+			BaselineP95TTFT:         base.P95TTFT,
+			CurrentP95TTFT:          cur.P95TTFT,
+			BaselineTokensPerSecond: averageTokensPerSecond(baselineResults),
+			CurrentTokensPerSecond:  averageTokensPerSecond(currentResults),
+			BaselineAvgCost:         base.AvgCostPerRun,
+			CurrentAvgCost:          cur.AvgCostPerRun,
+		}
+		c.P95TTFTDeltaPct = percentChange(float64(c.BaselineP95TTFT), float64(c.CurrentP95TTFT))
+		c.TokensPerSecondDeltaPct = percentChange(c.BaselineTokensPerSecond, c.CurrentTokensPerSecond)
+		c.CostDeltaPct = percentChange(c.BaselineAvgCost, c.CurrentAvgCost)
+
+		// Higher TTFT and cost are worse, lower throughput is worse
+		if c.P95TTFTDeltaPct > thresholdPct {
+			c.Regressions = append(c.Regressions, "p95_ttft")
+		}
+		if -c.TokensPerSecondDeltaPct > thresholdPct {
+			c.Regressions = append(c.Regressions, "tokens_per_second")
+		}
+		if c.CostDeltaPct > thresholdPct {
+			c.Regressions = append(c.Regressions, "cost")
+		}
+
+		comparisons = append(comparisons, c)
+	}
+
+	sort.Slice(comparisons, func(i, j int) bool {
+		a, b := comparisons[i], comparisons[j]
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		if a.Model != b.Model {
+			return a.Model < b.Model
+		}
+		return a.PromptFile < b.PromptFile
+	})
+
+	return comparisons
+}
+
+func groupByComparisonKey(results []BenchmarkResult) map[comparisonKey][]BenchmarkResult {
+	groups := make(map[comparisonKey][]BenchmarkResult)
+	for _, result := range results {
+		key := comparisonKey{provider: result.Provider, model: result.Model, promptFile: result.PromptFile}
+		groups[key] = append(groups[key], result)
+	}
+	return groups
+}
+
+// averageTokensPerSecond returns the mean tokens/sec over successful results,
+// leaving out empty responses
+func averageTokensPerSecond(results []BenchmarkResult) float64 {
+	var total float64
+	var count int
+	for _, result := range results {
+		if result.Success && !result.EmptyResponse {
+			total += result.TokensPerSecond
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
+
+// percentChange returns the relative change from baseline to current in percent,
+// or 0 when the baseline is zero
+func percentChange(baseline, current float64) float64 {
+	if baseline == 0 {
+		return 0
+	}
+	return (current - baseline) / baseline * 100
+}
@@ -0,0 +1,76 @@
+package benchmark
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareResults(t *testing.T) {
+	baseline := []BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", Success: true, TTFT: 200 * time.Millisecond, TokensPerSecond: 100, Cost: 0.010},
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", Success: true, TTFT: 300 * time.Millisecond, TokensPerSecond: 100, Cost: 0.010},
+		{Provider: "groq", Model: "qwen/qwen3-32b", PromptFile: "simple", Success: true, TTFT: 100 * time.Millisecond, TokensPerSecond: 500, Cost: 0.001},
+		{Provider: "anthropic", Model: "claude-sonnet-4-20250514", PromptFile: "simple", Success: true, TTFT: 500 * time.Millisecond, TokensPerSecond: 50, Cost: 0.020},
+	}
+	current := []BenchmarkResult{
+		// p95 TTFT 300ms -> 450ms (+50%), throughput and cost unchanged
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", Success: true, TTFT: 250 * time.Millisecond, TokensPerSecond: 100, Cost: 0.010},
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", Success: true, TTFT: 450 * time.Millisecond, TokensPerSecond: 100, Cost: 0.010},
+		// Throughput 500 -> 450 (-10%) stays within a 20% threshold
+		{Provider: "groq", Model: "qwen/qwen3-32b", PromptFile: "simple", Success: true, TTFT: 100 * time.Millisecond, TokensPerSecond: 450, Cost: 0.001},
+		// Not present in the baseline, so it is skipped
+		{Provider: "gemini", Model: "gemini-2.5-flash", PromptFile: "simple", Success: true, TTFT: 300 * time.Millisecond, TokensPerSecond: 200, Cost: 0.002},
+	}
+
+	comparisons := CompareResults(baseline, current, 20)
+	require.Len(t, comparisons, 2)
+
+	// Sorted by provider, model, prompt
+	groq := comparisons[0]
+	assert.Equal(t, "groq", groq.Provider)
+	assert.InDelta(t, -10.0, groq.TokensPerSecondDeltaPct, 0.001)
+	assert.False(t, groq.IsRegression())
+
+	openai := comparisons[1]
+	assert.Equal(t, "openai", openai.Provider)
+	assert.Equal(t, 300*time.Millisecond, openai.BaselineP95TTFT)
+	assert.Equal(t, 450*time.Millisecond, openai.CurrentP95TTFT)
+	assert.InDelta(t, 50.0, openai.P95TTFTDeltaPct, 0.001)
+	assert.InDelta(t, 0.0, openai.CostDeltaPct, 0.001)
+	assert.True(t, openai.IsRegression())
+	assert.Equal(t, []string{"p95_ttft"}, openai.Regressions)
+}
+
+func TestCompareResults_ThroughputAndCostRegressions(t *testing.T) {
+	baseline := []BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1-mini", PromptFile: "complex", Success: true, TTFT: 200 * time.Millisecond, TokensPerSecond: 100, Cost: 0.010},
+	}
+	current := []BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1-mini", PromptFile: "complex", Success: true, TTFT: 180 * time.Millisecond, TokensPerSecond: 70, Cost: 0.015},
+		// Failed runs do not contribute to the aggregates
+		{Provider: "openai", Model: "gpt-4.1-mini", PromptFile: "complex", Success: false, TTFT: 10 * time.Second},
+	}
+
+	comparisons := CompareResults(baseline, current, 10)
+	require.Len(t, comparisons, 1)
+
+	c := comparisons[0]
+	assert.InDelta(t, -10.0, c.P95TTFTDeltaPct, 0.001)
+	assert.InDelta(t, -30.0, c.TokensPerSecondDeltaPct, 0.001)
+	assert.InDelta(t, 50.0, c.CostDeltaPct, 0.001)
+	assert.Equal(t, []string{"tokens_per_second", "cost"}, c.Regressions)
+}
+
+func TestCompareResults_NoSuccessfulRuns(t *testing.T) {
+	baseline := []BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", Success: false},
+	}
+	current := []BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", Success: true, TTFT: time.Second},
+	}
+
+	assert.Empty(t, CompareResults(baseline, current, 10))
+}
@@ -0,0 +1,56 @@
+package benchmark
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConcurrencyLevel summarizes the results run at one level of a concurrency sweep
+type ConcurrencyLevel struct {
+	Concurrency int
+	Summary     Summary
+}
+
+// SummarizeConcurrencyLevels groups sweep results by their Concurrency tag, in
+// ascending order, so latency and throughput can be read against concurrency
+func SummarizeConcurrencyLevels(results []BenchmarkResult) []ConcurrencyLevel {
+	groups := make(map[int][]BenchmarkResult)
+	for _, result := range results {
+		groups[result.Concurrency] = append(groups[result.Concurrency], result)
+	}
+
+	levels := make([]ConcurrencyLevel, 0, len(groups))
+	for concurrency, group := range groups {
+		levels = append(levels, ConcurrencyLevel{Concurrency: concurrency, Summary: CalculateSummary(group)})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i].Concurrency < levels[j].Concurrency
+	})
+	return levels
+}
+
+// ParseConcurrencySweep parses comma-separated concurrency levels such as "1,2,4,8"
+func ParseConcurrencySweep(value string) ([]int, error) {
+	var levels []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		level, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid concurrency level %q: %w", part, err)
+		}
+		if level < 1 {
+			return nil, fmt.Errorf("concurrency level %d must be at least 1", level)
+		}
+		levels = append(levels, level)
+	}
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("no concurrency levels in %q", value)
+	}
+	return levels, nil
+}
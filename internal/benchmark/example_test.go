@@ -0,0 +1,79 @@
+package benchmark_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/megzo/llm-latency-benchmark/internal/benchmark"
+	"github.com/megzo/llm-latency-benchmark/internal/config"
+	"github.com/megzo/llm-latency-benchmark/providers"
+)
+
+// echoProvider is a minimal providers.Provider that streams back a fixed reply
+type echoProvider struct{}
+
+func (echoProvider) Name() string { return "openai" }
+
+func (echoProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	ch := make(chan providers.ChatResponse, 2)
+	ch <- providers.ChatResponse{Content: "Hello!", Timestamp: time.Now()}
+	ch <- providers.ChatResponse{IsComplete: true, Timestamp: time.Now(), FinishReason: "stop"}
+	close(ch)
+	return ch, nil
+}
+
+func (echoProvider) TokenCount(response providers.ChatResponse) (input, output, total int) {
+	return 0, 2, 2
+}
+
+func (echoProvider) GetTokenCount(text string) int { return 2 }
+
+// Example shows running a benchmark programmatically: build the configuration in
+// code, silence the runner's logs and consume results as they complete.
+func Example() {
+	promptsDir, _ := os.MkdirTemp("", "prompts")
+	defer os.RemoveAll(promptsDir)
+	os.WriteFile(filepath.Join(promptsDir, "greeting.yaml"), []byte("user: \"Say hello\"\n"), 0644)
+
+	cfg := &config.Config{
+		Models: &config.ModelsConfig{OpenAI: map[string]config.ModelSpec{
+			"mock-model": {TokenPrice: config.ModelPricing{Input: 1, Output: 1}},
+		}},
+		Concurrent:     1,
+		Runs:           2,
+		PromptsDir:     promptsDir,
+		RequestTimeout: 5 * time.Second,
+	}
+
+	runner := benchmark.NewRunner(cfg, map[string]providers.Provider{"openai": echoProvider{}}, false)
+	runner.SetLogOutput(io.Discard)
+
+	// Stream results while the run is in progress
+	resultsCh := make(chan benchmark.BenchmarkResult)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range resultsCh {
+			fmt.Printf("%s/%s %s: success=%t response=%q\n", result.Provider, result.Model, result.PromptFile, result.Success, result.Response)
+		}
+	}()
+	runner.StreamResults(resultsCh)
+
+	results, summary, err := runner.Execute(context.Background())
+	close(resultsCh)
+	<-done
+	if err != nil {
+		fmt.Println("benchmark failed:", err)
+		return
+	}
+
+	fmt.Printf("%d results, %d successful, error rate %.0f%%\n", len(results), summary.SuccessfulRuns, summary.ErrorRate*100)
+	// Output:
+	// openai/mock-model greeting: success=true response="Hello!"
+	// openai/mock-model greeting: success=true response="Hello!"
+	// 2 results, 2 successful, error rate 0%
+}
@@ -0,0 +1,97 @@
+package benchmark
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHistogramBuckets are the TTFT bucket boundaries used unless overridden:
+// <100ms, 100-250ms, 250-500ms, 500ms-1s and >=1s
+var DefaultHistogramBuckets = []time.Duration{
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// HistogramBucket counts durations in [Min, Max). The first bucket has no lower
+// bound (Min 0) and the last has no upper bound (Max 0).
+type HistogramBucket struct {
+	Min   time.Duration `json:"min"`
+	Max   time.Duration `json:"max"`
+	Label string        `json:"label"`
+	Count int           `json:"count"`
+}
+
+// Histogram buckets durations by the ascending boundaries, returning len(bounds)+1
+// buckets so that bimodal distributions (e.g. cache hits vs misses) stand out
+func Histogram(durations []time.Duration, bounds []time.Duration) []HistogramBucket {
+	buckets := make([]HistogramBucket, len(bounds)+1)
+	for i := range buckets {
+		switch {
+		case len(bounds) == 0:
+			buckets[i] = HistogramBucket{Label: "all"}
+		case i == 0:
+			buckets[i] = HistogramBucket{Max: bounds[0], Label: "<" + bounds[0].String()}
+		case i == len(bounds):
+			buckets[i] = HistogramBucket{Min: bounds[i-1], Label: ">=" + bounds[i-1].String()}
+		default:
+			buckets[i] = HistogramBucket{Min: bounds[i-1], Max: bounds[i], Label: bounds[i-1].String() + "-" + bounds[i].String()}
+		}
+	}
+	for _, d := range durations {
+		i := 0
+		for i < len(bounds) && d >= bounds[i] {
+			i++
+		}
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// TTFTHistogram buckets the TTFT of successful results
+func TTFTHistogram(results []BenchmarkResult, bounds []time.Duration) []HistogramBucket {
+	var ttfts []time.Duration
+	for _, result := range results {
+		if result.Success {
+			ttfts = append(ttfts, result.TTFT)
+		}
+	}
+	return Histogram(ttfts, bounds)
+}
+
+// ParseHistogramBuckets parses comma-separated ascending boundaries such as
+// "100ms,250ms,1s"; bare numbers are milliseconds
+func ParseHistogramBuckets(value string) ([]time.Duration, error) {
+	var bounds []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		bound, err := time.ParseDuration(part)
+		if err != nil {
+			ms, msErr := strconv.ParseFloat(part, 64)
+			if msErr != nil {
+				return nil, fmt.Errorf("invalid histogram bucket %q: %w", part, err)
+			}
+			bound = time.Duration(ms * float64(time.Millisecond))
+		}
+
+		if bound <= 0 {
+			return nil, fmt.Errorf("histogram bucket %q must be positive", part)
+		}
+		if len(bounds) > 0 && bound <= bounds[len(bounds)-1] {
+			return nil, fmt.Errorf("histogram buckets must be ascending, got %v after %v", bound, bounds[len(bounds)-1])
+		}
+		bounds = append(bounds, bound)
+	}
+
+	if len(bounds) == 0 {
+		return nil, fmt.Errorf("no histogram buckets given")
+	}
+	return bounds, nil
+}
@@ -0,0 +1,81 @@
+package benchmark
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/megzo/llm-latency-benchmark/providers"
+)
+
+func TestHistogram_DefaultBuckets(t *testing.T) {
+	// Bimodal: cache hits around 80ms and misses around 700ms
+	var durations []time.Duration
+	for _, ms := range []int{50, 80, 99, 100, 249, 250, 700, 720, 999, 1000, 2500} {
+		durations = append(durations, time.Duration(ms)*time.Millisecond)
+	}
+
+	buckets := Histogram(durations, DefaultHistogramBuckets)
+
+	labels := make([]string, len(buckets))
+	counts := make([]int, len(buckets))
+	for i, bucket := range buckets {
+		labels[i] = bucket.Label
+		counts[i] = bucket.Count
+	}
+	assert.Equal(t, []string{"<100ms", "100ms-250ms", "250ms-500ms", "500ms-1s", ">=1s"}, labels)
+	assert.Equal(t, []int{3, 2, 1, 3, 2}, counts)
+
+	assert.Equal(t, time.Duration(0), buckets[0].Min)
+	assert.Equal(t, 100*time.Millisecond, buckets[0].Max)
+	assert.Equal(t, time.Second, buckets[4].Min)
+	assert.Equal(t, time.Duration(0), buckets[4].Max)
+}
+
+func TestTTFTHistogram_SkipsFailedRuns(t *testing.T) {
+	results := []BenchmarkResult{
+		{Success: true, TTFT: 50 * time.Millisecond},
+		{Success: true, TTFT: 300 * time.Millisecond},
+		{Success: false, TTFT: 10 * time.Millisecond},
+	}
+
+	buckets := TTFTHistogram(results, []time.Duration{200 * time.Millisecond})
+	require.Len(t, buckets, 2)
+	assert.Equal(t, 1, buckets[0].Count)
+	assert.Equal(t, 1, buckets[1].Count)
+
+	// The summary carries the default histogram
+	summary := CalculateSummary(results)
+	require.Len(t, summary.TTFTHistogram, len(DefaultHistogramBuckets)+1)
+	assert.Equal(t, 1, summary.TTFTHistogram[0].Count)
+	assert.Equal(t, 1, summary.TTFTHistogram[2].Count)
+}
+
+func TestParseHistogramBuckets(t *testing.T) {
+	bounds, err := ParseHistogramBuckets("50, 100ms,1.5s")
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 1500 * time.Millisecond}, bounds)
+
+	for _, value := range []string{"", "100ms,50ms", "100ms,100ms", "-5", "soon"} {
+		_, err := ParseHistogramBuckets(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestRunner_CustomHistogramBuckets(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Runs = 2
+	cfg.HistogramBuckets = []time.Duration{time.Millisecond, time.Hour}
+
+	provider := &stubProvider{name: "openai", content: "Hello", delay: 5 * time.Millisecond, tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	_, summary, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, summary.TTFTHistogram, 3)
+	assert.Equal(t, "1ms-1h0m0s", summary.TTFTHistogram[1].Label)
+	assert.Equal(t, 2, summary.TTFTHistogram[1].Count)
+}
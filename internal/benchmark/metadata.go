@@ -0,0 +1,50 @@
+package benchmark
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// RunMetadata describes the environment a benchmark run was executed in, so
+// results can be correlated with code and configuration changes
+type RunMetadata struct {
+	Hostname    string    `json:"hostname"`
+	OS          string    `json:"os"`
+	Arch        string    `json:"arch"`
+	StartTime   time.Time `json:"start_time"`
+	ToolVersion string    `json:"tool_version"`
+	ModelsFile  string    `json:"models_file"`
+	Concurrency int       `json:"concurrency"`
+	GitCommit   string    `json:"git_commit"`
+}
+
+// NewRunMetadata captures metadata for a run starting now
+func NewRunMetadata(toolVersion, modelsFile string, concurrency int) RunMetadata {
+	hostname, _ := os.Hostname()
+	return RunMetadata{
+		Hostname:    hostname,
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		StartTime:   time.Now(),
+		ToolVersion: toolVersion,
+		ModelsFile:  modelsFile,
+		Concurrency: concurrency,
+		GitCommit:   gitCommit(),
+	}
+}
+
+// gitCommit returns the current git commit, or "" when git or a repository is unavailable
+func gitCommit() string {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
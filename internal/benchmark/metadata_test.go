@@ -0,0 +1,45 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRunMetadata(t *testing.T) {
+	before := time.Now()
+	metadata := NewRunMetadata("1.2.3", "models.yaml", 4)
+
+	assert.NotEmpty(t, metadata.Hostname)
+	assert.Equal(t, runtime.GOOS, metadata.OS)
+	assert.Equal(t, runtime.GOARCH, metadata.Arch)
+	assert.False(t, metadata.StartTime.Before(before))
+	assert.Equal(t, "1.2.3", metadata.ToolVersion)
+	assert.Equal(t, "models.yaml", metadata.ModelsFile)
+	assert.Equal(t, 4, metadata.Concurrency)
+	// The commit is only available inside a git checkout
+	if metadata.GitCommit != "" {
+		assert.Regexp(t, `^[0-9a-f]{40,64}$`, metadata.GitCommit)
+	}
+}
+
+func TestRunMetadata_JSON(t *testing.T) {
+	metadata := RunMetadata{
+		Hostname:    "bench-01",
+		OS:          "linux",
+		Arch:        "amd64",
+		StartTime:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		ToolVersion: "0.1.0",
+		ModelsFile:  "models.yaml",
+		Concurrency: 2,
+		GitCommit:   "abc123",
+	}
+
+	data, err := json.Marshal(metadata)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"hostname":"bench-01","os":"linux","arch":"amd64","start_time":"2025-01-02T03:04:05Z","tool_version":"0.1.0","models_file":"models.yaml","concurrency":2,"git_commit":"abc123"}`, string(data))
+}
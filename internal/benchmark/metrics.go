@@ -1,8 +1,14 @@
 package benchmark
 
 import (
+	"cmp"
+	"math"
+	"slices"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/megzo/llm-latency-benchmark/providers"
 )
 
 // Metrics holds timing and performance metrics for a benchmark run
@@ -14,15 +20,46 @@ type Metrics struct {
 	FirstTokenTime time.Time
 	EndTime        time.Time
 
+	// Tool calling: FirstToolCallTime is when the first tool-call fragment arrived
+	ToolCall          bool
+	FirstToolCallTime time.Time
+
+	// Models that stream their reasoning: when the first reasoning and the first
+	// answer content arrived. FirstTokenTime is whichever came first.
+	FirstReasoningTime time.Time
+	FirstAnswerTime    time.Time
+
+	// LastTokenTime is when the last reasoning, answer or tool-call content arrived,
+	// before the provider's completion event
+	LastTokenTime time.Time
+
 	// Token tracking
-	InputTokens  int
-	OutputTokens int
-	TotalTokens  int
+	InputTokens       int
+	CachedInputTokens int // Input tokens the provider served from its prompt cache
+	OutputTokens      int
+	ReasoningTokens   int // Output tokens spent on hidden reasoning, included in OutputTokens
+	TotalTokens       int
+
+	// VisibleTokensPerSecond excludes ReasoningTokens from TokensPerSecond
+	VisibleTokensPerSecond bool
+
+	// NormalizeTokens computes TokensPerSecond from NormalizedOutputTokens, the
+	// response recounted with a reference tokenizer shared by every provider
+	NormalizeTokens        bool
+	NormalizedOutputTokens int
+
+	// TotalTimeToLastToken stops TotalTime at LastTokenTime instead of EndTime,
+	// leaving out the completion event and stream close; EndTime is still recorded
+	TotalTimeToLastToken bool
 
 	// Calculated metrics
 	TTFT            time.Duration
 	TotalTime       time.Duration
-	TokensPerSecond float64
+	TimeToToolCall  time.Duration
+	TimeToReasoning time.Duration
+	TimeToAnswer    time.Duration
+	TimeToLastToken time.Duration
+	TokensPerSecond float64 // Output tokens over the first-to-last token window
 
 	// Cost
 	Cost float64
@@ -30,6 +67,27 @@ type Metrics struct {
 	// Response content
 	Response string
 
+	// Chunk arrival times, recorded only when TraceChunks is set to avoid the overhead
+	TraceChunks bool
+	Chunks      []ChunkArrival
+
+	// Finish reason reported by the provider; Truncated is set when the token limit cut it off
+	FinishReason string
+	Truncated    bool
+
+	// Network phases before the first response byte; zero when the provider doesn't report them
+	Network providers.NetworkTiming
+
+	// Rate-limit state from the response headers; nil when the provider doesn't report it
+	RateLimit *providers.RateLimitInfo
+
+	// Source URLs cited by search-backed models such as Perplexity's
+	Citations []string
+
+	// Model version and backend fingerprint the provider reported serving
+	ResponseModel     string
+	SystemFingerprint string
+
 	// Error tracking
 	Error   error
 	Success bool
@@ -61,6 +119,85 @@ func (m *Metrics) AddTokens(input, output int) {
 	m.OutputTokens += output
 }
 
+// SetCachedInputTokens records how many input tokens were prompt-cache hits
+func (m *Metrics) SetCachedInputTokens(cached int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.CachedInputTokens = cached
+}
+
+// SetReasoningTokens records how many output tokens were hidden reasoning
+func (m *Metrics) SetReasoningTokens(reasoning int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ReasoningTokens = reasoning
+}
+
+// SetNormalizedOutputTokens records the output token count under the reference tokenizer
+func (m *Metrics) SetNormalizedOutputTokens(tokens int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.NormalizedOutputTokens = tokens
+}
+
+// RecordToolCall records that the response contains a tool call, timing its first fragment
+func (m *Metrics) RecordToolCall() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.ToolCall = true
+	if m.FirstToolCallTime.IsZero() {
+		m.FirstToolCallTime = now
+	}
+	m.LastTokenTime = now
+}
+
+// RecordReasoningToken records the arrival of streamed reasoning content,
+// which also counts as the first token
+func (m *Metrics) RecordReasoningToken() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if m.FirstReasoningTime.IsZero() {
+		m.FirstReasoningTime = now
+		if m.FirstTokenTime.IsZero() {
+			m.FirstTokenTime = now
+		}
+	}
+	m.LastTokenTime = now
+}
+
+// RecordAnswerToken records the arrival of answer content, which also counts
+// as the first token
+func (m *Metrics) RecordAnswerToken() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if m.FirstAnswerTime.IsZero() {
+		m.FirstAnswerTime = now
+		if m.FirstTokenTime.IsZero() {
+			m.FirstTokenTime = now
+		}
+	}
+	m.LastTokenTime = now
+}
+
+// RecordChunk records the arrival of a content chunk when tracing is enabled
+func (m *Metrics) RecordChunk(length int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.TraceChunks {
+		m.Chunks = append(m.Chunks, ChunkArrival{Offset: time.Since(m.StartTime), Length: length})
+	}
+}
+
 // AddResponseContent appends content to the response
 func (m *Metrics) AddResponseContent(content string) {
 	m.mu.Lock()
@@ -69,6 +206,10 @@ func (m *Metrics) AddResponseContent(content string) {
 	m.Response += content
 }
 
+// minStreamingWindow is the shortest first-to-last token window tokens/sec is
+// measured over
+const minStreamingWindow = time.Millisecond
+
 // Complete marks the benchmark as complete and calculates final metrics
 func (m *Metrics) Complete() {
 	m.mu.Lock()
@@ -81,12 +222,44 @@ func (m *Metrics) Complete() {
 	if !m.FirstTokenTime.IsZero() {
 		m.TTFT = m.FirstTokenTime.Sub(m.StartTime)
 	}
+	if !m.FirstToolCallTime.IsZero() {
+		m.TimeToToolCall = m.FirstToolCallTime.Sub(m.StartTime)
+	}
+	if !m.FirstReasoningTime.IsZero() {
+		m.TimeToReasoning = m.FirstReasoningTime.Sub(m.StartTime)
+	}
+	if !m.FirstAnswerTime.IsZero() {
+		m.TimeToAnswer = m.FirstAnswerTime.Sub(m.StartTime)
+	}
+	if !m.LastTokenTime.IsZero() {
+		m.TimeToLastToken = m.LastTokenTime.Sub(m.StartTime)
+	}
 	
 	m.TotalTime = m.EndTime.Sub(m.StartTime)
+	if m.TotalTimeToLastToken && !m.LastTokenTime.IsZero() {
+		m.TotalTime = m.TimeToLastToken
+	}
 	m.TotalTokens = m.InputTokens + m.OutputTokens
 	
-	if m.TotalTime > 0 && m.OutputTokens > 0 {
-		m.TokensPerSecond = float64(m.OutputTokens) / m.TotalTime.Seconds()
+	outputTokens := m.OutputTokens
+	if m.VisibleTokensPerSecond {
+		outputTokens -= m.ReasoningTokens
+	}
+	if m.NormalizeTokens {
+		outputTokens = m.NormalizedOutputTokens
+	}
+	// Streaming throughput is measured while tokens arrive, from the first token to
+	// the last, excluding the wait for the first token and the trailing completion
+	// event; a response that arrived in one piece has no such window, so it uses the
+	// total time. So does one whose chunks arrived within minStreamingWindow of each
+	// other, as with very fast providers whose chunks are delivered together, since
+	// dividing by a few microseconds would report an absurd rate
+	window := m.TotalTime
+	if !m.FirstTokenTime.IsZero() && m.LastTokenTime.Sub(m.FirstTokenTime) >= minStreamingWindow {
+		window = m.LastTokenTime.Sub(m.FirstTokenTime)
+	}
+	if window > 0 && outputTokens > 0 {
+		m.TokensPerSecond = float64(outputTokens) / window.Seconds()
 	}
 }
 
@@ -100,6 +273,48 @@ func (m *Metrics) SetError(err error) {
 	m.EndTime = time.Now()
 }
 
+// SetFinishReason records the provider's finish reason and whether it means truncation
+func (m *Metrics) SetFinishReason(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	m.FinishReason = reason
+	m.Truncated = providers.IsTruncated(reason)
+}
+
+// SetNetworkTiming records the provider's network timing breakdown
+func (m *Metrics) SetNetworkTiming(timing providers.NetworkTiming) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	m.Network = timing
+}
+
+// SetRateLimit records the rate-limit state the provider reported
+func (m *Metrics) SetRateLimit(info *providers.RateLimitInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.RateLimit = info
+}
+
+// SetCitations records the sources the model cited
+func (m *Metrics) SetCitations(citations []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Citations = citations
+}
+
+// SetModelVersion records the model version and system fingerprint the provider reported
+func (m *Metrics) SetModelVersion(responseModel, systemFingerprint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ResponseModel = responseModel
+	m.SystemFingerprint = systemFingerprint
+}
+
 // SetCost sets the cost for this benchmark run
 func (m *Metrics) SetCost(cost float64) {
 	m.mu.Lock()
@@ -114,34 +329,81 @@ type BenchmarkResult struct {
 	Model           string    `json:"model"`
 	PromptFile      string    `json:"prompt_file"`
 	
+	// Request parameters
+	Temperature     float64   `json:"temperature"`
+	MaxTokens       int       `json:"max_tokens"`
+	Seed            *int64    `json:"seed,omitempty"` // Sampling seed, if one was pinned
+	Concurrency     int       `json:"concurrency,omitempty"` // Level this ran at in a concurrency sweep
+	Run             int       `json:"run,omitempty"` // 1-based index among the runs of its provider, model and prompt
+	
 	// Timing metrics
+	QueueWait       time.Duration `json:"queue_wait"` // Time from dispatch until the first request started, e.g. waiting for a free worker
 	StartTime       time.Time `json:"start_time"`
 	FirstTokenTime  time.Time `json:"first_token_time"`
 	EndTime         time.Time `json:"end_time"`
 	TTFT            time.Duration `json:"ttft"`           // Time to first token
 	TotalTime       time.Duration `json:"total_time"`     // Total response time
+	TimeToToolCall  time.Duration `json:"time_to_tool_call"` // Time to the first tool-call fragment (0 = no tool call)
+	TimeToReasoning time.Duration `json:"time_to_reasoning"` // Time to the first streamed reasoning token (0 = none streamed)
+	TimeToAnswer    time.Duration `json:"time_to_answer"`    // Time to the first answer token, after any streamed reasoning
+	TimeToLastToken time.Duration `json:"time_to_last_token"` // Time to the last content token, before the completion event
+	
+	// Network timing breakdown (direct-HTTP providers only)
+	DNSLookup       time.Duration `json:"dns_lookup"`
+	Connect         time.Duration `json:"connect"`
+	TLSHandshake    time.Duration `json:"tls_handshake"`
+	TimeToFirstByte time.Duration `json:"time_to_first_byte"`
+
+	// Provider rate-limit headers (direct-HTTP providers only)
+	RateLimit       *providers.RateLimitInfo `json:"rate_limit,omitempty"`
 	
 	// Token metrics
 	InputTokens     int       `json:"input_tokens"`
+	CachedInputTokens int     `json:"cached_input_tokens"` // Included in InputTokens
 	OutputTokens    int       `json:"output_tokens"`
+	ReasoningTokens int       `json:"reasoning_tokens"` // Included in OutputTokens
+	// With -normalize-tokens: the provider's own output count (as OutputTokens) and the
+	// visible output recounted with the shared reference tokenizer, which TokensPerSecond uses
+	NativeOutputTokens     int `json:"native_output_tokens,omitempty"`
+	NormalizedOutputTokens int `json:"normalized_output_tokens,omitempty"`
 	TotalTokens     int       `json:"total_tokens"`
 	TokensPerSecond float64   `json:"tokens_per_second"`
+	Dimensions      int       `json:"dimensions,omitempty"` // Embedding vector length (embeddings mode)
 	
 	// Cost metrics
 	Cost            float64   `json:"cost"`
 	
 	// Response content
 	Response        string    `json:"response"`
+	FinishReason    string    `json:"finish_reason,omitempty"`
+	Truncated       bool      `json:"truncated"`
+	EmptyResponse   bool      `json:"empty_response"` // The stream completed without any answer text
+	ToolCall        bool      `json:"tool_call"` // The model called a tool
+	Citations       []string  `json:"citations,omitempty"` // Sources cited by search-backed models
+	ResponseModel   string    `json:"response_model,omitempty"` // Model version the provider reported serving
+	SystemFingerprint string  `json:"system_fingerprint,omitempty"` // Backend configuration the provider reported (OpenAI-compatible APIs)
+	Backend         string    `json:"backend,omitempty"` // Backend that served the request, e.g. gemini_api or vertex_ai (Gemini only)
+	RequestID       string    `json:"request_id,omitempty"` // X-Request-Id sent with the run (with -request-ids only)
+	ValidJSON       *bool     `json:"valid_json,omitempty"` // Whether a structured-output (response_format) answer parsed as JSON; nil otherwise
+	Chunks          []ChunkArrival `json:"chunks,omitempty"` // Content chunk arrivals (with -trace-dir only)
 	
 	// Error information
 	Error           error     `json:"error,omitempty"`
 	Success         bool      `json:"success"`
+	Attempts        int       `json:"attempts"` // Requests made for this result, including retries
+	LastRetryError  string    `json:"last_retry_error,omitempty"` // Error that prompted the last retry, if any
 }
 
 // ToBenchmarkResult converts metrics to a BenchmarkResult
 func (m *Metrics) ToBenchmarkResult(provider, model, promptFile string) BenchmarkResult {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+
+	// The native count is only recorded alongside a normalized one
+	var nativeOutputTokens int
+	if m.NormalizeTokens {
+		nativeOutputTokens = m.OutputTokens
+	}
 	
 	return BenchmarkResult{
 		Provider:        provider,
@@ -152,12 +414,32 @@ func (m *Metrics) ToBenchmarkResult(provider, model, promptFile string) Benchmar
 		EndTime:         m.EndTime,
 		TTFT:            m.TTFT,
 		TotalTime:       m.TotalTime,
+		TimeToToolCall:  m.TimeToToolCall,
+		TimeToReasoning: m.TimeToReasoning,
+		TimeToAnswer:    m.TimeToAnswer,
+		TimeToLastToken: m.TimeToLastToken,
+		DNSLookup:       m.Network.DNSLookup,
+		Connect:         m.Network.Connect,
+		TLSHandshake:    m.Network.TLSHandshake,
+		TimeToFirstByte: m.Network.TimeToFirstByte,
+		RateLimit:       m.RateLimit,
 		InputTokens:     m.InputTokens,
+		CachedInputTokens: m.CachedInputTokens,
 		OutputTokens:    m.OutputTokens,
+		ReasoningTokens: m.ReasoningTokens,
+		NativeOutputTokens:     nativeOutputTokens,
+		NormalizedOutputTokens: m.NormalizedOutputTokens,
 		TotalTokens:     m.TotalTokens,
 		TokensPerSecond: m.TokensPerSecond,
 		Cost:            m.Cost,
 		Response:        m.Response,
+		FinishReason:    m.FinishReason,
+		Truncated:       m.Truncated,
+		ToolCall:        m.ToolCall,
+		Citations:       m.Citations,
+		ResponseModel:   m.ResponseModel,
+		SystemFingerprint: m.SystemFingerprint,
+		Chunks:          m.Chunks,
 		Error:           m.Error,
 		Success:         m.Success,
 	}
@@ -168,73 +450,297 @@ type Summary struct {
 	TotalRuns       int
 	SuccessfulRuns  int
 	FailedRuns      int
+	TruncatedRuns   int // Successful runs cut off by the token limit
+	EmptyRuns       int // Successful runs whose answer was empty or whitespace
 	
 	// Timing statistics
 	AvgTTFT         time.Duration
 	AvgTotalTime    time.Duration
 	MinTTFT         time.Duration
 	MaxTTFT         time.Duration
+	P50TTFT         time.Duration
 	P95TTFT         time.Duration
 	P99TTFT         time.Duration
+	StdDevTTFT      time.Duration
+	CoeffVarTTFT    float64 // StdDevTTFT / AvgTTFT
+	MinTotalTime    time.Duration
+	MaxTotalTime    time.Duration
+	P50TotalTime    time.Duration
+	P95TotalTime    time.Duration
+	P99TotalTime    time.Duration
+	TTFTHistogram   []HistogramBucket // Successful runs by TTFT range
+	// TTFT and total time at each requested percentile (DefaultPercentiles
+	// unless overridden), keyed by percentile, e.g. 99.9
+	TTFTPercentiles      map[float64]time.Duration
+	TotalTimePercentiles map[float64]time.Duration
 	
 	// Token statistics
-	AvgTokensPerSecond float64
+	AvgTokensPerSecond float64 // Mean of successful, non-empty runs' TokensPerSecond
 	TotalInputTokens   int
 	TotalOutputTokens  int
+
+	// Throughput of the run as a whole: WallTime spans the earliest start to the
+	// latest end, so concurrent requests overlap in it. A Runner's summary spans
+	// RunStart to RunEnd instead, which also covers warm-up and the gaps between
+	// requests.
+	WallTime            time.Duration
+	AggregateThroughput float64 // TotalOutputTokens / WallTime, in tokens per second
+
+	// When Runner.Run started and returned; zero outside a Runner
+	RunStart time.Time
+	RunEnd   time.Time
 	
 	// Cost statistics
 	TotalCost         float64
 	AvgCostPerRun     float64
+	MinCostPerRun     float64
+	MaxCostPerRun     float64
+	// EffectiveCostPer1MTokens is TotalCost per million input and output tokens, for
+	// comparing models independent of response length
+	EffectiveCostPer1MTokens float64
 	
 	// Error rate
 	ErrorRate         float64
+	SuccessRate       float64 // SuccessfulRuns / TotalRuns
+	// TopErrorCategory is the providers.ErrorCategory most failed runs share, ""
+	// when no run failed with an error
+	TopErrorCategory  string
+
+	// HaltReason is set when the run stopped before all work was dispatched
+	HaltReason        string
+
+	// Fingerprints lists the distinct system fingerprints of each model (by
+	// ModelKey) that reported more than one, i.e. whose backend build changed mid-run
+	Fingerprints      map[string][]string
 }
 
 // CalculateSummary calculates summary statistics from a slice of results
 func CalculateSummary(results []BenchmarkResult) Summary {
+	return CalculateSummaryWithPercentiles(results, DefaultPercentiles)
+}
+
+// CalculateSummaryWithPercentiles calculates summary statistics like
+// CalculateSummary, reporting TTFT and total time at the given percentiles
+// (DefaultPercentiles when empty)
+func CalculateSummaryWithPercentiles(results []BenchmarkResult, percentiles []float64) Summary {
+	if len(percentiles) == 0 {
+		percentiles = DefaultPercentiles
+	}
 	if len(results) == 0 {
 		return Summary{}
 	}
 	
 	var summary Summary
 	var ttftDurations []time.Duration
+	var totalTimeDurations []time.Duration
 	var totalCost float64
+	var costs []float64
+	var totalTokensPerSecond float64
+	var throughputRuns int
+	var firstStart, lastEnd time.Time
+	errorCategories := make(map[string]int)
 	
 	for _, result := range results {
 		summary.TotalRuns++
+
+		if !result.StartTime.IsZero() && (firstStart.IsZero() || result.StartTime.Before(firstStart)) {
+			firstStart = result.StartTime
+		}
+		if result.EndTime.After(lastEnd) {
+			lastEnd = result.EndTime
+		}
 		
 		if result.Success {
 			summary.SuccessfulRuns++
 			ttftDurations = append(ttftDurations, result.TTFT)
+			totalTimeDurations = append(totalTimeDurations, result.TotalTime)
 			totalCost += result.Cost
+			costs = append(costs, result.Cost)
 			summary.TotalInputTokens += result.InputTokens
 			summary.TotalOutputTokens += result.OutputTokens
+			if result.EmptyResponse {
+				// Nothing was generated, so there's no throughput to measure
+				summary.EmptyRuns++
+			} else {
+				totalTokensPerSecond += result.TokensPerSecond
+				throughputRuns++
+			}
+			if result.Truncated {
+				summary.TruncatedRuns++
+			}
 		} else {
 			summary.FailedRuns++
+			if category := providers.ErrorCategory(result.Error); category != "" {
+				errorCategories[category]++
+			}
 		}
 	}
 	
 	// Calculate error rate
 	summary.ErrorRate = float64(summary.FailedRuns) / float64(summary.TotalRuns)
+	summary.SuccessRate = float64(summary.SuccessfulRuns) / float64(summary.TotalRuns)
+	summary.TopErrorCategory = mostCommon(errorCategories)
 	
 	// Calculate timing statistics
 	if len(ttftDurations) > 0 {
 		summary.AvgTTFT = calculateAverageDuration(ttftDurations)
 		summary.MinTTFT = calculateMinDuration(ttftDurations)
 		summary.MaxTTFT = calculateMaxDuration(ttftDurations)
+		summary.P50TTFT = calculatePercentileDuration(ttftDurations, 50)
 		summary.P95TTFT = calculatePercentileDuration(ttftDurations, 95)
 		summary.P99TTFT = calculatePercentileDuration(ttftDurations, 99)
+		summary.StdDevTTFT = calculateStdDevDuration(ttftDurations)
+		summary.TTFTHistogram = Histogram(ttftDurations, DefaultHistogramBuckets)
+		summary.TTFTPercentiles = calculatePercentiles(ttftDurations, percentiles)
+		if summary.AvgTTFT > 0 {
+			summary.CoeffVarTTFT = float64(summary.StdDevTTFT) / float64(summary.AvgTTFT)
+		}
+	}
+
+	if len(totalTimeDurations) > 0 {
+		summary.AvgTotalTime = calculateAverageDuration(totalTimeDurations)
+		summary.MinTotalTime = calculateMinDuration(totalTimeDurations)
+		summary.MaxTotalTime = calculateMaxDuration(totalTimeDurations)
+		summary.P50TotalTime = calculatePercentileDuration(totalTimeDurations, 50)
+		summary.P95TotalTime = calculatePercentileDuration(totalTimeDurations, 95)
+		summary.P99TotalTime = calculatePercentileDuration(totalTimeDurations, 99)
+		summary.TotalTimePercentiles = calculatePercentiles(totalTimeDurations, percentiles)
 	}
 	
+	// Calculate token throughput
+	if throughputRuns > 0 {
+		summary.AvgTokensPerSecond = totalTokensPerSecond / float64(throughputRuns)
+	}
+	if !firstStart.IsZero() && lastEnd.After(firstStart) {
+		summary.WallTime = lastEnd.Sub(firstStart)
+		summary.AggregateThroughput = float64(summary.TotalOutputTokens) / summary.WallTime.Seconds()
+	}
+
 	// Calculate cost statistics
 	summary.TotalCost = totalCost
 	if summary.SuccessfulRuns > 0 {
 		summary.AvgCostPerRun = totalCost / float64(summary.SuccessfulRuns)
+		summary.MinCostPerRun = slices.Min(costs)
+		summary.MaxCostPerRun = slices.Max(costs)
 	}
+	if totalTokens := summary.TotalInputTokens + summary.TotalOutputTokens; totalTokens > 0 {
+		summary.EffectiveCostPer1MTokens = totalCost / float64(totalTokens) * 1e6
+	}
+
+	summary.Fingerprints = changedFingerprints(results)
 	
 	return summary
 }
 
+// changedFingerprints returns the distinct system fingerprints, in order of first
+// appearance, of every model that reported more than one; nil when none did
+func changedFingerprints(results []BenchmarkResult) map[string][]string {
+	byModel := make(map[string][]string)
+	for _, result := range results {
+		if result.SystemFingerprint == "" {
+			continue
+		}
+		key := ModelKey(result.Provider, result.Model)
+		if !slices.Contains(byModel[key], result.SystemFingerprint) {
+			byModel[key] = append(byModel[key], result.SystemFingerprint)
+		}
+	}
+
+	var changed map[string][]string
+	for key, fingerprints := range byModel {
+		if len(fingerprints) > 1 {
+			if changed == nil {
+				changed = make(map[string][]string)
+			}
+			changed[key] = fingerprints
+		}
+	}
+	return changed
+}
+
+// mostCommon returns the key with the highest count, breaking ties alphabetically
+// so the result is stable; "" for an empty map
+func mostCommon(counts map[string]int) string {
+	var top string
+	for key, count := range counts {
+		if top == "" || count > counts[top] || (count == counts[top] && key < top) {
+			top = key
+		}
+	}
+	return top
+}
+
+// SortResults orders results by provider, model, prompt, concurrency level, run
+// index and sweep parameters, so concurrent runs, whose workers finish in no
+// particular order, write identically ordered output
+func SortResults(results []BenchmarkResult) {
+	slices.SortStableFunc(results, func(a, b BenchmarkResult) int {
+		return cmp.Or(
+			cmp.Compare(a.Provider, b.Provider),
+			cmp.Compare(a.Model, b.Model),
+			cmp.Compare(a.PromptFile, b.PromptFile),
+			cmp.Compare(a.Concurrency, b.Concurrency),
+			cmp.Compare(a.Run, b.Run),
+			cmp.Compare(a.Temperature, b.Temperature),
+			cmp.Compare(a.MaxTokens, b.MaxTokens),
+		)
+	})
+}
+
+// ModelKey identifies a model across providers, e.g. "groq/qwen/qwen3-32b"
+func ModelKey(provider, model string) string {
+	return provider + "/" + model
+}
+
+// CalculateSummaryByModel summarizes results per model, keyed by ModelKey so the
+// same model name served by different providers isn't merged
+func CalculateSummaryByModel(results []BenchmarkResult) map[string]Summary {
+	return CalculateSummaryByModelWithPercentiles(results, DefaultPercentiles)
+}
+
+// CalculateSummaryByModelWithPercentiles summarizes results per model like
+// CalculateSummaryByModel, reporting TTFT and total time at the given percentiles
+func CalculateSummaryByModelWithPercentiles(results []BenchmarkResult, percentiles []float64) map[string]Summary {
+	return calculateGroupedSummaries(results, percentiles, func(result BenchmarkResult) string {
+		return ModelKey(result.Provider, result.Model)
+	})
+}
+
+// CalculateSummaryByProvider summarizes results per provider
+func CalculateSummaryByProvider(results []BenchmarkResult) map[string]Summary {
+	return calculateGroupedSummaries(results, DefaultPercentiles, func(result BenchmarkResult) string {
+		return result.Provider
+	})
+}
+
+// CalculateSummaryByPrompt summarizes results per prompt file across all models,
+// showing which prompts are slow regardless of the model serving them
+func CalculateSummaryByPrompt(results []BenchmarkResult) map[string]Summary {
+	return CalculateSummaryByPromptWithPercentiles(results, DefaultPercentiles)
+}
+
+// CalculateSummaryByPromptWithPercentiles summarizes results per prompt like
+// CalculateSummaryByPrompt, reporting TTFT and total time at the given percentiles
+func CalculateSummaryByPromptWithPercentiles(results []BenchmarkResult, percentiles []float64) map[string]Summary {
+	return calculateGroupedSummaries(results, percentiles, func(result BenchmarkResult) string {
+		return result.PromptFile
+	})
+}
+
+func calculateGroupedSummaries(results []BenchmarkResult, percentiles []float64, key func(BenchmarkResult) string) map[string]Summary {
+	groups := make(map[string][]BenchmarkResult)
+	for _, result := range results {
+		k := key(result)
+		groups[k] = append(groups[k], result)
+	}
+
+	summaries := make(map[string]Summary, len(groups))
+	for k, group := range groups {
+		summaries[k] = CalculateSummaryWithPercentiles(group, percentiles)
+	}
+	return summaries
+}
+
 // Helper functions for duration calculations
 func calculateAverageDuration(durations []time.Duration) time.Duration {
 	if len(durations) == 0 {
@@ -276,12 +782,40 @@ func calculateMaxDuration(durations []time.Duration) time.Duration {
 	return max
 }
 
-func calculatePercentileDuration(durations []time.Duration, percentile int) time.Duration {
+// calculateStdDevDuration returns the population standard deviation of durations
+func calculateStdDevDuration(durations []time.Duration) time.Duration {
 	if len(durations) == 0 {
 		return 0
 	}
-	
-	// Sort durations (simplified - in production you'd want a proper sort)
-	// For now, just return the average as a placeholder
-	return calculateAverageDuration(durations)
+
+	mean := float64(calculateAverageDuration(durations))
+	var sumSquares float64
+	for _, d := range durations {
+		diff := float64(d) - mean
+		sumSquares += diff * diff
+	}
+	return time.Duration(math.Sqrt(sumSquares / float64(len(durations))))
+}
+
+// calculatePercentileDuration returns the nearest-rank percentile of durations
+func calculatePercentileDuration(durations []time.Duration, percentile float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	// Sort a copy so the caller's slice order is preserved
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	// Multiplying first keeps fractional percentiles exact: 99.9/100*1000 rounds
+	// up past 999
+	rank := int(math.Ceil(percentile * float64(len(sorted)) / 100))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
 } 
\ No newline at end of file
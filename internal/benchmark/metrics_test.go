@@ -1,294 +1,448 @@
 package benchmark
 
 import (
+	"math"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
-)
-
-func TestBenchmarkResult_CalculateCost(t *testing.T) {
-	tests := []struct {
-		name           string
-		result         BenchmarkResult
-		inputCostPer1K float64
-		outputCostPer1K float64
-		expectedCost   float64
-	}{
-		{
-			name: "basic cost calculation",
-			result: BenchmarkResult{
-				InputTokens:  1000,
-				OutputTokens: 500,
-			},
-			inputCostPer1K:  0.001,
-			outputCostPer1K: 0.002,
-			expectedCost:    0.002, // (1000 * 0.001) + (500 * 0.002) = 1 + 1 = 2
-		},
-		{
-			name: "zero tokens",
-			result: BenchmarkResult{
-				InputTokens:  0,
-				OutputTokens: 0,
-			},
-			inputCostPer1K:  0.001,
-			outputCostPer1K: 0.002,
-			expectedCost:    0.0,
-		},
-		{
-			name: "high token count",
-			result: BenchmarkResult{
-				InputTokens:  5000,
-				OutputTokens: 3000,
-			},
-			inputCostPer1K:  0.0001,
-			outputCostPer1K: 0.0002,
-			expectedCost:    0.0011, // (5000 * 0.0001) + (3000 * 0.0002) = 0.5 + 0.6 = 1.1
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			cost := tt.result.CalculateCost(tt.inputCostPer1K, tt.outputCostPer1K)
-			assert.InDelta(t, tt.expectedCost, cost, 0.0001, "Cost calculation mismatch")
-		})
-	}
-}
-
-func TestBenchmarkResult_TokensPerSecond(t *testing.T) {
-	tests := []struct {
-		name           string
-		result         BenchmarkResult
-		expectedTPS    float64
-	}{
-		{
-			name: "normal response time",
-			result: BenchmarkResult{
-				OutputTokens: 100,
-				TotalTime:    2 * time.Second,
-			},
-			expectedTPS: 50.0, // 100 tokens / 2 seconds
-		},
-		{
-			name: "fast response",
-			result: BenchmarkResult{
-				OutputTokens: 50,
-				TotalTime:    500 * time.Millisecond,
-			},
-			expectedTPS: 100.0, // 50 tokens / 0.5 seconds
-		},
-		{
-			name: "zero tokens",
-			result: BenchmarkResult{
-				OutputTokens: 0,
-				TotalTime:    1 * time.Second,
-			},
-			expectedTPS: 0.0,
-		},
-		{
-			name: "zero time",
-			result: BenchmarkResult{
-				OutputTokens: 100,
-				TotalTime:    0,
-			},
-			expectedTPS: 0.0,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			tps := tt.result.TokensPerSecond()
-			assert.InDelta(t, tt.expectedTPS, tps, 0.1, "Tokens per second calculation mismatch")
-		})
-	}
-}
-
-func TestBenchmarkResult_IsSuccessful(t *testing.T) {
-	tests := []struct {
-		name     string
-		result   BenchmarkResult
-		expected bool
-	}{
-		{
-			name: "successful result",
-			result: BenchmarkResult{
-				Error: nil,
-			},
-			expected: true,
-		},
-		{
-			name: "failed result",
-			result: BenchmarkResult{
-				Error: assert.AnError,
-			},
-			expected: false,
-		},
-	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			success := tt.result.IsSuccessful()
-			assert.Equal(t, tt.expected, success)
-		})
-	}
-}
+	"github.com/megzo/llm-latency-benchmark/providers"
+)
 
-func TestBenchmarkSummary_CalculateStatistics(t *testing.T) {
+func TestCalculateSummary_Statistics(t *testing.T) {
 	results := []BenchmarkResult{
 		{
 			Model:        "gpt-4o-mini",
-			PromptName:   "test1",
+			PromptFile:   "test1",
 			TTFT:         1 * time.Second,
 			TotalTime:    5 * time.Second,
 			InputTokens:  100,
 			OutputTokens: 200,
 			Cost:         0.001,
-			Error:        nil,
+			Success:      true,
 		},
 		{
 			Model:        "gpt-4o-mini",
-			PromptName:   "test2",
+			PromptFile:   "test2",
 			TTFT:         2 * time.Second,
 			TotalTime:    6 * time.Second,
 			InputTokens:  150,
 			OutputTokens: 250,
 			Cost:         0.002,
-			Error:        nil,
+			Success:      true,
 		},
 		{
-			Model:        "gpt-4o-mini",
-			PromptName:   "test3",
-			TTFT:         3 * time.Second,
-			TotalTime:    7 * time.Second,
-			InputTokens:  200,
-			OutputTokens: 300,
-			Cost:         0.003,
-			Error:        assert.AnError, // This one should fail
+			Model:      "gpt-4o-mini",
+			PromptFile: "test3",
+			Error:      assert.AnError, // This one failed
 		},
 	}
 
-	summary := NewBenchmarkSummary()
-	for _, result := range results {
-		summary.AddResult(result)
-	}
+	summary := CalculateSummary(results)
 
 	// Test basic statistics
 	assert.Equal(t, 3, summary.TotalRuns)
 	assert.Equal(t, 2, summary.SuccessfulRuns)
 	assert.Equal(t, 1, summary.FailedRuns)
-	assert.InDelta(t, 33.33, summary.ErrorRate, 0.1)
+	assert.InDelta(t, 1.0/3, summary.ErrorRate, 0.001)
 
-	// Test timing statistics
-	assert.InDelta(t, 2.0, summary.AverageTTFT.Seconds(), 0.1) // (1+2+3)/3 = 2
-	assert.InDelta(t, 6.0, summary.AverageTotalTime.Seconds(), 0.1) // (5+6+7)/3 = 6
+	// Timing statistics cover successful runs only
+	assert.Equal(t, 1500*time.Millisecond, summary.AvgTTFT)
+	assert.Equal(t, 5500*time.Millisecond, summary.AvgTotalTime)
 
 	// Test cost statistics
-	assert.InDelta(t, 0.002, summary.TotalCost, 0.0001) // Only successful runs count
-	assert.InDelta(t, 0.001, summary.AverageCost, 0.0001) // 0.002 / 2 successful runs
+	assert.InDelta(t, 0.003, summary.TotalCost, 1e-9)
+	assert.InDelta(t, 0.0015, summary.AvgCostPerRun, 1e-9)
 
 	// Test token statistics
-	assert.Equal(t, 250, summary.TotalInputTokens) // 100+150 (failed run doesn't count)
-	assert.Equal(t, 450, summary.TotalOutputTokens) // 200+250
+	assert.Equal(t, 250, summary.TotalInputTokens)
+	assert.Equal(t, 450, summary.TotalOutputTokens)
 }
 
-func TestBenchmarkSummary_EmptyResults(t *testing.T) {
-	summary := NewBenchmarkSummary()
+func TestCalculateSummary_EmptyResults(t *testing.T) {
+	summary := CalculateSummary(nil)
 
 	assert.Equal(t, 0, summary.TotalRuns)
 	assert.Equal(t, 0, summary.SuccessfulRuns)
 	assert.Equal(t, 0, summary.FailedRuns)
 	assert.Equal(t, 0.0, summary.ErrorRate)
 	assert.Equal(t, 0.0, summary.TotalCost)
-	assert.Equal(t, 0.0, summary.AverageCost)
+	assert.Equal(t, 0.0, summary.AvgCostPerRun)
 	assert.Equal(t, 0, summary.TotalInputTokens)
 	assert.Equal(t, 0, summary.TotalOutputTokens)
 }
 
-func TestBenchmarkSummary_AllFailed(t *testing.T) {
+func TestCalculateSummary_AllFailed(t *testing.T) {
 	results := []BenchmarkResult{
-		{
-			Model:      "gpt-4o-mini",
-			PromptName: "test1",
-			Error:      assert.AnError,
-		},
-		{
-			Model:      "gpt-4o-mini",
-			PromptName: "test2",
-			Error:      assert.AnError,
-		},
+		{Model: "gpt-4o-mini", PromptFile: "test1", Error: assert.AnError},
+		{Model: "gpt-4o-mini", PromptFile: "test2", Error: assert.AnError},
 	}
 
-	summary := NewBenchmarkSummary()
-	for _, result := range results {
-		summary.AddResult(result)
-	}
+	summary := CalculateSummary(results)
 
 	assert.Equal(t, 2, summary.TotalRuns)
 	assert.Equal(t, 0, summary.SuccessfulRuns)
 	assert.Equal(t, 2, summary.FailedRuns)
-	assert.Equal(t, 100.0, summary.ErrorRate)
+	assert.Equal(t, 1.0, summary.ErrorRate)
 	assert.Equal(t, 0.0, summary.TotalCost)
-	assert.Equal(t, 0.0, summary.AverageCost)
+	assert.Equal(t, 0.0, summary.AvgCostPerRun)
+	assert.Zero(t, summary.AvgTTFT)
 }
 
-func TestBenchmarkSummary_Percentiles(t *testing.T) {
+func TestCalculateSummary_Percentiles(t *testing.T) {
 	// Create results with known values for percentile testing
 	results := []BenchmarkResult{
-		{TTFT: 1 * time.Second, TotalTime: 5 * time.Second},
-		{TTFT: 2 * time.Second, TotalTime: 6 * time.Second},
-		{TTFT: 3 * time.Second, TotalTime: 7 * time.Second},
-		{TTFT: 4 * time.Second, TotalTime: 8 * time.Second},
-		{TTFT: 5 * time.Second, TotalTime: 9 * time.Second},
+		{TTFT: 1 * time.Second, TotalTime: 5 * time.Second, Success: true},
+		{TTFT: 2 * time.Second, TotalTime: 6 * time.Second, Success: true},
+		{TTFT: 3 * time.Second, TotalTime: 7 * time.Second, Success: true},
+		{TTFT: 4 * time.Second, TotalTime: 8 * time.Second, Success: true},
+		{TTFT: 5 * time.Second, TotalTime: 9 * time.Second, Success: true},
 	}
 
-	summary := NewBenchmarkSummary()
-	for _, result := range results {
-		summary.AddResult(result)
-	}
+	summary := CalculateSummary(results)
 
-	// Test percentiles (these should be calculated from successful runs only)
 	// For 5 values: p50 = 3rd value, p95 = 5th value, p99 = 5th value
-	assert.InDelta(t, 3.0, summary.TTFTPercentiles[50].Seconds(), 0.1)
-	assert.InDelta(t, 5.0, summary.TTFTPercentiles[95].Seconds(), 0.1)
-	assert.InDelta(t, 5.0, summary.TTFTPercentiles[99].Seconds(), 0.1)
+	assert.Equal(t, 3*time.Second, summary.P50TTFT)
+	assert.Equal(t, 5*time.Second, summary.P95TTFT)
+	assert.Equal(t, 5*time.Second, summary.P99TTFT)
 
-	assert.InDelta(t, 7.0, summary.TotalTimePercentiles[50].Seconds(), 0.1)
-	assert.InDelta(t, 9.0, summary.TotalTimePercentiles[95].Seconds(), 0.1)
-	assert.InDelta(t, 9.0, summary.TotalTimePercentiles[99].Seconds(), 0.1)
+	assert.Equal(t, 7*time.Second, summary.P50TotalTime)
+	assert.Equal(t, 9*time.Second, summary.P95TotalTime)
+	assert.Equal(t, 9*time.Second, summary.P99TotalTime)
 }
 
-func TestBenchmarkResult_String(t *testing.T) {
-	result := BenchmarkResult{
-		Model:        "gpt-4o-mini",
-		PromptName:   "test-prompt",
-		TTFT:         1 * time.Second,
-		TotalTime:    5 * time.Second,
-		InputTokens:  100,
-		OutputTokens: 200,
-		Cost:         0.001,
-		Error:        nil,
+func TestCalculateSummary_TotalTimeDistribution(t *testing.T) {
+	results := []BenchmarkResult{
+		{Success: true, TotalTime: 400 * time.Millisecond},
+		{Success: true, TotalTime: 100 * time.Millisecond},
+		{Success: true, TotalTime: 300 * time.Millisecond},
+		{Success: true, TotalTime: 500 * time.Millisecond},
+		{Success: true, TotalTime: 200 * time.Millisecond},
+		{Success: false, TotalTime: 10 * time.Second}, // Failed runs are ignored
 	}
 
-	str := result.String()
-	assert.Contains(t, str, "gpt-4o-mini")
-	assert.Contains(t, str, "test-prompt")
-	assert.Contains(t, str, "1s") // TTFT
-	assert.Contains(t, str, "5s") // Total time
-	assert.Contains(t, str, "100") // Input tokens
-	assert.Contains(t, str, "200") // Output tokens
-	assert.Contains(t, str, "0.001") // Cost
+	summary := CalculateSummary(results)
+
+	assert.Equal(t, 100*time.Millisecond, summary.MinTotalTime)
+	assert.Equal(t, 500*time.Millisecond, summary.MaxTotalTime)
+	assert.Equal(t, 300*time.Millisecond, summary.AvgTotalTime)
+	assert.Equal(t, 300*time.Millisecond, summary.P50TotalTime)
+	assert.Equal(t, 500*time.Millisecond, summary.P95TotalTime)
+	assert.Equal(t, 500*time.Millisecond, summary.P99TotalTime)
 }
 
-func TestBenchmarkResult_StringWithError(t *testing.T) {
-	result := BenchmarkResult{
-		Model:        "gpt-4o-mini",
-		PromptName:   "test-prompt",
-		Error:        assert.AnError,
+func TestCalculateSummary_TTFTVariance(t *testing.T) {
+	// Values 2,4,4,4,5,5,7,9 (x100ms) have mean 5 and population stddev 2
+	var results []BenchmarkResult
+	for _, v := range []int{2, 4, 4, 4, 5, 5, 7, 9} {
+		results = append(results, BenchmarkResult{
+			Success: true,
+			TTFT:    time.Duration(v) * 100 * time.Millisecond,
+		})
+	}
+
+	summary := CalculateSummary(results)
+
+	assert.Equal(t, 500*time.Millisecond, summary.AvgTTFT)
+	assert.InDelta(t, float64(200*time.Millisecond), float64(summary.StdDevTTFT), float64(time.Microsecond))
+	assert.InDelta(t, 0.4, summary.CoeffVarTTFT, 0.001)
+}
+
+func TestCalculateSummary_Throughput(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	// Three overlapping requests spanning 4s of wall-clock time, as with -concurrent 3
+	results := []BenchmarkResult{
+		{Success: true, OutputTokens: 200, TokensPerSecond: 100, StartTime: start, EndTime: start.Add(2 * time.Second)},
+		{Success: true, OutputTokens: 300, TokensPerSecond: 50, StartTime: start.Add(time.Second), EndTime: start.Add(4 * time.Second)},
+		{Success: true, OutputTokens: 300, TokensPerSecond: 150, StartTime: start, EndTime: start.Add(3 * time.Second)},
+		{Success: false, TokensPerSecond: 999, StartTime: start, EndTime: start.Add(time.Second)}, // Failed runs don't count
+	}
+
+	summary := CalculateSummary(results)
+
+	assert.InDelta(t, 100, summary.AvgTokensPerSecond, 0.001)
+	assert.Equal(t, 4*time.Second, summary.WallTime)
+	assert.InDelta(t, 800.0/4, summary.AggregateThroughput, 0.001)
+}
+
+func TestCalculateSummary_EmptyResponses(t *testing.T) {
+	results := []BenchmarkResult{
+		{Success: true, TokensPerSecond: 80},
+		{Success: true, TokensPerSecond: 40},
+		{Success: true, EmptyResponse: true, TokensPerSecond: 0}, // Nothing generated, so no throughput
 	}
 
-	str := result.String()
-	assert.Contains(t, str, "gpt-4o-mini")
-	assert.Contains(t, str, "test-prompt")
-	assert.Contains(t, str, "ERROR")
-	assert.Contains(t, str, assert.AnError.Error())
-} 
\ No newline at end of file
+	summary := CalculateSummary(results)
+
+	assert.Equal(t, 3, summary.SuccessfulRuns)
+	assert.Equal(t, 1, summary.EmptyRuns)
+	assert.InDelta(t, 60, summary.AvgTokensPerSecond, 0.001)
+}
+
+func TestCalculateSummary_CostStatistics(t *testing.T) {
+	results := []BenchmarkResult{
+		{Success: true, Cost: 0.002, InputTokens: 100, OutputTokens: 400},
+		{Success: true, Cost: 0.0005, InputTokens: 100, OutputTokens: 50},
+		{Success: true, Cost: 0.0035, InputTokens: 200, OutputTokens: 650},
+		{Success: false, Cost: 0.01, InputTokens: 5000}, // Failed runs don't count
+	}
+
+	summary := CalculateSummary(results)
+
+	assert.InDelta(t, 0.006, summary.TotalCost, 1e-12)
+	assert.InDelta(t, 0.002, summary.AvgCostPerRun, 1e-12)
+	assert.InDelta(t, 0.0005, summary.MinCostPerRun, 1e-12)
+	assert.InDelta(t, 0.0035, summary.MaxCostPerRun, 1e-12)
+	// $0.006 over 1,500 tokens
+	assert.InDelta(t, 4.0, summary.EffectiveCostPer1MTokens, 1e-9)
+}
+
+func TestCalculateSummary_FingerprintChanges(t *testing.T) {
+	results := []BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1", Success: true, SystemFingerprint: "fp_a"},
+		{Provider: "openai", Model: "gpt-4.1", Success: true, SystemFingerprint: "fp_b"},
+		{Provider: "openai", Model: "gpt-4.1", Success: true, SystemFingerprint: "fp_a"},
+		{Provider: "openai", Model: "gpt-4.1-mini", Success: true, SystemFingerprint: "fp_c"},
+		{Provider: "openai", Model: "gpt-4.1-mini", Success: true, SystemFingerprint: "fp_c"},
+		{Provider: "anthropic", Model: "claude-sonnet-4-0", Success: true}, // No fingerprint reported
+	}
+
+	summary := CalculateSummary(results)
+	assert.Equal(t, map[string][]string{"openai/gpt-4.1": {"fp_a", "fp_b"}}, summary.Fingerprints)
+
+	assert.Nil(t, CalculateSummary(results[3:]).Fingerprints, "a single fingerprint per model is not flagged")
+}
+
+func TestCalculateSummaryByModelAndProvider(t *testing.T) {
+	// "shared-model" is served by both providers and must not be merged
+	results := []BenchmarkResult{
+		{Provider: "openai", Model: "shared-model", Success: true, TTFT: 100 * time.Millisecond, Cost: 0.01},
+		{Provider: "openai", Model: "shared-model", Success: true, TTFT: 300 * time.Millisecond, Cost: 0.01},
+		{Provider: "openai", Model: "gpt-4.1", Success: false},
+		{Provider: "groq", Model: "shared-model", Success: true, TTFT: 50 * time.Millisecond, Cost: 0.002},
+		{Provider: "groq", Model: "qwen/qwen3-32b", Success: true, TTFT: 70 * time.Millisecond, Cost: 0.003},
+	}
+
+	byModel := CalculateSummaryByModel(results)
+	assert.Len(t, byModel, 4)
+
+	openaiShared := byModel[ModelKey("openai", "shared-model")]
+	assert.Equal(t, 2, openaiShared.TotalRuns)
+	assert.Equal(t, 200*time.Millisecond, openaiShared.AvgTTFT)
+	assert.InDelta(t, 0.02, openaiShared.TotalCost, 1e-9)
+
+	groqShared := byModel[ModelKey("groq", "shared-model")]
+	assert.Equal(t, 1, groqShared.TotalRuns)
+	assert.Equal(t, 50*time.Millisecond, groqShared.AvgTTFT)
+
+	failed := byModel["openai/gpt-4.1"]
+	assert.Equal(t, 1, failed.FailedRuns)
+	assert.Equal(t, 1.0, failed.ErrorRate)
+
+	assert.Equal(t, 1, byModel["groq/qwen/qwen3-32b"].SuccessfulRuns)
+
+	byProvider := CalculateSummaryByProvider(results)
+	assert.Len(t, byProvider, 2)
+	assert.Equal(t, 3, byProvider["openai"].TotalRuns)
+	assert.Equal(t, 1, byProvider["openai"].FailedRuns)
+	assert.InDelta(t, 0.02, byProvider["openai"].TotalCost, 1e-9)
+	assert.Equal(t, 2, byProvider["groq"].TotalRuns)
+	assert.Equal(t, 60*time.Millisecond, byProvider["groq"].AvgTTFT)
+	assert.InDelta(t, 0.005, byProvider["groq"].TotalCost, 1e-9)
+}
+
+func TestCalculateSummaryByModel_SuccessRate(t *testing.T) {
+	results := []BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1", Success: true},
+		{Provider: "openai", Model: "gpt-4.1", Success: true},
+		{Provider: "groq", Model: "qwen/qwen3-32b", Success: true},
+		{Provider: "groq", Model: "qwen/qwen3-32b", Success: false, Error: &providers.RateLimitError{Provider: "groq"}},
+		{Provider: "groq", Model: "qwen/qwen3-32b", Success: true},
+		{Provider: "groq", Model: "qwen/qwen3-32b", Success: false, Error: &providers.RateLimitError{Provider: "groq"}},
+		{Provider: "groq", Model: "qwen/qwen3-32b", Success: false, Error: &providers.ServerError{Provider: "groq", StatusCode: 503}},
+		{Provider: "groq", Model: "qwen/qwen3-32b", Success: true},
+	}
+
+	byModel := CalculateSummaryByModel(results)
+
+	healthy := byModel["openai/gpt-4.1"]
+	assert.Equal(t, 1.0, healthy.SuccessRate)
+	assert.Empty(t, healthy.TopErrorCategory)
+
+	flaky := byModel["groq/qwen/qwen3-32b"]
+	assert.Equal(t, 0.5, flaky.SuccessRate)
+	assert.Equal(t, 0.5, flaky.ErrorRate)
+	assert.Equal(t, providers.ErrorCategoryRateLimit, flaky.TopErrorCategory)
+
+	overall := CalculateSummary(results)
+	assert.InDelta(t, 5.0/8, overall.SuccessRate, 1e-9)
+}
+
+func TestCalculateSummaryByPrompt(t *testing.T) {
+	results := []BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "short", Success: true, TTFT: 100 * time.Millisecond, Cost: 0.001},
+		{Provider: "groq", Model: "llama-3.1-8b-instant", PromptFile: "short", Success: true, TTFT: 50 * time.Millisecond, Cost: 0.0001},
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "long-context", Success: true, TTFT: 900 * time.Millisecond, Cost: 0.01},
+		{Provider: "groq", Model: "llama-3.1-8b-instant", PromptFile: "long-context", Success: false},
+		{Provider: "groq", Model: "llama-3.1-8b-instant", PromptFile: "long-context", Success: true, TTFT: 700 * time.Millisecond, Cost: 0.002},
+		{Provider: "groq", Model: "llama-3.1-8b-instant", PromptFile: "long-context", Success: false},
+	}
+
+	byPrompt := CalculateSummaryByPrompt(results)
+	assert.Len(t, byPrompt, 2)
+
+	short := byPrompt["short"]
+	assert.Equal(t, 2, short.TotalRuns)
+	assert.Equal(t, 0.0, short.ErrorRate)
+	assert.Equal(t, 75*time.Millisecond, short.AvgTTFT)
+	assert.InDelta(t, 0.0011, short.TotalCost, 1e-9)
+
+	long := byPrompt["long-context"]
+	assert.Equal(t, 4, long.TotalRuns)
+	assert.Equal(t, 2, long.FailedRuns)
+	assert.Equal(t, 0.5, long.ErrorRate)
+	assert.Equal(t, 800*time.Millisecond, long.AvgTTFT)
+	assert.InDelta(t, 0.012, long.TotalCost, 1e-9)
+}
+
+func TestCalculateSummaryByModelWithPercentiles(t *testing.T) {
+	var results []BenchmarkResult
+	for i := 1; i <= 10; i++ {
+		results = append(results,
+			BenchmarkResult{Provider: "openai", Model: "gpt-4.1", Success: true, TTFT: time.Duration(i) * 100 * time.Millisecond, TotalTime: time.Duration(i) * time.Second},
+			BenchmarkResult{Provider: "groq", Model: "llama-3.1-8b-instant", Success: true, TTFT: time.Duration(i) * 10 * time.Millisecond, TotalTime: time.Duration(i) * 100 * time.Millisecond},
+		)
+	}
+
+	byModel := CalculateSummaryByModelWithPercentiles(results, []float64{50, 90, 99.9})
+	assert.Len(t, byModel, 2)
+
+	// Every per-model summary carries the requested quantiles, not the defaults
+	for key, summary := range byModel {
+		for _, distribution := range []map[float64]time.Duration{summary.TTFTPercentiles, summary.TotalTimePercentiles} {
+			assert.Len(t, distribution, 3, key)
+			assert.Contains(t, distribution, 50.0, key)
+			assert.Contains(t, distribution, 90.0, key)
+			assert.Contains(t, distribution, 99.9, key)
+			assert.NotContains(t, distribution, 95.0, key)
+		}
+	}
+
+	gpt := byModel[ModelKey("openai", "gpt-4.1")]
+	assert.Equal(t, 900*time.Millisecond, gpt.TTFTPercentiles[90])
+	assert.Equal(t, 10*time.Second, gpt.TotalTimePercentiles[99.9])
+}
+
+func TestMetrics_TimeToLastToken(t *testing.T) {
+	start := time.Now().Add(-3 * time.Second)
+	m := &Metrics{
+		StartTime:      start,
+		FirstTokenTime: start.Add(500 * time.Millisecond),
+		LastTokenTime:  start.Add(2500 * time.Millisecond),
+	}
+	m.AddTokens(20, 100)
+	m.Complete()
+
+	// The completion event arrives after the last token, so TTLT is shorter than total time
+	assert.Equal(t, 2500*time.Millisecond, m.TimeToLastToken)
+	assert.Less(t, m.TimeToLastToken, m.TotalTime)
+	// Tokens/sec covers the 2s between the first and last tokens, not the 3s total
+	assert.InDelta(t, 50, m.TokensPerSecond, 0.01)
+	assert.Equal(t, m.TimeToLastToken, m.ToBenchmarkResult("openai", "gpt-4.1", "simple").TimeToLastToken)
+
+	// Recorded as chunks arrive, the last one sets the time
+	streamed := NewMetrics()
+	streamed.RecordAnswerToken()
+	time.Sleep(10 * time.Millisecond)
+	streamed.RecordAnswerToken()
+	last := streamed.LastTokenTime
+	time.Sleep(10 * time.Millisecond)
+	streamed.AddTokens(5, 10)
+	streamed.Complete()
+	assert.Equal(t, last.Sub(streamed.StartTime), streamed.TimeToLastToken)
+	assert.Less(t, streamed.TimeToLastToken, streamed.TotalTime)
+	assert.InDelta(t, 10/last.Sub(streamed.FirstTokenTime).Seconds(), streamed.TokensPerSecond, 1e-6)
+}
+
+func TestMetrics_TotalTimeToLastToken(t *testing.T) {
+	start := time.Now().Add(-3 * time.Second)
+	newMetrics := func(toLastToken bool) *Metrics {
+		m := &Metrics{
+			StartTime:            start,
+			FirstTokenTime:       start.Add(500 * time.Millisecond),
+			LastTokenTime:        start.Add(2500 * time.Millisecond),
+			TotalTimeToLastToken: toLastToken,
+		}
+		m.AddTokens(20, 100)
+		m.Complete()
+		return m
+	}
+
+	// By default total time runs to the stream close, past the last token
+	completion := newMetrics(false)
+	assert.Equal(t, completion.EndTime.Sub(start), completion.TotalTime)
+	assert.Greater(t, completion.TotalTime, completion.TimeToLastToken)
+
+	// Measured to the last token, it stops there while EndTime keeps the close
+	lastToken := newMetrics(true)
+	assert.Equal(t, 2500*time.Millisecond, lastToken.TotalTime)
+	assert.Equal(t, lastToken.TimeToLastToken, lastToken.TotalTime)
+	assert.Greater(t, lastToken.EndTime.Sub(start), lastToken.TotalTime)
+	assert.Less(t, lastToken.TotalTime, completion.TotalTime)
+	// Tokens/sec is measured over the same streaming window either way
+	assert.InDelta(t, completion.TokensPerSecond, lastToken.TokensPerSecond, 1e-9)
+
+	// Without a streamed token there is nothing to stop at, so the close is used
+	unstreamed := &Metrics{StartTime: start, TotalTimeToLastToken: true}
+	unstreamed.Complete()
+	assert.Equal(t, unstreamed.EndTime.Sub(start), unstreamed.TotalTime)
+}
+
+func TestMetrics_TokensPerSecondWithoutStreamingWindow(t *testing.T) {
+	// A single chunk has no first-to-last window, so throughput uses the total time
+	m := &Metrics{StartTime: time.Now().Add(-2 * time.Second)}
+	m.RecordAnswerToken()
+	m.AddTokens(10, 100)
+	m.Complete()
+	assert.InDelta(t, 100/m.TotalTime.Seconds(), m.TokensPerSecond, 1e-6)
+}
+
+func TestMetrics_TokensPerSecondSubMillisecondWindow(t *testing.T) {
+	// Chunks delivered together are microseconds apart; throughput falls back to the total time
+	start := time.Now().Add(-time.Second)
+	m := &Metrics{
+		StartTime:      start,
+		FirstTokenTime: start.Add(200 * time.Millisecond),
+		LastTokenTime:  start.Add(200*time.Millisecond + 50*time.Microsecond),
+	}
+	m.AddTokens(10, 500)
+	m.Complete()
+	assert.InDelta(t, 500/m.TotalTime.Seconds(), m.TokensPerSecond, 1e-6)
+
+	// A response completing as soon as it starts still reports a finite rate
+	instant := NewMetrics()
+	instant.RecordAnswerToken()
+	instant.AddTokens(10, 500)
+	instant.Complete()
+	assert.False(t, math.IsInf(instant.TokensPerSecond, 0) || math.IsNaN(instant.TokensPerSecond))
+}
+
+func TestMetrics_ReasoningTokensPerSecond(t *testing.T) {
+	newMetrics := func(visibleOnly bool) *Metrics {
+		m := &Metrics{StartTime: time.Now().Add(-2 * time.Second), VisibleTokensPerSecond: visibleOnly}
+		m.AddTokens(30, 450)
+		m.SetReasoningTokens(400)
+		m.Complete()
+		return m
+	}
+
+	// Reasoning tokens are billed as output, so they count by default
+	all := newMetrics(false)
+	assert.InDelta(t, 225, all.TokensPerSecond, 1)
+	assert.Equal(t, 480, all.TotalTokens)
+
+	visible := newMetrics(true)
+	assert.InDelta(t, 25, visible.TokensPerSecond, 0.1)
+	assert.Equal(t, 400, visible.ToBenchmarkResult("openai", "o4-mini", "simple").ReasoningTokens)
+}
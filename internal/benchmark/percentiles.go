@@ -0,0 +1,71 @@
+package benchmark
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPercentiles are the TTFT and total time quantiles summaries report in
+// TTFTPercentiles and TotalTimePercentiles unless overridden
+var DefaultPercentiles = []float64{95, 99}
+
+// ParsePercentiles parses comma-separated ascending percentiles such as
+// "50,90,99.9" (or "p50,p90,p99.9"), each above 0 and at most 100
+func ParsePercentiles(value string) ([]float64, error) {
+	var percentiles []float64
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimPrefix(strings.TrimSpace(part), "p")
+		if part == "" {
+			continue
+		}
+
+		percentile, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid percentile %q: %w", part, err)
+		}
+		if percentile <= 0 || percentile > 100 {
+			return nil, fmt.Errorf("percentile %q must be above 0 and at most 100", part)
+		}
+		if len(percentiles) > 0 && percentile <= percentiles[len(percentiles)-1] {
+			return nil, fmt.Errorf("percentiles must be ascending, got %v after %v", percentile, percentiles[len(percentiles)-1])
+		}
+		percentiles = append(percentiles, percentile)
+	}
+
+	if len(percentiles) == 0 {
+		return nil, fmt.Errorf("no percentiles given")
+	}
+	return percentiles, nil
+}
+
+// PercentileLabel names a percentile the way summaries print it: p95, p99.9
+func PercentileLabel(percentile float64) string {
+	return "p" + strconv.FormatFloat(percentile, 'f', -1, 64)
+}
+
+// SortedPercentiles returns the percentiles of a TTFTPercentiles or
+// TotalTimePercentiles map in ascending order
+func SortedPercentiles(values map[float64]time.Duration) []float64 {
+	percentiles := make([]float64, 0, len(values))
+	for percentile := range values {
+		percentiles = append(percentiles, percentile)
+	}
+	slices.Sort(percentiles)
+	return percentiles
+}
+
+// calculatePercentiles returns the nearest-rank value of durations at each
+// percentile, nil when there are no durations
+func calculatePercentiles(durations []time.Duration, percentiles []float64) map[float64]time.Duration {
+	if len(durations) == 0 || len(percentiles) == 0 {
+		return nil
+	}
+	values := make(map[float64]time.Duration, len(percentiles))
+	for _, percentile := range percentiles {
+		values[percentile] = calculatePercentileDuration(durations, percentile)
+	}
+	return values
+}
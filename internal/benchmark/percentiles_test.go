@@ -0,0 +1,68 @@
+package benchmark
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/megzo/llm-latency-benchmark/providers"
+)
+
+func TestCalculateSummaryWithPercentiles(t *testing.T) {
+	// TTFTs of 1ms to 1000ms, totals ten times those, plus a failure that's ignored
+	var results []BenchmarkResult
+	for ms := 1; ms <= 1000; ms++ {
+		results = append(results, BenchmarkResult{
+			Success:   true,
+			TTFT:      time.Duration(ms) * time.Millisecond,
+			TotalTime: time.Duration(ms) * 10 * time.Millisecond,
+		})
+	}
+	results = append(results, BenchmarkResult{Success: false, TTFT: time.Hour})
+
+	summary := CalculateSummaryWithPercentiles(results, []float64{90, 99.9})
+	assert.Equal(t, map[float64]time.Duration{90: 900 * time.Millisecond, 99.9: 999 * time.Millisecond}, summary.TTFTPercentiles)
+	assert.Equal(t, map[float64]time.Duration{90: 9 * time.Second, 99.9: 9990 * time.Millisecond}, summary.TotalTimePercentiles)
+	// The fixed percentiles are still there
+	assert.Equal(t, 950*time.Millisecond, summary.P95TTFT)
+	assert.Equal(t, 990*time.Millisecond, summary.P99TTFT)
+
+	// Without an override, and in CalculateSummary, the defaults are reported
+	defaults := CalculateSummary(results)
+	assert.Equal(t, []float64{95, 99}, SortedPercentiles(defaults.TTFTPercentiles))
+	assert.Equal(t, 990*time.Millisecond, defaults.TTFTPercentiles[99])
+	assert.Equal(t, defaults.TTFTPercentiles, CalculateSummaryWithPercentiles(results, nil).TTFTPercentiles)
+
+	assert.Nil(t, CalculateSummaryWithPercentiles([]BenchmarkResult{{Success: false}}, []float64{90}).TTFTPercentiles)
+}
+
+func TestParsePercentiles(t *testing.T) {
+	percentiles, err := ParsePercentiles("50, 90,p99.9,100")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{50, 90, 99.9, 100}, percentiles)
+
+	for _, value := range []string{"", "99,95", "95,95", "0", "-5", "100.1", "high"} {
+		_, err := ParsePercentiles(value)
+		assert.Error(t, err, value)
+	}
+
+	assert.Equal(t, "p99.9", PercentileLabel(99.9))
+	assert.Equal(t, "p50", PercentileLabel(50))
+}
+
+func TestRunner_CustomPercentiles(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Runs = 2
+	cfg.Percentiles = []float64{90, 99.9}
+
+	provider := &stubProvider{name: "openai", content: "Hello", delay: 5 * time.Millisecond, tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	_, summary, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []float64{90, 99.9}, SortedPercentiles(summary.TTFTPercentiles))
+	assert.Equal(t, summary.MaxTTFT, summary.TTFTPercentiles[99.9])
+}
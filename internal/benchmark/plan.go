@@ -0,0 +1,221 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/megzo/llm-latency-benchmark/internal/config"
+	"github.com/megzo/llm-latency-benchmark/providers"
+)
+
+// Plan describes the work a run would do, built without calling any provider API
+type Plan struct {
+	PromptFiles    int
+	Models         int
+	WorkItems      int // measured requests: prompts × models × runs × sweep variants × concurrency levels
+	WarmupRequests int
+	Skipped        int // requests that would be skipped for exceeding context_length
+
+	// Cost range over all requests, from input tokens only to every request
+	// producing max_tokens of output
+	MinCost float64
+	MaxCost float64
+
+	Problems []PlanProblem
+}
+
+// PlanProblem is a configuration error that would make requests fail
+type PlanProblem struct {
+	Provider string
+	Model    string
+	Prompt   string // empty for problems that affect every prompt
+	Err      error
+}
+
+func (p PlanProblem) String() string {
+	subject := ModelKey(p.Provider, p.Model)
+	switch {
+	case p.Provider == "":
+		subject = p.Model
+	case p.Model == "":
+		subject = p.Provider
+	}
+	if p.Prompt != "" {
+		subject += " [" + p.Prompt + "]"
+	}
+	return fmt.Sprintf("%s: %v", subject, p.Err)
+}
+
+// Valid reports whether the plan found no problems
+func (p Plan) Valid() bool {
+	return len(p.Problems) == 0
+}
+
+// Write prints the plan as a human-readable report
+func (p Plan) Write(w io.Writer) {
+	fmt.Fprintf(w, "Dry run plan:\n")
+	fmt.Fprintf(w, "  Prompt files: %d\n", p.PromptFiles)
+	fmt.Fprintf(w, "  Models: %d\n", p.Models)
+	fmt.Fprintf(w, "  Requests: %d\n", p.WorkItems)
+	if p.WarmupRequests > 0 {
+		fmt.Fprintf(w, "  Warm-up requests: %d\n", p.WarmupRequests)
+	}
+	if p.Skipped > 0 {
+		fmt.Fprintf(w, "  Skipped (exceed context_length): %d\n", p.Skipped)
+	}
+	fmt.Fprintf(w, "  Estimated cost: $%.6f - $%.6f\n", p.MinCost, p.MaxCost)
+
+	if p.Valid() {
+		fmt.Fprintf(w, "No problems found\n")
+		return
+	}
+	fmt.Fprintf(w, "Problems (%d):\n", len(p.Problems))
+	for _, problem := range p.Problems {
+		fmt.Fprintf(w, "  - %s\n", problem)
+	}
+}
+
+// PlannedBenchmark is one provider/model/prompt combination a run would execute
+type PlannedBenchmark struct {
+	Provider string
+	Model    string
+	Prompt   string
+	Requests int // measured requests: runs × sweep variants × concurrency levels
+}
+
+// WorkList resolves the combinations a run would execute after provider selection
+// and model filters, sorted by provider, model and prompt. Unlike Plan it only
+// loads prompts and models; nothing is validated or priced.
+func (r *Runner) WorkList() ([]PlannedBenchmark, error) {
+	promptFiles, err := r.loadPrompts()
+	if err != nil {
+		return nil, err
+	}
+	requests := r.config.Runs * len(r.variants()) * len(r.concurrencyLevels())
+
+	var list []PlannedBenchmark
+	for providerName := range r.providers {
+		models, err := r.listModels(providerName)
+		if err != nil {
+			r.logger.Warn("Failed to get models for provider", "provider", providerName, "error", err)
+			continue
+		}
+		for _, modelName := range models {
+			for _, promptFile := range promptFiles {
+				list = append(list, PlannedBenchmark{Provider: providerName, Model: modelName, Prompt: promptFile.Name, Requests: requests})
+			}
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		if a.Model != b.Model {
+			return a.Model < b.Model
+		}
+		return a.Prompt < b.Prompt
+	})
+	return list, nil
+}
+
+// Plan loads prompts and models and checks every request the run would send,
+// validating it against its provider and pricing it, without issuing network calls
+func (r *Runner) Plan() (Plan, error) {
+	promptFiles, err := r.loadPrompts()
+	if err != nil {
+		return Plan{}, err
+	}
+
+	plan := Plan{PromptFiles: len(promptFiles)}
+	variants := r.variants()
+	runs := r.config.Runs * len(r.concurrencyLevels())
+	embeddings := r.config.Mode == config.ModeEmbeddings
+
+	// Walk providers in a stable order so problems are reported deterministically
+	providerNames := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		providerNames = append(providerNames, name)
+	}
+	sort.Strings(providerNames)
+
+	for _, providerName := range providerNames {
+		provider := r.providers[providerName]
+		models, err := r.listModels(providerName)
+		if err != nil {
+			plan.Problems = append(plan.Problems, PlanProblem{Provider: providerName, Err: err})
+			continue
+		}
+		sort.Strings(models)
+
+		for _, modelName := range models {
+			plan.Models++
+
+			pricing, err := r.modelPricing(providerName, modelName)
+			if err == nil && pricing.Input == 0 && pricing.Output == 0 {
+				err = fmt.Errorf("no pricing configured")
+			}
+			if err != nil {
+				plan.Problems = append(plan.Problems, PlanProblem{Provider: providerName, Model: modelName, Err: err})
+				pricing = nil
+			}
+
+			for i, promptFile := range promptFiles {
+				for j, variant := range variants {
+					req := r.buildRequest(provider, modelName, promptFile, variant)
+					plan.WorkItems += runs
+
+					if validator, ok := provider.(providers.RequestValidator); ok && !embeddings {
+						if err := validator.ValidateRequest(req); err != nil {
+							plan.Problems = append(plan.Problems, PlanProblem{Provider: providerName, Model: modelName, Prompt: promptFile.Name, Err: err})
+							continue
+						}
+					}
+					if err := r.checkContextLength(provider, modelName, req); err != nil && !embeddings {
+						plan.Skipped += runs
+						continue
+					}
+					if pricing == nil {
+						continue
+					}
+
+					inputTokens := countTokens(provider, modelName, req.PromptText())
+					requests := runs
+					// Warm-up requests use the first prompt and are billed too
+					if i == 0 && j == 0 {
+						requests += r.config.Warmup
+						plan.WarmupRequests += r.config.Warmup
+					}
+					plan.MinCost += float64(requests) * pricing.CalculateCost(inputTokens, 0)
+					// Embeddings have no output tokens
+					outputTokens := req.MaxTokens
+					if embeddings {
+						outputTokens = 0
+					}
+					plan.MaxCost += float64(requests) * pricing.CalculateCost(inputTokens, outputTokens)
+				}
+			}
+		}
+	}
+
+	// Models named in the filter that aren't configured for any running provider
+	for _, name := range r.config.ModelFilter {
+		if !r.modelPlanned(name) {
+			plan.Problems = append(plan.Problems, PlanProblem{Model: name, Err: fmt.Errorf("model not configured for any available provider")})
+		}
+	}
+
+	return plan, nil
+}
+
+// modelPlanned reports whether any running provider has modelName configured
+func (r *Runner) modelPlanned(modelName string) bool {
+	for providerName := range r.providers {
+		if _, err := r.modelPricing(providerName, modelName); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package benchmark
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/megzo/llm-latency-benchmark/internal/config"
+	"github.com/megzo/llm-latency-benchmark/providers"
+)
+
+// validatingProvider rejects requests for one model and fails any real call
+type validatingProvider struct {
+	*stubProvider
+	rejectModel string
+	calls       int
+}
+
+func (v *validatingProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	v.calls++
+	return v.stubProvider.StreamChat(ctx, req)
+}
+
+func (v *validatingProvider) ValidateRequest(req providers.ChatRequest) error {
+	if req.Model == v.rejectModel {
+		return &providers.ValidationError{Field: "model", Message: "model not supported"}
+	}
+	return nil
+}
+
+func TestRunner_Plan(t *testing.T) {
+	cfg := newStubConfig(t, 2, "good-model", "rejected-model", "unpriced-model")
+	cfg.Models.OpenAI["unpriced-model"] = config.ModelSpec{}
+	cfg.Runs = 3
+	cfg.ModelFilter = []string{"good-model", "rejected-model", "unpriced-model", "missing-model"}
+
+	provider := &validatingProvider{stubProvider: &stubProvider{name: "openai", tokens: 100}, rejectModel: "rejected-model"}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	plan, err := runner.Plan()
+	require.NoError(t, err)
+	assert.Zero(t, provider.calls, "planning must not call the provider")
+
+	assert.Equal(t, 2, plan.PromptFiles)
+	assert.Equal(t, 3, plan.Models)
+	assert.Equal(t, 2*3*3, plan.WorkItems)
+
+	// Only good-model is priced and valid: 2 prompts × 3 runs × 100 input tokens at $1/M,
+	// plus up to 1000 output tokens each
+	assert.InDelta(t, 6*100/1e6, plan.MinCost, 1e-12)
+	assert.InDelta(t, 6*1100/1e6, plan.MaxCost, 1e-12)
+
+	require.False(t, plan.Valid())
+	var validationErr *providers.ValidationError
+	var problems []string
+	for _, problem := range plan.Problems {
+		problems = append(problems, problem.String())
+		if problem.Model == "rejected-model" {
+			assert.True(t, errors.As(problem.Err, &validationErr))
+		}
+	}
+	assert.Len(t, problems, 4) // rejected-model on both prompts, unpriced-model, missing-model
+	assert.Contains(t, problems, "openai/unpriced-model: no pricing configured")
+	assert.Contains(t, problems, "missing-model: model not configured for any available provider")
+
+	var out bytes.Buffer
+	plan.Write(&out)
+	assert.Contains(t, out.String(), "Requests: 18")
+	assert.Contains(t, out.String(), "Estimated cost: $0.000600 - $0.006600")
+	assert.Contains(t, out.String(), "Problems (4):")
+	assert.Contains(t, out.String(), "openai/rejected-model [prompt0]: ")
+}
+
+func TestRunner_PlanValid(t *testing.T) {
+	cfg := newStubConfig(t, 1, "good-model")
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": &stubProvider{name: "openai", tokens: 10}}, false)
+
+	plan, err := runner.Plan()
+	require.NoError(t, err)
+	assert.True(t, plan.Valid())
+
+	var out bytes.Buffer
+	plan.Write(&out)
+	assert.Contains(t, out.String(), "No problems found")
+}
+
+func TestRunner_WorkList(t *testing.T) {
+	cfg := newStubConfig(t, 2, "gpt-4.1-mini", "gpt-4.1-nano")
+	cfg.Models.Groq = map[string]config.ModelSpec{"qwen/qwen3-32b": {}, "llama-3.1-8b-instant": {}}
+	cfg.Models.Anthropic = map[string]config.ModelSpec{"claude-sonnet-4": {}}
+	cfg.Models.Sweep = config.SweepConfig{Temperatures: []float64{0, 1}}
+	cfg.ModelFilter = []string{"gpt-4.1-mini", "qwen/qwen3-32b", "claude-sonnet-4"}
+	cfg.Runs = 3
+
+	// Only OpenAI and Groq have keys, so Anthropic's model is never listed
+	openai := &validatingProvider{stubProvider: &stubProvider{name: "openai"}}
+	groq := &validatingProvider{stubProvider: &stubProvider{name: "groq"}}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": openai, "groq": groq}, false)
+
+	list, err := runner.WorkList()
+	require.NoError(t, err)
+	assert.Zero(t, openai.calls+groq.calls, "listing must not call the providers")
+
+	// 3 runs × 2 temperatures per combination
+	assert.Equal(t, []PlannedBenchmark{
+		{Provider: "groq", Model: "qwen/qwen3-32b", Prompt: "prompt0", Requests: 6},
+		{Provider: "groq", Model: "qwen/qwen3-32b", Prompt: "prompt1", Requests: 6},
+		{Provider: "openai", Model: "gpt-4.1-mini", Prompt: "prompt0", Requests: 6},
+		{Provider: "openai", Model: "gpt-4.1-mini", Prompt: "prompt1", Requests: 6},
+	}, list)
+}
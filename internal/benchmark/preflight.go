@@ -0,0 +1,92 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/megzo/llm-latency-benchmark/internal/config"
+	"github.com/megzo/llm-latency-benchmark/providers"
+)
+
+// PreflightResult is the outcome of pinging one provider before a run
+type PreflightResult struct {
+	Provider string
+	Model    string // The provider's first enabled model, which the ping was sent to
+	Latency  time.Duration
+	Err      error
+}
+
+// Preflight pings every provider with its first enabled model, in provider name
+// order, so a bad endpoint or key surfaces before any work is dispatched.
+// Providers without enabled models are skipped.
+func (r *Runner) Preflight(ctx context.Context) []PreflightResult {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var results []PreflightResult
+	for _, name := range names {
+		models, err := r.listModels(name)
+		if err != nil {
+			results = append(results, PreflightResult{Provider: name, Err: err})
+			continue
+		}
+		if len(models) == 0 {
+			continue
+		}
+
+		result := PreflightResult{Provider: name, Model: models[0]}
+		pingCtx, cancel := context.WithTimeout(ctx, r.config.RequestTimeoutFor(name))
+		start := time.Now()
+		result.Err = r.ping(pingCtx, r.providers[name], models[0])
+		result.Latency = time.Since(start)
+		cancel()
+		results = append(results, result)
+	}
+	return results
+}
+
+// ping sends the cheapest request the mode allows: a one-token chat completion,
+// or a one-word embedding in embeddings mode
+func (r *Runner) ping(ctx context.Context, provider providers.Provider, modelName string) error {
+	if r.config.Mode == config.ModeEmbeddings {
+		embedder, ok := provider.(providers.EmbeddingProvider)
+		if !ok {
+			return fmt.Errorf("provider %s does not support embeddings", provider.Name())
+		}
+		_, err := embedder.Embed(ctx, providers.EmbeddingRequest{Model: modelName, Input: []string{"ping"}})
+		return err
+	}
+	return providers.Ping(ctx, provider, modelName)
+}
+
+// PreflightFailed reports whether any provider failed its preflight ping
+func PreflightFailed(results []PreflightResult) bool {
+	for _, result := range results {
+		if result.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// WritePreflight prints one line per provider with its ping latency or error
+func WritePreflight(w io.Writer, results []PreflightResult) {
+	fmt.Fprintf(w, "Preflight:\n")
+	for _, result := range results {
+		subject := result.Provider
+		if result.Model != "" {
+			subject = ModelKey(result.Provider, result.Model)
+		}
+		if result.Err != nil {
+			fmt.Fprintf(w, "  %s: FAILED: %v\n", subject, result.Err)
+		} else {
+			fmt.Fprintf(w, "  %s: ok (%v)\n", subject, result.Latency.Round(time.Millisecond))
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package benchmark
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/megzo/llm-latency-benchmark/internal/config"
+	"github.com/megzo/llm-latency-benchmark/providers"
+)
+
+func TestRunner_Preflight(t *testing.T) {
+	cfg := newStubConfig(t, 1, "gpt-4.1", "gpt-4.1-mini")
+	cfg.Models.Groq = map[string]config.ModelSpec{"qwen/qwen3-32b": {}}
+	cfg.Models.Anthropic = map[string]config.ModelSpec{}
+
+	openai := &recordingProvider{stubProvider: &stubProvider{name: "openai", content: "p", tokens: 1}}
+	groq := &stubProvider{name: "groq", err: &providers.AuthError{Provider: "groq", Message: "invalid API key"}}
+	anthropic := &recordingProvider{stubProvider: &stubProvider{name: "anthropic"}}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": openai, "groq": groq, "anthropic": anthropic}, false)
+
+	results := runner.Preflight(context.Background())
+	require.Len(t, results, 2, "providers without models aren't pinged")
+	assert.Empty(t, anthropic.requests)
+
+	assert.Equal(t, "groq", results[0].Provider)
+	assert.Equal(t, "qwen/qwen3-32b", results[0].Model)
+	assert.ErrorAs(t, results[0].Err, new(*providers.AuthError))
+
+	assert.Equal(t, "openai", results[1].Provider)
+	assert.NoError(t, results[1].Err)
+	require.Len(t, openai.requests, 1, "one ping per provider")
+	assert.Equal(t, results[1].Model, openai.requests[0].Model)
+	assert.Equal(t, 1, openai.requests[0].MaxTokens)
+
+	assert.True(t, PreflightFailed(results))
+	assert.False(t, PreflightFailed(results[1:]))
+
+	var buf bytes.Buffer
+	WritePreflight(&buf, results)
+	assert.Contains(t, buf.String(), "groq/qwen/qwen3-32b: FAILED:")
+	assert.Contains(t, buf.String(), "openai/"+results[1].Model+": ok")
+
+	// Nothing is recorded as a benchmark result
+	assert.Empty(t, runner.GetResults())
+}
@@ -2,21 +2,88 @@ package benchmark
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"os"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/google/uuid"
 
 	"github.com/megzo/llm-latency-benchmark/internal/config"
+	"github.com/megzo/llm-latency-benchmark/internal/tokenizer"
 	"github.com/megzo/llm-latency-benchmark/providers"
 )
 
-// Runner handles the execution of benchmark tests
+// haltReasonCostBudget is recorded when the accumulated cost exceeds config.MaxCost
+const haltReasonCostBudget = "cost budget exceeded"
+
+// haltReasonMaxDuration is recorded when the run outlasts config.MaxDuration
+const haltReasonMaxDuration = "max duration reached"
+
+// resultsChanBuffer is how far a ResultsChan subscriber can fall behind before
+// results are dropped for it
+const resultsChanBuffer = 256
+
+// ErrContextLengthExceeded is recorded instead of sending a request whose prompt plus
+// max_tokens would overflow the model's configured context_length
+var ErrContextLengthExceeded = errors.New("prompt exceeds context length")
+
+// Runner handles the execution of benchmark tests. It has no CLI or stdout
+// dependencies so it can be embedded in other programs: logs go to the logger
+// set with SetLogger or SetLogOutput and results can be streamed with StreamResults
+// or ResultsChan.
 type Runner struct {
 	config     *config.Config
 	providers  map[string]providers.Provider
 	results    []BenchmarkResult
 	resultsMu  sync.RWMutex
 	verbose    bool
+	logger     *slog.Logger
+	resultsCh  chan<- BenchmarkResult
+	sink       ResultSink
+	sinkMu     sync.Mutex
+
+	// ResultsChan channels, closed when Run returns (guarded by resultsMu)
+	subscribers []chan BenchmarkResult
+
+	// Wall-clock bounds of Run (guarded by resultsMu)
+	runStart time.Time
+	runEnd   time.Time
+
+	// Budget tracking (guarded by resultsMu)
+	totalCost  float64
+	haltReason string
+	cancelRun  context.CancelFunc
+
+	// Per-provider concurrency caps in adaptive mode, created by runConcurrent
+	limiters   map[string]*aimdLimiter
+
+	// Worker count for the level being run; changes between levels of a concurrency sweep
+	concurrency int
+
+	// Numbers trace files in the order results are recorded (guarded by resultsMu)
+	traceSeq    int
+
+	// Recounts output tokens with -normalize-tokens, created on first use
+	reference     tokenizer.Tokenizer
+	referenceOnce sync.Once
+
+	// Exact prompt token counts from providers' count-tokens APIs, by model and prompt
+	inputTokenCounts   map[string]inputTokenCount
+	inputTokenCountsMu sync.Mutex
+}
+
+// inputTokenCount is a cached count-tokens result; ok is false when counting failed
+type inputTokenCount struct {
+	tokens int
+	ok     bool
 }
 
 // NewRunner creates a new benchmark runner
@@ -26,40 +93,310 @@ func NewRunner(cfg *config.Config, providers map[string]providers.Provider, verb
 		providers: providers,
 		results:   make([]BenchmarkResult, 0),
 		verbose:   verbose,
+		logger:    slog.New(slog.NewTextHandler(os.Stderr, nil)),
 	}
 }
 
+// SetLogOutput directs warnings and verbose progress to w as text records;
+// io.Discard silences them
+func (r *Runner) SetLogOutput(w io.Writer) {
+	r.logger = slog.New(slog.NewTextHandler(w, nil))
+}
+
+// SetLogger sends warnings (level warn) and verbose progress (level info) to logger
+func (r *Runner) SetLogger(logger *slog.Logger) {
+	r.logger = logger
+}
+
+// SetReferenceTokenizer replaces the tokenizer that recounts every response's output
+// tokens when token normalization is enabled; it defaults to tokenizer.NewReference
+func (r *Runner) SetReferenceTokenizer(t tokenizer.Tokenizer) {
+	r.reference = t
+}
+
+// referenceTokenizer returns the tokenizer shared by every provider for normalized counts
+func (r *Runner) referenceTokenizer() tokenizer.Tokenizer {
+	r.referenceOnce.Do(func() {
+		if r.reference == nil {
+			r.reference = tokenizer.NewReference()
+		}
+	})
+	return r.reference
+}
+
+// StreamResults makes the runner send each result on ch as soon as it completes.
+// Sends block, so the caller must keep receiving until Run returns; ch is not closed.
+func (r *Runner) StreamResults(ch chan<- BenchmarkResult) {
+	r.resultsCh = ch
+}
+
+// ResultsChan returns a channel that receives each result as soon as it completes
+// and is closed when Run returns; call it before Run, once per consumer. Unlike
+// StreamResults it never holds up the run: a consumer more than resultsChanBuffer
+// results behind misses the newest ones, which is logged, so it can't skew latency.
+func (r *Runner) ResultsChan() <-chan BenchmarkResult {
+	ch := make(chan BenchmarkResult, resultsChanBuffer)
+	r.resultsMu.Lock()
+	r.subscribers = append(r.subscribers, ch)
+	r.resultsMu.Unlock()
+	return ch
+}
+
+// publish offers result to every ResultsChan subscriber without waiting on any
+func (r *Runner) publish(result BenchmarkResult) {
+	r.resultsMu.Lock()
+	defer r.resultsMu.Unlock()
+	for _, ch := range r.subscribers {
+		select {
+		case ch <- result:
+		default:
+			r.logger.Warn("Dropped result for a slow results subscriber", "provider", result.Provider, "model", result.Model)
+		}
+	}
+}
+
+// closeSubscribers closes the ResultsChan channels once no more results can arrive
+func (r *Runner) closeSubscribers() {
+	r.resultsMu.Lock()
+	defer r.resultsMu.Unlock()
+	for _, ch := range r.subscribers {
+		close(ch)
+	}
+	r.subscribers = nil
+}
+
+// ResultSink persists results as they're produced, such as an output.CSVStream
+type ResultSink interface {
+	WriteResult(result BenchmarkResult) error
+}
+
+// SetResultSink makes the runner write each result to sink as soon as it completes,
+// so a run that crashes partway keeps what it measured. Writes are serialized; a
+// failed write is logged and doesn't stop the run.
+func (r *Runner) SetResultSink(sink ResultSink) {
+	r.sink = sink
+}
+
+// Execute runs the benchmark and returns its results and summary
+func (r *Runner) Execute(ctx context.Context) ([]BenchmarkResult, Summary, error) {
+	err := r.Run(ctx)
+	return r.GetResults(), r.GetSummary(), err
+}
+
 // Run executes the benchmark according to configuration
 func (r *Runner) Run(ctx context.Context) error {
-	// Load prompts
-	promptFiles, err := config.LoadPrompts(r.config.PromptsDir)
+	defer r.closeSubscribers()
+
+	r.resultsMu.Lock()
+	r.runStart = time.Now()
+	r.runEnd = time.Time{}
+	r.resultsMu.Unlock()
+	defer func() {
+		r.resultsMu.Lock()
+		r.runEnd = time.Now()
+		r.resultsMu.Unlock()
+	}()
+
+	promptFiles, err := r.loadPrompts()
 	if err != nil {
-		return fmt.Errorf("failed to load prompts: %w", err)
+		return err
 	}
 
 	if r.verbose {
-		log.Printf("Loaded %d prompt files", len(promptFiles))
+		r.logger.Info("Loaded prompt files", "count", len(promptFiles))
 	}
 
-	// Create a cancellable context for the entire run
+	// Create a cancellable context for the entire run, which ends at MaxDuration
 	runCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
+	if r.config.MaxDuration > 0 {
+		var cancelTimeout context.CancelFunc
+		runCtx, cancelTimeout = context.WithTimeout(runCtx, r.config.MaxDuration)
+		defer cancelTimeout()
+	}
+
+	r.resultsMu.Lock()
+	r.cancelRun = cancel
+	r.resultsMu.Unlock()
+
+	if r.config.Warmup > 0 && len(promptFiles) > 0 {
+		r.warmUp(runCtx, promptFiles[0])
+	}
+
+	// A concurrency sweep runs everything once per level; each level drains
+	// completely before the next starts so levels don't overlap
+	for _, level := range r.concurrencyLevels() {
+		r.concurrency = level
+		if r.verbose && len(r.config.ConcurrencySweep) > 0 {
+			r.logger.Info("Concurrency sweep: running level", "concurrency", level)
+		}
+
+		// Start the benchmark based on concurrency setting
+		if r.maxConcurrency() <= 1 {
+			err = r.runSequential(runCtx, promptFiles)
+		} else {
+			err = r.runConcurrent(runCtx, promptFiles)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if err != nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		r.halt(haltReasonMaxDuration)
+	}
+
+	// A deliberate halt is not a failure as long as the caller didn't cancel
+	if err != nil && r.HaltReason() != "" && ctx.Err() == nil {
+		return nil
+	}
+	return err
+}
+
+// maxConcurrency returns the largest worker pool size of any provider
+func (r *Runner) maxConcurrency() int {
+	workers := r.concurrency
+	for providerName := range r.providers {
+		workers = max(workers, r.providerConcurrency(providerName))
+	}
+	return workers
+}
 
-	// Start the benchmark based on concurrency setting
-	if r.config.Concurrent <= 1 {
-		return r.runSequential(runCtx, promptFiles)
+// concurrencyLevels returns the worker counts to run everything at, in order
+func (r *Runner) concurrencyLevels() []int {
+	if len(r.config.ConcurrencySweep) > 0 {
+		return r.config.ConcurrencySweep
+	}
+	return []int{r.config.Concurrent}
+}
+
+// variants returns the sweep variants to run each model with; sampling parameters
+// don't apply to embeddings, so that mode always runs a single variant
+func (r *Runner) variants() []config.SweepVariant {
+	if r.config.Mode == config.ModeEmbeddings {
+		return []config.SweepVariant{{}}
+	}
+	return r.config.Models.Sweep.Variants()
+}
+
+// loadPrompts loads the prompt files from the configured directory, shuffled with
+// config.ShuffleSeed when config.Shuffle is set and then cut to config.LimitPrompts
+func (r *Runner) loadPrompts() ([]config.PromptFile, error) {
+	loadPrompts := config.LoadPrompts
+	if r.config.RecursivePrompts {
+		loadPrompts = config.LoadPromptsRecursive
+	}
+	promptFiles, err := loadPrompts(r.config.PromptsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompts: %w", err)
+	}
+	if r.config.Shuffle {
+		rng := rand.New(rand.NewPCG(r.config.ShuffleSeed, 0))
+		rng.Shuffle(len(promptFiles), func(i, j int) {
+			promptFiles[i], promptFiles[j] = promptFiles[j], promptFiles[i]
+		})
+	}
+	if r.config.LimitPrompts > 0 && len(promptFiles) > r.config.LimitPrompts {
+		promptFiles = promptFiles[:r.config.LimitPrompts]
+	}
+	return promptFiles, nil
+}
+
+// listModels returns the models to benchmark for a provider after applying the model filter
+func (r *Runner) listModels(providerName string) ([]string, error) {
+	var models []string
+	if r.config.Mode == config.ModeEmbeddings {
+		models = r.config.Models.ListEmbeddingModels(providerName)
+		if _, ok := r.providers[providerName].(providers.EmbeddingProvider); !ok && len(models) > 0 {
+			return nil, fmt.Errorf("provider %s does not support embeddings", providerName)
+		}
 	} else {
-		return r.runConcurrent(runCtx, promptFiles)
+		var err error
+		if models, err = r.config.Models.ListModels(providerName); err != nil {
+			return nil, err
+		}
+	}
+
+	enabled := models[:0]
+	for _, modelName := range models {
+		if r.config.ModelEnabled(providerName, modelName) {
+			enabled = append(enabled, modelName)
+		}
+	}
+	return enabled, nil
+}
+
+// applySamplingOverrides applies the sampling parameters set on the command line
+// over the defaults, sweep and model parameters. Temperature and top_p are left
+// alone for models that don't accept them, so results aren't tagged with values
+// that were never sent.
+func (r *Runner) applySamplingOverrides(provider providers.Provider, req *providers.ChatRequest) {
+	if r.config.MaxTokens != nil {
+		req.MaxTokens = *r.config.MaxTokens
+		delete(req.ExtraParams, "max_tokens")
+	}
+	if !providers.AcceptsSamplingParameters(provider.Name(), *req) {
+		return
+	}
+	if r.config.Temperature != nil {
+		req.Temperature = r.config.Temperature
+		delete(req.ExtraParams, "temperature")
+	}
+	if r.config.TopP != nil {
+		req.TopP = *r.config.TopP
+		delete(req.ExtraParams, "top_p")
+	}
+}
+
+// warmUp sends config.Warmup unrecorded requests to every model so connection setup
+// and cold starts don't skew the first measured runs
+func (r *Runner) warmUp(ctx context.Context, promptFile config.PromptFile) {
+	for providerName, provider := range r.providers {
+		models, err := r.listModels(providerName)
+		if err != nil {
+			continue
+		}
+		for _, modelName := range models {
+			if r.verbose {
+				r.logger.Info("Warming up", "provider", providerName, "model", modelName, "requests", r.config.Warmup)
+			}
+			for i := 0; i < r.config.Warmup; i++ {
+				if ctx.Err() != nil {
+					return
+				}
+				r.runOnce(ctx, provider, modelName, promptFile, config.SweepVariant{})
+				if !r.coolDown(ctx) {
+					return
+				}
+			}
+		}
 	}
 }
 
 // runSequential executes benchmarks sequentially
 func (r *Runner) runSequential(ctx context.Context, promptFiles []config.PromptFile) error {
 	if r.verbose {
-		log.Println("Running benchmarks sequentially")
+		r.logger.Info("Running benchmarks sequentially")
 	}
 
+	// Every provider's work runs in one sequence, so interleaving rotates across all of their models
+	var work []workItem
 	for _, promptFile := range promptFiles {
+		for providerName, provider := range r.providers {
+			// Get models for this provider
+			models, err := r.listModels(providerName)
+			if err != nil {
+				r.logger.Warn("Failed to get models for provider", "provider", providerName, "error", err)
+				continue
+			}
+			work = append(work, r.workItems(provider, models, []config.PromptFile{promptFile})...)
+		}
+	}
+	if r.config.Interleave {
+		work = interleaveModels(work)
+	}
+
+	for _, item := range work {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
@@ -67,108 +404,132 @@ func (r *Runner) runSequential(ctx context.Context, promptFiles []config.PromptF
 		}
 
 		if r.verbose {
-			log.Printf("Processing prompt file: %s", promptFile.Name)
+			r.logger.Info("Processing", "prompt", item.promptFile.Name, "provider", item.provider.Name(), "model", item.modelName, "run", item.run, "runs", r.config.Runs)
 		}
 
-		// Test each provider and their models
-		for providerName, provider := range r.providers {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-			}
-
-			// Get models for this provider
-			models, err := r.config.Models.ListModels(providerName)
-			if err != nil {
-				log.Printf("Warning: Failed to get models for provider %s: %v", providerName, err)
-				continue
-			}
+		item.dispatched = time.Now()
+		result := r.runWithRetries(ctx, item)
+		if cancelled(ctx, result) {
+			return ctx.Err()
+		}
+		result.Run = item.run
+		r.addResult(result)
+		if !r.coolDown(ctx) {
+			return ctx.Err()
+		}
+	}
 
-			for _, modelName := range models {
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				default:
-				}
+	return nil
+}
 
-				if r.verbose {
-					log.Printf("  Testing model: %s (%d runs)", modelName, r.config.Runs)
+// workItems materializes a provider's work in prompt, model, run, variant order
+func (r *Runner) workItems(provider providers.Provider, models []string, promptFiles []config.PromptFile) []workItem {
+	var work []workItem
+	for _, promptFile := range promptFiles {
+		for _, modelName := range models {
+			for run := 1; run <= r.config.Runs; run++ {
+				for _, variant := range r.variants() {
+					work = append(work, workItem{promptFile: promptFile, provider: provider, modelName: modelName, run: run, variant: variant})
 				}
+			}
+		}
+	}
+	return work
+}
 
-				// Run the benchmark multiple times
-				for run := 1; run <= r.config.Runs; run++ {
-					select {
-					case <-ctx.Done():
-						return ctx.Err()
-					default:
-					}
-
-					if r.verbose && r.config.Runs > 1 {
-						log.Printf("    Run %d/%d", run, r.config.Runs)
-					}
+// interleaveModels reorders work so models take turns (A, B, C, A, B, C, ...)
+// instead of each running all of its work back to back, spreading every model
+// over the whole run. Each model's own items keep their order.
+func interleaveModels(work []workItem) []workItem {
+	var keys []string
+	byModel := make(map[string][]workItem)
+	for _, item := range work {
+		key := ModelKey(item.provider.Name(), item.modelName)
+		if _, ok := byModel[key]; !ok {
+			keys = append(keys, key)
+		}
+		byModel[key] = append(byModel[key], item)
+	}
 
-					// Run the benchmark
-					result := r.runSingleBenchmark(ctx, provider, modelName, promptFile)
-					r.addResult(result)
-				}
+	interleaved := make([]workItem, 0, len(work))
+	for len(interleaved) < len(work) {
+		for _, key := range keys {
+			if items := byModel[key]; len(items) > 0 {
+				interleaved = append(interleaved, items[0])
+				byModel[key] = items[1:]
 			}
 		}
 	}
-
-	return nil
+	return interleaved
 }
 
-// runConcurrent executes benchmarks with worker pools
+// runConcurrent executes benchmarks with an independent worker pool per provider,
+// so a slow or rate-limited provider can't take workers from the others
 func (r *Runner) runConcurrent(ctx context.Context, promptFiles []config.PromptFile) error {
-	if r.verbose {
-		log.Printf("Running benchmarks with %d concurrent workers", r.config.Concurrent)
+	// In adaptive mode each pool's size is a ceiling and the provider gets its own cap
+	if r.config.AdaptiveConcurrency {
+		limiters := make(map[string]*aimdLimiter, len(r.providers))
+		for providerName := range r.providers {
+			limiters[providerName] = newAIMDLimiter(r.providerConcurrency(providerName))
+		}
+		r.resultsMu.Lock()
+		r.limiters = limiters
+		r.resultsMu.Unlock()
 	}
 
-	// Create a channel to receive work items
-	// Estimate work items: promptFiles * providers * models per provider * runs
-	estimatedWorkItems := len(promptFiles) * len(r.providers) * 5 * r.config.Runs // Assume ~5 models per provider
-	workChan := make(chan workItem, estimatedWorkItems)
-	defer close(workChan)
-
-	// Create a wait group to track worker completion
+	// Create a wait group to track worker completion across all pools
 	var wg sync.WaitGroup
 
-	// Start workers
-	for i := 0; i < r.config.Concurrent; i++ {
-		wg.Add(1)
-		go r.worker(ctx, &wg, workChan, i+1)
-	}
-
-	// Send work items
-	go func() {
-		defer close(workChan)
-		for _, promptFile := range promptFiles {
-			for providerName, provider := range r.providers {
-				// Get models for this provider
-				models, err := r.config.Models.ListModels(providerName)
-				if err != nil {
-					log.Printf("Warning: Failed to get models for provider %s: %v", providerName, err)
-					continue
-				}
+	for providerName, provider := range r.providers {
+		// Get models for this provider
+		models, err := r.listModels(providerName)
+		if err != nil {
+			r.logger.Warn("Failed to get models for provider", "provider", providerName, "error", err)
+			continue
+		}
 
-				for _, modelName := range models {
-					for run := 1; run <= r.config.Runs; run++ {
-						select {
-						case <-ctx.Done():
-							return
-						case workChan <- workItem{promptFile: promptFile, provider: provider, modelName: modelName, run: run}:
-						}
-					}
-				}
-			}
+		work := r.workItems(provider, models, promptFiles)
+		if r.config.Interleave {
+			work = interleaveModels(work)
+		}
+		if len(work) == 0 {
+			continue
 		}
-	}()
+
+		// Queue all of the provider's work up front; the channel holds it all, and
+		// the time each item waits there for a worker is its queue wait
+		workChan := make(chan workItem, len(work))
+		dispatched := time.Now()
+		for _, item := range work {
+			item.dispatched = dispatched
+			workChan <- item
+		}
+		close(workChan)
+
+		workers := min(r.providerConcurrency(providerName), len(work))
+		if r.verbose {
+			r.logger.Info("Running benchmarks concurrently", "provider", providerName, "workers", workers)
+		}
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go r.worker(ctx, &wg, workChan, i+1)
+		}
+	}
 
 	// Wait for all workers to complete
 	wg.Wait()
 
-	return nil
+	// Queued work is left behind when the run is cancelled, as in runSequential
+	return ctx.Err()
+}
+
+// providerConcurrency returns the size of a provider's worker pool: its configured
+// override, except in a concurrency sweep where every provider runs at the level
+func (r *Runner) providerConcurrency(providerName string) int {
+	if workers, ok := r.config.ProviderConcurrency[providerName]; ok && len(r.config.ConcurrencySweep) == 0 {
+		return workers
+	}
+	return r.concurrency
 }
 
 // workItem represents a single benchmark task
@@ -177,6 +538,8 @@ type workItem struct {
 	provider   providers.Provider
 	modelName  string
 	run        int
+	variant    config.SweepVariant
+	dispatched time.Time // When the item was handed to the runner or queued for a worker
 }
 
 // worker processes work items from the channel
@@ -184,6 +547,12 @@ func (r *Runner) worker(ctx context.Context, wg *sync.WaitGroup, workChan <-chan
 	defer wg.Done()
 
 	for {
+		// select picks at random when work is also ready, so check for cancellation
+		// first rather than starting another request
+		if ctx.Err() != nil {
+			return
+		}
+
 		select {
 		case <-ctx.Done():
 			return
@@ -193,57 +562,229 @@ func (r *Runner) worker(ctx context.Context, wg *sync.WaitGroup, workChan <-chan
 			}
 
 			if r.verbose {
-				if r.config.Runs > 1 {
-					log.Printf("Worker %d: Processing %s with model %s (run %d/%d)", workerID, work.promptFile.Name, work.modelName, work.run, r.config.Runs)
-				} else {
-					log.Printf("Worker %d: Processing %s with model %s", workerID, work.promptFile.Name, work.modelName)
-				}
+				r.logger.Info("Processing", "prompt", work.promptFile.Name, "provider", work.provider.Name(), "model", work.modelName, "run", work.run, "runs", r.config.Runs, "worker", workerID)
 			}
 
 			// Run the benchmark
-			result := r.runSingleBenchmark(ctx, work.provider, work.modelName, work.promptFile)
+			result := r.runWithRetries(ctx, work)
+			if cancelled(ctx, result) {
+				return
+			}
+			result.Run = work.run
 			r.addResult(result)
+			if !r.coolDown(ctx) {
+				return
+			}
 		}
 	}
 }
 
-// runSingleBenchmark executes a single benchmark test
-func (r *Runner) runSingleBenchmark(ctx context.Context, provider providers.Provider, modelName string, promptFile config.PromptFile) BenchmarkResult {
-	// Create metrics for this run
+// coolDown pauses for config.Cooldown after a request so providers that penalize
+// bursts see spaced-out traffic; it reports false if ctx is cancelled first
+func (r *Runner) coolDown(ctx context.Context) bool {
+	if r.config.Cooldown <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(r.config.Cooldown)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// cancelled reports whether result failed only because the run was cancelled; such
+// results aren't recorded so an interrupted run keeps just the benchmarks that completed
+func cancelled(ctx context.Context, result BenchmarkResult) bool {
+	return result.Error != nil && ctx.Err() != nil
+}
+
+// runWithRetries runs a benchmark, retrying failures the provider reports as transient
+// up to config.Retries times. config.Timeout bounds all attempts and backoff delays
+// together, while the provider's request timeout bounds each attempt. Only the last attempt's
+// result is kept, so its timings don't include failed attempts; it records how many
+// attempts were made and the error that prompted the last retry, and how long the
+// item waited between being dispatched and its first request starting.
+func (r *Runner) runWithRetries(ctx context.Context, item workItem) BenchmarkResult {
+	provider, modelName, promptFile, variant := item.provider, item.modelName, item.promptFile, item.variant
+	if r.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.config.Timeout)
+		defer cancel()
+	}
+
+	policy, _ := provider.(providers.RetryPolicy)
+	lastRetryError := ""
+	var queueWait time.Duration
+	for attempt := 1; ; attempt++ {
+		result := r.runLimited(ctx, provider, modelName, promptFile, variant)
+		if attempt == 1 && !item.dispatched.IsZero() {
+			queueWait = max(result.StartTime.Sub(item.dispatched), 0)
+		}
+		result.QueueWait = queueWait
+		result.Attempts = attempt
+		result.LastRetryError = lastRetryError
+		if result.Error == nil || policy == nil || attempt > r.config.Retries ||
+			ctx.Err() != nil || errors.Is(result.Error, ErrContextLengthExceeded) ||
+			!policy.IsRetryableError(result.Error) {
+			return result
+		}
+		lastRetryError = result.Error.Error()
+
+		delay := policy.GetRetryDelay(attempt, result.Error)
+		if r.verbose {
+			r.logger.Info("Retrying", "provider", provider.Name(), "model", modelName, "delay", delay, "attempt", attempt+1, "max_attempts", r.config.Retries+1, "error", result.Error)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result
+		case <-timer.C:
+		}
+	}
+}
+
+// runLimited runs a single benchmark within the provider's adaptive concurrency cap,
+// halving the cap when the provider rate-limits
+func (r *Runner) runLimited(ctx context.Context, provider providers.Provider, modelName string, promptFile config.PromptFile, variant config.SweepVariant) BenchmarkResult {
+	r.resultsMu.RLock()
+	limiter := r.limiters[provider.Name()]
+	r.resultsMu.RUnlock()
+	if limiter == nil {
+		return r.runOnce(ctx, provider, modelName, promptFile, variant)
+	}
+
+	epoch, ok := limiter.acquire(ctx)
+	if !ok {
+		metrics := NewMetrics()
+		metrics.SetError(ctx.Err())
+		return metrics.ToBenchmarkResult(provider.Name(), modelName, promptFile.Name)
+	}
+	result := r.runOnce(ctx, provider, modelName, promptFile, variant)
+	rateLimited := providers.ErrorCategory(result.Error) == providers.ErrorCategoryRateLimit
+	if limiter.release(epoch, rateLimited) && r.verbose {
+		r.logger.Info("Rate limited, reducing concurrency", "provider", provider.Name(), "concurrency", limiter.Limit())
+	}
+	return result
+}
+
+// ConcurrencyLimits returns each provider's current concurrency cap in adaptive
+// mode, or nil when concurrency is fixed
+func (r *Runner) ConcurrencyLimits() map[string]int {
+	r.resultsMu.RLock()
+	defer r.resultsMu.RUnlock()
+	if r.limiters == nil {
+		return nil
+	}
+	limits := make(map[string]int, len(r.limiters))
+	for providerName, limiter := range r.limiters {
+		limits[providerName] = limiter.Limit()
+	}
+	return limits
+}
+
+// runOnce runs a single chat or embeddings benchmark depending on the mode
+func (r *Runner) runOnce(ctx context.Context, provider providers.Provider, modelName string, promptFile config.PromptFile, variant config.SweepVariant) BenchmarkResult {
+	var result BenchmarkResult
+	if r.config.Mode == config.ModeEmbeddings {
+		result = r.runSingleEmbedding(ctx, provider, modelName, promptFile)
+	} else {
+		result = r.runSingleBenchmark(ctx, provider, modelName, promptFile, variant)
+	}
+	if reporter, ok := provider.(providers.BackendReporter); ok {
+		result.Backend = reporter.GetBackendInfo()
+	}
+	return result
+}
+
+// runSingleEmbedding embeds the prompt text once. The whole response arrives at
+// once, so TTFT and total time are both the full round trip.
+func (r *Runner) runSingleEmbedding(ctx context.Context, provider providers.Provider, modelName string, promptFile config.PromptFile) BenchmarkResult {
 	metrics := NewMetrics()
 
-    // Create the chat request
-    req := providers.ChatRequest{
-		Model:        modelName,
-		SystemPrompt: promptFile.Prompt.System,
-		UserPrompt:   promptFile.Prompt.User,
-		MaxTokens:    1000, // Default max tokens
-		Temperature:  0.7,  // Default temperature
-		TopP:         1.0,  // Default top_p
+	embedder, ok := provider.(providers.EmbeddingProvider)
+	if !ok {
+		metrics.SetError(&providers.ProviderError{Provider: provider.Name(), Message: "embeddings are not supported"})
+		return metrics.ToBenchmarkResult(provider.Name(), modelName, promptFile.Name)
+	}
+
+	input := r.buildRequest(provider, modelName, promptFile, config.SweepVariant{}).PromptText()
+	req := providers.EmbeddingRequest{Model: modelName, Input: []string{input}}
+	if spec, exists := r.config.Models.Embeddings[provider.Name()][modelName]; exists {
+		req.ExtraParams = spec.Parameters
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, r.config.RequestTimeoutFor(provider.Name()))
+	defer cancel()
+
+	resp, err := embedder.Embed(timeoutCtx, req)
+	if err != nil {
+		metrics.SetError(&providers.ProviderError{
+			Provider: provider.Name(),
+			Message:  "embeddings request failed",
+			Cause:    err,
+		})
+		return metrics.ToBenchmarkResult(provider.Name(), modelName, promptFile.Name)
+	}
+	metrics.RecordFirstToken()
+
+	inputTokens := countTokens(provider, modelName, input)
+	if resp.Usage != nil {
+		inputTokens = resp.Usage.InputTokens
+	}
+	metrics.AddTokens(inputTokens, 0)
+	if resp.NetworkTiming != nil {
+		metrics.SetNetworkTiming(*resp.NetworkTiming)
+	}
+	metrics.Complete()
+	metrics.SetCost(r.calculateCost(provider.Name(), modelName, inputTokens, 0, 0))
+
+	result := metrics.ToBenchmarkResult(provider.Name(), modelName, promptFile.Name)
+	result.Dimensions = resp.Dimensions
+	return result
+}
+
+// runSingleBenchmark executes a single benchmark test
+func (r *Runner) runSingleBenchmark(ctx context.Context, provider providers.Provider, modelName string, promptFile config.PromptFile, variant config.SweepVariant) (result BenchmarkResult) {
+	req := r.buildRequest(provider, modelName, promptFile, variant)
+	if r.config.RequestIDs {
+		req.RequestID = uuid.NewString()
 	}
 
-    // Apply per-model parameters from config (if present)
-    if params, err := r.config.Models.GetModelParameters(provider.Name(), modelName); err == nil && params != nil {
-        // Merge into ExtraParams map
-        req.ExtraParams = make(map[string]interface{}, len(params))
-        for k, v := range params {
-            req.ExtraParams[k] = v
-        }
-    }
+	// Counting takes an API call, so it's made before the run's timing starts
+	exactInputTokens, haveExactInputTokens := r.countInputTokens(ctx, provider, promptFile, req)
 
-    // Add Groq-specific parameters for reasoning models (only if not already provided via model parameters)
-	if provider.Name() == "groq" {
-		// Check if this is a reasoning model that supports reasoning_effort
-		if isReasoningModel(modelName) {
-            if req.ExtraParams == nil || req.ExtraParams["reasoning_effort"] == nil {
-                if req.ExtraParams == nil { req.ExtraParams = make(map[string]interface{}) }
-                req.ExtraParams["reasoning_effort"] = "none"
-            }
+	// Create metrics for this run
+	metrics := NewMetrics()
+	metrics.VisibleTokensPerSecond = r.config.VisibleTokensPerSecond
+	metrics.TraceChunks = r.config.TraceDir != ""
+	metrics.NormalizeTokens = r.config.NormalizeTokens
+	metrics.TotalTimeToLastToken = r.config.Measure == config.MeasureLastToken
+
+	// Tag every result with the parameters it was run with
+	defer func() {
+		if req.Temperature != nil {
+			result.Temperature = *req.Temperature
 		}
+		result.MaxTokens = req.MaxTokens
+		result.Seed = req.Seed
+		result.RequestID = req.RequestID
+	}()
+
+	// Skip requests that can't fit in the model's context window rather than paying for an API error
+	if err := r.checkContextLength(provider, modelName, req); err != nil {
+		metrics.SetError(err)
+		return metrics.ToBenchmarkResult(provider.Name(), modelName, promptFile.Name)
 	}
 
 	// Create a timeout context for this request
-	timeoutCtx, cancel := context.WithTimeout(ctx, r.config.RequestTimeout)
+	requestTimeout := r.config.RequestTimeoutFor(provider.Name())
+	timeoutCtx, cancel := context.WithTimeout(ctx, requestTimeout)
 	defer cancel()
 
 	// Start the streaming request
@@ -260,25 +801,53 @@ func (r *Runner) runSingleBenchmark(ctx context.Context, provider providers.Prov
 	// Process the streaming response
 	var firstTokenReceived bool
 	var fullResponse string
+	var toolCallText string
+	var reasoningText string
+	var usage *providers.Usage
 	for {
 		select {
 		case <-timeoutCtx.Done():
-			metrics.SetError(&providers.TimeoutError{
-				Operation: "streaming response",
-				Duration:  r.config.RequestTimeout,
-			})
+			// The run being cancelled or timing out closes the stream too; only
+			// the request timeout expiring is a request timeout
+			if err := ctx.Err(); err != nil {
+				metrics.SetError(err)
+			} else {
+				metrics.SetError(&providers.TimeoutError{
+					Operation: "streaming response",
+					Duration:  requestTimeout,
+				})
+			}
 			return metrics.ToBenchmarkResult(provider.Name(), modelName, promptFile.Name)
 
 		case response, ok := <-responseChan:
 			if !ok {
 				// Stream completed successfully
+				if r.config.NormalizeTokens {
+					metrics.SetNormalizedOutputTokens(r.referenceTokenizer().Count(modelName, fullResponse+toolCallText))
+				}
 				metrics.Complete()
 				
 				// Calculate costs
-				cost := r.calculateCost(provider.Name(), modelName, metrics.InputTokens, metrics.OutputTokens)
+				cost := r.calculateCost(provider.Name(), modelName, metrics.InputTokens, metrics.CachedInputTokens, metrics.OutputTokens)
 				metrics.SetCost(cost)
 				
-				return metrics.ToBenchmarkResult(provider.Name(), modelName, promptFile.Name)
+				result = metrics.ToBenchmarkResult(provider.Name(), modelName, promptFile.Name)
+				// Structured-output modes promise JSON; record whether the answer kept that promise
+				if requestsJSON(req.ExtraParams) {
+					valid := json.Valid([]byte(fullResponse))
+					result.ValidJSON = &valid
+				}
+				// A stream can finish cleanly without saying anything
+				if !result.ToolCall && strings.TrimSpace(fullResponse) == "" {
+					result.EmptyResponse = true
+					r.logger.Warn("Provider returned an empty completion", "provider", provider.Name(), "model", modelName, "prompt", promptFile.Name)
+				}
+				return result
+			}
+
+			// Rate-limit headers come with failed requests too
+			if response.RateLimit != nil {
+				metrics.SetRateLimit(response.RateLimit)
 			}
 
 					// Check for errors in the response
@@ -291,48 +860,362 @@ func (r *Runner) runSingleBenchmark(ctx context.Context, provider providers.Prov
 			return metrics.ToBenchmarkResult(provider.Name(), modelName, promptFile.Name)
 		}
 
-			// Record first token time
-			if !firstTokenReceived && response.Content != "" {
+			// Time reasoning and answer apart; either one is the first token
+			if response.ReasoningContent != "" || response.RedactedReasoning {
+				metrics.RecordReasoningToken()
+				reasoningText += response.ReasoningContent
+			}
+			if response.Content != "" {
+				metrics.RecordAnswerToken()
+			}
+
+			// Record first token time; a tool call is output too
+			if !firstTokenReceived && (response.Content != "" || response.ReasoningContent != "" || response.RedactedReasoning || len(response.ToolCalls) > 0) {
 				metrics.RecordFirstToken()
 				firstTokenReceived = true
 			}
+			if len(response.ToolCalls) > 0 {
+				metrics.RecordToolCall()
+				for _, call := range response.ToolCalls {
+					toolCallText += call.Name + call.Arguments
+				}
+			}
 
 			// Add response content
 			if response.Content != "" {
 				fullResponse += response.Content
 				metrics.AddResponseContent(response.Content)
+				metrics.RecordChunk(len(response.Content))
+			}
+
+			if response.FinishReason != "" {
+				metrics.SetFinishReason(response.FinishReason)
+			}
+			if response.NetworkTiming != nil {
+				metrics.SetNetworkTiming(*response.NetworkTiming)
+			}
+			if response.Usage != nil {
+				usage = response.Usage
+			}
+			if len(response.Citations) > 0 {
+				metrics.SetCitations(response.Citations)
+			}
+			if response.ResponseModel != "" || response.SystemFingerprint != "" {
+				metrics.SetModelVersion(response.ResponseModel, response.SystemFingerprint)
 			}
 
 			// Calculate token counts if response is complete
 			if response.IsComplete {
-				// Estimate input tokens from the request
-				inputTokens := provider.GetTokenCount(req.SystemPrompt + req.UserPrompt)
-				// Estimate output tokens from the response
-				outputTokens := provider.GetTokenCount(fullResponse)
-				
-				metrics.AddTokens(inputTokens, outputTokens)
+				if usage != nil {
+					// Prefer the counts the provider reported
+					metrics.AddTokens(usage.InputTokens, usage.OutputTokens)
+					metrics.SetCachedInputTokens(usage.CachedInputTokens)
+					metrics.SetReasoningTokens(usage.ReasoningTokens)
+				} else {
+					// Count input tokens exactly where the provider can, otherwise estimate them from the request
+					inputTokens := exactInputTokens
+					if !haveExactInputTokens {
+						inputTokens = countTokens(provider, modelName, req.PromptText())
+					}
+					// Estimate output tokens from the response, including billed reasoning
+					outputTokens := countTokens(provider, modelName, reasoningText+fullResponse+toolCallText)
+
+					metrics.AddTokens(inputTokens, outputTokens)
+				}
 			}
 		}
 	}
 }
 
-// calculateCost calculates the cost for a benchmark run
-func (r *Runner) calculateCost(providerName, modelName string, inputTokens, outputTokens int) float64 {
+// buildRequest creates the chat request for one benchmark of a model on a prompt
+func (r *Runner) buildRequest(provider providers.Provider, modelName string, promptFile config.PromptFile, variant config.SweepVariant) providers.ChatRequest {
+	temperature := 0.7 // Default temperature
+	req := providers.ChatRequest{
+		Model:        modelName,
+		SystemPrompt: promptFile.Prompt.System,
+		UserPrompt:   promptFile.Prompt.User,
+		MaxTokens:    1000, // Default max tokens
+		Temperature:  &temperature,
+		TopP:         1.0, // Default top_p
+		NoStream:     r.config.NoStream,
+	}
+	for _, message := range promptFile.Prompt.Messages {
+		req.Messages = append(req.Messages, providers.Message{Role: message.Role, Content: message.Content})
+	}
+	if prompt := promptFile.Prompt; prompt.Image != "" {
+		req.Image = &providers.Image{MediaType: prompt.ImageMediaType, Data: prompt.ImageData}
+		if prompt.ImageData == nil {
+			req.Image.URL = prompt.Image
+		}
+	}
+
+	// Apply the sweep variant on top of the defaults
+	if variant.Temperature != nil {
+		req.Temperature = variant.Temperature
+	}
+	if variant.MaxTokens != nil {
+		req.MaxTokens = *variant.MaxTokens
+	}
+
+	// Apply per-model parameters from config (if present)
+	if params, err := r.config.Models.GetModelParameters(provider.Name(), modelName); err == nil && params != nil {
+		// Merge into ExtraParams map
+		req.ExtraParams = make(map[string]interface{}, len(params))
+		for k, v := range params {
+			req.ExtraParams[k] = v
+		}
+	}
+
+	// Request the prompt's structured-output mode, which overrides the model's
+	if promptFile.Prompt.ResponseFormat != nil {
+		if req.ExtraParams == nil {
+			req.ExtraParams = make(map[string]interface{})
+		}
+		req.ExtraParams["response_format"] = promptFile.Prompt.ResponseFormat
+	}
+
+	// Offer the prompt's tools; the OpenAI provider passes ExtraParams through as is
+	if len(promptFile.Prompt.Tools) > 0 {
+		if req.ExtraParams == nil {
+			req.ExtraParams = make(map[string]interface{})
+		}
+		req.ExtraParams["tools"] = promptFile.Prompt.Tools
+		if promptFile.Prompt.ToolChoice != nil {
+			req.ExtraParams["tool_choice"] = promptFile.Prompt.ToolChoice
+		}
+	}
+
+	// Send the model's stop sequences as the "stop" parameter every provider reads,
+	// unless the parameters already set one
+	if stop, err := r.config.Models.GetModelStop(provider.Name(), modelName); err == nil && len(stop) > 0 {
+		if req.ExtraParams == nil {
+			req.ExtraParams = make(map[string]interface{})
+		}
+		if _, ok := req.ExtraParams["stop"]; !ok {
+			req.ExtraParams["stop"] = stop
+		}
+	}
+
+	// Pin the sampling seed from a "seed" parameter or the model's seed field
+	if seed, ok := seedParam(req.ExtraParams); ok {
+		req.Seed = &seed
+	} else if seed, err := r.config.Models.GetModelSeed(provider.Name(), modelName); err == nil && seed != nil {
+		req.Seed = seed
+	}
+
+	// Which parameters the model accepts, where models.yaml says
+	if capabilities, err := r.config.Models.GetModelCapabilities(provider.Name(), modelName); err == nil {
+		req.Capabilities = capabilities
+	}
+	if headers, err := r.config.Models.GetModelHeaders(provider.Name(), modelName); err == nil {
+		req.Headers = headers
+	}
+
+	r.applySamplingOverrides(provider, &req)
+
+	// Add Groq-specific parameters for reasoning models (only if not already provided via model parameters)
+	if provider.Name() == "groq" && isReasoningModel(modelName) {
+		if req.ExtraParams == nil {
+			req.ExtraParams = make(map[string]interface{})
+		}
+		if req.ExtraParams["reasoning_effort"] == nil {
+			req.ExtraParams["reasoning_effort"] = "none"
+		}
+	}
+
+	return req
+}
+
+// checkContextLength returns an ErrContextLengthExceeded error when the prompt plus
+// max_tokens would overflow the model's configured context_length
+func (r *Runner) checkContextLength(provider providers.Provider, modelName string, req providers.ChatRequest) error {
+	contextLength, err := r.config.Models.GetModelContextLength(provider.Name(), modelName)
+	if err != nil || contextLength <= 0 {
+		return nil
+	}
+	promptTokens := countTokens(provider, modelName, req.PromptText())
+	if promptTokens+req.MaxTokens > contextLength {
+		return fmt.Errorf("%w: ~%d prompt tokens + %d max tokens > %d", ErrContextLengthExceeded, promptTokens, req.MaxTokens, contextLength)
+	}
+	return nil
+}
+
+// requestsJSON reports whether request parameters ask for JSON output with an
+// OpenAI-style response_format of type json_object or json_schema
+func requestsJSON(params map[string]interface{}) bool {
+	format, _ := params["response_format"].(map[string]interface{})
+	switch format["type"] {
+	case "json_object", "json_schema":
+		return true
+	}
+	return false
+}
+
+// seedParam extracts an integer "seed" from request parameters, which may have been
+// decoded from YAML (int) or JSON (float64)
+func seedParam(params map[string]interface{}) (int64, bool) {
+	switch v := params["seed"].(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), true
+	case float64:
+		if v == math.Trunc(v) {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
+
+// countTokens estimates tokens with the provider's tokenizer for the model,
+// falling back to GetTokenCount for providers without one
+func countTokens(provider providers.Provider, modelName, text string) int {
+	if p, ok := provider.(providers.TokenizerProvider); ok {
+		return p.Tokenizer().Count(modelName, text)
+	}
+	return provider.GetTokenCount(text)
+}
+
+// countInputTokens returns the exact prompt token count from providers that implement
+// providers.InputTokenCounter. Results, including failures, are cached per model and
+// prompt so each combination is counted once rather than on every run.
+func (r *Runner) countInputTokens(ctx context.Context, provider providers.Provider, promptFile config.PromptFile, req providers.ChatRequest) (int, bool) {
+	counter, ok := provider.(providers.InputTokenCounter)
+	if !ok {
+		return 0, false
+	}
+
+	key := ModelKey(provider.Name(), req.Model) + "\x00" + promptFile.Name
+	r.inputTokenCountsMu.Lock()
+	cached, found := r.inputTokenCounts[key]
+	r.inputTokenCountsMu.Unlock()
+	if found {
+		return cached.tokens, cached.ok
+	}
+
+	countCtx, cancel := context.WithTimeout(ctx, r.config.RequestTimeoutFor(provider.Name()))
+	defer cancel()
+	tokens, err := counter.CountInputTokens(countCtx, req)
+	if err != nil {
+		// A cancelled run isn't a counting failure worth remembering
+		if ctx.Err() != nil {
+			return 0, false
+		}
+		if r.verbose {
+			r.logger.Warn("Failed to count input tokens, estimating instead", "provider", provider.Name(), "model", req.Model, "error", err)
+		}
+	}
+	count := inputTokenCount{tokens: tokens, ok: err == nil}
+
+	r.inputTokenCountsMu.Lock()
+	if r.inputTokenCounts == nil {
+		r.inputTokenCounts = make(map[string]inputTokenCount)
+	}
+	r.inputTokenCounts[key] = count
+	r.inputTokenCountsMu.Unlock()
+	return count.tokens, count.ok
+}
+
+// calculateCost calculates the cost for a benchmark run; cachedInputTokens are part of inputTokens
+func (r *Runner) calculateCost(providerName, modelName string, inputTokens, cachedInputTokens, outputTokens int) float64 {
 	// Get pricing from the model configuration
-	pricing, err := r.config.Models.GetModelPricing(providerName, modelName)
+	pricing, err := r.modelPricing(providerName, modelName)
 	if err != nil {
 		// Return 0 cost if pricing not found
 		return 0.0
 	}
 	
-	return pricing.CalculateCost(inputTokens, outputTokens)
+	return pricing.CalculateCostWithCache(inputTokens, cachedInputTokens, outputTokens)
+}
+
+// modelPricing returns the pricing of a chat or embedding model depending on the mode
+func (r *Runner) modelPricing(providerName, modelName string) (*config.ModelPricing, error) {
+	if r.config.Mode == config.ModeEmbeddings {
+		return r.config.Models.GetEmbeddingModelPricing(providerName, modelName)
+	}
+	return r.config.Models.GetModelPricing(providerName, modelName)
 }
 
 // addResult adds a result to the results slice in a thread-safe manner
 func (r *Runner) addResult(result BenchmarkResult) {
+	if len(r.config.ConcurrencySweep) > 0 {
+		result.Concurrency = r.concurrency
+	}
+	if r.config.TraceDir != "" && len(result.Chunks) > 0 {
+		r.writeTrace(result)
+	}
+	r.recordResult(result)
+	if r.verbose && result.Success {
+		r.logger.Info("Timing breakdown", "provider", result.Provider, "model", result.Model, "prompt", result.PromptFile, "run", result.Run,
+			"queue_wait", result.QueueWait.Round(time.Millisecond), "ttft", result.TTFT.Round(time.Millisecond),
+			"streaming", (result.TotalTime - result.TTFT).Round(time.Millisecond))
+	}
+	if r.sink != nil {
+		r.sinkMu.Lock()
+		err := r.sink.WriteResult(result)
+		r.sinkMu.Unlock()
+		if err != nil {
+			r.logger.Warn("Failed to write result", "provider", result.Provider, "model", result.Model, "error", err)
+		}
+	}
+	if r.resultsCh != nil {
+		r.resultsCh <- result
+	}
+	r.publish(result)
+}
+
+// writeTrace writes a result's chunk arrivals to the trace directory; a missing
+// trace shouldn't fail the run, so errors are only logged
+func (r *Runner) writeTrace(result BenchmarkResult) {
+	r.resultsMu.Lock()
+	r.traceSeq++
+	seq := r.traceSeq
+	r.resultsMu.Unlock()
+
+	if _, err := WriteTrace(r.config.TraceDir, seq, result); err != nil {
+		r.logger.Warn("Failed to write trace", "error", err)
+	}
+}
+
+// recordResult stores a result and enforces the cost budget
+func (r *Runner) recordResult(result BenchmarkResult) {
 	r.resultsMu.Lock()
 	defer r.resultsMu.Unlock()
 	r.results = append(r.results, result)
+
+	// Halt the run once the cost budget is exceeded
+	r.totalCost += result.Cost
+	if r.config.MaxCost > 0 && r.totalCost > r.config.MaxCost && r.haltReason == "" {
+		r.haltReason = haltReasonCostBudget
+		if r.verbose {
+			r.logger.Warn("Cost budget exceeded, halting run", "budget", r.config.MaxCost, "spent", r.totalCost)
+		}
+		if r.cancelRun != nil {
+			r.cancelRun()
+		}
+	}
+}
+
+// halt records why the run stopped early unless an earlier halt already did
+func (r *Runner) halt(reason string) {
+	r.resultsMu.Lock()
+	defer r.resultsMu.Unlock()
+	if r.haltReason != "" {
+		return
+	}
+	r.haltReason = reason
+	if r.verbose {
+		r.logger.Warn("Halting run", "reason", reason)
+	}
+}
+
+// HaltReason returns why the run was stopped early, or "" if it ran to completion
+func (r *Runner) HaltReason() string {
+	r.resultsMu.RLock()
+	defer r.resultsMu.RUnlock()
+	return r.haltReason
 }
 
 // GetResults returns a copy of all benchmark results
@@ -348,7 +1231,20 @@ func (r *Runner) GetResults() []BenchmarkResult {
 // GetSummary returns a summary of all benchmark results
 func (r *Runner) GetSummary() Summary {
 	results := r.GetResults()
-	return CalculateSummary(results)
+	summary := CalculateSummaryWithPercentiles(results, r.config.Percentiles)
+	if len(r.config.HistogramBuckets) > 0 && summary.SuccessfulRuns > 0 {
+		summary.TTFTHistogram = TTFTHistogram(results, r.config.HistogramBuckets)
+	}
+	summary.HaltReason = r.HaltReason()
+
+	r.resultsMu.RLock()
+	summary.RunStart, summary.RunEnd = r.runStart, r.runEnd
+	r.resultsMu.RUnlock()
+	if !summary.RunStart.IsZero() && summary.RunEnd.After(summary.RunStart) {
+		summary.WallTime = summary.RunEnd.Sub(summary.RunStart)
+		summary.AggregateThroughput = float64(summary.TotalOutputTokens) / summary.WallTime.Seconds()
+	}
+	return summary
 }
 
 // isReasoningModel checks if a Groq model supports the reasoning_effort parameter
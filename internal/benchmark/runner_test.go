@@ -1,468 +1,1845 @@
 package benchmark
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"llm-latency-benchmark/internal/config"
-	"llm-latency-benchmark/providers"
+	"github.com/megzo/llm-latency-benchmark/internal/config"
+	"github.com/megzo/llm-latency-benchmark/internal/tokenizer"
+	"github.com/megzo/llm-latency-benchmark/providers"
 )
 
-// MockProvider for testing
-type MockProvider struct {
-	name string
-	delay time.Duration
-	shouldFail bool
+func TestRunner_SequentialExecution(t *testing.T) {
+	cfg := newStubConfig(t, 2, "stub-model")
+
+	provider := &stubProvider{name: "openai", content: "Hello there", delay: 10 * time.Millisecond, tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	results := runner.GetResults()
+	assert.Len(t, results, 2) // 2 prompts
+
+	for _, result := range results {
+		assert.Equal(t, "stub-model", result.Model)
+		assert.True(t, result.Success)
+		assert.Greater(t, result.TTFT, time.Duration(0))
+		assert.Greater(t, result.TotalTime, time.Duration(0))
+		assert.Equal(t, 10, result.InputTokens)
+		assert.Equal(t, 10, result.OutputTokens)
+		assert.Greater(t, result.Cost, 0.0)
+	}
 }
 
-func (m *MockProvider) Name() string {
-	return m.name
+func TestRunner_ConcurrentExecution(t *testing.T) {
+	cfg := newStubConfig(t, 3, "stub-model")
+	cfg.Concurrent = 2
+
+	provider := &stubProvider{name: "openai", content: "Hello there", delay: 50 * time.Millisecond, tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	startTime := time.Now()
+	require.NoError(t, runner.Run(context.Background()))
+
+	results := runner.GetResults()
+	assert.Len(t, results, 3) // 3 prompts
+
+	// Sequential would take 3 * 50ms; 2 workers need about 100ms
+	assert.Less(t, time.Since(startTime), 150*time.Millisecond, "Concurrent execution should be faster than sequential")
+
+	for _, result := range results {
+		assert.Equal(t, "stub-model", result.Model)
+		assert.True(t, result.Success)
+		assert.Greater(t, result.TTFT, time.Duration(0))
+	}
 }
 
-func (m *MockProvider) Chat(request providers.ChatRequest) (*providers.ChatResponse, error) {
-	if m.shouldFail {
-		return nil, assert.AnError
+func TestRunner_ErrorHandling(t *testing.T) {
+	cfg := newStubConfig(t, 2, "stub-model")
+
+	provider := &stubProvider{name: "openai", err: assert.AnError}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	results := runner.GetResults()
+	assert.Len(t, results, 2) // 2 prompts
+
+	for _, result := range results {
+		assert.Equal(t, "stub-model", result.Model)
+		assert.False(t, result.Success)
+		assert.Error(t, result.Error)
+		assert.Equal(t, 0, result.OutputTokens)
+		assert.Equal(t, 0.0, result.Cost)
 	}
-	
-	// Simulate processing delay
-	time.Sleep(m.delay)
-	
-	return &providers.ChatResponse{
-		Content: "Mock response for " + request.UserPrompt,
-	}, nil
 }
 
-func (m *MockProvider) ValidateRequest(request providers.ChatRequest) error {
-	if request.Model == "" {
-		return assert.AnError
+func TestRunner_EmptyPrompts(t *testing.T) {
+	cfg := newStubConfig(t, 0, "stub-model")
+
+	provider := &stubProvider{name: "openai", content: "Hello there", tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	assert.Error(t, runner.Run(context.Background()))
+	assert.Empty(t, runner.GetResults())
+}
+
+// stubProvider implements providers.Provider with a canned streamed response
+type stubProvider struct {
+	name    string
+	content string
+	delay   time.Duration
+	err     error
+	tokens  int // Fixed token count returned by GetTokenCount
+
+	finishReason string // Reported on the final response
+	fingerprint  string // System fingerprint reported on the final response
+}
+
+func (s *stubProvider) Name() string {
+	return s.name
+}
+
+func (s *stubProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	if s.err != nil {
+		return nil, s.err
 	}
-	return nil
+
+	responseChan := make(chan providers.ChatResponse)
+	go func() {
+		defer close(responseChan)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.delay):
+		}
+
+		for _, resp := range []providers.ChatResponse{
+			{Content: s.content, Timestamp: time.Now()},
+			{IsComplete: true, Timestamp: time.Now(), FinishReason: s.finishReason, ResponseModel: req.Model, SystemFingerprint: s.fingerprint},
+		} {
+			select {
+			case <-ctx.Done():
+				return
+			case responseChan <- resp:
+			}
+		}
+	}()
+	return responseChan, nil
 }
 
-func (m *MockProvider) TokenCount(response providers.ChatResponse) (int, int, int) {
-	return 10, len(response.Content), 10 + len(response.Content)
+func (s *stubProvider) TokenCount(response providers.ChatResponse) (input, output, total int) {
+	return 0, s.tokens, s.tokens
 }
 
-func (m *MockProvider) IsRetryableError(err error) bool {
-	return false
+func (s *stubProvider) GetTokenCount(text string) int {
+	return s.tokens
 }
 
-func (m *MockProvider) GetRetryDelay(attempt int) time.Duration {
-	return time.Second
+// newStubConfig builds a config with the given OpenAI models priced at $1/M tokens
+// and a prompts directory containing numPrompts prompt files
+func newStubConfig(t *testing.T, numPrompts int, models ...string) *config.Config {
+	t.Helper()
+
+	promptsDir := t.TempDir()
+	for i := 0; i < numPrompts; i++ {
+		path := filepath.Join(promptsDir, fmt.Sprintf("prompt%d.yaml", i))
+		require.NoError(t, os.WriteFile(path, []byte("user: \"Hello\"\n"), 0644))
+	}
+
+	specs := make(map[string]config.ModelSpec, len(models))
+	for _, model := range models {
+		specs[model] = config.ModelSpec{TokenPrice: config.ModelPricing{Input: 1, Output: 1}}
+	}
+
+	return &config.Config{
+		Models:         &config.ModelsConfig{OpenAI: specs},
+		Concurrent:     1,
+		Runs:           1,
+		PromptsDir:     promptsDir,
+		RequestTimeout: 5 * time.Second,
+	}
 }
 
-func TestBenchmarkRunner_SequentialExecution(t *testing.T) {
-	// Create test configuration
-	cfg := &config.Config{
-		Models: map[string]config.ModelConfig{
-			"mock-model": {
-				Provider:         "mock",
-				InputCostPer1K:   0.001,
-				OutputCostPer1K:  0.002,
-				MaxTokens:        1000,
-				ContextLength:    4000,
-			},
-		},
+func TestRunner_CostBudgetHaltsRun(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Runs = 10
+	// Each run costs (1000 + 1000) tokens * $1/M = $0.002, so the budget is exceeded on run 3
+	cfg.MaxCost = 0.005
+
+	provider := &stubProvider{name: "openai", content: "Hello there", tokens: 1000}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	err := runner.Run(context.Background())
+	require.NoError(t, err)
+
+	results := runner.GetResults()
+	assert.Len(t, results, 3)
+	for _, result := range results {
+		assert.True(t, result.Success)
+		assert.InDelta(t, 0.002, result.Cost, 1e-9)
 	}
 
-	// Create test prompts
-	prompts := map[string]config.Prompt{
-		"test1": {
-			Name:        "Test Prompt 1",
-			Description: "First test prompt",
-			Prompt:      "Hello, world!",
-		},
-		"test2": {
-			Name:        "Test Prompt 2",
-			Description: "Second test prompt",
-			Prompt:      "How are you today?",
-		},
+	summary := runner.GetSummary()
+	assert.Equal(t, "cost budget exceeded", summary.HaltReason)
+	assert.Equal(t, 3, summary.TotalRuns)
+}
+
+func TestRunner_ParameterSweep(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Models.Sweep = config.SweepConfig{
+		Temperatures: []float64{0, 1},
+		MaxTokens:    []int{256, 512},
 	}
 
-	// Create mock provider
-	provider := &MockProvider{
-		name:  "mock",
-		delay: 10 * time.Millisecond,
+	provider := &stubProvider{name: "openai", content: "Hello there", tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	results := runner.GetResults()
+	require.Len(t, results, 4)
+
+	seen := make(map[string]bool)
+	for _, result := range results {
+		assert.True(t, result.Success)
+		seen[fmt.Sprintf("%g/%d", result.Temperature, result.MaxTokens)] = true
 	}
+	assert.Equal(t, map[string]bool{"0/256": true, "0/512": true, "1/256": true, "1/512": true}, seen)
+}
 
-	// Create provider factory and register mock provider
-	factory := providers.NewProviderFactory()
-	err := factory.RegisterProvider("mock", func(config interface{}) (providers.Provider, error) {
-		return provider, nil
-	})
+func TestRunner_SweepSendsZeroTemperature(t *testing.T) {
+	var mu sync.Mutex
+	var sent []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		temperature, ok := payload["temperature"]
+		if !ok {
+			temperature = "missing"
+		}
+		sent = append(sent, temperature)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"1","model":"deepseek-chat","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := providers.NewOpenAICompatibleProvider("deepseek", &providers.OpenAICompatibleConfig{APIKey: "test-key", BaseURL: server.URL})
 	require.NoError(t, err)
 
-	// Create benchmark runner
-	runner := NewBenchmarkRunner(cfg, prompts, factory)
+	cfg := newStubConfig(t, 1, "deepseek-chat")
+	cfg.Models.DeepSeek, cfg.Models.OpenAI = cfg.Models.OpenAI, nil
+	cfg.Models.Sweep = config.SweepConfig{Temperatures: []float64{0, 1}}
+	runner := NewRunner(cfg, map[string]providers.Provider{"deepseek": provider}, false)
 
-	// Run benchmarks sequentially
-	results := make(chan BenchmarkResult, 10)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		require.True(t, result.Success, "%v", result.Error)
+	}
 
-	go func() {
-		defer close(results)
-		err := runner.RunSequential(ctx, results)
-		assert.NoError(t, err)
-	}()
+	// The 0 sweep point is sent as 0, not left to the provider's default
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []interface{}{0.0, 1.0}, sent)
+}
+
+func TestRunner_NoSweepUsesDefaults(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+
+	provider := &stubProvider{name: "openai", content: "Hello there", tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
 
-	// Collect results
-	var allResults []BenchmarkResult
-	for result := range results {
-		allResults = append(allResults, result)
+	results := runner.GetResults()
+	require.Len(t, results, 1)
+	assert.Equal(t, 0.7, results[0].Temperature)
+	assert.Equal(t, 1000, results[0].MaxTokens)
+}
+
+func TestRunner_ConcurrentQueuesExactWork(t *testing.T) {
+	cfg := newStubConfig(t, 3, "model-a", "model-b", "model-c")
+	cfg.Models.Groq = map[string]config.ModelSpec{"model-d": {TokenPrice: config.ModelPricing{Input: 1, Output: 1}}}
+	cfg.Models.Sweep = config.SweepConfig{Temperatures: []float64{0, 1}}
+	cfg.Concurrent = 4
+	cfg.Runs = 2
+
+	openai := &stubProvider{name: "openai", content: "Hello", tokens: 10}
+	groq := &stubProvider{name: "groq", content: "Hello", tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": openai, "groq": groq}, false)
+
+	// Each provider's queue holds exactly its models x prompts x runs x variants,
+	// all of which are queued before any worker starts
+	require.NoError(t, runner.Run(context.Background()))
+	byModel := CalculateSummaryByModel(runner.GetResults())
+	require.Len(t, byModel, 4)
+	for key, summary := range byModel {
+		assert.Equal(t, 3*2*2, summary.TotalRuns, key)
+		assert.Equal(t, summary.TotalRuns, summary.SuccessfulRuns, key)
 	}
+}
 
-	// Verify results
-	assert.Len(t, allResults, 2) // 2 prompts
+func TestRunner_InterleaveRotatesModels(t *testing.T) {
+	cfg := newStubConfig(t, 1, "model-a", "model-b")
+	cfg.Models.Groq = map[string]config.ModelSpec{"model-c": {TokenPrice: config.ModelPricing{Input: 1, Output: 1}}}
+	cfg.Runs = 3
+	cfg.Interleave = true
+
+	openai := &stubProvider{name: "openai", content: "Hello", tokens: 10}
+	groq := &stubProvider{name: "groq", content: "Hello", tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": openai, "groq": groq}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	// Sequential results are in execution order: each round visits every model once
+	results := runner.GetResults()
+	require.Len(t, results, 9)
+	for round := 0; round < 3; round++ {
+		seen := make(map[string]bool)
+		for _, result := range results[round*3 : round*3+3] {
+			seen[ModelKey(result.Provider, result.Model)] = true
+		}
+		assert.Len(t, seen, 3, "round %d should run every model once", round+1)
+	}
+}
 
-	for _, result := range allResults {
-		assert.Equal(t, "mock-model", result.Model)
-		assert.True(t, result.IsSuccessful())
-		assert.Greater(t, result.TTFT, time.Duration(0))
-		assert.Greater(t, result.TotalTime, time.Duration(0))
-		assert.Equal(t, 10, result.InputTokens)
-		assert.Greater(t, result.OutputTokens, 0)
-		assert.Greater(t, result.Cost, 0.0)
+func TestInterleaveModels(t *testing.T) {
+	a := &stubProvider{name: "openai"}
+	b := &stubProvider{name: "groq"}
+	var work []workItem
+	for _, item := range []struct {
+		provider providers.Provider
+		model    string
+		runs     int
+	}{{a, "a", 3}, {a, "b", 2}, {b, "c", 1}} {
+		for run := 1; run <= item.runs; run++ {
+			work = append(work, workItem{provider: item.provider, modelName: item.model, run: run})
+		}
+	}
+
+	var order []string
+	for _, item := range interleaveModels(work) {
+		order = append(order, fmt.Sprintf("%s%d", item.modelName, item.run))
 	}
+	assert.Equal(t, []string{"a1", "b1", "c1", "a2", "b2", "a3"}, order)
 }
 
-func TestBenchmarkRunner_ConcurrentExecution(t *testing.T) {
-	// Create test configuration
-	cfg := &config.Config{
-		Models: map[string]config.ModelConfig{
-			"mock-model": {
-				Provider:         "mock",
-				InputCostPer1K:   0.001,
-				OutputCostPer1K:  0.002,
-				MaxTokens:        1000,
-				ContextLength:    4000,
-			},
-		},
+func TestRunner_RecordsTruncation(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+
+	provider := &stubProvider{name: "openai", content: "Hello there", tokens: 10, finishReason: "length"}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	results := runner.GetResults()
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Success)
+	assert.Equal(t, "length", results[0].FinishReason)
+	assert.True(t, results[0].Truncated)
+	assert.Equal(t, 1, runner.GetSummary().TruncatedRuns)
+}
+
+func TestRunner_RecordsEmptyResponse(t *testing.T) {
+	cfg := newStubConfig(t, 1, "empty-model", "chatty-model")
+
+	// The stream completes cleanly without a word for one model
+	provider := &modelContentProvider{
+		stubProvider: &stubProvider{name: "openai", tokens: 10},
+		content:      map[string]string{"empty-model": " \n", "chatty-model": "Hello there"},
+	}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	results := runner.GetResults()
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.True(t, result.Success, result.Model)
+		assert.Equal(t, result.Model == "empty-model", result.EmptyResponse, result.Model)
 	}
 
-	// Create test prompts
-	prompts := map[string]config.Prompt{
-		"test1": {
-			Name:        "Test Prompt 1",
-			Description: "First test prompt",
-			Prompt:      "Hello, world!",
-		},
-		"test2": {
-			Name:        "Test Prompt 2",
-			Description: "Second test prompt",
-			Prompt:      "How are you today?",
+	summary := runner.GetSummary()
+	assert.Equal(t, 2, summary.SuccessfulRuns)
+	assert.Equal(t, 1, summary.EmptyRuns)
+}
+
+// modelContentProvider streams a different answer for each model
+type modelContentProvider struct {
+	*stubProvider
+	content map[string]string
+}
+
+func (p *modelContentProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	stub := *p.stubProvider
+	stub.content = p.content[req.Model]
+	return stub.StreamChat(ctx, req)
+}
+
+// flakyProvider fails the first failures StreamChat calls with a retryable error
+type flakyProvider struct {
+	*stubProvider
+	failures int
+	calls    int
+}
+
+func (f *flakyProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, &providers.ServerError{StatusCode: 503, Message: "overloaded"}
+	}
+	return f.stubProvider.StreamChat(ctx, req)
+}
+
+func (f *flakyProvider) IsRetryableError(err error) bool {
+	return true
+}
+
+func (f *flakyProvider) GetRetryDelay(attempt int, err error) time.Duration {
+	return time.Millisecond
+}
+
+func TestRunner_RetriesTransientErrors(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Retries = 3
+
+	provider := &flakyProvider{stubProvider: &stubProvider{name: "openai", content: "Hello there", tokens: 10}, failures: 2}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	results := runner.GetResults()
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Success)
+	assert.Equal(t, 3, provider.calls)
+}
+
+func TestRunner_RecordsAttempts(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Retries = 3
+
+	provider := &flakyProvider{stubProvider: &stubProvider{name: "openai", content: "Hello there", tokens: 10}, failures: 1}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	results := runner.GetResults()
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Success)
+	assert.Equal(t, 2, results[0].Attempts)
+	assert.Contains(t, results[0].LastRetryError, "overloaded")
+
+	// A first-try success took one attempt and had nothing to retry
+	provider = &flakyProvider{stubProvider: provider.stubProvider}
+	runner = NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	results = runner.GetResults()
+	require.Len(t, results, 1)
+	assert.Equal(t, 1, results[0].Attempts)
+	assert.Empty(t, results[0].LastRetryError)
+}
+
+func TestRunner_RetriesRateLimitedResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider func(baseURL string) (providers.Provider, error)
+		params   map[string]interface{}
+		models   func(cfg *config.Config)
+	}{
+		{
+			name: "groq",
+			provider: func(baseURL string) (providers.Provider, error) {
+				return providers.NewGroqProvider(&providers.GroqConfig{APIKey: "test-key", BaseURL: baseURL, RetryMaxDelay: time.Millisecond})
+			},
+			// reasoning_effort sends Groq requests over the direct HTTP path
+			params: map[string]interface{}{"reasoning_effort": "low"},
+			models: func(cfg *config.Config) { cfg.Models.Groq, cfg.Models.OpenAI = cfg.Models.OpenAI, nil },
 		},
-		"test3": {
-			Name:        "Test Prompt 3",
-			Description: "Third test prompt",
-			Prompt:      "What is the weather like?",
+		{
+			name: "openai",
+			provider: func(baseURL string) (providers.Provider, error) {
+				return providers.NewOpenAIProvider(&providers.OpenAIConfig{APIKey: "test-key", BaseURL: baseURL, RetryMaxDelay: time.Millisecond})
+			},
+			// Extra parameters send OpenAI requests over the direct HTTP path
+			params: map[string]interface{}{"user": "benchmark"},
+			models: func(cfg *config.Config) {},
 		},
 	}
 
-	// Create mock provider
-	provider := &MockProvider{
-		name:  "mock",
-		delay: 50 * time.Millisecond,
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				calls++
+				call := calls
+				mu.Unlock()
+
+				if call == 1 {
+					w.WriteHeader(http.StatusTooManyRequests)
+					w.Write([]byte(`{"error":{"message":"rate limit reached"}}`))
+					return
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte(`data: {"id":"1","model":"test-model","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}` + "\n\n"))
+				w.Write([]byte("data: [DONE]\n\n"))
+			}))
+			defer server.Close()
+
+			provider, err := tt.provider(server.URL)
+			require.NoError(t, err)
+
+			cfg := newStubConfig(t, 1, "test-model")
+			spec := cfg.Models.OpenAI["test-model"]
+			spec.Parameters = tt.params
+			cfg.Models.OpenAI["test-model"] = spec
+			tt.models(cfg)
+			cfg.Retries = 2
+			runner := NewRunner(cfg, map[string]providers.Provider{tt.name: provider}, false)
+
+			results, _, err := runner.Execute(context.Background())
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+			assert.True(t, results[0].Success, "%v", results[0].Error)
+			assert.Equal(t, 2, results[0].Attempts)
+			assert.Contains(t, results[0].LastRetryError, "rate limit")
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, 2, calls)
+		})
 	}
+}
 
-	// Create provider factory and register mock provider
-	factory := providers.NewProviderFactory()
-	err := factory.RegisterProvider("mock", func(config interface{}) (providers.Provider, error) {
-		return provider, nil
-	})
-	require.NoError(t, err)
+func TestRunner_RetriesExhausted(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Retries = 1
+
+	provider := &flakyProvider{stubProvider: &stubProvider{name: "openai", content: "Hello there", tokens: 10}, failures: 5}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	results := runner.GetResults()
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Success)
+	assert.Equal(t, 2, provider.calls)
+}
 
-	// Create benchmark runner
-	runner := NewBenchmarkRunner(cfg, prompts, factory)
+func TestRunner_TimeoutBoundsAllAttempts(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Retries = 3
+	cfg.Timeout = 50 * time.Millisecond
 
-	// Run benchmarks concurrently
-	results := make(chan BenchmarkResult, 10)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Each attempt would outlast the overall timeout but not the request timeout
+	provider := &flakyProvider{stubProvider: &stubProvider{name: "openai", content: "Hello there", delay: time.Second}}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	start := time.Now()
+	require.NoError(t, runner.Run(context.Background()))
+	assert.Less(t, time.Since(start), time.Second)
+
+	results := runner.GetResults()
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Success)
+	assert.Equal(t, 1, provider.calls, "an attempt cut off by the overall timeout is not retried")
+}
+
+// countingProvider counts StreamChat calls per model
+type countingProvider struct {
+	*stubProvider
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (c *countingProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	c.mu.Lock()
+	c.calls[req.Model]++
+	c.mu.Unlock()
+	return c.stubProvider.StreamChat(ctx, req)
+}
+
+func TestRunner_WarmupAndModelFilter(t *testing.T) {
+	cfg := newStubConfig(t, 2, "model-a", "model-b")
+	cfg.ModelFilter = []string{"model-a"}
+	cfg.Warmup = 2
+
+	provider := &countingProvider{stubProvider: &stubProvider{name: "openai", content: "Hello there", tokens: 10}, calls: make(map[string]int)}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	// Warm-up requests are sent but not recorded
+	results := runner.GetResults()
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.Equal(t, "model-a", result.Model)
+	}
+	assert.Equal(t, map[string]int{"model-a": 4}, provider.calls)
+}
+
+func TestRunner_CooldownBetweenRequests(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Runs = 3
+	cfg.Warmup = 1
+	cfg.Cooldown = 30 * time.Millisecond
+
+	provider := &stubProvider{name: "openai", content: "Hello", tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	// One warm-up and three measured requests, each followed by a cooldown
+	start := time.Now()
+	require.NoError(t, runner.Run(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 4*cfg.Cooldown)
+	assert.Len(t, runner.GetResults(), 3)
+}
+
+func TestRunner_CooldownPerWorker(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Concurrent = 2
+	cfg.Runs = 6
+	cfg.Cooldown = 30 * time.Millisecond
+
+	provider := &stubProvider{name: "openai", content: "Hello", tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	// Each of the two workers runs three requests with a cooldown after each
+	start := time.Now()
+	require.NoError(t, runner.Run(context.Background()))
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 3*cfg.Cooldown)
+	assert.Less(t, elapsed, 6*cfg.Cooldown, "workers should cool down in parallel")
+	assert.Len(t, runner.GetResults(), 6)
+}
+
+func TestRunner_CooldownRespectsCancellation(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Runs = 3
+	cfg.Cooldown = time.Minute
+
+	provider := &stubProvider{name: "openai", content: "Hello", tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 	defer cancel()
+	start := time.Now()
+	err := runner.Run(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Len(t, runner.GetResults(), 1)
+}
 
-	startTime := time.Now()
-	go func() {
-		defer close(results)
-		err := runner.RunConcurrent(ctx, results, 2) // 2 concurrent workers
-		assert.NoError(t, err)
-	}()
+// tokenCountingProvider counts prompt tokens exactly like Anthropic's and Gemini's APIs
+type tokenCountingProvider struct {
+	*stubProvider
+	inputTokens int
+	err         error
+	mu          sync.Mutex
+	counts      map[string]int // CountInputTokens calls per model
+}
+
+func (p *tokenCountingProvider) CountInputTokens(ctx context.Context, req providers.ChatRequest) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.counts[req.Model]++
+	return p.inputTokens, p.err
+}
+
+func TestRunner_UsesExactInputTokenCount(t *testing.T) {
+	cfg := newStubConfig(t, 2, "model-a", "model-b")
+	cfg.Runs = 3
+
+	provider := &tokenCountingProvider{stubProvider: &stubProvider{name: "openai", content: "Hello", tokens: 5}, inputTokens: 123, counts: make(map[string]int)}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
 
-	// Collect results
-	var allResults []BenchmarkResult
-	for result := range results {
-		allResults = append(allResults, result)
+	results := runner.GetResults()
+	require.Len(t, results, 12)
+	for _, result := range results {
+		assert.Equal(t, 123, result.InputTokens)
+		assert.Equal(t, 5, result.OutputTokens, "output is still estimated")
 	}
 
-	// Verify results
-	assert.Len(t, allResults, 3) // 3 prompts
+	// Counted once per model and prompt, not on every run
+	assert.Equal(t, map[string]int{"model-a": 2, "model-b": 2}, provider.counts)
+}
 
-	// Check that concurrent execution was faster than sequential
-	// Sequential would take: 3 * 50ms = 150ms
-	// Concurrent should take: ~100ms (with 2 workers)
-	executionTime := time.Since(startTime)
-	assert.Less(t, executionTime, 150*time.Millisecond, "Concurrent execution should be faster than sequential")
+func TestRunner_FallsBackToEstimateWhenCountingFails(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Runs = 3
 
-	for _, result := range allResults {
-		assert.Equal(t, "mock-model", result.Model)
-		assert.True(t, result.IsSuccessful())
-		assert.Greater(t, result.TTFT, time.Duration(0))
-		assert.Greater(t, result.TotalTime, time.Duration(0))
-		assert.Equal(t, 10, result.InputTokens)
-		assert.Greater(t, result.OutputTokens, 0)
-		assert.Greater(t, result.Cost, 0.0)
+	provider := &tokenCountingProvider{stubProvider: &stubProvider{name: "openai", content: "Hello", tokens: 5}, err: fmt.Errorf("count_tokens unavailable"), counts: make(map[string]int)}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	results := runner.GetResults()
+	require.Len(t, results, 3)
+	for _, result := range results {
+		assert.True(t, result.Success)
+		assert.Equal(t, 5, result.InputTokens)
 	}
+	assert.Equal(t, map[string]int{"stub-model": 1}, provider.counts, "a failed count isn't retried on every run")
 }
 
-func TestBenchmarkRunner_ErrorHandling(t *testing.T) {
-	// Create test configuration
-	cfg := &config.Config{
-		Models: map[string]config.ModelConfig{
-			"mock-model": {
-				Provider:         "mock",
-				InputCostPer1K:   0.001,
-				OutputCostPer1K:  0.002,
-				MaxTokens:        1000,
-				ContextLength:    4000,
-			},
-		},
+// heuristicProvider estimates tokens from the text like the real providers do
+type heuristicProvider struct {
+	*flakyProvider
+}
+
+func (h *heuristicProvider) Tokenizer() tokenizer.Tokenizer {
+	return tokenizer.Heuristic{}
+}
+
+func TestRunner_SkipsPromptsExceedingContextLength(t *testing.T) {
+	// prompt0 ("Hello") fits; the long prompt needs ~1500 tokens plus 1000 max tokens
+	cfg := newStubConfig(t, 1, "tiny-model")
+	spec := cfg.Models.OpenAI["tiny-model"]
+	spec.ContextLength = 2000
+	cfg.Models.OpenAI["tiny-model"] = spec
+	cfg.Retries = 3
+	longPrompt := strings.Repeat("lorem ipsum ", 500)
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.PromptsDir, "long.yaml"), []byte("user: \""+longPrompt+"\"\n"), 0644))
+
+	provider := &heuristicProvider{&flakyProvider{stubProvider: &stubProvider{name: "openai", content: "Hello there"}}}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	results := runner.GetResults()
+	require.Len(t, results, 2)
+	for _, result := range results {
+		if result.PromptFile == "long" {
+			assert.False(t, result.Success)
+			assert.ErrorIs(t, result.Error, ErrContextLengthExceeded)
+			assert.Contains(t, result.Error.Error(), "prompt exceeds context length")
+		} else {
+			assert.True(t, result.Success)
+		}
 	}
+	assert.Equal(t, 1, provider.calls, "no request is sent for the long prompt")
+}
 
-	// Create test prompts
-	prompts := map[string]config.Prompt{
-		"test1": {
-			Name:        "Test Prompt 1",
-			Description: "First test prompt",
-			Prompt:      "Hello, world!",
-		},
-		"test2": {
-			Name:        "Test Prompt 2",
-			Description: "Second test prompt",
-			Prompt:      "How are you today?",
-		},
+func TestRunner_RecordsSeed(t *testing.T) {
+	cfg := newStubConfig(t, 1, "seeded-model", "param-model", "plain-model")
+	seed := int64(7)
+	seeded := cfg.Models.OpenAI["seeded-model"]
+	seeded.Seed = &seed
+	cfg.Models.OpenAI["seeded-model"] = seeded
+	param := cfg.Models.OpenAI["param-model"]
+	param.Seed = &seed
+	param.Parameters = map[string]interface{}{"seed": 42} // The parameter wins over the field
+	cfg.Models.OpenAI["param-model"] = param
+
+	provider := &stubProvider{name: "openai", content: "Hello there", tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	seeds := make(map[string]*int64)
+	for _, result := range runner.GetResults() {
+		seeds[result.Model] = result.Seed
 	}
+	require.Len(t, seeds, 3)
+	require.NotNil(t, seeds["seeded-model"])
+	assert.Equal(t, int64(7), *seeds["seeded-model"])
+	require.NotNil(t, seeds["param-model"])
+	assert.Equal(t, int64(42), *seeds["param-model"])
+	assert.Nil(t, seeds["plain-model"])
+}
+
+// usageProvider reports provider-side token usage on the final response
+type usageProvider struct {
+	*stubProvider
+	usage providers.Usage
+}
+
+func (u *usageProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	responseChan := make(chan providers.ChatResponse, 2)
+	responseChan <- providers.ChatResponse{Content: u.content, Timestamp: time.Now()}
+	usage := u.usage
+	responseChan <- providers.ChatResponse{IsComplete: true, Timestamp: time.Now(), Usage: &usage}
+	close(responseChan)
+	return responseChan, nil
+}
 
-	// Create mock provider that fails
-	provider := &MockProvider{
-		name:        "mock",
-		delay:       10 * time.Millisecond,
-		shouldFail:  true,
+func TestRunner_UsesReportedUsageAndCachedPrice(t *testing.T) {
+	cfg := newStubConfig(t, 1, "cached-model")
+	cfg.Models.OpenAI["cached-model"] = config.ModelSpec{TokenPrice: config.ModelPricing{Input: 2, CachedInput: 0.5, Output: 8}}
+
+	provider := &usageProvider{
+		stubProvider: &stubProvider{name: "openai", content: "Hello there", tokens: 1},
+		usage:        providers.Usage{InputTokens: 1_000_000, OutputTokens: 100_000, CachedInputTokens: 800_000},
+	}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	results := runner.GetResults()
+	require.Len(t, results, 1)
+	assert.Equal(t, 1_000_000, results[0].InputTokens, "reported usage replaces the estimate")
+	assert.Equal(t, 100_000, results[0].OutputTokens)
+	assert.Equal(t, 800_000, results[0].CachedInputTokens)
+	assert.InDelta(t, 0.2*2+0.8*0.5+0.1*8, results[0].Cost, 1e-9)
+}
+
+func TestRunner_NormalizeTokens(t *testing.T) {
+	newProviders := func() map[string]providers.Provider {
+		// The provider reports 50 output tokens for text the heuristic counts as 6
+		return map[string]providers.Provider{"openai": &usageProvider{
+			stubProvider: &stubProvider{name: "openai", content: "A response of 24 letters"},
+			usage:        providers.Usage{InputTokens: 10, OutputTokens: 50},
+		}}
 	}
 
-	// Create provider factory and register mock provider
-	factory := providers.NewProviderFactory()
-	err := factory.RegisterProvider("mock", func(config interface{}) (providers.Provider, error) {
-		return provider, nil
-	})
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.NormalizeTokens = true
+	runner := NewRunner(cfg, newProviders(), false)
+	runner.SetReferenceTokenizer(tokenizer.Heuristic{})
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	result := results[0]
+	assert.Equal(t, 50, result.OutputTokens, "the native count is kept for cost")
+	assert.Equal(t, 50, result.NativeOutputTokens)
+	assert.Equal(t, 6, result.NormalizedOutputTokens)
+	assert.InDelta(t, 60/1e6, result.Cost, 1e-12)
+	assert.InDelta(t, 6/result.TotalTime.Seconds(), result.TokensPerSecond, 1e-6, "tokens/sec uses the normalized count")
+
+	// Without normalization only the native count is recorded
+	cfg = newStubConfig(t, 1, "stub-model")
+	results, _, err = NewRunner(cfg, newProviders(), false).Execute(context.Background())
 	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Zero(t, results[0].NativeOutputTokens)
+	assert.Zero(t, results[0].NormalizedOutputTokens)
+	assert.InDelta(t, 50/results[0].TotalTime.Seconds(), results[0].TokensPerSecond, 1e-6)
+}
 
-	// Create benchmark runner
-	runner := NewBenchmarkRunner(cfg, prompts, factory)
+func TestRunner_CancelKeepsCompletedResults(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Runs = 20
 
-	// Run benchmarks
-	results := make(chan BenchmarkResult, 10)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	provider := &stubProvider{name: "openai", content: "Hello there", delay: 20 * time.Millisecond, tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	// Cancel partway through the third request, like SIGINT does
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 	defer cancel()
 
+	results, summary, err := runner.Execute(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Only the requests that finished are kept; the one cut off is not recorded as a failure
+	require.NotEmpty(t, results)
+	assert.Less(t, len(results), cfg.Runs)
+	for _, result := range results {
+		assert.NoError(t, result.Error)
+	}
+	assert.Equal(t, len(results), summary.TotalRuns)
+	assert.Zero(t, summary.FailedRuns)
+}
+
+// rateLimitedProvider rejects requests with a RateLimitError while more than limit are in flight
+type rateLimitedProvider struct {
+	*stubProvider
+	limit int
+
+	mu       sync.Mutex
+	inFlight int
+	rejected int
+}
+
+func (p *rateLimitedProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	p.mu.Lock()
+	if p.inFlight >= p.limit {
+		p.rejected++
+		p.mu.Unlock()
+		return nil, &providers.RateLimitError{Provider: p.name}
+	}
+	p.inFlight++
+	p.mu.Unlock()
+
+	stream, err := p.stubProvider.StreamChat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	responseChan := make(chan providers.ChatResponse)
 	go func() {
-		defer close(results)
-		err := runner.RunSequential(ctx, results)
-		assert.NoError(t, err)
+		defer close(responseChan)
+		for resp := range stream {
+			if resp.IsComplete {
+				// Free the slot before the runner sees the stream end
+				p.mu.Lock()
+				p.inFlight--
+				p.mu.Unlock()
+			}
+			responseChan <- resp
+		}
 	}()
+	return responseChan, nil
+}
+
+func TestRunner_AdaptiveConcurrencyBacksOffOnRateLimits(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Concurrent = 8
+	cfg.AdaptiveConcurrency = true
+	cfg.Runs = 80
+
+	provider := &rateLimitedProvider{stubProvider: &stubProvider{name: "openai", content: "Hello", delay: 5 * time.Millisecond, tokens: 10}, limit: 3}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, results, cfg.Runs)
+
+	// The cap climbs from 1 to the provider's limit and settles there instead of
+	// overrunning it with all 8 workers
+	limit := runner.ConcurrencyLimits()["openai"]
+	assert.GreaterOrEqual(t, limit, 2)
+	assert.LessOrEqual(t, limit, provider.limit+1)
+	assert.Less(t, provider.rejected, cfg.Runs/5, "rejected %d of %d requests", provider.rejected, cfg.Runs)
+}
+
+func TestRunner_ConcurrencySweep(t *testing.T) {
+	// 8 work items of ~40ms each: wall time halves with each doubling of workers
+	// until there is one worker per item, after which extra workers sit idle
+	cfg := newStubConfig(t, 8, "stub-model")
+	cfg.ConcurrencySweep = []int{1, 2, 4, 8, 16}
+
+	provider := &stubProvider{name: "openai", content: "Hello", delay: 40 * time.Millisecond, tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
 
-	// Collect results
-	var allResults []BenchmarkResult
-	for result := range results {
-		allResults = append(allResults, result)
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 8*len(cfg.ConcurrencySweep))
+
+	levels := SummarizeConcurrencyLevels(results)
+	require.Len(t, levels, len(cfg.ConcurrencySweep))
+	for i, level := range levels {
+		assert.Equal(t, cfg.ConcurrencySweep[i], level.Concurrency)
+		assert.Equal(t, 8, level.Summary.SuccessfulRuns)
+		assert.Greater(t, level.Summary.AggregateThroughput, 0.0)
 	}
 
-	// Verify results
-	assert.Len(t, allResults, 2) // 2 prompts
+	// Each level drains before the next starts
+	for i := 1; i < len(levels); i++ {
+		var prevEnd, start time.Time
+		for _, result := range results {
+			switch result.Concurrency {
+			case levels[i-1].Concurrency:
+				if result.EndTime.After(prevEnd) {
+					prevEnd = result.EndTime
+				}
+			case levels[i].Concurrency:
+				if start.IsZero() || result.StartTime.Before(start) {
+					start = result.StartTime
+				}
+			}
+		}
+		assert.False(t, start.Before(prevEnd), "level %d started before level %d drained", levels[i].Concurrency, levels[i-1].Concurrency)
+	}
 
-	for _, result := range allResults {
-		assert.Equal(t, "mock-model", result.Model)
-		assert.False(t, result.IsSuccessful())
-		assert.NotNil(t, result.Error)
-		assert.Equal(t, 0, result.InputTokens)
-		assert.Equal(t, 0, result.OutputTokens)
-		assert.Equal(t, 0.0, result.Cost)
+	// Higher concurrency is faster until the workers outnumber the work
+	for i := 1; i < 4; i++ {
+		assert.Less(t, levels[i].Summary.WallTime, levels[i-1].Summary.WallTime*3/4,
+			"concurrency %d took %v, %d took %v", levels[i].Concurrency, levels[i].Summary.WallTime, levels[i-1].Concurrency, levels[i-1].Summary.WallTime)
 	}
+	assert.Greater(t, levels[4].Summary.WallTime, levels[3].Summary.WallTime/2, "saturated at 8 workers")
 }
 
-func TestBenchmarkRunner_ContextCancellation(t *testing.T) {
-	// Create test configuration
-	cfg := &config.Config{
-		Models: map[string]config.ModelConfig{
-			"mock-model": {
-				Provider:         "mock",
-				InputCostPer1K:   0.001,
-				OutputCostPer1K:  0.002,
-				MaxTokens:        1000,
-				ContextLength:    4000,
-			},
-		},
+// peakOverlap returns the most of a provider's results that were in flight at once
+func peakOverlap(results []BenchmarkResult, providerName string) int {
+	peak := 0
+	for _, a := range results {
+		if a.Provider != providerName {
+			continue
+		}
+		inFlight := 0
+		for _, b := range results {
+			if b.Provider == providerName && !b.StartTime.After(a.StartTime) && b.EndTime.After(a.StartTime) {
+				inFlight++
+			}
+		}
+		peak = max(peak, inFlight)
 	}
+	return peak
+}
 
-	// Create test prompts
-	prompts := map[string]config.Prompt{
-		"test1": {
-			Name:        "Test Prompt 1",
-			Description: "First test prompt",
-			Prompt:      "Hello, world!",
-		},
-		"test2": {
-			Name:        "Test Prompt 2",
-			Description: "Second test prompt",
-			Prompt:      "How are you today?",
-		},
+func TestRunner_PerProviderPools(t *testing.T) {
+	cfg := newStubConfig(t, 1, "fast-model")
+	cfg.Models.Groq = map[string]config.ModelSpec{"slow-model": {TokenPrice: config.ModelPricing{Input: 1, Output: 1}}}
+	cfg.Concurrent = 4
+	cfg.ProviderConcurrency = map[string]int{"groq": 2}
+	cfg.Runs = 16
+
+	fast := &stubProvider{name: "openai", content: "Hello", delay: 5 * time.Millisecond, tokens: 10}
+	slow := &stubProvider{name: "groq", content: "Hello", delay: 50 * time.Millisecond, tokens: 10}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": fast, "groq": slow}, false)
+
+	start := time.Now()
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 32)
+
+	var fastDone, slowDone time.Time
+	for _, result := range results {
+		require.True(t, result.Success)
+		done := &fastDone
+		if result.Provider == "groq" {
+			done = &slowDone
+		}
+		if result.EndTime.After(*done) {
+			*done = result.EndTime
+		}
 	}
 
-	// Create mock provider with long delay
-	provider := &MockProvider{
-		name:  "mock",
-		delay: 1 * time.Second, // Long delay
+	// The slow provider's 2 workers take ~400ms for 16 runs; the fast provider's 4
+	// workers aren't held up by them and finish all of theirs in a fraction of that
+	assert.Less(t, fastDone.Sub(start), slowDone.Sub(start)/2, "fast provider finished at %v, slow at %v", fastDone.Sub(start), slowDone.Sub(start))
+	assert.Equal(t, 2, peakOverlap(results, "groq"))
+	assert.LessOrEqual(t, peakOverlap(results, "openai"), 4)
+}
+
+func TestParseConcurrencySweep(t *testing.T) {
+	levels, err := ParseConcurrencySweep("1, 2,4,,8")
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 4, 8}, levels)
+
+	for _, value := range []string{"", "1,two", "0,2", "-1"} {
+		_, err := ParseConcurrencySweep(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestAIMDLimiter(t *testing.T) {
+	limiter := newAIMDLimiter(4)
+	ctx := context.Background()
+
+	// One success at cap 1 grows it to 2, two more grow it to 3
+	for _, want := range []int{2, 2, 3} {
+		epoch, ok := limiter.acquire(ctx)
+		require.True(t, ok)
+		limiter.release(epoch, false)
+		assert.Equal(t, want, limiter.Limit())
 	}
 
-	// Create provider factory and register mock provider
-	factory := providers.NewProviderFactory()
-	err := factory.RegisterProvider("mock", func(config interface{}) (providers.Provider, error) {
-		return provider, nil
-	})
+	// Rate limits from requests in flight together halve the cap once
+	first, _ := limiter.acquire(ctx)
+	second, _ := limiter.acquire(ctx)
+	assert.True(t, limiter.release(first, true))
+	assert.False(t, limiter.release(second, true))
+	assert.Equal(t, 1, limiter.Limit())
+
+	// acquire gives up when the context ends while the cap is full
+	held, _ := limiter.acquire(ctx)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, ok := limiter.acquire(cancelled)
+	assert.False(t, ok)
+	limiter.release(held, false)
+}
+
+// embeddingProvider is a stubProvider that also embeds, returning fixed-size vectors
+type embeddingProvider struct {
+	*stubProvider
+	requests []providers.EmbeddingRequest
+}
+
+func (e *embeddingProvider) Embed(ctx context.Context, req providers.EmbeddingRequest) (*providers.EmbeddingResponse, error) {
+	e.requests = append(e.requests, req)
+	time.Sleep(e.delay)
+	return &providers.EmbeddingResponse{Count: len(req.Input), Dimensions: 1536, Latency: e.delay, Usage: &providers.Usage{InputTokens: 2000}}, nil
+}
+
+func TestRunner_EmbeddingsMode(t *testing.T) {
+	cfg := newStubConfig(t, 2, "chat-model")
+	cfg.Mode = config.ModeEmbeddings
+	cfg.Models.Embeddings = map[string]map[string]config.ModelSpec{
+		"openai": {"text-embedding-3-small": {
+			TokenPrice: config.ModelPricing{Input: 0.02},
+			Parameters: map[string]interface{}{"dimensions": 1536},
+		}},
+	}
+	// Sampling parameters don't apply to embeddings
+	cfg.Models.Sweep = config.SweepConfig{Temperatures: []float64{0, 1}}
+
+	provider := &embeddingProvider{stubProvider: &stubProvider{name: "openai", delay: 10 * time.Millisecond, err: assert.AnError}}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2, "one embedding per prompt; chat models are not run")
+
+	for _, result := range results {
+		require.NoError(t, result.Error)
+		assert.Equal(t, "text-embedding-3-small", result.Model)
+		assert.Equal(t, 1536, result.Dimensions)
+		assert.Equal(t, 2000, result.InputTokens)
+		assert.Zero(t, result.OutputTokens)
+		assert.GreaterOrEqual(t, result.TTFT, 10*time.Millisecond)
+		// The round trip is both TTFT and total time
+		assert.InDelta(t, float64(result.TotalTime), float64(result.TTFT), float64(5*time.Millisecond))
+		assert.InDelta(t, 2000*0.02/1e6, result.Cost, 1e-12)
+	}
+	assert.Equal(t, []string{"Hello"}, provider.requests[0].Input)
+	assert.Equal(t, 1536, provider.requests[0].ExtraParams["dimensions"])
+}
+
+func TestRunner_EmbeddingsModeUnsupportedProvider(t *testing.T) {
+	cfg := newStubConfig(t, 1)
+	cfg.Mode = config.ModeEmbeddings
+	cfg.Models.Embeddings = map[string]map[string]config.ModelSpec{
+		"openai": {"text-embedding-3-small": {TokenPrice: config.ModelPricing{Input: 0.02}}},
+	}
+
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": &stubProvider{name: "openai"}}, false)
+	plan, err := runner.Plan()
 	require.NoError(t, err)
+	require.Len(t, plan.Problems, 1)
+	assert.Contains(t, plan.Problems[0].String(), "does not support embeddings")
+}
 
-	// Create benchmark runner
-	runner := NewBenchmarkRunner(cfg, prompts, factory)
+// toolCallProvider streams some text and then a tool call, capturing the request
+type toolCallProvider struct {
+	*stubProvider
+	request providers.ChatRequest
+}
 
-	// Run benchmarks with short timeout
-	results := make(chan BenchmarkResult, 10)
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond) // Short timeout
-	defer cancel()
+func (p *toolCallProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	p.request = req
+	responseChan := make(chan providers.ChatResponse, 4)
+	responseChan <- providers.ChatResponse{Content: "Checking.", Timestamp: time.Now()}
+	go func() {
+		defer close(responseChan)
+		time.Sleep(p.delay)
+		responseChan <- providers.ChatResponse{ToolCalls: []providers.ToolCallDelta{{Name: "get_weather", Arguments: `{"city":`}}, Timestamp: time.Now()}
+		responseChan <- providers.ChatResponse{ToolCalls: []providers.ToolCallDelta{{Arguments: `"Paris"}`}}, Timestamp: time.Now()}
+		responseChan <- providers.ChatResponse{IsComplete: true, FinishReason: "tool_calls", Timestamp: time.Now()}
+	}()
+	return responseChan, nil
+}
+
+func TestRunner_RecordsToolCalls(t *testing.T) {
+	cfg := newStubConfig(t, 0, "stub-model")
+	prompt := `
+user: "Weather in Paris?"
+tool_choice: required
+tools:
+  - type: function
+    function:
+      name: get_weather
+      parameters:
+        type: object
+        properties:
+          city: {type: string}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.PromptsDir, "tools.yaml"), []byte(prompt), 0644))
+
+	provider := &toolCallProvider{stubProvider: &stubProvider{name: "openai", delay: 20 * time.Millisecond, tokens: 5}}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	result := results[0]
+	require.NoError(t, result.Error)
+
+	// Tools are passed through as request parameters
+	assert.Equal(t, "required", provider.request.ExtraParams["tool_choice"])
+	tools, ok := provider.request.ExtraParams["tools"].([]map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "function", tools[0]["type"])
+
+	// Text and tool call are recorded apart, with the tool call timed on its own
+	assert.True(t, result.ToolCall)
+	assert.Equal(t, "Checking.", result.Response)
+	assert.Equal(t, "tool_calls", result.FinishReason)
+	assert.GreaterOrEqual(t, result.TimeToToolCall, 20*time.Millisecond)
+	assert.Less(t, result.TTFT, result.TimeToToolCall)
+}
+
+func TestRunner_NoToolCall(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": &stubProvider{name: "openai", content: "Hi", tokens: 5}}, false)
+
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].ToolCall)
+	assert.Zero(t, results[0].TimeToToolCall)
+}
 
+// reasoningProvider streams reasoning, then after a delay the answer
+type reasoningProvider struct {
+	*stubProvider
+}
+
+func (p *reasoningProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	responseChan := make(chan providers.ChatResponse, 4)
+	responseChan <- providers.ChatResponse{ReasoningContent: "Thinking it over.", Timestamp: time.Now()}
 	go func() {
-		defer close(results)
-		err := runner.RunSequential(ctx, results)
-		assert.NoError(t, err)
+		defer close(responseChan)
+		time.Sleep(p.delay)
+		responseChan <- providers.ChatResponse{Content: "Hello!", Timestamp: time.Now()}
+		responseChan <- providers.ChatResponse{IsComplete: true, FinishReason: "stop", Timestamp: time.Now()}
 	}()
+	return responseChan, nil
+}
+
+func TestRunner_RecordsModelVersion(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	provider := &stubProvider{name: "openai", content: "Hello", tokens: 5, fingerprint: "fp_b42ea4cc6f"}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "stub-model", results[0].ResponseModel)
+	assert.Equal(t, "fp_b42ea4cc6f", results[0].SystemFingerprint)
+}
+
+func TestRunner_RecordsReasoningAndAnswerTimes(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	provider := &reasoningProvider{stubProvider: &stubProvider{name: "openai", delay: 20 * time.Millisecond, tokens: 5}}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	result := results[0]
+	require.NoError(t, result.Error)
+
+	// TTFT is the first reasoning token; the answer is timed on its own and kept apart
+	assert.Equal(t, "Hello!", result.Response)
+	assert.Equal(t, result.TTFT, result.TimeToReasoning)
+	assert.GreaterOrEqual(t, result.TimeToAnswer-result.TimeToReasoning, 20*time.Millisecond)
+}
+
+func TestRunner_RedactedReasoning(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	provider := &redactedReasoningProvider{stubProvider: &stubProvider{name: "openai", delay: 20 * time.Millisecond, tokens: 5}}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	// Encrypted reasoning has no text but still starts the reasoning phase
+	result := results[0]
+	assert.Equal(t, "Hello!", result.Response)
+	assert.NotZero(t, result.TimeToReasoning)
+	assert.Equal(t, result.TTFT, result.TimeToReasoning)
+	assert.GreaterOrEqual(t, result.TimeToAnswer-result.TimeToReasoning, 20*time.Millisecond)
+}
+
+// redactedReasoningProvider streams an encrypted reasoning block, then after a delay the answer
+type redactedReasoningProvider struct {
+	*stubProvider
+}
 
-	// Collect results
-	var allResults []BenchmarkResult
-	for result := range results {
-		allResults = append(allResults, result)
+func (p *redactedReasoningProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	responseChan := make(chan providers.ChatResponse, 4)
+	responseChan <- providers.ChatResponse{RedactedReasoning: true, Timestamp: time.Now()}
+	go func() {
+		defer close(responseChan)
+		time.Sleep(p.delay)
+		responseChan <- providers.ChatResponse{Content: "Hello!", Timestamp: time.Now()}
+		responseChan <- providers.ChatResponse{IsComplete: true, FinishReason: "stop", Timestamp: time.Now()}
+	}()
+	return responseChan, nil
+}
+
+func TestRunner_NoStreamedReasoning(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": &stubProvider{name: "openai", content: "Hi", tokens: 5}}, false)
+
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Zero(t, results[0].TimeToReasoning)
+	assert.Equal(t, results[0].TTFT, results[0].TimeToAnswer)
+}
+
+func TestRunner_JSONLogWarnsAboutMissingModels(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	runner := NewRunner(cfg, map[string]providers.Provider{
+		"openai":  &stubProvider{name: "openai", content: "Hi", tokens: 5},
+		"mistral": &stubProvider{name: "mistral", content: "Hi", tokens: 5},
+	}, false)
+	var logs bytes.Buffer
+	runner.SetLogger(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	// The provider with no models in the config is skipped with one JSON warning
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	require.Len(t, lines, 1)
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "Failed to get models for provider", record["msg"])
+	assert.Equal(t, "mistral", record["provider"])
+	assert.Equal(t, "unknown provider: mistral", record["error"])
+	assert.Contains(t, record, "time")
+}
+
+func TestRunner_ValidatesJSONResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		format  map[string]interface{}
+		checked bool // Whether ValidJSON is recorded at all
+		valid   bool
+	}{
+		{"valid JSON", `{"answer": 42}`, map[string]interface{}{"type": "json_object"}, true, true},
+		{"invalid JSON", `Sure! {"answer": 42}`, map[string]interface{}{"type": "json_schema"}, true, false},
+		{"plain text", "Hello", map[string]interface{}{"type": "text"}, false, false},
+		{"no response format", "Hello", nil, false, false},
 	}
 
-	// Should have fewer results due to timeout
-	assert.LessOrEqual(t, len(allResults), 2)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newStubConfig(t, 1, "stub-model")
+			if tt.format != nil {
+				cfg.Models.OpenAI["stub-model"] = config.ModelSpec{
+					TokenPrice: config.ModelPricing{Input: 1, Output: 1},
+					Parameters: map[string]interface{}{"response_format": tt.format},
+				}
+			}
+			runner := NewRunner(cfg, map[string]providers.Provider{"openai": &stubProvider{name: "openai", content: tt.content, tokens: 5}}, false)
+
+			results, _, err := runner.Execute(context.Background())
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+			if !tt.checked {
+				assert.Nil(t, results[0].ValidJSON)
+				return
+			}
+			require.NotNil(t, results[0].ValidJSON)
+			assert.Equal(t, tt.valid, *results[0].ValidJSON)
+		})
+	}
 }
 
-func TestBenchmarkRunner_InvalidModel(t *testing.T) {
-	// Create test configuration with non-existent model
-	cfg := &config.Config{
-		Models: map[string]config.ModelConfig{
-			"non-existent-model": {
-				Provider:         "non-existent-provider",
-				InputCostPer1K:   0.001,
-				OutputCostPer1K:  0.002,
-				MaxTokens:        1000,
-				ContextLength:    4000,
-			},
-		},
+// recordingProvider streams like stubProvider, capturing the requests
+type recordingProvider struct {
+	*stubProvider
+	requests []providers.ChatRequest
+}
+
+func (p *recordingProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	p.requests = append(p.requests, req)
+	return p.stubProvider.StreamChat(ctx, req)
+}
+
+func TestRunner_PromptResponseFormatOverridesModel(t *testing.T) {
+	cfg := newStubConfig(t, 0, "stub-model")
+	cfg.Models.OpenAI["stub-model"] = config.ModelSpec{
+		TokenPrice: config.ModelPricing{Input: 1, Output: 1},
+		Parameters: map[string]interface{}{"response_format": map[string]interface{}{"type": "text"}},
 	}
+	prompt := "user: \"List three colors\"\nresponse_format:\n  type: json_object\n"
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.PromptsDir, "colors.yaml"), []byte(prompt), 0644))
 
-	// Create test prompts
-	prompts := map[string]config.Prompt{
-		"test1": {
-			Name:        "Test Prompt 1",
-			Description: "First test prompt",
-			Prompt:      "Hello, world!",
-		},
+	provider := &recordingProvider{stubProvider: &stubProvider{name: "openai", content: `["red", "green", "blue"]`, tokens: 5}}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Len(t, provider.requests, 1)
+	assert.Equal(t, map[string]interface{}{"type": "json_object"}, provider.requests[0].ExtraParams["response_format"])
+	require.NotNil(t, results[0].ValidJSON)
+	assert.True(t, *results[0].ValidJSON)
+}
+
+func TestRunner_ModelPatterns(t *testing.T) {
+	cfg := newStubConfig(t, 1, "gpt-4o", "gpt-4o-mini", "gpt-4o-realtime-preview", "o3-mini")
+	var err error
+	cfg.IncludeModels, err = config.ParseModelPatterns("gpt-4o.*")
+	require.NoError(t, err)
+	cfg.ExcludeModels, err = config.ParseModelPatterns("openai/.*-preview")
+	require.NoError(t, err)
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": &stubProvider{name: "openai", content: "Hi", tokens: 5}}, false)
+
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+
+	var models []string
+	for _, result := range results {
+		models = append(models, result.Model)
 	}
+	assert.ElementsMatch(t, []string{"gpt-4o", "gpt-4o-mini"}, models)
+}
 
-	// Create provider factory (no providers registered)
-	factory := providers.NewProviderFactory()
+func TestRunner_CancelStopsConcurrentWorkers(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Runs = 20
+	cfg.Concurrent = 2
+
+	// Every request would take a second unless its stream is closed
+	provider := &countingProvider{stubProvider: &stubProvider{name: "openai", content: "Hello", delay: time.Second, tokens: 5}, calls: make(map[string]int)}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	err := runner.Run(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "in-flight streams end with the run")
+
+	// Workers don't pick up queued work after the cancellation
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, runner.GetResults())
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	assert.Equal(t, cfg.Concurrent, provider.calls["stub-model"])
+}
 
-	// Create benchmark runner
-	runner := NewBenchmarkRunner(cfg, prompts, factory)
+func TestRunner_SamplingOverrides(t *testing.T) {
+	cfg := newStubConfig(t, 1, "gpt-3.5-turbo", "gpt-5-mini")
+	for model := range cfg.Models.OpenAI {
+		cfg.Models.OpenAI[model] = config.ModelSpec{
+			TokenPrice: config.ModelPricing{Input: 1, Output: 1},
+			Parameters: map[string]interface{}{"temperature": 1.2, "max_tokens": 4096, "top_p": 0.5},
+		}
+	}
+	cfg.Models.Sweep = config.SweepConfig{MaxTokens: []int{2048}}
+	temperature, maxTokens, topP := 0.2, 256, 0.9
+	cfg.Temperature, cfg.MaxTokens, cfg.TopP = &temperature, &maxTokens, &topP
 
-	// Run benchmarks
-	results := make(chan BenchmarkResult, 10)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	provider := &recordingProvider{stubProvider: &stubProvider{name: "openai", content: "Hi", tokens: 5}}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
 
-	go func() {
-		defer close(results)
-		err := runner.RunSequential(ctx, results)
-		assert.NoError(t, err)
-	}()
+	requests := make(map[string]providers.ChatRequest)
+	for _, req := range provider.requests {
+		requests[req.Model] = req
+	}
 
-	// Collect results
-	var allResults []BenchmarkResult
-	for result := range results {
-		allResults = append(allResults, result)
+	// The overrides win over the sweep and the model's parameters
+	req := requests["gpt-3.5-turbo"]
+	require.NotNil(t, req.Temperature)
+	assert.Equal(t, 0.2, *req.Temperature)
+	assert.Equal(t, 256, req.MaxTokens)
+	assert.Equal(t, 0.9, req.TopP)
+	assert.Equal(t, map[string]interface{}{}, req.ExtraParams)
+
+	// gpt-5-class models take only max_tokens; their sampling parameters are left alone
+	req = requests["gpt-5-mini"]
+	assert.Equal(t, 256, req.MaxTokens)
+	require.NotNil(t, req.Temperature)
+	assert.Equal(t, 0.7, *req.Temperature)
+	assert.Equal(t, 1.0, req.TopP)
+	assert.Equal(t, map[string]interface{}{"temperature": 1.2, "top_p": 0.5}, req.ExtraParams)
+}
+
+func TestRunner_ModelHeaders(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Models.OpenAI["stub-model"] = config.ModelSpec{
+		TokenPrice:   config.ModelPricing{Input: 1, Output: 1},
+		ExtraHeaders: map[string]string{"X-Gateway-Token": "gw-secret"},
+		AuthHeader:   "X-Api-Key",
+		AuthScheme:   "Token",
 	}
 
-	// Should have results but they should be failed
-	assert.Len(t, allResults, 1)
+	provider := &recordingProvider{stubProvider: &stubProvider{name: "openai", content: "Hi", tokens: 5}}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	_, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
 
-	for _, result := range allResults {
-		assert.Equal(t, "non-existent-model", result.Model)
-		assert.False(t, result.IsSuccessful())
-		assert.NotNil(t, result.Error)
+	require.Len(t, provider.requests, 1)
+	assert.Equal(t, providers.RequestHeaders{
+		Extra:      map[string]string{"X-Gateway-Token": "gw-secret"},
+		AuthHeader: "X-Api-Key",
+		AuthScheme: "Token",
+	}, provider.requests[0].Headers)
+}
+
+func TestSortResults_ConcurrentRunsOrderedIdentically(t *testing.T) {
+	run := func() []BenchmarkResult {
+		cfg := newStubConfig(t, 2, "model-a", "model-b", "model-c")
+		cfg.Runs = 3
+		cfg.Concurrent = 6
+
+		// Uneven delays make workers finish out of dispatch order
+		provider := &jitterProvider{stubProvider: &stubProvider{name: "openai", content: "Hi", tokens: 5}}
+		runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+		results, _, err := runner.Execute(context.Background())
+		require.NoError(t, err)
+		SortResults(results)
+		return results
 	}
+
+	order := func(results []BenchmarkResult) []string {
+		var keys []string
+		for _, result := range results {
+			keys = append(keys, fmt.Sprintf("%s/%s/%s#%d", result.Provider, result.Model, result.PromptFile, result.Run))
+		}
+		return keys
+	}
+
+	first, second := order(run()), order(run())
+	require.Len(t, first, 18)
+	assert.Equal(t, first, second)
+	assert.Equal(t, "openai/model-a/prompt0#1", first[0])
+	assert.Equal(t, "openai/model-a/prompt0#2", first[1])
+	assert.Equal(t, "openai/model-c/prompt1#3", first[17])
 }
 
-func TestBenchmarkRunner_EmptyPrompts(t *testing.T) {
-	// Create test configuration
-	cfg := &config.Config{
-		Models: map[string]config.ModelConfig{
-			"mock-model": {
-				Provider:         "mock",
-				InputCostPer1K:   0.001,
-				OutputCostPer1K:  0.002,
-				MaxTokens:        1000,
-				ContextLength:    4000,
-			},
-		},
+// jitterProvider delays each stream by a random few milliseconds
+type jitterProvider struct {
+	*stubProvider
+}
+
+func (p *jitterProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	time.Sleep(time.Duration(rand.IntN(5)) * time.Millisecond)
+	return p.stubProvider.StreamChat(ctx, req)
+}
+
+// backendProvider reports which backend serves its requests, like Gemini does
+type backendProvider struct {
+	*stubProvider
+	backend string
+}
+
+func (p *backendProvider) GetBackendInfo() string {
+	return p.backend
+}
+
+func TestRunner_RecordsBackend(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	provider := &backendProvider{stubProvider: &stubProvider{name: "openai", content: "Hi", tokens: 5}, backend: "vertex_ai"}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "vertex_ai", results[0].Backend)
+
+	// Providers with a single backend leave it empty
+	runner = NewRunner(cfg, map[string]providers.Provider{"openai": provider.stubProvider}, false)
+	results, _, err = runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].Backend)
+}
+
+func TestRunner_MaxDurationHaltsRun(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Runs = 20
+	cfg.Concurrent = 2
+	cfg.MaxDuration = 250 * time.Millisecond
+
+	// Each request takes 100ms, so only a few complete before the cap
+	provider := &stubProvider{name: "openai", content: "Hello", delay: 100 * time.Millisecond, tokens: 5}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	start := time.Now()
+	results, summary, err := runner.Execute(context.Background())
+	require.NoError(t, err, "reaching the max duration is a halt, not a failure")
+	assert.Less(t, time.Since(start), time.Second, "in-flight requests are cancelled")
+
+	assert.NotEmpty(t, results)
+	assert.Less(t, len(results), 20)
+	for _, result := range results {
+		assert.True(t, result.Success, "cancelled in-flight requests aren't recorded")
 	}
+	assert.Equal(t, "max duration reached", summary.HaltReason)
+}
 
-	// Create empty prompts
-	prompts := map[string]config.Prompt{}
+func TestRunner_RequestIDs(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent = append(sent, r.Header.Get(providers.RequestIDHeader))
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"1","model":"deepseek-chat","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := providers.NewOpenAICompatibleProvider("deepseek", &providers.OpenAICompatibleConfig{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	cfg := newStubConfig(t, 1, "deepseek-chat")
+	cfg.Models.DeepSeek, cfg.Models.OpenAI = cfg.Models.OpenAI, nil
+	cfg.Runs = 3
+	cfg.RequestIDs = true
+	runner := NewRunner(cfg, map[string]providers.Provider{"deepseek": provider}, false)
 
-	// Create mock provider
-	provider := &MockProvider{
-		name:  "mock",
-		delay: 10 * time.Millisecond,
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	// Each run sends its own ID and records the one it sent
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, sent, 3)
+	seen := make(map[string]bool)
+	for i, result := range results {
+		require.True(t, result.Success, "run %d: %v", i, result.Error)
+		assert.NotEmpty(t, result.RequestID)
+		assert.Equal(t, sent[i], result.RequestID, "run %d", i)
+		seen[result.RequestID] = true
 	}
+	assert.Len(t, seen, 3, "request IDs should be unique per run")
+}
+
+func TestRunner_RequestIDsOff(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	provider := &recordingProvider{stubProvider: &stubProvider{name: "openai", content: "Hi", tokens: 5}}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
 
-	// Create provider factory and register mock provider
-	factory := providers.NewProviderFactory()
-	err := factory.RegisterProvider("mock", func(config interface{}) (providers.Provider, error) {
-		return provider, nil
-	})
+	results, _, err := runner.Execute(context.Background())
 	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].RequestID)
+	require.Len(t, provider.requests, 1)
+	assert.Empty(t, provider.requests[0].RequestID)
+}
+
+func TestRunner_ModelStopSequences(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stop-model", "param-model", "plain-model")
+	stopped := cfg.Models.OpenAI["stop-model"]
+	stopped.Stop = []string{"\n\n", "END"}
+	cfg.Models.OpenAI["stop-model"] = stopped
+	param := cfg.Models.OpenAI["param-model"]
+	param.Stop = []string{"END"}
+	param.Parameters = map[string]interface{}{"stop": []interface{}{"STOP"}} // The parameter wins over the field
+	cfg.Models.OpenAI["param-model"] = param
+
+	provider := &recordingProvider{stubProvider: &stubProvider{name: "openai", content: "Hi", tokens: 5}}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	require.NoError(t, runner.Run(context.Background()))
+
+	stops := make(map[string][]string)
+	for _, req := range provider.requests {
+		stops[req.Model] = req.StopSequences()
+	}
+	require.Len(t, stops, 3)
+	assert.Equal(t, []string{"\n\n", "END"}, stops["stop-model"])
+	assert.Equal(t, []string{"STOP"}, stops["param-model"])
+	assert.Nil(t, stops["plain-model"])
+}
 
-	// Create benchmark runner
-	runner := NewBenchmarkRunner(cfg, prompts, factory)
+func TestRunner_ResultsChan(t *testing.T) {
+	cfg := newStubConfig(t, 2, "stub-model")
+	cfg.Runs = 3
+	cfg.Concurrent = 3
+	provider := &stubProvider{name: "openai", content: "Hello", delay: 5 * time.Millisecond, tokens: 5}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	// Two consumers each see every result, and their channels close when the run ends
+	var wg sync.WaitGroup
+	received := make([][]BenchmarkResult, 2)
+	for i := range received {
+		ch := runner.ResultsChan()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for result := range ch {
+				received[i] = append(received[i], result)
+			}
+		}()
+	}
 
-	// Run benchmarks
-	results := make(chan BenchmarkResult, 10)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	wg.Wait()
+
+	require.Len(t, results, 6)
+	for _, got := range received {
+		assert.ElementsMatch(t, results, got)
+	}
+}
 
+func TestRunner_ResultsChanSlowConsumer(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Runs = resultsChanBuffer + 10
+	provider := &stubProvider{name: "openai", content: "Hello", tokens: 5}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	var logs bytes.Buffer
+	runner.SetLogOutput(&logs)
+
+	// Nothing reads until the run is over, which must not hold the run up
+	ch := runner.ResultsChan()
+	done := make(chan struct{})
 	go func() {
-		defer close(results)
-		err := runner.RunSequential(ctx, results)
-		assert.NoError(t, err)
+		defer close(done)
+		require.NoError(t, runner.Run(context.Background()))
 	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run blocked on a consumer that isn't reading")
+	}
 
-	// Collect results
-	var allResults []BenchmarkResult
-	for result := range results {
-		allResults = append(allResults, result)
+	// The buffered results are still delivered, then the channel is closed
+	count := 0
+	for range ch {
+		count++
 	}
+	assert.Equal(t, resultsChanBuffer, count)
+	assert.Len(t, runner.GetResults(), resultsChanBuffer+10)
+	assert.Contains(t, logs.String(), "Dropped result for a slow results subscriber")
+}
+
+func TestRunner_WallClockDuration(t *testing.T) {
+	cfg := newStubConfig(t, 3, "stub-model")
+
+	provider := &stubProvider{name: "openai", content: "Hello", delay: 20 * time.Millisecond, tokens: 5}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	before := time.Now()
+	results, summary, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	// Sequential requests don't overlap, so the run takes at least as long as all of them
+	var sum time.Duration
+	for _, result := range results {
+		sum += result.TotalTime
+	}
+	assert.GreaterOrEqual(t, summary.WallTime, sum)
+	assert.False(t, summary.RunStart.Before(before))
+	assert.Equal(t, summary.RunEnd.Sub(summary.RunStart), summary.WallTime)
+
+	// Aggregate throughput is measured over the same wall-clock time
+	assert.InDelta(t, float64(summary.TotalOutputTokens)/summary.WallTime.Seconds(), summary.AggregateThroughput, 1e-9)
+}
+
+func TestRunner_QueueWait(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Runs = 4
+	cfg.Concurrent = 2
+
+	// Two workers share four 50ms requests, so the last two wait for a free worker
+	provider := &stubProvider{name: "openai", content: "Hello", delay: 50 * time.Millisecond, tokens: 5}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, true)
+	var logs bytes.Buffer
+	runner.SetLogger(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 4)
 
-	// Should have no results
-	assert.Len(t, allResults, 0)
-} 
\ No newline at end of file
+	var waits []time.Duration
+	for _, result := range results {
+		waits = append(waits, result.QueueWait)
+	}
+	slices.Sort(waits)
+	assert.Less(t, waits[1], 25*time.Millisecond, "the first two start right away")
+	assert.GreaterOrEqual(t, waits[2], 40*time.Millisecond, "the rest wait for a request to finish")
+	assert.GreaterOrEqual(t, waits[3], 40*time.Millisecond)
+
+	// Verbose mode logs where each run's time went
+	breakdowns := 0
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		if record["msg"] == "Timing breakdown" {
+			breakdowns++
+			assert.Contains(t, record, "queue_wait")
+			assert.Contains(t, record, "ttft")
+			assert.Contains(t, record, "streaming")
+		}
+	}
+	assert.Equal(t, 4, breakdowns)
+}
+
+func TestRunner_ProviderTimeout(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.RequestTimeout = 50 * time.Millisecond
+
+	// The provider takes longer than the default request timeout allows
+	provider := &stubProvider{name: "openai", content: "Hello", delay: 150 * time.Millisecond, tokens: 5}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Success)
+	var timeoutErr *providers.TimeoutError
+	require.ErrorAs(t, results[0].Error, &timeoutErr)
+	assert.Equal(t, 50*time.Millisecond, timeoutErr.Duration)
+
+	// Its own longer timeout lets it finish
+	cfg.ProviderTimeouts = map[string]time.Duration{"openai": time.Second}
+	runner = NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	results, _, err = runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Success, "%v", results[0].Error)
+}
+
+func TestRunner_LimitPrompts(t *testing.T) {
+	cfg := newStubConfig(t, 5, "stub-model")
+	cfg.LimitPrompts = 2
+	provider := &stubProvider{name: "openai", content: "Hi", tokens: 5}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+
+	var prompts []string
+	for _, result := range results {
+		prompts = append(prompts, result.PromptFile)
+	}
+	assert.Equal(t, []string{"prompt0", "prompt1"}, prompts)
+
+	// A limit above the number of prompts runs them all
+	cfg.LimitPrompts = 10
+	runner = NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	results, _, err = runner.Execute(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, results, 5)
+}
+
+func TestRunner_ShufflePrompts(t *testing.T) {
+	cfg := newStubConfig(t, 8, "stub-model")
+	cfg.Shuffle = true
+	cfg.ShuffleSeed = 42
+	provider := &stubProvider{name: "openai", content: "Hi", tokens: 5}
+
+	runPrompts := func() []string {
+		runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+		results, _, err := runner.Execute(context.Background())
+		require.NoError(t, err)
+		var prompts []string
+		for _, result := range results {
+			prompts = append(prompts, result.PromptFile)
+		}
+		return prompts
+	}
+
+	// Every prompt runs once, in an order other than name order
+	shuffled := runPrompts()
+	want := []string{"prompt0", "prompt1", "prompt2", "prompt3", "prompt4", "prompt5", "prompt6", "prompt7"}
+	assert.ElementsMatch(t, want, shuffled)
+	assert.NotEqual(t, want, shuffled)
+
+	// The same seed repeats the order
+	assert.Equal(t, shuffled, runPrompts())
+
+	// The limit applies after shuffling, giving a random sample
+	cfg.LimitPrompts = 3
+	assert.Equal(t, shuffled[:3], runPrompts())
+}
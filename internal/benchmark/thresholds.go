@@ -0,0 +1,68 @@
+package benchmark
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Thresholds are SLA limits a run must stay within; zero fields are not checked
+type Thresholds struct {
+	MaxP95TTFT   time.Duration
+	MaxErrorRate float64 // Fraction of failed runs, 0-1
+	// PerModel checks every model's summary instead of the run as a whole
+	PerModel bool
+}
+
+// ThresholdViolation describes a breached threshold
+type ThresholdViolation struct {
+	Scope   string // ModelKey of the model, or "" for the whole run
+	Metric  string // "p95_ttft" or "error_rate"
+	Message string
+}
+
+func (v ThresholdViolation) String() string {
+	if v.Scope == "" {
+		return v.Message
+	}
+	return v.Scope + ": " + v.Message
+}
+
+// Enabled reports whether any threshold is set
+func (t Thresholds) Enabled() bool {
+	return t.MaxP95TTFT > 0 || t.MaxErrorRate > 0
+}
+
+// CheckThresholds returns the thresholds the results breach, ordered by scope
+func CheckThresholds(results []BenchmarkResult, thresholds Thresholds) []ThresholdViolation {
+	summaries := map[string]Summary{"": CalculateSummary(results)}
+	if thresholds.PerModel {
+		summaries = CalculateSummaryByModel(results)
+	}
+
+	scopes := make([]string, 0, len(summaries))
+	for scope := range summaries {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	var violations []ThresholdViolation
+	for _, scope := range scopes {
+		summary := summaries[scope]
+		if thresholds.MaxP95TTFT > 0 && summary.P95TTFT > thresholds.MaxP95TTFT {
+			violations = append(violations, ThresholdViolation{
+				Scope:   scope,
+				Metric:  "p95_ttft",
+				Message: fmt.Sprintf("p95 TTFT %v exceeds %v", summary.P95TTFT, thresholds.MaxP95TTFT),
+			})
+		}
+		if thresholds.MaxErrorRate > 0 && summary.ErrorRate > thresholds.MaxErrorRate {
+			violations = append(violations, ThresholdViolation{
+				Scope:   scope,
+				Metric:  "error_rate",
+				Message: fmt.Sprintf("error rate %.2f%% exceeds %.2f%%", summary.ErrorRate*100, thresholds.MaxErrorRate*100),
+			})
+		}
+	}
+	return violations
+}
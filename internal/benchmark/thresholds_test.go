@@ -0,0 +1,48 @@
+package benchmark
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func thresholdResults() []BenchmarkResult {
+	var results []BenchmarkResult
+	// A fast, reliable model and a slow one that fails 1 in 4 runs
+	for i := 0; i < 4; i++ {
+		results = append(results, BenchmarkResult{Provider: "groq", Model: "fast", Success: true, TTFT: 100 * time.Millisecond})
+	}
+	for i := 0; i < 3; i++ {
+		results = append(results, BenchmarkResult{Provider: "openai", Model: "slow", Success: true, TTFT: 900 * time.Millisecond})
+	}
+	results = append(results, BenchmarkResult{Provider: "openai", Model: "slow", Success: false})
+	return results
+}
+
+func TestCheckThresholds_Pass(t *testing.T) {
+	thresholds := Thresholds{MaxP95TTFT: time.Second, MaxErrorRate: 0.25}
+	assert.True(t, thresholds.Enabled())
+	assert.Empty(t, CheckThresholds(thresholdResults(), thresholds))
+
+	// Nothing is checked without thresholds
+	assert.False(t, Thresholds{}.Enabled())
+	assert.Empty(t, CheckThresholds(thresholdResults(), Thresholds{PerModel: true}))
+}
+
+func TestCheckThresholds_Fail(t *testing.T) {
+	// Across the whole run: p95 is the slow model's 900ms, 1 of 8 runs failed
+	violations := CheckThresholds(thresholdResults(), Thresholds{MaxP95TTFT: 500 * time.Millisecond, MaxErrorRate: 0.2})
+	require.Len(t, violations, 1)
+	assert.Equal(t, "p95_ttft", violations[0].Metric)
+	assert.Equal(t, "p95 TTFT 900ms exceeds 500ms", violations[0].String())
+
+	// Per model, the slow model breaches both and the fast one neither
+	violations = CheckThresholds(thresholdResults(), Thresholds{MaxP95TTFT: 500 * time.Millisecond, MaxErrorRate: 0.2, PerModel: true})
+	require.Len(t, violations, 2)
+	for _, v := range violations {
+		assert.Equal(t, "openai/slow", v.Scope)
+	}
+	assert.Equal(t, "openai/slow: error rate 25.00% exceeds 20.00%", violations[1].String())
+}
@@ -0,0 +1,59 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// ChunkArrival is when one streamed content chunk arrived, relative to the request start
+type ChunkArrival struct {
+	Offset time.Duration
+	Length int // Bytes of content in the chunk
+}
+
+// MarshalJSON writes the offset in milliseconds, matching the CSV's timing columns
+func (c ChunkArrival) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		OffsetMS float64 `json:"offset_ms"`
+		ChunkLen int     `json:"chunk_len"`
+	}{
+		OffsetMS: float64(c.Offset.Microseconds()) / 1000.0,
+		ChunkLen: c.Length,
+	})
+}
+
+// unsafeFilenameChars matches characters replaced when building trace file names
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// WriteTrace writes a result's chunk arrivals to dir as NDJSON, one chunk per line,
+// and returns the file's path. seq distinguishes runs of the same model and prompt.
+func WriteTrace(dir string, seq int, result BenchmarkResult) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create trace directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%04d_%s_%s_%s.ndjson", seq, result.Provider, result.Model, result.PromptFile)
+	path := filepath.Join(dir, unsafeFilenameChars.ReplaceAllString(name, "_"))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create trace file: %w", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	for _, chunk := range result.Chunks {
+		if err := encoder.Encode(chunk); err != nil {
+			return "", fmt.Errorf("failed to write trace: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return "", fmt.Errorf("failed to write trace: %w", err)
+	}
+	return path, file.Close()
+}
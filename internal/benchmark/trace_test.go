@@ -0,0 +1,140 @@
+package benchmark
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/megzo/llm-latency-benchmark/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type traceLine struct {
+	OffsetMS float64 `json:"offset_ms"`
+	ChunkLen int     `json:"chunk_len"`
+}
+
+func readTrace(t *testing.T, path string) []traceLine {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var lines []traceLine
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line traceLine
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.NoError(t, scanner.Err())
+	return lines
+}
+
+func TestMetrics_RecordChunk(t *testing.T) {
+	// Off by default
+	metrics := NewMetrics()
+	metrics.RecordChunk(5)
+	assert.Empty(t, metrics.Chunks)
+
+	metrics = NewMetrics()
+	metrics.TraceChunks = true
+	lengths := []int{3, 1, 4, 1, 5}
+	for _, length := range lengths {
+		time.Sleep(2 * time.Millisecond)
+		metrics.RecordChunk(length)
+	}
+	metrics.Complete()
+
+	result := metrics.ToBenchmarkResult("groq", "qwen/qwen3-32b", "simple")
+	path, err := WriteTrace(t.TempDir(), 7, result)
+	require.NoError(t, err)
+	assert.Equal(t, "0007_groq_qwen_qwen3-32b_simple.ndjson", filepath.Base(path))
+
+	lines := readTrace(t, path)
+	require.Len(t, lines, len(lengths))
+	for i, line := range lines {
+		assert.Equal(t, lengths[i], line.ChunkLen)
+		assert.Greater(t, line.OffsetMS, 0.0)
+		if i > 0 {
+			assert.Greater(t, line.OffsetMS, lines[i-1].OffsetMS, "offsets must increase")
+		}
+	}
+	assert.LessOrEqual(t, lines[len(lines)-1].OffsetMS, float64(result.TotalTime.Microseconds())/1000.0)
+}
+
+// chunkedProvider streams its chunks with a fixed gap between them
+type chunkedProvider struct {
+	*stubProvider
+	chunks []string
+	gap    time.Duration
+}
+
+func (c *chunkedProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	responseChan := make(chan providers.ChatResponse)
+	go func() {
+		defer close(responseChan)
+		responses := make([]providers.ChatResponse, 0, len(c.chunks)+1)
+		for _, chunk := range c.chunks {
+			responses = append(responses, providers.ChatResponse{Content: chunk})
+		}
+		responses = append(responses, providers.ChatResponse{IsComplete: true})
+
+		for _, resp := range responses {
+			time.Sleep(c.gap)
+			resp.Timestamp = time.Now()
+			select {
+			case <-ctx.Done():
+				return
+			case responseChan <- resp:
+			}
+		}
+	}()
+	return responseChan, nil
+}
+
+func TestRunner_TraceDir(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	cfg.Runs = 2
+	cfg.TraceDir = filepath.Join(t.TempDir(), "traces")
+
+	provider := &chunkedProvider{
+		stubProvider: &stubProvider{name: "openai", tokens: 10},
+		chunks:       []string{"Hel", "lo", " there", "!"},
+		gap:          2 * time.Millisecond,
+	}
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": provider}, false)
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	paths, err := filepath.Glob(filepath.Join(cfg.TraceDir, "*.ndjson"))
+	require.NoError(t, err)
+	require.Len(t, paths, 2, "one trace per run")
+
+	for _, path := range paths {
+		lines := readTrace(t, path)
+		require.Len(t, lines, len(provider.chunks))
+		for i, line := range lines {
+			assert.Equal(t, len(provider.chunks[i]), line.ChunkLen)
+			if i > 0 {
+				assert.Greater(t, line.OffsetMS, lines[i-1].OffsetMS)
+			}
+		}
+	}
+}
+
+func TestRunner_NoTraceByDefault(t *testing.T) {
+	cfg := newStubConfig(t, 1, "stub-model")
+	runner := NewRunner(cfg, map[string]providers.Provider{"openai": &stubProvider{name: "openai", content: "Hello", tokens: 10}}, false)
+	results, _, err := runner.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].Chunks)
+}
@@ -4,44 +4,129 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/megzo/llm-latency-benchmark/internal/retry"
+	"github.com/megzo/llm-latency-benchmark/internal/tokenizer"
 	"github.com/megzo/llm-latency-benchmark/providers"
 )
 
 // Config holds all application configuration
 type Config struct {
-	// API Keys
+	// API Keys; OpenAI and Groq take a comma-separated list to rotate across
 	OpenAIAPIKey    string
 	GroqAPIKey      string
 	AnthropicAPIKey string
 	AzureOpenAIAPIKey string
 	GoogleAPIKey    string
+	PerplexityAPIKey string
+	DeepSeekAPIKey  string
+	XAIAPIKey       string
+	TogetherAPIKey  string
+	CerebrasAPIKey  string
+	SambaNovaAPIKey string
 
 	// Provider Base URLs
 	OpenAIBaseURL    string
+	OpenAIOrgID      string
+	OpenAIProjectID  string
 	GroqBaseURL      string
 	AnthropicBaseURL string
 	AzureOpenAIEndpoint string
 	AzureOpenAIAPIVersion string
+	AzureOpenAIAuthMode string // "api_key" (default) or "aad"
+	PerplexityBaseURL string
+	DeepSeekBaseURL  string
+	XAIBaseURL       string
+	TogetherBaseURL  string
+	CerebrasBaseURL  string
+	SambaNovaBaseURL string
+	GeminiUseVertex  bool // Run Gemini on Vertex AI instead of the public API
+	GoogleCloudProject  string
+	GoogleCloudLocation string
 
 	// Models configuration
 	Models *ModelsConfig
 
 	// CLI flags
 	Concurrent int
+	AdaptiveConcurrency bool // Treat Concurrent as a ceiling and adapt each provider's concurrency to rate limits
+	ConcurrencySweep []int // Run everything once at each of these concurrency levels in turn (nil = just Concurrent)
+	ProviderConcurrency map[string]int // Worker pool size per provider, overriding Concurrent for that provider
 	Runs       int
+	Mode       string // What to benchmark: ModeChat (default) or ModeEmbeddings
 	PromptsDir string
+	RecursivePrompts bool // Also load prompts from subdirectories of PromptsDir
+	LimitPrompts int // Run only the first this many prompts, in load order (0 = all)
+	Shuffle    bool   // Run the prompts in a random order, shuffled before LimitPrompts applies
+	ShuffleSeed uint64 // Seed for Shuffle, so a shuffled order can be repeated
 	OutputFile string
+	Append     bool // Append to OutputFile instead of replacing it
 	Verbose    bool
+	NoStream   bool
+	Tokenizer  string // Token estimator: "heuristic" (default) or "tiktoken"
+	VisibleTokensPerSecond bool // Exclude reasoning tokens from tokens/sec
+	NormalizeTokens bool // Recount output with one reference tokenizer for every provider; tokens/sec uses that count
+	OutputFormat string // Results format: "csv" (the default) or "table"
+	Measure    string // Where total time stops: MeasureCompletion (default) or MeasureLastToken
+
+	// Sampling overrides for every request, taking precedence over the sweep and
+	// per-model parameters (nil = not overridden)
+	Temperature *float64
+	MaxTokens   *int
+	TopP        *float64
+
+	// Selection (empty = everything available)
+	Providers   []string // Provider names to benchmark
+	ModelFilter []string // Model names to benchmark
+	IncludeModels []*regexp.Regexp // Only models whose name or provider/model matches one of these
+	ExcludeModels []*regexp.Regexp // Skip models whose name or provider/model matches one of these
 
 	// Benchmark settings
-	Timeout        time.Duration
-	RequestTimeout time.Duration
-	Retries        int
+	Timeout        time.Duration // Deadline for one benchmark including all retries (0 = none)
+	RequestTimeout time.Duration // Deadline for each attempt's streaming request
+	ProviderTimeouts map[string]time.Duration // Request deadline per provider, overriding RequestTimeout for that provider
+	Retries        int           // Extra attempts after a transient failure
+	RetryStrategy  string        // Backoff between attempts: "exponential" (default), "fixed" or "exponential-full-jitter"
+	RetryMaxDelay  time.Duration // Cap on the backoff between attempts (0 = retry.MaxDelay)
+	Warmup         int           // Unrecorded requests per model before measuring
+	Interleave     bool          // Rotate work across models instead of running each model's work together
+	Cooldown       time.Duration // Pause after each request, per sequential run or worker (0 = none)
+	ProxyURL       string        // Proxy for every provider's requests, overriding HTTP_PROXY/HTTPS_PROXY (empty = environment)
+	Strict         bool          // Fail validation on problems that are otherwise warnings, such as models without pricing
+	Incremental    bool          // Write each result to the output file as soon as it completes
+	MaxCost        float64 // Halt the run once accumulated cost exceeds this ($, 0 = unlimited)
+	MaxDuration    time.Duration // Halt the run after this much wall-clock time (0 = unlimited)
+	HistogramBuckets []time.Duration // Ascending TTFT histogram boundaries (nil = the defaults)
+	Percentiles    []float64 // Ascending TTFT and total time percentiles in the summary (nil = p95 and p99)
+	TraceDir       string // Write each run's chunk arrival times here as NDJSON (empty = off)
+	RequestIDs     bool   // Send a unique X-Request-Id with every run and record it on the result
+	SummaryJSONFile string // Also write the run and per-model summaries here as JSON (empty = off)
+
+	// NotifyWebhookURL receives the run summary when set (Slack/Discord incoming webhook)
+	NotifyWebhookURL string
+
+	// tokenCounter is built from Tokenizer on first use and shared by all providers
+	tokenCounter tokenizer.Tokenizer
 }
 
+// Benchmark modes
+const (
+	ModeChat       = "chat"       // Chat completions, streamed where supported
+	ModeEmbeddings = "embeddings" // Embedding requests to the models under embeddings: in models.yaml
+)
+
+// Where a run's total time stops
+const (
+	MeasureCompletion = "completion" // When the provider's stream closes, after its completion event
+	MeasureLastToken  = "last-token" // At the last streamed content token
+)
+
 // LoadConfig loads configuration from environment variables and files
 func LoadConfig(modelsFile string) (*Config, error) {
 	// Load .env file if it exists
@@ -55,22 +140,46 @@ func LoadConfig(modelsFile string) (*Config, error) {
 		AnthropicAPIKey: os.Getenv("ANTHROPIC_API_KEY"),
 		AzureOpenAIAPIKey: os.Getenv("AZURE_OPENAI_API_KEY"),
 		GoogleAPIKey:    os.Getenv("GOOGLE_API_KEY"),
+		PerplexityAPIKey: os.Getenv("PERPLEXITY_API_KEY"),
+		DeepSeekAPIKey:  os.Getenv("DEEPSEEK_API_KEY"),
+		XAIAPIKey:       os.Getenv("XAI_API_KEY"),
+		TogetherAPIKey:  os.Getenv("TOGETHER_API_KEY"),
+		CerebrasAPIKey:  os.Getenv("CEREBRAS_API_KEY"),
+		SambaNovaAPIKey: os.Getenv("SAMBANOVA_API_KEY"),
 
 		OpenAIBaseURL:    getEnvOrDefault("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+		OpenAIOrgID:      os.Getenv("OPENAI_ORG_ID"),
+		OpenAIProjectID:  os.Getenv("OPENAI_PROJECT_ID"),
 		GroqBaseURL:      getEnvOrDefault("GROQ_BASE_URL", "https://api.groq.com/openai/v1"),
 		AnthropicBaseURL: getEnvOrDefault("ANTHROPIC_BASE_URL", "https://api.anthropic.com"),
 		AzureOpenAIEndpoint: os.Getenv("AZURE_OPENAI_ENDPOINT"),
 		AzureOpenAIAPIVersion: getEnvOrDefault("AZURE_OPENAI_API_VERSION", "2024-02-15-preview"),
+		AzureOpenAIAuthMode: getEnvOrDefault("AZURE_OPENAI_AUTH_MODE", providers.AzureAuthAPIKey),
+		PerplexityBaseURL: getEnvOrDefault("PERPLEXITY_BASE_URL", "https://api.perplexity.ai"),
+		DeepSeekBaseURL:  getEnvOrDefault("DEEPSEEK_BASE_URL", "https://api.deepseek.com"),
+		XAIBaseURL:       getEnvOrDefault("XAI_BASE_URL", "https://api.x.ai/v1"),
+		GeminiUseVertex:  getEnvBool("GOOGLE_GENAI_USE_VERTEXAI"),
+		GoogleCloudProject:  os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		GoogleCloudLocation: os.Getenv("GOOGLE_CLOUD_LOCATION"),
+		TogetherBaseURL:  getEnvOrDefault("TOGETHER_BASE_URL", "https://api.together.xyz/v1"),
+		CerebrasBaseURL:  getEnvOrDefault("CEREBRAS_BASE_URL", "https://api.cerebras.ai/v1"),
+		SambaNovaBaseURL: getEnvOrDefault("SAMBANOVA_BASE_URL", "https://api.sambanova.ai/v1"),
 
 		Concurrent: 1,
 		Runs:       1,
+		Mode:       ModeChat,
 		PromptsDir: "prompts",
 		OutputFile: "",
+		OutputFormat: OutputFormatCSV,
+		Measure:    MeasureCompletion,
 		Verbose:    false,
 
-		Timeout:        30 * time.Second,
+		Timeout:        5 * time.Minute,
 		RequestTimeout: 60 * time.Second,
 		Retries:        3,
+		RetryMaxDelay:  retry.MaxDelay,
+
+		NotifyWebhookURL: os.Getenv("NOTIFY_WEBHOOK_URL"),
 	}
 
 	// Load models configuration
@@ -93,6 +202,104 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("runs must be at least 1")
 	}
 
+	if c.LimitPrompts < 0 {
+		return fmt.Errorf("prompt limit cannot be negative")
+	}
+
+	for _, level := range c.ConcurrencySweep {
+		if level < 1 {
+			return fmt.Errorf("concurrency sweep levels must be at least 1")
+		}
+	}
+
+	for provider, workers := range c.ProviderConcurrency {
+		if workers < 1 {
+			return fmt.Errorf("concurrency for provider %s must be at least 1", provider)
+		}
+	}
+
+	if c.MaxCost < 0 {
+		return fmt.Errorf("max cost cannot be negative")
+	}
+
+	if c.MaxDuration < 0 {
+		return fmt.Errorf("max duration cannot be negative")
+	}
+
+	if c.Retries < 0 {
+		return fmt.Errorf("retries cannot be negative")
+	}
+
+	if _, err := retry.Parse(c.RetryStrategy); err != nil {
+		return err
+	}
+
+	if c.RetryMaxDelay < 0 {
+		return fmt.Errorf("retry max delay cannot be negative")
+	}
+
+	if c.Warmup < 0 {
+		return fmt.Errorf("warmup cannot be negative")
+	}
+
+	if c.Cooldown < 0 {
+		return fmt.Errorf("cooldown cannot be negative")
+	}
+
+	if c.Temperature != nil && *c.Temperature < 0 {
+		return fmt.Errorf("temperature cannot be negative")
+	}
+
+	if c.MaxTokens != nil && *c.MaxTokens < 1 {
+		return fmt.Errorf("max tokens must be at least 1")
+	}
+
+	if c.TopP != nil && (*c.TopP <= 0 || *c.TopP > 1) {
+		return fmt.Errorf("top_p must be greater than 0 and at most 1")
+	}
+
+	if c.ProxyURL != "" {
+		if _, err := providers.ParseProxyURL(c.ProxyURL); err != nil {
+			return err
+		}
+	}
+
+	if c.Strict {
+		if unpriced := c.UnpricedModels(); len(unpriced) > 0 {
+			return fmt.Errorf("no token_price configured for %s", strings.Join(unpriced, ", "))
+		}
+	}
+
+	if c.Mode != "" && c.Mode != ModeChat && c.Mode != ModeEmbeddings {
+		return fmt.Errorf("unsupported mode %q (expected %q or %q)", c.Mode, ModeChat, ModeEmbeddings)
+	}
+
+	if c.OutputFormat != "" && c.OutputFormat != OutputFormatCSV && c.OutputFormat != OutputFormatTable {
+		return fmt.Errorf("unsupported output format %q (expected %q or %q)", c.OutputFormat, OutputFormatCSV, OutputFormatTable)
+	}
+
+	if c.Measure != "" && c.Measure != MeasureCompletion && c.Measure != MeasureLastToken {
+		return fmt.Errorf("unsupported measure %q (expected %q or %q)", c.Measure, MeasureCompletion, MeasureLastToken)
+	}
+
+	if c.Timeout < 0 || c.RequestTimeout < 0 {
+		return fmt.Errorf("timeouts cannot be negative")
+	}
+
+	for provider, timeout := range c.ProviderTimeouts {
+		if timeout <= 0 {
+			return fmt.Errorf("request timeout for provider %s must be positive", provider)
+		}
+	}
+
+	if _, err := tokenizer.New(c.Tokenizer); err != nil {
+		return err
+	}
+
+	if c.Append && c.OutputFile == "" {
+		return fmt.Errorf("append mode requires an output file")
+	}
+
 	if c.PromptsDir == "" {
 		return fmt.Errorf("prompts directory cannot be empty")
 	}
@@ -104,6 +311,79 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// Warnings returns problems with a valid configuration that are worth reporting
+func (c *Config) Warnings() []string {
+	var warnings []string
+	if c.Models != nil {
+		warnings = append(warnings, c.Models.Warnings()...)
+	}
+	if unpriced := c.UnpricedModels(); len(unpriced) > 0 && !c.Strict {
+		warnings = append(warnings, fmt.Sprintf("no token_price configured for %s; their cost will be reported as $0 (-strict makes this an error)", strings.Join(unpriced, ", ")))
+	}
+	if c.AdaptiveConcurrency && c.Concurrent <= 1 && !c.providerPoolsAbove(1) {
+		warnings = append(warnings, "adaptive concurrency has no effect with a concurrency of 1; set -concurrent to the maximum to allow")
+	}
+	if c.Timeout > 0 && c.RequestTimeout > c.Timeout {
+		warnings = append(warnings, fmt.Sprintf("request timeout (%v) exceeds the overall timeout (%v); requests will be cut off at %v and never retried", c.RequestTimeout, c.Timeout, c.Timeout))
+	}
+	providerNames := make([]string, 0, len(c.ProviderTimeouts))
+	for provider := range c.ProviderTimeouts {
+		providerNames = append(providerNames, provider)
+	}
+	sort.Strings(providerNames)
+	for _, provider := range providerNames {
+		if timeout := c.ProviderTimeouts[provider]; c.Timeout > 0 && timeout > c.Timeout {
+			warnings = append(warnings, fmt.Sprintf("request timeout for %s (%v) exceeds the overall timeout (%v); its requests will be cut off at %v and never retried", provider, timeout, c.Timeout, c.Timeout))
+		}
+	}
+	return warnings
+}
+
+// RequestTimeoutFor returns the deadline for each of a provider's requests: its
+// ProviderTimeouts entry when set, otherwise RequestTimeout
+func (c *Config) RequestTimeoutFor(provider string) time.Duration {
+	if timeout, ok := c.ProviderTimeouts[provider]; ok {
+		return timeout
+	}
+	return c.RequestTimeout
+}
+
+// UnpricedModels returns the models the run would benchmark, as sorted provider/model
+// keys, that have neither an input nor an output price and so would cost $0
+func (c *Config) UnpricedModels() []string {
+	if c.Models == nil {
+		return nil
+	}
+	specs := c.Models.chatModelSpecs()
+	if c.Mode == ModeEmbeddings {
+		specs = c.Models.Embeddings
+	}
+
+	var unpriced []string
+	for provider, models := range specs {
+		if !c.ProviderEnabled(provider) {
+			continue
+		}
+		for model, spec := range models {
+			if c.ModelEnabled(provider, model) && spec.TokenPrice.Input == 0 && spec.TokenPrice.Output == 0 {
+				unpriced = append(unpriced, provider+"/"+model)
+			}
+		}
+	}
+	sort.Strings(unpriced)
+	return unpriced
+}
+
+// providerPoolsAbove reports whether any provider's worker pool is larger than n
+func (c *Config) providerPoolsAbove(n int) bool {
+	for _, workers := range c.ProviderConcurrency {
+		if workers > n {
+			return true
+		}
+	}
+	return false
+}
+
 // GetOutputFile returns the output file path, generating a default if not specified
 func (c *Config) GetOutputFile() string {
 	if c.OutputFile != "" {
@@ -115,27 +395,58 @@ func (c *Config) GetOutputFile() string {
 	return filepath.Join("results", fmt.Sprintf("benchmark_%s.csv", timestamp))
 }
 
+// GetTokenizer returns the configured tokenizer; unknown names (rejected by
+// Validate) fall back to the default heuristic
+func (c *Config) GetTokenizer() tokenizer.Tokenizer {
+	if c.tokenCounter == nil {
+		t, err := tokenizer.New(c.Tokenizer)
+		if err != nil {
+			t = tokenizer.Default
+		}
+		c.tokenCounter = t
+	}
+	return c.tokenCounter
+}
+
 // GetOpenAIConfig returns OpenAI provider configuration
 func (c *Config) GetOpenAIConfig() *providers.OpenAIConfig {
+	key, moreKeys := splitAPIKeys(c.OpenAIAPIKey)
 	return &providers.OpenAIConfig{
-		APIKey:  c.OpenAIAPIKey,
-		BaseURL: c.OpenAIBaseURL,
+		APIKey:        key,
+		APIKeys:       moreKeys,
+		BaseURL:       c.OpenAIBaseURL,
+		Organization:  c.OpenAIOrgID,
+		Project:       c.OpenAIProjectID,
+		Tokenizer:     c.GetTokenizer(),
+		RetryStrategy: retry.Strategy(c.RetryStrategy),
+		RetryMaxDelay: c.RetryMaxDelay,
+		ProxyURL:      c.ProxyURL,
 	}
 }
 
 // GetGroqConfig returns Groq provider configuration
 func (c *Config) GetGroqConfig() *providers.GroqConfig {
+	key, moreKeys := splitAPIKeys(c.GroqAPIKey)
 	return &providers.GroqConfig{
-		APIKey:  c.GroqAPIKey,
-		BaseURL: c.GroqBaseURL,
+		APIKey:        key,
+		APIKeys:       moreKeys,
+		BaseURL:       c.GroqBaseURL,
+		Tokenizer:     c.GetTokenizer(),
+		RetryStrategy: retry.Strategy(c.RetryStrategy),
+		RetryMaxDelay: c.RetryMaxDelay,
+		ProxyURL:      c.ProxyURL,
 	}
 }
 
 // GetAnthropicConfig returns Anthropic provider configuration
 func (c *Config) GetAnthropicConfig() *providers.AnthropicConfig {
 	return &providers.AnthropicConfig{
-		APIKey:  c.AnthropicAPIKey,
-		BaseURL: c.AnthropicBaseURL,
+		APIKey:        c.AnthropicAPIKey,
+		BaseURL:       c.AnthropicBaseURL,
+		Tokenizer:     c.GetTokenizer(),
+		RetryStrategy: retry.Strategy(c.RetryStrategy),
+		RetryMaxDelay: c.RetryMaxDelay,
+		ProxyURL:      c.ProxyURL,
 	}
 }
 
@@ -145,17 +456,70 @@ func (c *Config) GetAzureOpenAIConfig() *providers.AzureOpenAIConfig {
 		Endpoint:       c.AzureOpenAIEndpoint,
 		APIKey:         c.AzureOpenAIAPIKey,
 		APIVersion:     c.AzureOpenAIAPIVersion,
+		AuthMode:       c.AzureOpenAIAuthMode,
+		Tokenizer:      c.GetTokenizer(),
+		RetryStrategy:  retry.Strategy(c.RetryStrategy),
+		RetryMaxDelay:  c.RetryMaxDelay,
+		ProxyURL:       c.ProxyURL,
 	}
 }
 
 // GetGeminiConfig returns Gemini provider configuration
 func (c *Config) GetGeminiConfig() *providers.GeminiConfig {
 	return &providers.GeminiConfig{
-		APIKey: c.GoogleAPIKey,
+		APIKey:         c.GoogleAPIKey,
+		UseVertex:      c.GeminiUseVertex,
+		Project:        c.GoogleCloudProject,
+		Location:       c.GoogleCloudLocation,
+		Tokenizer:      c.GetTokenizer(),
+		RetryStrategy:  retry.Strategy(c.RetryStrategy),
+		RetryMaxDelay:  c.RetryMaxDelay,
+		ProxyURL:       c.ProxyURL,
 	}
 }
 
+// GetOpenAICompatibleConfig returns the configuration of a provider served through
+// an OpenAI-compatible API: perplexity, deepseek, xai, together, cerebras or sambanova
+func (c *Config) GetOpenAICompatibleConfig(provider string) *providers.OpenAICompatibleConfig {
+	credentials := map[string]struct{ apiKey, baseURL string }{
+		"perplexity": {c.PerplexityAPIKey, c.PerplexityBaseURL},
+		"deepseek":   {c.DeepSeekAPIKey, c.DeepSeekBaseURL},
+		"xai":        {c.XAIAPIKey, c.XAIBaseURL},
+		"together":   {c.TogetherAPIKey, c.TogetherBaseURL},
+		"cerebras":   {c.CerebrasAPIKey, c.CerebrasBaseURL},
+		"sambanova":  {c.SambaNovaAPIKey, c.SambaNovaBaseURL},
+	}
+	return &providers.OpenAICompatibleConfig{
+		APIKey:        credentials[provider].apiKey,
+		BaseURL:       credentials[provider].baseURL,
+		Tokenizer:     c.GetTokenizer(),
+		RetryStrategy: retry.Strategy(c.RetryStrategy),
+		RetryMaxDelay: c.RetryMaxDelay,
+		ProxyURL:      c.ProxyURL,
+	}
+}
+
+// splitAPIKeys splits a comma-separated list of API keys into the first and the rest
+func splitAPIKeys(value string) (string, []string) {
+	var keys []string
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return "", nil
+	}
+	return keys[0], keys[1:]
+}
+
 // Helper function to get environment variable with default
+// getEnvBool reports whether an environment variable is set to true or 1
+func getEnvBool(key string) bool {
+	value, _ := strconv.ParseBool(os.Getenv(key))
+	return value
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
 
+	"github.com/megzo/llm-latency-benchmark/providers"
 	"gopkg.in/yaml.v3"
 )
 
@@ -15,18 +17,88 @@ type ModelsConfig struct {
 	Anthropic    map[string]ModelSpec `yaml:"anthropic"`
 	AzureOpenAI  map[string]ModelSpec `yaml:"azure_openai"`
 	Gemini       map[string]ModelSpec `yaml:"gemini"`
+	Perplexity   map[string]ModelSpec `yaml:"perplexity"`
+	DeepSeek     map[string]ModelSpec `yaml:"deepseek"`
+	XAI          map[string]ModelSpec `yaml:"xai"`
+	Together     map[string]ModelSpec `yaml:"together"`
+	Cerebras     map[string]ModelSpec `yaml:"cerebras"`
+	SambaNova    map[string]ModelSpec `yaml:"sambanova"`
+
+	// Sweep expands every model into one variant per parameter combination
+	Sweep        SweepConfig          `yaml:"sweep"`
+
+	// Embeddings lists embedding models by provider, benchmarked with -mode embeddings
+	Embeddings   map[string]map[string]ModelSpec `yaml:"embeddings"`
+
+	// warnings collects problems found while loading, such as unset variables
+	warnings     []string
+}
+
+// SweepConfig lists sampling parameter values to benchmark as a grid
+type SweepConfig struct {
+	Temperatures []float64 `yaml:"temperatures"`
+	MaxTokens    []int     `yaml:"max_tokens"`
+}
+
+// SweepVariant is a single point of the sweep grid; nil fields keep the defaults
+type SweepVariant struct {
+	Temperature *float64
+	MaxTokens   *int
+}
+
+// Variants returns the cartesian product of the sweep values. Without a sweep
+// it returns a single empty variant so callers can always iterate over it.
+func (s SweepConfig) Variants() []SweepVariant {
+	temperatures := []*float64{nil}
+	if len(s.Temperatures) > 0 {
+		temperatures = temperatures[:0]
+		for i := range s.Temperatures {
+			temperatures = append(temperatures, &s.Temperatures[i])
+		}
+	}
+	maxTokens := []*int{nil}
+	if len(s.MaxTokens) > 0 {
+		maxTokens = maxTokens[:0]
+		for i := range s.MaxTokens {
+			maxTokens = append(maxTokens, &s.MaxTokens[i])
+		}
+	}
+
+	variants := make([]SweepVariant, 0, len(temperatures)*len(maxTokens))
+	for _, temperature := range temperatures {
+		for _, tokens := range maxTokens {
+			variants = append(variants, SweepVariant{Temperature: temperature, MaxTokens: tokens})
+		}
+	}
+	return variants
 }
 
 // ModelSpec defines token pricing and optional provider-specific parameters
 type ModelSpec struct {
 	TokenPrice ModelPricing            `yaml:"token_price"`
 	Parameters map[string]interface{} `yaml:"parameters"`
+	// ContextLength is the model's context window in tokens (0 = unknown, not checked)
+	ContextLength int                  `yaml:"context_length"`
+	// Seed pins the sampling seed for reproducible runs; a "seed" parameter takes precedence
+	Seed          *int64               `yaml:"seed"`
+	// Stop lists sequences that end generation; a "stop" parameter takes precedence
+	Stop          []string             `yaml:"stop"`
+	// UsesMaxCompletionTokens and FixedSampling override what the OpenAI providers
+	// infer from the model name: that it rejects max_tokens, or temperature and top_p
+	UsesMaxCompletionTokens *bool      `yaml:"uses_max_completion_tokens"`
+	FixedSampling           *bool      `yaml:"fixed_sampling"`
+	// ExtraHeaders, AuthHeader and AuthScheme change the HTTP headers the
+	// OpenAI-compatible providers send, for gateways with their own auth
+	ExtraHeaders map[string]string     `yaml:"extra_headers"`
+	AuthHeader   string                `yaml:"auth_header"`
+	AuthScheme   string                `yaml:"auth_scheme"`
 }
 
 // ModelPricing holds the pricing information for a specific model
 type ModelPricing struct {
-	Input  float64 `yaml:"input"`  // $ per million input tokens
-	Output float64 `yaml:"output"` // $ per million output tokens
+	Input       float64 `yaml:"input"`        // $ per million input tokens
+	CachedInput float64 `yaml:"cached_input"` // $ per million prompt-cache hits (0 = billed as input)
+	Output      float64 `yaml:"output"`       // $ per million output tokens
 }
 
 // LoadModelsConfig loads the models configuration from a YAML file
@@ -36,14 +108,35 @@ func LoadModelsConfig(filename string) (*ModelsConfig, error) {
 		return nil, fmt.Errorf("failed to read models config file: %w", err)
 	}
 
+	// Expand ${VAR} references so one committed file works across environments
 	var config ModelsConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	expanded := os.Expand(string(data), func(name string) string {
+		// Keep a literal $ before anything that isn't a variable name, e.g. "$0.15"
+		if !envVarName.MatchString(name) {
+			return "$" + name
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			config.warnings = append(config.warnings, fmt.Sprintf("%s references unset environment variable %s; using an empty value", filename, name))
+		}
+		return value
+	})
+
+	if err := yaml.Unmarshal([]byte(expanded), &config); err != nil {
 		return nil, fmt.Errorf("failed to parse models config YAML: %w", err)
 	}
 
 	return &config, nil
 }
 
+// envVarName matches the names expanded in models config files
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Warnings returns problems found while loading the config that didn't prevent it
+func (c *ModelsConfig) Warnings() []string {
+	return c.warnings
+}
+
 // GetModelPricing returns the pricing for a specific model
 func (c *ModelsConfig) GetModelPricing(provider, model string) (*ModelPricing, error) {
 	var specs map[string]ModelSpec
@@ -61,6 +154,18 @@ func (c *ModelsConfig) GetModelPricing(provider, model string) (*ModelPricing, e
 		specs = c.AzureOpenAI
 	case "gemini":
 		specs = c.Gemini
+	case "perplexity":
+		specs = c.Perplexity
+	case "deepseek":
+		specs = c.DeepSeek
+	case "xai":
+		specs = c.XAI
+	case "together":
+		specs = c.Together
+	case "cerebras":
+		specs = c.Cerebras
+	case "sambanova":
+		specs = c.SambaNova
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", provider)
 	}
@@ -89,6 +194,18 @@ func (c *ModelsConfig) GetModelParameters(provider, model string) (map[string]in
 		specs = c.AzureOpenAI
 	case "gemini":
 		specs = c.Gemini
+	case "perplexity":
+		specs = c.Perplexity
+	case "deepseek":
+		specs = c.DeepSeek
+	case "xai":
+		specs = c.XAI
+	case "together":
+		specs = c.Together
+	case "cerebras":
+		specs = c.Cerebras
+	case "sambanova":
+		specs = c.SambaNova
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", provider)
 	}
@@ -100,6 +217,99 @@ func (c *ModelsConfig) GetModelParameters(provider, model string) (map[string]in
 	return nil, fmt.Errorf("model %s not found for provider %s", model, provider)
 }
 
+// GetModelContextLength returns the context window for a specific model (0 if not configured)
+func (c *ModelsConfig) GetModelContextLength(provider, model string) (int, error) {
+	spec, err := c.getModelSpec(provider, model)
+	if err != nil {
+		return 0, err
+	}
+	return spec.ContextLength, nil
+}
+
+// GetModelSeed returns the pinned sampling seed for a specific model (nil if not configured)
+func (c *ModelsConfig) GetModelSeed(provider, model string) (*int64, error) {
+	spec, err := c.getModelSpec(provider, model)
+	if err != nil {
+		return nil, err
+	}
+	return spec.Seed, nil
+}
+
+// GetModelStop returns the stop sequences configured for a specific model (nil if none)
+func (c *ModelsConfig) GetModelStop(provider, model string) ([]string, error) {
+	spec, err := c.getModelSpec(provider, model)
+	if err != nil {
+		return nil, err
+	}
+	return spec.Stop, nil
+}
+
+// GetModelCapabilities returns the request parameters a model is configured to
+// accept; unset fields leave providers to infer them from the model name
+func (c *ModelsConfig) GetModelCapabilities(provider, model string) (providers.ModelCapabilities, error) {
+	spec, err := c.getModelSpec(provider, model)
+	if err != nil {
+		return providers.ModelCapabilities{}, err
+	}
+	return providers.ModelCapabilities{
+		UsesMaxCompletionTokens: spec.UsesMaxCompletionTokens,
+		FixedSampling:           spec.FixedSampling,
+	}, nil
+}
+
+// GetModelHeaders returns the HTTP header overrides configured for a specific model
+func (c *ModelsConfig) GetModelHeaders(provider, model string) (providers.RequestHeaders, error) {
+	spec, err := c.getModelSpec(provider, model)
+	if err != nil {
+		return providers.RequestHeaders{}, err
+	}
+	return providers.RequestHeaders{
+		Extra:      spec.ExtraHeaders,
+		AuthHeader: spec.AuthHeader,
+		AuthScheme: spec.AuthScheme,
+	}, nil
+}
+
+// getModelSpec looks up the spec for a model of a provider
+func (c *ModelsConfig) getModelSpec(provider, model string) (ModelSpec, error) {
+	var specs map[string]ModelSpec
+
+	switch provider {
+	case "openai":
+		specs = c.OpenAI
+	case "openai_responses":
+		specs = c.OpenAIResponses
+	case "groq":
+		specs = c.Groq
+	case "anthropic":
+		specs = c.Anthropic
+	case "azure_openai":
+		specs = c.AzureOpenAI
+	case "gemini":
+		specs = c.Gemini
+	case "perplexity":
+		specs = c.Perplexity
+	case "deepseek":
+		specs = c.DeepSeek
+	case "xai":
+		specs = c.XAI
+	case "together":
+		specs = c.Together
+	case "cerebras":
+		specs = c.Cerebras
+	case "sambanova":
+		specs = c.SambaNova
+	default:
+		return ModelSpec{}, fmt.Errorf("unknown provider: %s", provider)
+	}
+
+	if spec, exists := specs[model]; exists {
+		return spec, nil
+	}
+
+	return ModelSpec{}, fmt.Errorf("model %s not found for provider %s", model, provider)
+}
+
 // CalculateCost calculates the cost for a given number of input and output tokens
 func (p *ModelPricing) CalculateCost(inputTokens, outputTokens int) float64 {
 	inputCost := (float64(inputTokens) / 1_000_000) * p.Input
@@ -107,6 +317,54 @@ func (p *ModelPricing) CalculateCost(inputTokens, outputTokens int) float64 {
 	return inputCost + outputCost
 }
 
+// CalculateCostWithCache is CalculateCost with cachedInputTokens of the inputTokens billed
+// at the cached input rate, when one is configured
+func (p *ModelPricing) CalculateCostWithCache(inputTokens, cachedInputTokens, outputTokens int) float64 {
+	if p.CachedInput == 0 || cachedInputTokens <= 0 {
+		return p.CalculateCost(inputTokens, outputTokens)
+	}
+	if cachedInputTokens > inputTokens {
+		cachedInputTokens = inputTokens
+	}
+	cachedCost := (float64(cachedInputTokens) / 1_000_000) * p.CachedInput
+	return p.CalculateCost(inputTokens-cachedInputTokens, outputTokens) + cachedCost
+}
+
+// chatModelSpecs returns the chat model sections of the config by provider
+func (c *ModelsConfig) chatModelSpecs() map[string]map[string]ModelSpec {
+	return map[string]map[string]ModelSpec{
+		"openai":           c.OpenAI,
+		"openai_responses": c.OpenAIResponses,
+		"groq":             c.Groq,
+		"anthropic":        c.Anthropic,
+		"azure_openai":     c.AzureOpenAI,
+		"gemini":           c.Gemini,
+		"perplexity":       c.Perplexity,
+		"deepseek":         c.DeepSeek,
+		"xai":              c.XAI,
+		"together":         c.Together,
+		"cerebras":         c.Cerebras,
+		"sambanova":        c.SambaNova,
+	}
+}
+
+// ListEmbeddingModels returns the embedding models configured for a provider
+func (c *ModelsConfig) ListEmbeddingModels(provider string) []string {
+	modelNames := make([]string, 0, len(c.Embeddings[provider]))
+	for modelName := range c.Embeddings[provider] {
+		modelNames = append(modelNames, modelName)
+	}
+	return modelNames
+}
+
+// GetEmbeddingModelPricing returns the pricing for an embedding model
+func (c *ModelsConfig) GetEmbeddingModelPricing(provider, model string) (*ModelPricing, error) {
+	if spec, exists := c.Embeddings[provider][model]; exists {
+		return &spec.TokenPrice, nil
+	}
+	return nil, fmt.Errorf("embedding model %s not found for provider %s", model, provider)
+}
+
 // ListModels returns all available models for a provider
 func (c *ModelsConfig) ListModels(provider string) ([]string, error) {
 	var specs map[string]ModelSpec
@@ -124,6 +382,18 @@ func (c *ModelsConfig) ListModels(provider string) ([]string, error) {
 		specs = c.AzureOpenAI
 	case "gemini":
 		specs = c.Gemini
+	case "perplexity":
+		specs = c.Perplexity
+	case "deepseek":
+		specs = c.DeepSeek
+	case "xai":
+		specs = c.XAI
+	case "together":
+		specs = c.Together
+	case "cerebras":
+		specs = c.Cerebras
+	case "sambanova":
+		specs = c.SambaNova
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", provider)
 	}
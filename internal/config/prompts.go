@@ -1,7 +1,11 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -9,10 +13,39 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// Prompt represents a single prompt configuration
+// Prompt represents a single prompt configuration. A prompt is either a single
+// user message or a multi-turn conversation given as messages.
 type Prompt struct {
-	System string `yaml:"system"`
-	User   string `yaml:"user"`
+	// Name names a prompt in a multi-document prompts file; prompts loaded from
+	// a directory are named by their file
+	Name     string    `yaml:"name"`
+	System   string    `yaml:"system"`
+	User     string    `yaml:"user"`
+	Messages []Message `yaml:"messages"`
+
+	// Tools are OpenAI function schemas offered to the model, sent with the
+	// optional ToolChoice (e.g. "auto", "required") to benchmark function calling
+	Tools      []map[string]interface{} `yaml:"tools"`
+	ToolChoice interface{}              `yaml:"tool_choice"`
+
+	// ResponseFormat requests structured output in OpenAI's response_format shape,
+	// e.g. {type: json_object} or {type: json_schema, json_schema: {name, schema}}
+	ResponseFormat map[string]interface{} `yaml:"response_format"`
+
+	// Image is sent with the last user message to benchmark vision models: an
+	// http(s) URL the provider fetches, or a file path relative to the prompt file
+	Image string `yaml:"image"`
+	// ImageData and ImageMediaType hold a local image, read when the prompt is
+	// loaded so reading it isn't timed; ImageMediaType is also set for URLs
+	// ending in a known extension
+	ImageData      []byte `yaml:"-"`
+	ImageMediaType string `yaml:"-"`
+}
+
+// Message is a single conversation turn in a multi-turn prompt
+type Message struct {
+	Role    string `yaml:"role"`
+	Content string `yaml:"content"`
 }
 
 // PromptFile represents a prompt file with metadata
@@ -22,8 +55,24 @@ type PromptFile struct {
 	Prompt Prompt
 }
 
-// LoadPrompts loads all prompt files from the specified directory
+// LoadPrompts loads all prompt files from the specified directory, ignoring
+// subdirectories. When promptsDir is a file instead, each of its YAML
+// documents, separated by ---, is loaded as a prompt.
 func LoadPrompts(promptsDir string) ([]PromptFile, error) {
+	return loadPrompts(promptsDir, false)
+}
+
+// LoadPromptsRecursive loads prompt files from the directory and all of its subdirectories.
+// Nested prompts are named by their path relative to promptsDir (e.g. "chat/short").
+func LoadPromptsRecursive(promptsDir string) ([]PromptFile, error) {
+	return loadPrompts(promptsDir, true)
+}
+
+func loadPrompts(promptsDir string, recursive bool) ([]PromptFile, error) {
+	if info, err := os.Stat(promptsDir); err == nil && !info.IsDir() {
+		return loadPromptStream(promptsDir)
+	}
+
 	var promptFiles []PromptFile
 
 	// Walk through the prompts directory
@@ -32,8 +81,16 @@ func LoadPrompts(promptsDir string) ([]PromptFile, error) {
 			return err
 		}
 
-		// Skip directories and non-YAML files
-		if info.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".yaml") && !strings.HasSuffix(strings.ToLower(path), ".yml") {
+		// Only descend into subdirectories when loading recursively
+		if info.IsDir() {
+			if !recursive && path != promptsDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Skip files that aren't YAML or plain-text prompts
+		if !isPromptFile(path) {
 			return nil
 		}
 
@@ -47,9 +104,12 @@ func LoadPrompts(promptsDir string) ([]PromptFile, error) {
 		if err := validatePrompt(prompt); err != nil {
 			return fmt.Errorf("invalid prompt in %s: %w", path, err)
 		}
+		if err := loadPromptImage(&prompt, path); err != nil {
+			return fmt.Errorf("invalid prompt in %s: %w", path, err)
+		}
 
 		promptFiles = append(promptFiles, PromptFile{
-			Name:   strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+			Name:   promptName(promptsDir, path),
 			Path:   path,
 			Prompt: prompt,
 		})
@@ -68,13 +128,90 @@ func LoadPrompts(promptsDir string) ([]PromptFile, error) {
 	return promptFiles, nil
 }
 
-// loadPromptFile loads a single prompt file
+// loadPromptStream loads every document of a multi-document YAML file as a
+// prompt, named by its name field or, without one, by the file name and its
+// 1-based position (e.g. "suite-3"). Empty documents are skipped.
+func loadPromptStream(path string) ([]PromptFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompts file: %w", err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	seen := make(map[string]bool)
+	var promptFiles []PromptFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for index := 1; ; index++ {
+		var node yaml.Node
+		if err := decoder.Decode(&node); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse YAML document %d in %s: %w", index, path, err)
+		}
+		if len(node.Content) == 0 || node.Content[0].Tag == "!!null" {
+			continue
+		}
+
+		var prompt Prompt
+		if err := node.Decode(&prompt); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML document %d in %s: %w", index, path, err)
+		}
+		if err := validatePrompt(prompt); err != nil {
+			return nil, fmt.Errorf("invalid prompt in document %d of %s: %w", index, path, err)
+		}
+		if err := loadPromptImage(&prompt, path); err != nil {
+			return nil, fmt.Errorf("invalid prompt in document %d of %s: %w", index, path, err)
+		}
+
+		name := prompt.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", base, index)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate prompt name %q in %s", name, path)
+		}
+		seen[name] = true
+
+		promptFiles = append(promptFiles, PromptFile{Name: name, Path: path, Prompt: prompt})
+	}
+
+	if len(promptFiles) == 0 {
+		return nil, fmt.Errorf("no prompts found in %s", path)
+	}
+	return promptFiles, nil
+}
+
+// promptName derives a prompt's name from its path relative to the prompts directory,
+// so files with the same base name in different subdirectories don't collide
+func promptName(promptsDir, path string) string {
+	rel, err := filepath.Rel(promptsDir, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	return filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+}
+
+// isPromptFile reports whether path is a YAML (.yaml, .yml) or plain-text (.txt) prompt file
+func isPromptFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".txt":
+		return true
+	}
+	return false
+}
+
+// loadPromptFile loads a single prompt file. A .txt file's entire content is the
+// user prompt, with no system prompt.
 func loadPromptFile(path string) (Prompt, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return Prompt{}, fmt.Errorf("failed to read file: %w", err)
 	}
 
+	if strings.EqualFold(filepath.Ext(path), ".txt") {
+		return Prompt{User: string(data)}, nil
+	}
+
 	var prompt Prompt
 	if err := yaml.Unmarshal(data, &prompt); err != nil {
 		return Prompt{}, fmt.Errorf("failed to parse YAML: %w", err)
@@ -83,9 +220,82 @@ func loadPromptFile(path string) (Prompt, error) {
 	return prompt, nil
 }
 
+// imageMediaTypes maps the image file extensions vision APIs accept to their MIME types
+var imageMediaTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// loadPromptImage reads the prompt's image file, found relative to the prompt
+// file at path, and sets its media type. Image URLs are left to the provider,
+// but still need a recognised extension since Gemini requires the media type.
+func loadPromptImage(prompt *Prompt, path string) error {
+	if prompt.Image == "" {
+		return nil
+	}
+	if strings.HasPrefix(prompt.Image, "http://") || strings.HasPrefix(prompt.Image, "https://") {
+		u, err := url.Parse(prompt.Image)
+		if err != nil {
+			return fmt.Errorf("invalid image URL %s: %w", prompt.Image, err)
+		}
+		mediaType, ok := imageMediaTypes[strings.ToLower(filepath.Ext(u.Path))]
+		if !ok {
+			return fmt.Errorf("image URL %s must end in .png, .jpg, .jpeg, .gif or .webp", prompt.Image)
+		}
+		prompt.ImageMediaType = mediaType
+		return nil
+	}
+
+	mediaType, ok := imageMediaTypes[strings.ToLower(filepath.Ext(prompt.Image))]
+	if !ok {
+		return fmt.Errorf("image %s must be a PNG, JPEG, GIF or WebP file", prompt.Image)
+	}
+	imagePath := prompt.Image
+	if !filepath.IsAbs(imagePath) {
+		imagePath = filepath.Join(filepath.Dir(path), imagePath)
+	}
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return fmt.Errorf("failed to read image: %w", err)
+	}
+	prompt.ImageData = data
+	prompt.ImageMediaType = mediaType
+	return nil
+}
+
 // validatePrompt validates a prompt configuration
 func validatePrompt(prompt Prompt) error {
-	if prompt.User == "" {
+	for i, tool := range prompt.Tools {
+		function, _ := tool["function"].(map[string]interface{})
+		if name, _ := function["name"].(string); tool["type"] != "function" || name == "" {
+			return fmt.Errorf("tool %d must have type function and a function name", i+1)
+		}
+	}
+
+	if len(prompt.Messages) > 0 {
+		if prompt.User != "" {
+			return fmt.Errorf("prompt cannot set both user and messages")
+		}
+		for i, message := range prompt.Messages {
+			switch message.Role {
+			case "system", "user", "assistant":
+			default:
+				return fmt.Errorf("message %d has invalid role %q (must be system, user or assistant)", i+1, message.Role)
+			}
+			if message.Content == "" {
+				return fmt.Errorf("message %d content cannot be empty", i+1)
+			}
+		}
+		if last := prompt.Messages[len(prompt.Messages)-1]; last.Role != "user" {
+			return fmt.Errorf("last message must have role user, got %q", last.Role)
+		}
+		return nil
+	}
+
+	if strings.TrimSpace(prompt.User) == "" {
 		return fmt.Errorf("user prompt cannot be empty")
 	}
 
@@ -94,10 +304,18 @@ func validatePrompt(prompt Prompt) error {
 	return nil
 }
 
-// GetPromptText returns the full prompt text (system + user)
+// GetPromptText returns the full prompt text (system + user, or system + all messages)
 func (p *Prompt) GetPromptText() string {
-	if p.System == "" {
-		return p.User
+	parts := []string{}
+	if p.System != "" {
+		parts = append(parts, p.System)
+	}
+	if len(p.Messages) > 0 {
+		for _, message := range p.Messages {
+			parts = append(parts, message.Content)
+		}
+	} else {
+		parts = append(parts, p.User)
 	}
-	return p.System + "\n\n" + p.User
+	return strings.Join(parts, "\n\n")
 } 
\ No newline at end of file
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats for the per-run results
+const (
+	OutputFormatCSV   = "csv"   // Write a CSV file (the default)
+	OutputFormatTable = "table" // Print an aligned table, writing a CSV file only when an output path is set
+)
+
+// RunConfig describes a benchmark run loaded from a run config file (-config run.yaml).
+// Zero fields are unset and leave the existing setting alone, so a file only needs
+// the settings it changes. Pricing stays in models.yaml.
+type RunConfig struct {
+	Providers    []string     `yaml:"providers"`     // Providers to enable (default: all with credentials)
+	Models       []string     `yaml:"models"`        // Model names to run (default: all in models.yaml)
+	Concurrency  int          `yaml:"concurrency"`
+	ProviderConcurrency map[string]int `yaml:"provider_concurrency"` // Per-provider worker pool sizes
+	ProviderTimeouts map[string]time.Duration `yaml:"provider_timeout"` // Per-provider request timeouts, e.g. openai: 120s
+	Runs         int          `yaml:"runs"`
+	Mode         string       `yaml:"mode"`          // chat (default) or embeddings
+	Warmup       int          `yaml:"warmup"`        // Unrecorded requests per model before measuring
+	PromptsDir   string       `yaml:"prompts"`
+	Output       string       `yaml:"output"`
+	OutputFormat string       `yaml:"output_format"`
+	Sweep        *SweepConfig `yaml:"sweep"`         // Replaces the sweep from models.yaml
+}
+
+// LoadRunConfig loads a run config file, rejecting unknown keys so typos aren't silently ignored
+func LoadRunConfig(filename string) (*RunConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run config file: %w", err)
+	}
+
+	var runConfig RunConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&runConfig); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse run config YAML: %w", err)
+	}
+
+	return &runConfig, nil
+}
+
+// ApplyRunConfig overrides the configuration with the set fields of rc. Apply the
+// run config file first and the explicitly passed CLI flags second, so flags win.
+func (c *Config) ApplyRunConfig(rc *RunConfig) {
+	if rc == nil {
+		return
+	}
+	if len(rc.Providers) > 0 {
+		c.Providers = rc.Providers
+	}
+	if len(rc.Models) > 0 {
+		c.ModelFilter = rc.Models
+	}
+	if rc.Concurrency != 0 {
+		c.Concurrent = rc.Concurrency
+	}
+	if len(rc.ProviderConcurrency) > 0 {
+		c.ProviderConcurrency = rc.ProviderConcurrency
+	}
+	if len(rc.ProviderTimeouts) > 0 {
+		c.ProviderTimeouts = rc.ProviderTimeouts
+	}
+	if rc.Runs != 0 {
+		c.Runs = rc.Runs
+	}
+	if rc.Mode != "" {
+		c.Mode = rc.Mode
+	}
+	if rc.Warmup != 0 {
+		c.Warmup = rc.Warmup
+	}
+	if rc.PromptsDir != "" {
+		c.PromptsDir = rc.PromptsDir
+	}
+	if rc.Output != "" {
+		c.OutputFile = rc.Output
+	}
+	if rc.OutputFormat != "" {
+		c.OutputFormat = rc.OutputFormat
+	}
+	if rc.Sweep != nil && c.Models != nil {
+		c.Models.Sweep = *rc.Sweep
+	}
+}
+
+// ProviderEnabled reports whether a provider should be benchmarked
+func (c *Config) ProviderEnabled(name string) bool {
+	return len(c.Providers) == 0 || contains(c.Providers, name)
+}
+
+// ModelEnabled reports whether a provider's model should be benchmarked: it must be
+// in ModelFilter when that is set, match one of IncludeModels when those are set,
+// and match none of ExcludeModels. Patterns are tried against both the model name
+// and provider/model.
+func (c *Config) ModelEnabled(provider, name string) bool {
+	if len(c.ModelFilter) > 0 && !contains(c.ModelFilter, name) {
+		return false
+	}
+	key := provider + "/" + name
+	matches := func(pattern *regexp.Regexp) bool {
+		return pattern.MatchString(name) || pattern.MatchString(key)
+	}
+	if len(c.IncludeModels) > 0 && !slices.ContainsFunc(c.IncludeModels, matches) {
+		return false
+	}
+	return !slices.ContainsFunc(c.ExcludeModels, matches)
+}
+
+// ParseModelPatterns compiles a comma-separated list of regular expressions for
+// IncludeModels or ExcludeModels, such as "gpt-4o.*,openai/o[0-9].*". Each pattern
+// must match the whole model name or provider/model.
+func ParseModelPatterns(value string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pattern, err := regexp.Compile("^(?:" + item + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid model pattern %q: %w", item, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
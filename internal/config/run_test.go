@@ -0,0 +1,174 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRunConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "run.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func defaultTestConfig() *Config {
+	return &Config{
+		Models:       &ModelsConfig{Sweep: SweepConfig{Temperatures: []float64{0.5}}},
+		Concurrent:   1,
+		Runs:         1,
+		PromptsDir:   "prompts",
+		OutputFormat: OutputFormatCSV,
+	}
+}
+
+func TestLoadRunConfig(t *testing.T) {
+	path := writeRunConfig(t, `
+providers: [openai, groq]
+models: [gpt-4.1-mini]
+concurrency: 4
+provider_concurrency:
+  groq: 2
+provider_timeout:
+  openai: 2m
+runs: 5
+warmup: 2
+prompts: custom-prompts
+output: results/run.csv
+output_format: csv
+sweep:
+  temperatures: [0, 1]
+  max_tokens: [256]
+`)
+
+	runConfig, err := LoadRunConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"openai", "groq"}, runConfig.Providers)
+	assert.Equal(t, []string{"gpt-4.1-mini"}, runConfig.Models)
+	assert.Equal(t, 4, runConfig.Concurrency)
+	assert.Equal(t, map[string]int{"groq": 2}, runConfig.ProviderConcurrency)
+	assert.Equal(t, map[string]time.Duration{"openai": 2 * time.Minute}, runConfig.ProviderTimeouts)
+	assert.Equal(t, 5, runConfig.Runs)
+	assert.Equal(t, 2, runConfig.Warmup)
+	assert.Equal(t, "custom-prompts", runConfig.PromptsDir)
+	assert.Equal(t, "results/run.csv", runConfig.Output)
+	assert.Equal(t, OutputFormatCSV, runConfig.OutputFormat)
+	require.NotNil(t, runConfig.Sweep)
+	assert.Equal(t, []float64{0, 1}, runConfig.Sweep.Temperatures)
+	assert.Equal(t, []int{256}, runConfig.Sweep.MaxTokens)
+}
+
+func TestLoadRunConfig_Errors(t *testing.T) {
+	_, err := LoadRunConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+
+	_, err = LoadRunConfig(writeRunConfig(t, "concurency: 4\n"))
+	assert.Error(t, err, "unknown keys are rejected")
+}
+
+func TestApplyRunConfig_OmittedFieldsKeepDefaults(t *testing.T) {
+	runConfig, err := LoadRunConfig(writeRunConfig(t, "runs: 3\n"))
+	require.NoError(t, err)
+
+	cfg := defaultTestConfig()
+	cfg.ApplyRunConfig(runConfig)
+
+	assert.Equal(t, 3, cfg.Runs)
+	assert.Equal(t, 1, cfg.Concurrent)
+	assert.Equal(t, "prompts", cfg.PromptsDir)
+	assert.Equal(t, OutputFormatCSV, cfg.OutputFormat)
+	assert.Equal(t, []float64{0.5}, cfg.Models.Sweep.Temperatures, "sweep from models.yaml is kept")
+	assert.True(t, cfg.ProviderEnabled("gemini"))
+	assert.True(t, cfg.ModelEnabled("openai", "any-model"))
+
+	// An empty file changes nothing
+	empty, err := LoadRunConfig(writeRunConfig(t, ""))
+	require.NoError(t, err)
+	cfg.ApplyRunConfig(empty)
+	assert.Equal(t, 3, cfg.Runs)
+}
+
+func TestApplyRunConfig_FlagsOverrideFile(t *testing.T) {
+	runConfig, err := LoadRunConfig(writeRunConfig(t, `
+providers: [openai]
+concurrency: 4
+runs: 5
+prompts: file-prompts
+sweep:
+  max_tokens: [128]
+`))
+	require.NoError(t, err)
+
+	cfg := defaultTestConfig()
+	cfg.ApplyRunConfig(runConfig)
+	// Only the flags that were passed explicitly are set
+	cfg.ApplyRunConfig(&RunConfig{Runs: 10, Providers: []string{"groq"}})
+
+	assert.Equal(t, 10, cfg.Runs, "flag wins")
+	assert.Equal(t, 4, cfg.Concurrent, "file value kept when the flag isn't passed")
+	assert.Equal(t, "file-prompts", cfg.PromptsDir)
+	assert.Equal(t, SweepConfig{MaxTokens: []int{128}}, cfg.Models.Sweep)
+	assert.True(t, cfg.ProviderEnabled("groq"))
+	assert.False(t, cfg.ProviderEnabled("openai"))
+}
+
+func TestConfig_ValidateOutputFormat(t *testing.T) {
+	cfg := defaultTestConfig()
+	cfg.PromptsDir = t.TempDir()
+	assert.NoError(t, cfg.Validate())
+
+	cfg.OutputFormat = OutputFormatTable
+	assert.NoError(t, cfg.Validate())
+
+	cfg.OutputFormat = "xml"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestConfig_ValidateMode(t *testing.T) {
+	cfg := defaultTestConfig()
+	cfg.PromptsDir = t.TempDir()
+
+	for _, mode := range []string{"", ModeChat, ModeEmbeddings} {
+		cfg.Mode = mode
+		assert.NoError(t, cfg.Validate(), mode)
+	}
+
+	cfg.Mode = "images"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestConfig_ModelEnabledPatterns(t *testing.T) {
+	include, err := ParseModelPatterns("gpt-4o.*, groq/llama-.*")
+	require.NoError(t, err)
+	exclude, err := ParseModelPatterns(".*-preview")
+	require.NoError(t, err)
+	cfg := &Config{IncludeModels: include, ExcludeModels: exclude}
+
+	assert.True(t, cfg.ModelEnabled("openai", "gpt-4o"))
+	assert.True(t, cfg.ModelEnabled("azure_openai", "gpt-4o-mini"))
+	assert.True(t, cfg.ModelEnabled("groq", "llama-3.1-8b-instant"), "matches provider/model")
+	assert.False(t, cfg.ModelEnabled("openai", "gpt-4o-audio-preview"), "excluded")
+	assert.False(t, cfg.ModelEnabled("openai", "ft:gpt-4o"), "patterns match the whole name")
+	assert.False(t, cfg.ModelEnabled("openai", "o3-mini"))
+	assert.False(t, cfg.ModelEnabled("together", "llama-3.1-8b-instant"))
+
+	// The run config's model list still applies
+	cfg.ModelFilter = []string{"gpt-4o-mini"}
+	assert.False(t, cfg.ModelEnabled("openai", "gpt-4o"))
+	assert.True(t, cfg.ModelEnabled("openai", "gpt-4o-mini"))
+}
+
+func TestParseModelPatterns_Invalid(t *testing.T) {
+	_, err := ParseModelPatterns("gpt-4o.*,claude-(3")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid model pattern "claude-(3"`)
+
+	patterns, err := ParseModelPatterns("")
+	require.NoError(t, err)
+	assert.Empty(t, patterns)
+}
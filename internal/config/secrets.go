@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Secrets maps provider names to API keys, read from a secrets file (-secrets) so
+// keys stay out of .env files, the shell history and the process environment
+type Secrets map[string]string
+
+// LoadSecrets loads a YAML secrets file with one provider: key entry per provider,
+// named as in -providers (openai, anthropic, gemini, ...)
+func LoadSecrets(filename string) (Secrets, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	var secrets Secrets
+	if err := yaml.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets YAML: %w", err)
+	}
+
+	return secrets, nil
+}
+
+// ApplySecrets fills in the API keys left unset by the environment and .env file.
+// Environment variables take precedence, then .env, then the secrets file.
+func (c *Config) ApplySecrets(secrets Secrets) error {
+	for _, provider := range slices.Sorted(maps.Keys(secrets)) {
+		key := c.apiKeyField(provider)
+		if key == nil {
+			return fmt.Errorf("unknown provider %q in secrets file", provider)
+		}
+		if *key == "" {
+			*key = secrets[provider]
+		}
+	}
+	return nil
+}
+
+// apiKeyField returns the API key setting of a provider, nil for an unknown name
+func (c *Config) apiKeyField(provider string) *string {
+	switch provider {
+	case "openai":
+		return &c.OpenAIAPIKey
+	case "groq":
+		return &c.GroqAPIKey
+	case "anthropic":
+		return &c.AnthropicAPIKey
+	case "azure_openai":
+		return &c.AzureOpenAIAPIKey
+	case "gemini":
+		return &c.GoogleAPIKey
+	case "perplexity":
+		return &c.PerplexityAPIKey
+	case "deepseek":
+		return &c.DeepSeekAPIKey
+	case "xai":
+		return &c.XAIAPIKey
+	case "together":
+		return &c.TogetherAPIKey
+	case "cerebras":
+		return &c.CerebrasAPIKey
+	case "sambanova":
+		return &c.SambaNovaAPIKey
+	}
+	return nil
+}
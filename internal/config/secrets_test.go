@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/megzo/llm-latency-benchmark/providers"
+)
+
+func TestLoadSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("openai: sk-from-secrets\nanthropic: sk-ant-from-secrets\ngemini: google-from-secrets\n"), 0o600))
+
+	secrets, err := LoadSecrets(path)
+	require.NoError(t, err)
+
+	// A key already set by the environment wins over the secrets file
+	cfg := &Config{AnthropicAPIKey: "sk-ant-from-env"}
+	require.NoError(t, cfg.ApplySecrets(secrets))
+	assert.Equal(t, "sk-from-secrets", cfg.OpenAIAPIKey)
+	assert.Equal(t, "sk-ant-from-env", cfg.AnthropicAPIKey)
+	assert.Equal(t, "google-from-secrets", cfg.GoogleAPIKey)
+	assert.Empty(t, cfg.GroqAPIKey)
+
+	// Providers are built from the merged keys
+	factory := providers.NewProviderFactory()
+	factory.RegisterConfig("openai", cfg.GetOpenAIConfig())
+	factory.RegisterConfig("anthropic", cfg.GetAnthropicConfig())
+	factory.RegisterConfig("groq", cfg.GetGroqConfig())
+
+	openai, err := factory.GetProvider("openai")
+	require.NoError(t, err)
+	assert.Equal(t, "openai", openai.Name())
+	assert.Equal(t, "sk-from-secrets", cfg.GetOpenAIConfig().APIKey)
+
+	anthropic, err := factory.GetProvider("anthropic")
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic", anthropic.Name())
+	assert.Equal(t, "sk-ant-from-env", cfg.GetAnthropicConfig().APIKey)
+
+	_, err = factory.GetProvider("groq")
+	assert.Error(t, err, "groq has no key in either source")
+}
+
+func TestLoadSecrets_Errors(t *testing.T) {
+	_, err := LoadSecrets(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read secrets file")
+
+	malformed := filepath.Join(t.TempDir(), "secrets.yaml")
+	require.NoError(t, os.WriteFile(malformed, []byte("openai: [unclosed\n"), 0o600))
+	_, err = LoadSecrets(malformed)
+	assert.ErrorContains(t, err, "failed to parse secrets YAML")
+
+	cfg := &Config{}
+	assert.ErrorContains(t, cfg.ApplySecrets(Secrets{"mistral": "key"}), `unknown provider "mistral"`)
+}
@@ -0,0 +1,117 @@
+// Package notify sends benchmark run summaries to external channels.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/megzo/llm-latency-benchmark/internal/benchmark"
+)
+
+// maxRegressions caps how many regressions are listed so messages stay within
+// chat message size limits
+const maxRegressions = 5
+
+// WebhookNotifier posts run summaries to a Slack or Discord incoming webhook
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier for the given incoming-webhook URL
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// webhookPayload carries the message under both Slack's and Discord's field
+// names; each service ignores the field it doesn't use
+type webhookPayload struct {
+	Text    string `json:"text"`
+	Content string `json:"content"`
+}
+
+// Notify posts the summary, and any regressions against a baseline, to the webhook
+func (n *WebhookNotifier) Notify(ctx context.Context, summary benchmark.Summary, comparisons []benchmark.Comparison) error {
+	message := FormatSummary(summary, comparisons)
+	body, err := json.Marshal(webhookPayload{Text: message, Content: message})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// FormatSummary renders a run summary as a plain-text chat message. Regressions
+// from comparisons are listed worst first, up to maxRegressions.
+func FormatSummary(summary benchmark.Summary, comparisons []benchmark.Comparison) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "LLM benchmark finished: %d runs, %d succeeded, %d failed (error rate %.2f%%)\n",
+		summary.TotalRuns, summary.SuccessfulRuns, summary.FailedRuns, summary.ErrorRate*100)
+	if summary.HaltReason != "" {
+		fmt.Fprintf(&b, "Halted early: %s\n", summary.HaltReason)
+	}
+	if summary.SuccessfulRuns > 0 {
+		fmt.Fprintf(&b, "TTFT avg %v, p95 %v, p99 %v\n",
+			summary.AvgTTFT.Round(time.Millisecond), summary.P95TTFT.Round(time.Millisecond), summary.P99TTFT.Round(time.Millisecond))
+		fmt.Fprintf(&b, "Total time p50 %v, p95 %v\n",
+			summary.P50TotalTime.Round(time.Millisecond), summary.P95TotalTime.Round(time.Millisecond))
+		fmt.Fprintf(&b, "Avg tokens/sec %.2f, total cost $%.6f\n", summary.AvgTokensPerSecond, summary.TotalCost)
+	}
+
+	var regressions []benchmark.Comparison
+	for _, c := range comparisons {
+		if c.IsRegression() {
+			regressions = append(regressions, c)
+		}
+	}
+	if len(regressions) == 0 {
+		if len(comparisons) > 0 {
+			b.WriteString("No regressions against the baseline\n")
+		}
+		return b.String()
+	}
+
+	sort.SliceStable(regressions, func(i, j int) bool {
+		return regressions[i].P95TTFTDeltaPct > regressions[j].P95TTFTDeltaPct
+	})
+	fmt.Fprintf(&b, "Regressions against the baseline: %d\n", len(regressions))
+	for i, c := range regressions {
+		if i == maxRegressions {
+			fmt.Fprintf(&b, "... and %d more\n", len(regressions)-maxRegressions)
+			break
+		}
+		fmt.Fprintf(&b, "- %s/%s %s: p95 TTFT %v (%+.1f%%), tokens/sec %+.1f%%, cost %+.1f%% [%s]\n",
+			c.Provider, c.Model, c.PromptFile,
+			c.CurrentP95TTFT.Round(time.Millisecond), c.P95TTFTDeltaPct,
+			c.TokensPerSecondDeltaPct, c.CostDeltaPct,
+			strings.Join(c.Regressions, ", "))
+	}
+	return b.String()
+}
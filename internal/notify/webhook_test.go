@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/megzo/llm-latency-benchmark/internal/benchmark"
+)
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	var payload webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	summary := benchmark.Summary{
+		TotalRuns:      20,
+		SuccessfulRuns: 19,
+		FailedRuns:     1,
+		ErrorRate:      0.05,
+		AvgTTFT:        300 * time.Millisecond,
+		P95TTFT:        540 * time.Millisecond,
+		P99TTFT:        610 * time.Millisecond,
+		P50TotalTime:   1200 * time.Millisecond,
+		P95TotalTime:   2300 * time.Millisecond,
+		TotalCost:      0.0123,
+	}
+	comparisons := []benchmark.Comparison{
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", CurrentP95TTFT: 540 * time.Millisecond, P95TTFTDeltaPct: 35, Regressions: []string{"p95_ttft"}},
+		{Provider: "groq", Model: "qwen/qwen3-32b", PromptFile: "simple", P95TTFTDeltaPct: -3},
+	}
+
+	notifier := NewWebhookNotifier(server.URL)
+	require.NoError(t, notifier.Notify(context.Background(), summary, comparisons))
+
+	assert.Equal(t, payload.Text, payload.Content)
+	assert.Contains(t, payload.Text, "error rate 5.00%")
+	assert.Contains(t, payload.Text, "p95 540ms")
+	assert.Contains(t, payload.Text, "p95 2.3s")
+	assert.Contains(t, payload.Text, "openai/gpt-4.1 simple: p95 TTFT 540ms (+35.0%)")
+	assert.NotContains(t, payload.Text, "groq")
+}
+
+func TestWebhookNotifier_NotifyErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := NewWebhookNotifier(server.URL).Notify(context.Background(), benchmark.Summary{TotalRuns: 1}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+	assert.Contains(t, err.Error(), "invalid_token")
+}
+
+func TestFormatSummary_LimitsRegressions(t *testing.T) {
+	var comparisons []benchmark.Comparison
+	for i := 0; i < maxRegressions+2; i++ {
+		comparisons = append(comparisons, benchmark.Comparison{
+			Provider:        "openai",
+			Model:           "gpt-4.1",
+			PromptFile:      "simple",
+			P95TTFTDeltaPct: float64(20 + i),
+			Regressions:     []string{"p95_ttft"},
+		})
+	}
+
+	message := FormatSummary(benchmark.Summary{TotalRuns: 7, SuccessfulRuns: 7}, comparisons)
+	assert.Contains(t, message, "Regressions against the baseline: 7")
+	assert.Contains(t, message, "... and 2 more")
+	// Worst regression is listed first
+	assert.Contains(t, message, "(+26.0%)")
+	assert.NotContains(t, message, "(+20.0%)")
+}
@@ -0,0 +1,54 @@
+package output
+
+import (
+	"fmt"
+	"os"
+)
+
+// Color modes accepted by -color
+const (
+	ColorAuto   = "auto"   // Colorize only a terminal, and only when NO_COLOR is unset
+	ColorAlways = "always" // Colorize even when output is redirected, e.g. for CI logs that render ANSI
+	ColorNever  = "never"  // Never write escape sequences
+)
+
+// ANSI escape sequences for the colors output uses
+const (
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+// UseColor reports whether output written to f should be colorized in mode
+func UseColor(mode string, f *os.File) (bool, error) {
+	switch mode {
+	case ColorAlways:
+		return true, nil
+	case ColorNever:
+		return false, nil
+	case ColorAuto, "":
+		// https://no-color.org: any non-empty value disables color
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		return isTerminal(f), nil
+	default:
+		return false, fmt.Errorf("invalid color mode %q (expected %s, %s or %s)", mode, ColorAuto, ColorAlways, ColorNever)
+	}
+}
+
+// isTerminal reports whether f is a character device such as a terminal rather
+// than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize wraps s in color when enabled
+func colorize(s, color string, enabled bool) string {
+	if !enabled || color == "" {
+		return s
+	}
+	return color + s + ansiReset
+}
@@ -0,0 +1,73 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/megzo/llm-latency-benchmark/internal/benchmark"
+	"github.com/megzo/llm-latency-benchmark/providers"
+)
+
+func TestUseColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	file, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	color, err := UseColor(ColorAlways, file)
+	require.NoError(t, err)
+	assert.True(t, color, "always colorizes even a file")
+
+	color, err = UseColor(ColorNever, file)
+	require.NoError(t, err)
+	assert.False(t, color)
+
+	color, err = UseColor(ColorAuto, file)
+	require.NoError(t, err)
+	assert.False(t, color, "auto leaves redirected output plain")
+
+	_, err = UseColor("sometimes", file)
+	assert.Error(t, err)
+}
+
+func TestUseColor_NoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	color, err := UseColor(ColorAuto, os.Stdout)
+	require.NoError(t, err)
+	assert.False(t, color)
+
+	// An explicit mode wins over NO_COLOR
+	color, err = UseColor(ColorAlways, os.Stdout)
+	require.NoError(t, err)
+	assert.True(t, color)
+}
+
+func TestWriteTable_Color(t *testing.T) {
+	results := []benchmark.BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4o-mini", PromptFile: "simple_chat", Success: true},
+		{Provider: "openai", Model: "gpt-4o-mini", PromptFile: "long", Success: true, Truncated: true},
+		{Provider: "openai", Model: "gpt-4o-mini", PromptFile: "blank", Success: true, EmptyResponse: true},
+		{Provider: "groq", Model: "llama-3.1-8b-instant", PromptFile: "simple_chat", Error: &providers.RateLimitError{Provider: "groq"}},
+	}
+
+	var plain bytes.Buffer
+	require.NoError(t, WriteTable(&plain, results, false))
+	assert.NotContains(t, plain.String(), "\x1b[")
+
+	var colored bytes.Buffer
+	require.NoError(t, WriteTable(&colored, results, true))
+	lines := strings.Split(strings.TrimRight(colored.String(), "\n"), "\n")
+	require.Len(t, lines, 5)
+	assert.NotContains(t, lines[0], "\x1b[", "the header stays plain")
+	assert.True(t, strings.HasSuffix(lines[1], ansiGreen+"ok"+ansiReset), lines[1])
+	assert.True(t, strings.HasSuffix(lines[2], ansiYellow+"truncated"+ansiReset), lines[2])
+	assert.True(t, strings.HasSuffix(lines[3], ansiYellow+"empty"+ansiReset), lines[3])
+	assert.True(t, strings.HasSuffix(lines[4], ansiRed+"error (rate_limit)"+ansiReset), lines[3])
+}
@@ -0,0 +1,86 @@
+package output
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+)
+
+// gzipSuffix marks output paths whose contents are gzip-compressed
+const gzipSuffix = ".gz"
+
+// outputFile writes to a file, gzip-compressing what's written when the path
+// ends in .gz. Appending to a compressed file adds a gzip member, which readers
+// decompress as one stream.
+type outputFile struct {
+	io.Writer
+	file *os.File
+	gz   *gzip.Writer // nil when uncompressed
+}
+
+// createOutput opens path for writing with os.OpenFile's flag
+func createOutput(path string, flag int) (*outputFile, error) {
+	file, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, gzipSuffix) {
+		return &outputFile{Writer: file, file: file}, nil
+	}
+	gz := gzip.NewWriter(file)
+	return &outputFile{Writer: gz, file: file, gz: gz}, nil
+}
+
+// Flush pushes compressed data written so far to the file, so a run that crashes
+// leaves everything before the flush readable
+func (f *outputFile) Flush() error {
+	if f.gz == nil {
+		return nil
+	}
+	return f.gz.Flush()
+}
+
+// Close finishes the gzip stream, if any, and closes the file
+func (f *outputFile) Close() error {
+	if f.gz != nil {
+		if err := f.gz.Close(); err != nil {
+			f.file.Close()
+			return err
+		}
+	}
+	return f.file.Close()
+}
+
+// inputFile reads a file, decompressing it when the path ends in .gz
+type inputFile struct {
+	io.Reader
+	file *os.File
+}
+
+// openInput opens path for reading; a compressed file's gzip header is checked
+// up front
+func openInput(path string) (*inputFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, gzipSuffix) {
+		return &inputFile{Reader: file, file: file}, nil
+	}
+	gz, err := gzip.NewReader(file)
+	if err == io.EOF {
+		// An empty file has no gzip header; read it as empty
+		return &inputFile{Reader: file, file: file}, nil
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &inputFile{Reader: gz, file: file}, nil
+}
+
+// Close closes the file
+func (f *inputFile) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,92 @@
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/megzo/llm-latency-benchmark/internal/benchmark"
+)
+
+// gunzip returns the decompressed contents of a gzip file
+func gunzip(t *testing.T, path string) []byte {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	require.NoError(t, err, "output should be gzip-compressed")
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return data
+}
+
+func TestCSVWriter_Gzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.csv.gz")
+
+	first := NewCSVWriter(path)
+	first.SetMetadata(benchmark.RunMetadata{Hostname: "bench-01"})
+	require.NoError(t, first.WriteResults([]benchmark.BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", TTFT: 250 * time.Millisecond, Success: true, Response: "Hello, world"},
+	}))
+
+	// Appending adds a second gzip member that decompresses as part of the same file
+	second := NewCSVWriter(path)
+	second.SetAppend(true)
+	require.NoError(t, second.WriteResults([]benchmark.BenchmarkResult{
+		{Provider: "groq", Model: "qwen/qwen3-32b", PromptFile: "simple", Success: true},
+	}))
+
+	reader := csv.NewReader(bytes.NewReader(gunzip(t, path)))
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3, "one header and two rows")
+	assert.Equal(t, resultsHeader, rows[0])
+
+	read, err := ReadResults(path)
+	require.NoError(t, err)
+	require.Len(t, read, 2)
+	assert.Equal(t, "gpt-4.1", read[0].Model)
+	assert.Equal(t, 250*time.Millisecond, read[0].TTFT)
+	assert.Equal(t, "Hello, world", read[0].Response)
+	assert.Equal(t, "groq", read[1].Provider)
+}
+
+func TestCSVStream_Gzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.csv.gz")
+	stream, err := NewCSVWriter(path).Open()
+	require.NoError(t, err)
+
+	require.NoError(t, stream.WriteResult(benchmark.BenchmarkResult{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", Success: true}))
+	require.NoError(t, stream.WriteResult(benchmark.BenchmarkResult{Provider: "openai", Model: "gpt-4.1-mini", PromptFile: "simple", Success: true}))
+	require.NoError(t, stream.Close())
+
+	read, err := ReadResults(path)
+	require.NoError(t, err)
+	require.Len(t, read, 2)
+	assert.Equal(t, "gpt-4.1-mini", read[1].Model)
+}
+
+func TestWriteSummaryJSON_Gzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.json.gz")
+	summary := benchmark.Summary{TotalRuns: 2, SuccessfulRuns: 2, AvgTTFT: 120 * time.Millisecond}
+
+	require.NoError(t, WriteSummaryJSON(path, benchmark.RunMetadata{Hostname: "bench-01"}, summary, nil))
+
+	var report SummaryReport
+	require.NoError(t, json.Unmarshal(gunzip(t, path), &report))
+	assert.Equal(t, "bench-01", report.Metadata.Hostname)
+	assert.Equal(t, 2, report.Summary.TotalRuns)
+	assert.Equal(t, 120.0, report.Summary.AvgTTFTMS)
+}
@@ -2,17 +2,81 @@ package output
 
 import (
 	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/megzo/llm-latency-benchmark/internal/benchmark"
+	"github.com/megzo/llm-latency-benchmark/providers"
 )
 
+// metadataPrefix starts the comment line that carries run metadata
+const metadataPrefix = "# run_metadata: "
+
+// resultsHeader is the CSV header row; ReadResults matches columns by these names
+var resultsHeader = []string{
+	"Provider",
+	"Model",
+	"PromptFile",
+	"Temperature",
+	"MaxTokens",
+	"Seed",
+	"Concurrency",
+	"Run",
+	"StartTime",
+	"FirstTokenTime",
+	"EndTime",
+	"TTFT_MS",
+	"TotalTime_MS",
+	"DNS_MS",
+	"Connect_MS",
+	"TLS_MS",
+	"TTFB_MS",
+	"TimeToToolCall_MS",
+	"TimeToReasoning_MS",
+	"TimeToAnswer_MS",
+	"TimeToLastToken_MS",
+	"InputTokens",
+	"CachedInputTokens",
+	"OutputTokens",
+	"ReasoningTokens",
+	"NormalizedOutputTokens",
+	"TotalTokens",
+	"TokensPerSecond",
+	"Dimensions",
+	"Cost",
+	"Success",
+	"FinishReason",
+	"Truncated",
+	"EmptyResponse",
+	"ToolCall",
+	"Citations",
+	"ResponseModel",
+	"SystemFingerprint",
+	"Backend",
+	"RequestID",
+	"ValidJSON",
+	"ErrorCategory",
+	"Attempts",
+	"LastRetryError",
+	"RateLimitRemainingRequests",
+	"RateLimitRemainingTokens",
+	"Error",
+	"Response",
+}
+
 // CSVWriter handles writing benchmark results to CSV files
 type CSVWriter struct {
 	filepath string
+	metadata *benchmark.RunMetadata
+	append   bool
 }
 
 // NewCSVWriter creates a new CSV writer
@@ -22,78 +86,433 @@ func NewCSVWriter(filepath string) *CSVWriter {
 	}
 }
 
+// SetMetadata makes WriteResults emit the run metadata as a leading comment line
+func (w *CSVWriter) SetMetadata(metadata benchmark.RunMetadata) {
+	w.metadata = &metadata
+}
+
+// SetAppend makes WriteResults add rows to an existing file instead of replacing it.
+// The existing header must match the current columns; a missing file is created as usual.
+func (w *CSVWriter) SetAppend(appendMode bool) {
+	w.append = appendMode
+}
+
 // WriteResults writes benchmark results to a CSV file
 func (w *CSVWriter) WriteResults(results []benchmark.BenchmarkResult) error {
+	file, writer, err := w.open()
+	if err != nil {
+		return err
+	}
+
+	// Write data rows
+	for _, result := range results {
+		if err := writer.Write(resultRow(result)); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	// Flush before closing so a compressed file gets every row
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write CSV file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write CSV file: %w", err)
+	}
+	return nil
+}
+
+// Open creates or opens the CSV file and writes its metadata and header, returning
+// a stream that writes each result as a row as soon as it's produced, so a run
+// that crashes partway keeps the rows already written
+func (w *CSVWriter) Open() (*CSVStream, error) {
+	file, writer, err := w.open()
+	if err != nil {
+		return nil, err
+	}
+	writer.Flush()
+	if err := errors.Join(writer.Error(), file.Flush()); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	return &CSVStream{file: file, writer: writer}, nil
+}
+
+// open creates the CSV file, or opens it for appending, and writes the run
+// metadata and header row ahead of the data rows. A path ending in .gz is
+// gzip-compressed.
+func (w *CSVWriter) open() (*outputFile, *csv.Writer, error) {
 	// Ensure the directory exists
 	dir := filepath.Dir(w.filepath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+		return nil, nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	// Create the CSV file
-	file, err := os.Create(w.filepath)
-	if err != nil {
-		return fmt.Errorf("failed to create CSV file: %w", err)
+	appending := false
+	if w.append {
+		header, err := readHeader(w.filepath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if header != nil {
+			if !equalHeaders(header, resultsHeader) {
+				return nil, nil, fmt.Errorf("cannot append to %s: its header does not match the current CSV columns (the output format has changed); write to a new file instead", w.filepath)
+			}
+			appending = true
+		}
+	}
+
+	// Create the CSV file, or open it for appending
+	var file *outputFile
+	if appending {
+		f, err := createOutput(w.filepath, os.O_WRONLY|os.O_APPEND)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open CSV file for appending: %w", err)
+		}
+		file = f
+	} else {
+		f, err := createOutput(w.filepath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create CSV file: %w", err)
+		}
+		file = f
+	}
+
+	// Write run metadata as a comment so CSV readers can skip it; appended
+	// runs get their own metadata line ahead of their rows
+	if w.metadata != nil {
+		data, err := json.Marshal(w.metadata)
+		if err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("failed to encode run metadata: %w", err)
+		}
+		if _, err := fmt.Fprintf(file, "%s%s\n", metadataPrefix, data); err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("failed to write run metadata: %w", err)
+		}
 	}
-	defer file.Close()
 
 	// Create CSV writer
 	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Write header
-	header := []string{
-		"Provider",
-		"Model",
-		"PromptFile",
-		"StartTime",
-		"FirstTokenTime",
-		"EndTime",
-		"TTFT_MS",
-		"TotalTime_MS",
-		"InputTokens",
-		"OutputTokens",
-		"TotalTokens",
-		"TokensPerSecond",
-		"Cost",
-		"Success",
-		"Error",
-		"Response",
-	}
-
-	if err := writer.Write(header); err != nil {
-		return fmt.Errorf("failed to write CSV header: %w", err)
-	}
 
-	// Write data rows
-	for _, result := range results {
-		row := []string{
-			result.Provider,
-			result.Model,
-			result.PromptFile,
-			result.StartTime.Format(time.RFC3339),
-			result.FirstTokenTime.Format(time.RFC3339),
-			result.EndTime.Format(time.RFC3339),
-			fmt.Sprintf("%.2f", float64(result.TTFT.Microseconds())/1000.0), // Convert to milliseconds
-			fmt.Sprintf("%.2f", float64(result.TotalTime.Microseconds())/1000.0), // Convert to milliseconds
-			fmt.Sprintf("%d", result.InputTokens),
-			fmt.Sprintf("%d", result.OutputTokens),
-			fmt.Sprintf("%d", result.TotalTokens),
-			fmt.Sprintf("%.2f", result.TokensPerSecond),
-			fmt.Sprintf("%.6f", result.Cost),
-			fmt.Sprintf("%t", result.Success),
-			getErrorMessage(result.Error),
-			truncateResponse(result.Response),
-		}
-
-		if err := writer.Write(row); err != nil {
-			return fmt.Errorf("failed to write CSV row: %w", err)
+	// Write header unless appending to a file that already has one
+	if !appending {
+		if err := writer.Write(resultsHeader); err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("failed to write CSV header: %w", err)
 		}
 	}
 
+	return file, writer, nil
+}
+
+// resultRow formats a result as a CSV row in resultsHeader order
+func resultRow(result benchmark.BenchmarkResult) []string {
+	return []string{
+		result.Provider,
+		result.Model,
+		result.PromptFile,
+		strconv.FormatFloat(result.Temperature, 'f', -1, 64),
+		fmt.Sprintf("%d", result.MaxTokens),
+		formatSeed(result.Seed),
+		fmt.Sprintf("%d", result.Concurrency),
+		fmt.Sprintf("%d", result.Run),
+		result.StartTime.Format(time.RFC3339),
+		result.FirstTokenTime.Format(time.RFC3339),
+		result.EndTime.Format(time.RFC3339),
+		fmt.Sprintf("%.2f", float64(result.TTFT.Microseconds())/1000.0), // Convert to milliseconds
+		fmt.Sprintf("%.2f", float64(result.TotalTime.Microseconds())/1000.0), // Convert to milliseconds
+		fmt.Sprintf("%.2f", float64(result.DNSLookup.Microseconds())/1000.0),
+		fmt.Sprintf("%.2f", float64(result.Connect.Microseconds())/1000.0),
+		fmt.Sprintf("%.2f", float64(result.TLSHandshake.Microseconds())/1000.0),
+		fmt.Sprintf("%.2f", float64(result.TimeToFirstByte.Microseconds())/1000.0),
+		fmt.Sprintf("%.2f", float64(result.TimeToToolCall.Microseconds())/1000.0),
+		fmt.Sprintf("%.2f", float64(result.TimeToReasoning.Microseconds())/1000.0),
+		fmt.Sprintf("%.2f", float64(result.TimeToAnswer.Microseconds())/1000.0),
+		fmt.Sprintf("%.2f", float64(result.TimeToLastToken.Microseconds())/1000.0),
+		fmt.Sprintf("%d", result.InputTokens),
+		fmt.Sprintf("%d", result.CachedInputTokens),
+		fmt.Sprintf("%d", result.OutputTokens),
+		fmt.Sprintf("%d", result.ReasoningTokens),
+		fmt.Sprintf("%d", result.NormalizedOutputTokens),
+		fmt.Sprintf("%d", result.TotalTokens),
+		fmt.Sprintf("%.2f", result.TokensPerSecond),
+		fmt.Sprintf("%d", result.Dimensions),
+		fmt.Sprintf("%.6f", result.Cost),
+		fmt.Sprintf("%t", result.Success),
+		result.FinishReason,
+		fmt.Sprintf("%t", result.Truncated),
+		fmt.Sprintf("%t", result.EmptyResponse),
+		fmt.Sprintf("%t", result.ToolCall),
+		strings.Join(result.Citations, " "),
+		result.ResponseModel,
+		result.SystemFingerprint,
+		result.Backend,
+		result.RequestID,
+		formatOptionalBool(result.ValidJSON),
+		providers.ErrorCategory(result.Error),
+		fmt.Sprintf("%d", result.Attempts),
+		result.LastRetryError,
+		formatRateLimitRemaining(result.RateLimit, func(info *providers.RateLimitInfo) int { return info.RemainingRequests }),
+		formatRateLimitRemaining(result.RateLimit, func(info *providers.RateLimitInfo) int { return info.RemainingTokens }),
+		getErrorMessage(result.Error),
+		truncateResponse(result.Response),
+	}
+}
+
+// CSVStream writes results to an open CSV file one row at a time. It's safe for
+// concurrent use and implements benchmark.ResultSink.
+type CSVStream struct {
+	mu     sync.Mutex
+	file   *outputFile
+	writer *csv.Writer
+}
+
+// WriteResult writes one result row and flushes it to the file
+func (s *CSVStream) WriteResult(result benchmark.BenchmarkResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.writer.Write(resultRow(result)); err != nil {
+		return fmt.Errorf("failed to write CSV row: %w", err)
+	}
+	s.writer.Flush()
+	if err := errors.Join(s.writer.Error(), s.file.Flush()); err != nil {
+		return fmt.Errorf("failed to write CSV row: %w", err)
+	}
 	return nil
 }
 
+// Close flushes any buffered rows and closes the file
+func (s *CSVStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writer.Flush()
+	if err := s.writer.Error(); err != nil {
+		s.file.Close()
+		return fmt.Errorf("failed to write CSV file: %w", err)
+	}
+	return s.file.Close()
+}
+
+// ReadResults loads benchmark results from a CSV file previously written by WriteResults,
+// decompressing it when the path ends in .gz. Columns are matched by header name so
+// files with extra or reordered columns still load.
+func ReadResults(path string) ([]benchmark.BenchmarkResult, error) {
+	file, err := openInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV file: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("CSV file %s is empty", path)
+	}
+
+	columns := make(map[string]int, len(rows[0]))
+	for i, name := range rows[0] {
+		columns[name] = i
+	}
+	for _, required := range []string{"Provider", "Model", "PromptFile", "TTFT_MS", "Success"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV file %s is missing column %s", path, required)
+		}
+	}
+
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	results := make([]benchmark.BenchmarkResult, 0, len(rows)-1)
+	for line, row := range rows[1:] {
+		result := benchmark.BenchmarkResult{
+			Provider:   field(row, "Provider"),
+			Model:      field(row, "Model"),
+			PromptFile: field(row, "PromptFile"),
+			Response:   field(row, "Response"),
+		}
+
+		var parseErr error
+		parseMillis := func(name string) time.Duration {
+			v := field(row, name)
+			if v == "" {
+				return 0
+			}
+			ms, err := strconv.ParseFloat(v, 64)
+			if err != nil && parseErr == nil {
+				parseErr = fmt.Errorf("invalid %s %q: %w", name, v, err)
+			}
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+		parseInt := func(name string) int {
+			v := field(row, name)
+			if v == "" {
+				return 0
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil && parseErr == nil {
+				parseErr = fmt.Errorf("invalid %s %q: %w", name, v, err)
+			}
+			return n
+		}
+		parseFloat := func(name string) float64 {
+			v := field(row, name)
+			if v == "" {
+				return 0
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil && parseErr == nil {
+				parseErr = fmt.Errorf("invalid %s %q: %w", name, v, err)
+			}
+			return f
+		}
+
+		result.Temperature = parseFloat("Temperature")
+		result.MaxTokens = parseInt("MaxTokens")
+		if v := field(row, "Seed"); v != "" {
+			seed, err := strconv.ParseInt(v, 10, 64)
+			if err != nil && parseErr == nil {
+				parseErr = fmt.Errorf("invalid Seed %q: %w", v, err)
+			}
+			result.Seed = &seed
+		}
+		result.Concurrency = parseInt("Concurrency")
+		result.Run = parseInt("Run")
+		result.TTFT = parseMillis("TTFT_MS")
+		result.TotalTime = parseMillis("TotalTime_MS")
+		result.DNSLookup = parseMillis("DNS_MS")
+		result.Connect = parseMillis("Connect_MS")
+		result.TLSHandshake = parseMillis("TLS_MS")
+		result.TimeToFirstByte = parseMillis("TTFB_MS")
+		result.TimeToToolCall = parseMillis("TimeToToolCall_MS")
+		result.TimeToReasoning = parseMillis("TimeToReasoning_MS")
+		result.TimeToAnswer = parseMillis("TimeToAnswer_MS")
+		result.TimeToLastToken = parseMillis("TimeToLastToken_MS")
+		result.InputTokens = parseInt("InputTokens")
+		result.CachedInputTokens = parseInt("CachedInputTokens")
+		result.OutputTokens = parseInt("OutputTokens")
+		result.ReasoningTokens = parseInt("ReasoningTokens")
+		result.NormalizedOutputTokens = parseInt("NormalizedOutputTokens")
+		if result.NormalizedOutputTokens > 0 {
+			result.NativeOutputTokens = result.OutputTokens
+		}
+		result.TotalTokens = parseInt("TotalTokens")
+		result.TokensPerSecond = parseFloat("TokensPerSecond")
+		result.Dimensions = parseInt("Dimensions")
+		result.Cost = parseFloat("Cost")
+		result.Success = field(row, "Success") == "true"
+		result.FinishReason = field(row, "FinishReason")
+		result.Truncated = field(row, "Truncated") == "true"
+		result.EmptyResponse = field(row, "EmptyResponse") == "true"
+		result.ToolCall = field(row, "ToolCall") == "true"
+		if citations := strings.Fields(field(row, "Citations")); len(citations) > 0 {
+			result.Citations = citations
+		}
+		result.ResponseModel = field(row, "ResponseModel")
+		result.SystemFingerprint = field(row, "SystemFingerprint")
+		result.Backend = field(row, "Backend")
+		result.RequestID = field(row, "RequestID")
+		if validJSON := field(row, "ValidJSON"); validJSON != "" {
+			valid := validJSON == "true"
+			result.ValidJSON = &valid
+		}
+		if field(row, "RateLimitRemainingRequests") != "" || field(row, "RateLimitRemainingTokens") != "" {
+			result.RateLimit = &providers.RateLimitInfo{
+				RemainingRequests: parseInt("RateLimitRemainingRequests"),
+				RemainingTokens:   parseInt("RateLimitRemainingTokens"),
+			}
+		}
+		result.Attempts = parseInt("Attempts")
+		result.LastRetryError = field(row, "LastRetryError")
+		if msg := field(row, "Error"); msg != "" {
+			result.Error = errors.New(msg)
+		}
+		if parseErr != nil {
+			return nil, fmt.Errorf("line %d of %s: %w", line+2, path, parseErr)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// readHeader returns the header row of an existing results file, or nil if the
+// file doesn't exist or is empty
+func readHeader(path string) ([]string, error) {
+	file, err := openInput(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header of %s: %w", path, err)
+	}
+	return header, nil
+}
+
+// equalHeaders reports whether two header rows have the same columns in the same order
+func equalHeaders(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// formatRateLimitRemaining formats one remaining count, leaving it blank when the
+// provider reported no rate-limit headers
+func formatRateLimitRemaining(info *providers.RateLimitInfo, remaining func(*providers.RateLimitInfo) int) string {
+	if info == nil {
+		return ""
+	}
+	return strconv.Itoa(remaining(info))
+}
+
+// formatSeed renders a pinned seed, leaving the column empty when none was set
+func formatSeed(seed *int64) string {
+	if seed == nil {
+		return ""
+	}
+	return strconv.FormatInt(*seed, 10)
+}
+
+// formatOptionalBool formats an optional flag, leaving the cell empty when it's unset
+func formatOptionalBool(value *bool) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatBool(*value)
+}
+
 // getErrorMessage safely extracts error message
 func getErrorMessage(err error) string {
 	if err == nil {
@@ -1,333 +1,421 @@
 package output
 
 import (
+	"context"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"llm-latency-benchmark/internal/benchmark"
+	"github.com/megzo/llm-latency-benchmark/internal/benchmark"
+	"github.com/megzo/llm-latency-benchmark/internal/config"
+	"github.com/megzo/llm-latency-benchmark/providers"
 )
 
 func TestCSVWriter_WriteHeader(t *testing.T) {
-	tempFile := "test_output.csv"
-	defer os.Remove(tempFile)
+	path := filepath.Join(t.TempDir(), "test_output.csv")
 
-	writer, err := NewCSVWriter(tempFile)
-	require.NoError(t, err)
-	defer writer.Close()
-
-	// Write header
-	err = writer.WriteHeader()
-	require.NoError(t, err)
+	require.NoError(t, NewCSVWriter(path).WriteResults(nil))
 
 	// Read the file and check header
-	content, err := os.ReadFile(tempFile)
+	content, err := os.ReadFile(path)
 	require.NoError(t, err)
-
-	expectedHeader := "timestamp,model,prompt_name,ttft_ms,total_time_ms,input_tokens,output_tokens,cost,error,tokens_per_second"
-	assert.Contains(t, string(content), expectedHeader)
+	assert.Equal(t, strings.Join(resultsHeader, ",")+"\n", string(content))
 }
 
 func TestCSVWriter_WriteResult(t *testing.T) {
-	tempFile := "test_results.csv"
-	defer os.Remove(tempFile)
-
-	writer, err := NewCSVWriter(tempFile)
-	require.NoError(t, err)
-	defer writer.Close()
-
-	// Write header first
-	err = writer.WriteHeader()
-	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "test_results.csv")
 
-	// Create test result
 	result := benchmark.BenchmarkResult{
-		Model:        "gpt-4o-mini",
-		PromptName:   "test-prompt",
-		TTFT:         1 * time.Second,
-		TotalTime:    5 * time.Second,
-		InputTokens:  100,
-		OutputTokens: 200,
-		Cost:         0.001,
-		Error:        nil,
+		Provider:        "openai",
+		Model:           "gpt-4o-mini",
+		PromptFile:      "test-prompt",
+		TTFT:            1 * time.Second,
+		TotalTime:       5 * time.Second,
+		InputTokens:     100,
+		OutputTokens:    200,
+		TokensPerSecond: 40,
+		Cost:            0.001,
+		Success:         true,
 	}
+	require.NoError(t, NewCSVWriter(path).WriteResults([]benchmark.BenchmarkResult{result}))
 
-	// Write result
-	err = writer.WriteResult(result)
-	require.NoError(t, err)
-
-	// Read the file and check content
-	content, err := os.ReadFile(tempFile)
+	content, err := os.ReadFile(path)
 	require.NoError(t, err)
 
 	lines := string(content)
 	assert.Contains(t, lines, "gpt-4o-mini")
 	assert.Contains(t, lines, "test-prompt")
-	assert.Contains(t, lines, "1000") // TTFT in ms
-	assert.Contains(t, lines, "5000") // Total time in ms
-	assert.Contains(t, lines, "100")  // Input tokens
-	assert.Contains(t, lines, "200")  // Output tokens
-	assert.Contains(t, lines, "0.001") // Cost
-	assert.Contains(t, lines, "40")   // Tokens per second (200 tokens / 5 seconds)
+	assert.Contains(t, lines, "1000.00")  // TTFT in ms
+	assert.Contains(t, lines, "5000.00")  // Total time in ms
+	assert.Contains(t, lines, "0.001000") // Cost
+	assert.Contains(t, lines, "40.00")    // Tokens per second
 }
 
 func TestCSVWriter_WriteResultWithError(t *testing.T) {
-	tempFile := "test_error_results.csv"
-	defer os.Remove(tempFile)
+	path := filepath.Join(t.TempDir(), "test_error_results.csv")
 
-	writer, err := NewCSVWriter(tempFile)
-	require.NoError(t, err)
-	defer writer.Close()
+	result := benchmark.BenchmarkResult{
+		Provider:   "openai",
+		Model:      "gpt-4o-mini",
+		PromptFile: "test-prompt",
+		Error:      assert.AnError,
+	}
+	require.NoError(t, NewCSVWriter(path).WriteResults([]benchmark.BenchmarkResult{result}))
 
-	// Write header first
-	err = writer.WriteHeader()
+	read, err := ReadResults(path)
 	require.NoError(t, err)
+	require.Len(t, read, 1)
+	assert.Equal(t, "test-prompt", read[0].PromptFile)
+	assert.False(t, read[0].Success)
+	require.Error(t, read[0].Error)
+	assert.Equal(t, assert.AnError.Error(), read[0].Error.Error())
+}
 
-	// Create test result with error
-	result := benchmark.BenchmarkResult{
-		Model:        "gpt-4o-mini",
-		PromptName:   "test-prompt",
-		Error:        assert.AnError,
+func TestCSVWriter_MultipleResults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test_multiple_results.csv")
+
+	results := []benchmark.BenchmarkResult{
+		{Model: "gpt-4o-mini", PromptFile: "prompt1", TTFT: 1 * time.Second, TotalTime: 5 * time.Second, InputTokens: 100, OutputTokens: 200, Cost: 0.001, Success: true},
+		{Model: "gpt-4o-mini", PromptFile: "prompt2", TTFT: 2 * time.Second, TotalTime: 6 * time.Second, InputTokens: 150, OutputTokens: 250, Cost: 0.002, Success: true},
+		{Model: "gpt-3.5-turbo", PromptFile: "prompt3", TTFT: 500 * time.Millisecond, TotalTime: 3 * time.Second, InputTokens: 80, OutputTokens: 120, Cost: 0.0005, Success: true},
 	}
+	require.NoError(t, NewCSVWriter(path).WriteResults(results))
 
-	// Write result
-	err = writer.WriteResult(result)
+	read, err := ReadResults(path)
 	require.NoError(t, err)
+	require.Len(t, read, 3)
+	for i, result := range results {
+		assert.Equal(t, result.Model, read[i].Model)
+		assert.Equal(t, result.PromptFile, read[i].PromptFile)
+		assert.Equal(t, result.TTFT, read[i].TTFT)
+		assert.Equal(t, result.OutputTokens, read[i].OutputTokens)
+	}
+}
 
-	// Read the file and check content
-	content, err := os.ReadFile(tempFile)
-	require.NoError(t, err)
+func TestCSVWriter_InvalidFilePath(t *testing.T) {
+	// A regular file can't be used as the output directory
+	parent := filepath.Join(t.TempDir(), "not-a-directory")
+	require.NoError(t, os.WriteFile(parent, nil, 0644))
 
-	lines := string(content)
-	assert.Contains(t, lines, "gpt-4o-mini")
-	assert.Contains(t, lines, "test-prompt")
-	assert.Contains(t, lines, "ERROR")
-	assert.Contains(t, lines, assert.AnError.Error())
+	err := NewCSVWriter(filepath.Join(parent, "test.csv")).WriteResults(nil)
+	assert.Error(t, err)
 }
 
-func TestCSVWriter_MultipleResults(t *testing.T) {
-	tempFile := "test_multiple_results.csv"
-	defer os.Remove(tempFile)
+func TestCSVWriter_FilePermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test_permissions.csv")
+	require.NoError(t, NewCSVWriter(path).WriteResults(nil))
 
-	writer, err := NewCSVWriter(tempFile)
+	info, err := os.Stat(path)
 	require.NoError(t, err)
-	defer writer.Close()
 
-	// Write header
-	err = writer.WriteHeader()
-	require.NoError(t, err)
+	// File should be readable and writable by owner
+	mode := info.Mode()
+	assert.True(t, mode.IsRegular())
+	assert.True(t, mode&0400 != 0) // Readable
+	assert.True(t, mode&0200 != 0) // Writable
+}
 
-	// Create multiple test results
-	results := []benchmark.BenchmarkResult{
-		{
-			Model:        "gpt-4o-mini",
-			PromptName:   "prompt1",
-			TTFT:         1 * time.Second,
-			TotalTime:    5 * time.Second,
-			InputTokens:  100,
-			OutputTokens: 200,
-			Cost:         0.001,
-			Error:        nil,
-		},
-		{
-			Model:        "gpt-4o-mini",
-			PromptName:   "prompt2",
-			TTFT:         2 * time.Second,
-			TotalTime:    6 * time.Second,
-			InputTokens:  150,
-			OutputTokens: 250,
-			Cost:         0.002,
-			Error:        nil,
-		},
-		{
-			Model:        "gpt-3.5-turbo",
-			PromptName:   "prompt3",
-			TTFT:         500 * time.Millisecond,
-			TotalTime:    3 * time.Second,
-			InputTokens:  80,
-			OutputTokens: 120,
-			Cost:         0.0005,
-			Error:        nil,
-		},
-	}
+func TestCSVWriter_CSVFormatting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test_formatting.csv")
 
-	// Write all results
-	for _, result := range results {
-		err = writer.WriteResult(result)
-		require.NoError(t, err)
+	// Fields with commas, quotes and newlines are escaped and read back intact
+	result := benchmark.BenchmarkResult{
+		Model:      "gpt-4o-mini",
+		PromptFile: "test,prompt",
+		Response:   "He said \"hi\",\nthen left.",
+		Success:    true,
 	}
+	require.NoError(t, NewCSVWriter(path).WriteResults([]benchmark.BenchmarkResult{result}))
 
-	// Read the file and check content
-	content, err := os.ReadFile(tempFile)
+	read, err := ReadResults(path)
 	require.NoError(t, err)
+	require.Len(t, read, 1)
+	assert.Equal(t, result.PromptFile, read[0].PromptFile)
+	assert.Equal(t, result.Response, read[0].Response)
+}
 
-	lines := string(content)
-	
-	// Check that all models are present
-	assert.Contains(t, lines, "gpt-4o-mini")
-	assert.Contains(t, lines, "gpt-3.5-turbo")
-	
-	// Check that all prompts are present
-	assert.Contains(t, lines, "prompt1")
-	assert.Contains(t, lines, "prompt2")
-	assert.Contains(t, lines, "prompt3")
-	
-	// Check that we have the expected number of data lines (3 results)
-	dataLines := 0
-	for _, line := range lines {
-		if line == '\n' {
-			dataLines++
-		}
+func TestCSVWriter_TimestampFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test_timestamp.csv")
+
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	result := benchmark.BenchmarkResult{
+		Model:      "gpt-4o-mini",
+		PromptFile: "test",
+		StartTime:  start,
 	}
-	// Should have header + 3 data lines = 4 lines total
-	assert.GreaterOrEqual(t, dataLines, 3)
+	require.NoError(t, NewCSVWriter(path).WriteResults([]benchmark.BenchmarkResult{result}))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "2025-01-02T03:04:05Z") // RFC 3339
 }
 
-func TestCSVWriter_InvalidFilePath(t *testing.T) {
-	// Try to create a writer with an invalid path
-	_, err := NewCSVWriter("/invalid/path/that/does/not/exist/test.csv")
-	assert.Error(t, err)
+func TestCSVWriter_DirectoryCreation(t *testing.T) {
+	// Test creating CSV in a directory that doesn't exist
+	tempDir := filepath.Join(t.TempDir(), "test_csv_dir")
+	path := filepath.Join(tempDir, "test.csv")
+
+	require.NoError(t, NewCSVWriter(path).WriteResults(nil))
+
+	_, err := os.Stat(path)
+	assert.NoError(t, err)
 }
 
-func TestCSVWriter_Close(t *testing.T) {
-	tempFile := "test_close.csv"
-	defer os.Remove(tempFile)
+func TestCSVWriter_WriteResultsWithMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.csv")
+
+	writer := NewCSVWriter(path)
+	writer.SetMetadata(benchmark.RunMetadata{
+		Hostname:    "bench-01",
+		OS:          "linux",
+		Arch:        "amd64",
+		StartTime:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		ToolVersion: "0.1.0",
+		ModelsFile:  "models.yaml",
+		Concurrency: 2,
+		GitCommit:   "abc123",
+	})
+	results := []benchmark.BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", Success: true, TTFT: 250 * time.Millisecond, Response: "Hello"},
+	}
+	require.NoError(t, writer.WriteResults(results))
 
-	writer, err := NewCSVWriter(tempFile)
+	content, err := os.ReadFile(path)
 	require.NoError(t, err)
+	lines := strings.SplitN(string(content), "\n", 3)
+	require.Len(t, lines, 3)
+	assert.Equal(t, `# run_metadata: {"hostname":"bench-01","os":"linux","arch":"amd64","start_time":"2025-01-02T03:04:05Z","tool_version":"0.1.0","models_file":"models.yaml","concurrency":2,"git_commit":"abc123"}`, lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "Provider,Model,PromptFile,"))
 
-	// Write some data
-	err = writer.WriteHeader()
+	// The metadata comment doesn't interfere with reading the results back
+	read, err := ReadResults(path)
 	require.NoError(t, err)
+	require.Len(t, read, 1)
+	assert.Equal(t, "openai", read[0].Provider)
+	assert.Equal(t, 250*time.Millisecond, read[0].TTFT)
+}
+
+func TestCSVWriter_AppendToExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rolling.csv")
+
+	first := NewCSVWriter(path)
+	require.NoError(t, first.WriteResults([]benchmark.BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", Success: true},
+	}))
+
+	second := NewCSVWriter(path)
+	second.SetAppend(true)
+	second.SetMetadata(benchmark.RunMetadata{Hostname: "bench-01"})
+	require.NoError(t, second.WriteResults([]benchmark.BenchmarkResult{
+		{Provider: "groq", Model: "qwen/qwen3-32b", PromptFile: "simple", Success: true},
+	}))
 
-	// Close the writer
-	err = writer.Close()
+	content, err := os.ReadFile(path)
 	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(content), "Provider,Model,PromptFile,"), "header should be written once")
 
-	// Try to write after closing should fail
-	result := benchmark.BenchmarkResult{
-		Model:      "test",
-		PromptName: "test",
-	}
-	err = writer.WriteResult(result)
-	assert.Error(t, err)
+	read, err := ReadResults(path)
+	require.NoError(t, err)
+	require.Len(t, read, 2)
+	assert.Equal(t, "openai", read[0].Provider)
+	assert.Equal(t, "groq", read[1].Provider)
 }
 
-func TestCSVWriter_FilePermissions(t *testing.T) {
-	tempFile := "test_permissions.csv"
-	defer os.Remove(tempFile)
+func TestCSVWriter_AppendToMissingCreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "rolling.csv")
 
-	writer, err := NewCSVWriter(tempFile)
-	require.NoError(t, err)
-	defer writer.Close()
+	writer := NewCSVWriter(path)
+	writer.SetAppend(true)
+	require.NoError(t, writer.WriteResults([]benchmark.BenchmarkResult{
+		{Provider: "anthropic", Model: "claude-sonnet-4", PromptFile: "simple", Success: true},
+	}))
 
-	// Write some data
-	err = writer.WriteHeader()
+	content, err := os.ReadFile(path)
 	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "Provider,Model,PromptFile,"))
 
-	// Check file permissions
-	info, err := os.Stat(tempFile)
+	read, err := ReadResults(path)
 	require.NoError(t, err)
+	require.Len(t, read, 1)
+	assert.Equal(t, "anthropic", read[0].Provider)
+}
 
-	// File should be readable and writable by owner
-	mode := info.Mode()
-	assert.True(t, mode.IsRegular())
-	assert.True(t, mode&0400 != 0) // Readable
-	assert.True(t, mode&0200 != 0) // Writable
+func TestCSVWriter_AppendRejectsHeaderMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.csv")
+	original := "Provider,Model,PromptFile,TTFT_MS\nopenai,gpt-4,simple,120.00\n"
+	require.NoError(t, os.WriteFile(path, []byte(original), 0644))
+
+	writer := NewCSVWriter(path)
+	writer.SetAppend(true)
+	err := writer.WriteResults([]benchmark.BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", Success: true},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "header does not match")
+
+	// The existing file is left untouched
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, original, string(content))
 }
 
-func TestCSVWriter_CSVFormatting(t *testing.T) {
-	tempFile := "test_formatting.csv"
-	defer os.Remove(tempFile)
+func TestCSVWriter_SeedRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seeded.csv")
+	seed := int64(1234)
 
-	writer, err := NewCSVWriter(tempFile)
-	require.NoError(t, err)
-	defer writer.Close()
+	writer := NewCSVWriter(path)
+	require.NoError(t, writer.WriteResults([]benchmark.BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", Seed: &seed, Success: true},
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", Success: true},
+	}))
 
-	// Write header
-	err = writer.WriteHeader()
+	read, err := ReadResults(path)
 	require.NoError(t, err)
+	require.Len(t, read, 2)
+	require.NotNil(t, read[0].Seed)
+	assert.Equal(t, seed, *read[0].Seed)
+	assert.Nil(t, read[1].Seed)
+}
 
-	// Create result with special characters that need escaping
-	result := benchmark.BenchmarkResult{
-		Model:        "gpt-4o-mini",
-		PromptName:   "test,prompt", // Contains comma
-		TTFT:         1 * time.Second,
-		TotalTime:    5 * time.Second,
-		InputTokens:  100,
-		OutputTokens: 200,
-		Cost:         0.001,
-		Error:        nil,
-	}
+func TestCSVWriter_RateLimitRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimits.csv")
 
-	// Write result
-	err = writer.WriteResult(result)
-	require.NoError(t, err)
+	writer := NewCSVWriter(path)
+	require.NoError(t, writer.WriteResults([]benchmark.BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", RateLimit: &providers.RateLimitInfo{RemainingRequests: 0, RemainingTokens: 149000}, Success: true},
+		{Provider: "gemini", Model: "gemini-2.5-flash", PromptFile: "simple", Success: true},
+	}))
 
-	// Read the file and check that CSV is properly formatted
-	content, err := os.ReadFile(tempFile)
+	read, err := ReadResults(path)
 	require.NoError(t, err)
-
-	lines := string(content)
-	// Should contain the comma in the prompt name, properly escaped
-	assert.Contains(t, lines, "test,prompt")
+	require.Len(t, read, 2)
+	require.NotNil(t, read[0].RateLimit, "zero remaining requests is still reported")
+	assert.Equal(t, 0, read[0].RateLimit.RemainingRequests)
+	assert.Equal(t, 149000, read[0].RateLimit.RemainingTokens)
+	assert.Nil(t, read[1].RateLimit, "providers without rate-limit headers leave the columns blank")
 }
 
-func TestCSVWriter_TimestampFormat(t *testing.T) {
-	tempFile := "test_timestamp.csv"
-	defer os.Remove(tempFile)
+func TestCSVWriter_CitationsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "citations.csv")
 
-	writer, err := NewCSVWriter(tempFile)
-	require.NoError(t, err)
-	defer writer.Close()
+	citations := []string{"https://a.example/page", "https://b.example/?q=1,2"}
+	writer := NewCSVWriter(path)
+	require.NoError(t, writer.WriteResults([]benchmark.BenchmarkResult{
+		{Provider: "perplexity", Model: "sonar", PromptFile: "simple", Citations: citations, Success: true},
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", Success: true},
+	}))
 
-	// Write header
-	err = writer.WriteHeader()
+	read, err := ReadResults(path)
 	require.NoError(t, err)
+	require.Len(t, read, 2)
+	assert.Equal(t, citations, read[0].Citations)
+	assert.Nil(t, read[1].Citations)
+}
 
-	// Create result
-	result := benchmark.BenchmarkResult{
-		Model:      "gpt-4o-mini",
-		PromptName: "test",
-	}
+func TestCSVWriter_ModelVersionRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fingerprints.csv")
+
+	writer := NewCSVWriter(path)
+	require.NoError(t, writer.WriteResults([]benchmark.BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", ResponseModel: "gpt-4.1-2025-04-14", SystemFingerprint: "fp_b42ea4cc6f", Success: true},
+		{Provider: "anthropic", Model: "claude-sonnet-4-0", PromptFile: "simple", Success: true},
+	}))
 
-	// Write result
-	err = writer.WriteResult(result)
+	read, err := ReadResults(path)
 	require.NoError(t, err)
+	require.Len(t, read, 2)
+	assert.Equal(t, "gpt-4.1-2025-04-14", read[0].ResponseModel)
+	assert.Equal(t, "fp_b42ea4cc6f", read[0].SystemFingerprint)
+	assert.Empty(t, read[1].ResponseModel)
+	assert.Empty(t, read[1].SystemFingerprint)
+}
+
+func TestCSVWriter_AttemptsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "attempts.csv")
 
-	// Read the file and check timestamp format
-	content, err := os.ReadFile(tempFile)
+	writer := NewCSVWriter(path)
+	require.NoError(t, writer.WriteResults([]benchmark.BenchmarkResult{
+		{Provider: "groq", Model: "llama-3.1-8b-instant", PromptFile: "simple", Attempts: 3, LastRetryError: "groq rate limit exceeded", Success: true},
+		{Provider: "openai", Model: "gpt-4.1", PromptFile: "simple", Attempts: 1, Success: true},
+	}))
+
+	read, err := ReadResults(path)
 	require.NoError(t, err)
+	require.Len(t, read, 2)
+	assert.Equal(t, 3, read[0].Attempts)
+	assert.Equal(t, "groq rate limit exceeded", read[0].LastRetryError)
+	assert.Equal(t, 1, read[1].Attempts)
+	assert.Empty(t, read[1].LastRetryError)
+}
 
-	lines := string(content)
-	// Should contain a timestamp in ISO format
-	assert.Contains(t, lines, "T") // ISO format contains 'T'
-	assert.Contains(t, lines, "Z") // ISO format ends with 'Z' for UTC
+// cancellingProvider answers its first two requests, then cancels the run from
+// inside the next one, as a crash partway through would stop it
+type cancellingProvider struct {
+	calls  int
+	cancel context.CancelFunc
 }
 
-func TestCSVWriter_DirectoryCreation(t *testing.T) {
-	// Test creating CSV in a directory that doesn't exist
-	tempDir := "test_csv_dir"
-	tempFile := filepath.Join(tempDir, "test.csv")
-	defer os.RemoveAll(tempDir)
+func (p *cancellingProvider) Name() string { return "openai" }
 
-	writer, err := NewCSVWriter(tempFile)
-	require.NoError(t, err)
-	defer writer.Close()
+func (p *cancellingProvider) StreamChat(ctx context.Context, req providers.ChatRequest) (<-chan providers.ChatResponse, error) {
+	p.calls++
+	if p.calls > 2 {
+		p.cancel()
+		return nil, ctx.Err()
+	}
+	ch := make(chan providers.ChatResponse, 2)
+	ch <- providers.ChatResponse{Content: "Hello!", Timestamp: time.Now()}
+	ch <- providers.ChatResponse{IsComplete: true, Timestamp: time.Now(), FinishReason: "stop"}
+	close(ch)
+	return ch, nil
+}
+
+func (p *cancellingProvider) TokenCount(response providers.ChatResponse) (input, output, total int) {
+	return 0, 2, 2
+}
+
+func (p *cancellingProvider) GetTokenCount(text string) int { return 2 }
+
+func TestCSVStream_KeepsCompletedRowsWhenRunStops(t *testing.T) {
+	promptsDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(promptsDir, "greeting.yaml"), []byte("user: \"Say hello\"\n"), 0644))
+	cfg := &config.Config{
+		Models: &config.ModelsConfig{OpenAI: map[string]config.ModelSpec{
+			"mock-model": {TokenPrice: config.ModelPricing{Input: 1, Output: 1}},
+		}},
+		Concurrent:     1,
+		Runs:           5,
+		PromptsDir:     promptsDir,
+		RequestTimeout: 5 * time.Second,
+	}
 
-	// Write header
-	err = writer.WriteHeader()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runner := benchmark.NewRunner(cfg, map[string]providers.Provider{"openai": &cancellingProvider{cancel: cancel}}, false)
+	runner.SetLogOutput(io.Discard)
+
+	path := filepath.Join(t.TempDir(), "partial.csv")
+	stream, err := NewCSVWriter(path).Open()
 	require.NoError(t, err)
+	runner.SetResultSink(stream)
 
-	// Check that directory was created
-	_, err = os.Stat(tempDir)
-	assert.NoError(t, err)
+	_, _, err = runner.Execute(ctx)
+	require.ErrorIs(t, err, context.Canceled)
 
-	// Check that file was created
-	_, err = os.Stat(tempFile)
-	assert.NoError(t, err)
-} 
\ No newline at end of file
+	// The completed rows are already on disk before the stream is closed
+	read, err := ReadResults(path)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(read), 2)
+	for _, result := range read[:2] {
+		assert.Equal(t, "mock-model", result.Model)
+		assert.True(t, result.Success)
+	}
+
+	require.NoError(t, stream.Close())
+}
@@ -0,0 +1,179 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/megzo/llm-latency-benchmark/internal/benchmark"
+)
+
+// SummaryReport is the machine-readable run summary written by WriteSummaryJSON
+type SummaryReport struct {
+	Metadata benchmark.RunMetadata  `json:"metadata"`
+	Summary  SummaryJSON            `json:"summary"`
+	Models   map[string]SummaryJSON `json:"models"` // Keyed by provider/model
+}
+
+// SummaryJSON is a benchmark.Summary with every duration in milliseconds
+type SummaryJSON struct {
+	TotalRuns      int `json:"total_runs"`
+	SuccessfulRuns int `json:"successful_runs"`
+	FailedRuns     int `json:"failed_runs"`
+	TruncatedRuns  int `json:"truncated_runs"`
+	EmptyRuns      int `json:"empty_runs"`
+
+	AvgTTFTMS      float64 `json:"avg_ttft_ms"`
+	AvgTotalTimeMS float64 `json:"avg_total_time_ms"`
+	MinTTFTMS      float64 `json:"min_ttft_ms"`
+	MaxTTFTMS      float64 `json:"max_ttft_ms"`
+	P50TTFTMS      float64 `json:"p50_ttft_ms"`
+	P95TTFTMS      float64 `json:"p95_ttft_ms"`
+	P99TTFTMS      float64 `json:"p99_ttft_ms"`
+	StdDevTTFTMS   float64 `json:"stddev_ttft_ms"`
+	CoeffVarTTFT   float64 `json:"coeff_var_ttft"`
+	MinTotalTimeMS float64 `json:"min_total_time_ms"`
+	MaxTotalTimeMS float64 `json:"max_total_time_ms"`
+	P50TotalTimeMS float64 `json:"p50_total_time_ms"`
+	P95TotalTimeMS float64 `json:"p95_total_time_ms"`
+	P99TotalTimeMS float64 `json:"p99_total_time_ms"`
+
+	// TTFT and total time at each -percentiles percentile, keyed by label (p90, p99.9)
+	TTFTPercentilesMS      map[string]float64 `json:"ttft_percentiles_ms,omitempty"`
+	TotalTimePercentilesMS map[string]float64 `json:"total_time_percentiles_ms,omitempty"`
+
+	TTFTHistogram []HistogramBucketJSON `json:"ttft_histogram,omitempty"`
+
+	AvgTokensPerSecond float64 `json:"avg_tokens_per_second"`
+	TotalInputTokens   int     `json:"total_input_tokens"`
+	TotalOutputTokens  int     `json:"total_output_tokens"`
+
+	WallTimeMS          float64 `json:"wall_time_ms"`
+	AggregateThroughput float64 `json:"aggregate_throughput"`
+
+	TotalCost                float64 `json:"total_cost"`
+	AvgCostPerRun            float64 `json:"avg_cost_per_run"`
+	MinCostPerRun            float64 `json:"min_cost_per_run"`
+	MaxCostPerRun            float64 `json:"max_cost_per_run"`
+	EffectiveCostPer1MTokens float64 `json:"effective_cost_per_1m_tokens"`
+	ErrorRate                float64 `json:"error_rate"`
+	SuccessRate              float64 `json:"success_rate"`
+	TopErrorCategory         string  `json:"top_error_category,omitempty"`
+
+	HaltReason string `json:"halt_reason,omitempty"`
+
+	// Fingerprints maps each model that reported more than one system fingerprint to them
+	Fingerprints map[string][]string `json:"fingerprints,omitempty"`
+}
+
+// HistogramBucketJSON is a benchmark.HistogramBucket with bounds in milliseconds
+type HistogramBucketJSON struct {
+	MinMS float64 `json:"min_ms"`
+	MaxMS float64 `json:"max_ms"` // 0 for the open-ended last bucket
+	Label string  `json:"label"`
+	Count int     `json:"count"`
+}
+
+// NewSummaryJSON converts a summary to its JSON form
+func NewSummaryJSON(s benchmark.Summary) SummaryJSON {
+	out := SummaryJSON{
+		TotalRuns:                s.TotalRuns,
+		SuccessfulRuns:           s.SuccessfulRuns,
+		FailedRuns:               s.FailedRuns,
+		TruncatedRuns:            s.TruncatedRuns,
+		EmptyRuns:                s.EmptyRuns,
+		AvgTTFTMS:                millis(s.AvgTTFT),
+		AvgTotalTimeMS:           millis(s.AvgTotalTime),
+		MinTTFTMS:                millis(s.MinTTFT),
+		MaxTTFTMS:                millis(s.MaxTTFT),
+		P50TTFTMS:                millis(s.P50TTFT),
+		P95TTFTMS:                millis(s.P95TTFT),
+		P99TTFTMS:                millis(s.P99TTFT),
+		StdDevTTFTMS:             millis(s.StdDevTTFT),
+		CoeffVarTTFT:             s.CoeffVarTTFT,
+		MinTotalTimeMS:           millis(s.MinTotalTime),
+		MaxTotalTimeMS:           millis(s.MaxTotalTime),
+		P50TotalTimeMS:           millis(s.P50TotalTime),
+		P95TotalTimeMS:           millis(s.P95TotalTime),
+		P99TotalTimeMS:           millis(s.P99TotalTime),
+		AvgTokensPerSecond:       s.AvgTokensPerSecond,
+		TotalInputTokens:         s.TotalInputTokens,
+		TotalOutputTokens:        s.TotalOutputTokens,
+		WallTimeMS:               millis(s.WallTime),
+		AggregateThroughput:      s.AggregateThroughput,
+		TotalCost:                s.TotalCost,
+		AvgCostPerRun:            s.AvgCostPerRun,
+		MinCostPerRun:            s.MinCostPerRun,
+		MaxCostPerRun:            s.MaxCostPerRun,
+		EffectiveCostPer1MTokens: s.EffectiveCostPer1MTokens,
+		ErrorRate:                s.ErrorRate,
+		SuccessRate:              s.SuccessRate,
+		TopErrorCategory:         s.TopErrorCategory,
+		HaltReason:               s.HaltReason,
+		Fingerprints:             s.Fingerprints,
+	}
+	out.TTFTPercentilesMS = percentilesMillis(s.TTFTPercentiles)
+	out.TotalTimePercentilesMS = percentilesMillis(s.TotalTimePercentiles)
+	for _, bucket := range s.TTFTHistogram {
+		out.TTFTHistogram = append(out.TTFTHistogram, HistogramBucketJSON{
+			MinMS: millis(bucket.Min),
+			MaxMS: millis(bucket.Max),
+			Label: bucket.Label,
+			Count: bucket.Count,
+		})
+	}
+	return out
+}
+
+// percentilesMillis keys percentile durations by label, in milliseconds
+func percentilesMillis(values map[float64]time.Duration) map[string]float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	out := make(map[string]float64, len(values))
+	for percentile, d := range values {
+		out[benchmark.PercentileLabel(percentile)] = millis(d)
+	}
+	return out
+}
+
+// millis converts a duration to fractional milliseconds
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000.0
+}
+
+// WriteSummaryJSON writes the run summary, a summary per provider/model and the
+// run metadata to path as indented JSON, gzip-compressed when path ends in .gz
+func WriteSummaryJSON(path string, metadata benchmark.RunMetadata, summary benchmark.Summary, byModel map[string]benchmark.Summary) error {
+	report := SummaryReport{
+		Metadata: metadata,
+		Summary:  NewSummaryJSON(summary),
+		Models:   make(map[string]SummaryJSON, len(byModel)),
+	}
+	for key, s := range byModel {
+		report.Models[key] = NewSummaryJSON(s)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	file, err := createOutput(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("failed to write summary JSON: %w", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write summary JSON: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write summary JSON: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package output
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/megzo/llm-latency-benchmark/internal/benchmark"
+)
+
+func TestWriteSummaryJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "summary.json")
+	summary := benchmark.Summary{
+		TotalRuns:         4,
+		SuccessfulRuns:    3,
+		FailedRuns:        1,
+		AvgTTFT:           250 * time.Millisecond,
+		P95TTFT:           1500 * time.Microsecond,
+		P95TotalTime:      2 * time.Second,
+		TotalOutputTokens: 300,
+		TotalCost:         0.0125,
+		ErrorRate:         0.25,
+		TTFTHistogram: []benchmark.HistogramBucket{
+			{Min: 0, Max: 100 * time.Millisecond, Label: "<100ms", Count: 3},
+		},
+		TTFTPercentiles: map[float64]time.Duration{90: 200 * time.Millisecond, 99.9: 1500 * time.Microsecond},
+	}
+	byModel := map[string]benchmark.Summary{
+		"groq/llama-3.1-8b-instant": {TotalRuns: 2, SuccessfulRuns: 2, AvgTTFT: 120 * time.Millisecond},
+		"openai/gpt-4o-mini":        {TotalRuns: 2, SuccessfulRuns: 1, FailedRuns: 1, ErrorRate: 0.5},
+	}
+	metadata := benchmark.RunMetadata{Hostname: "bench-01", ToolVersion: "1.0.0", Concurrency: 2}
+
+	require.NoError(t, WriteSummaryJSON(path, metadata, summary, byModel))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var report SummaryReport
+	require.NoError(t, json.Unmarshal(data, &report))
+
+	assert.Equal(t, "bench-01", report.Metadata.Hostname)
+	assert.Equal(t, 2, report.Metadata.Concurrency)
+	assert.Equal(t, 4, report.Summary.TotalRuns)
+	assert.Equal(t, 1, report.Summary.FailedRuns)
+	assert.Equal(t, 250.0, report.Summary.AvgTTFTMS)
+	assert.Equal(t, 1.5, report.Summary.P95TTFTMS)
+	assert.Equal(t, 2000.0, report.Summary.P95TotalTimeMS)
+	assert.Equal(t, 300, report.Summary.TotalOutputTokens)
+	assert.Equal(t, 0.0125, report.Summary.TotalCost)
+	assert.Equal(t, 0.25, report.Summary.ErrorRate)
+	require.Len(t, report.Summary.TTFTHistogram, 1)
+	assert.Equal(t, 100.0, report.Summary.TTFTHistogram[0].MaxMS)
+	assert.Equal(t, map[string]float64{"p90": 200, "p99.9": 1.5}, report.Summary.TTFTPercentilesMS)
+	assert.Nil(t, report.Summary.TotalTimePercentilesMS)
+
+	require.Len(t, report.Models, 2)
+	assert.Equal(t, 120.0, report.Models["groq/llama-3.1-8b-instant"].AvgTTFTMS)
+	assert.Equal(t, 0.5, report.Models["openai/gpt-4o-mini"].ErrorRate)
+
+	// Durations are plain millisecond numbers in the file
+	var raw map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &raw))
+	assert.Equal(t, 250.0, raw["summary"]["avg_ttft_ms"])
+}
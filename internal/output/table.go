@@ -0,0 +1,56 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/megzo/llm-latency-benchmark/internal/benchmark"
+	"github.com/megzo/llm-latency-benchmark/providers"
+)
+
+// WriteTable writes one aligned row per result to w, for reading results in the
+// terminal instead of a CSV file; color highlights each row's status
+func WriteTable(w io.Writer, results []benchmark.BenchmarkResult, color bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROVIDER\tMODEL\tPROMPT\tTTFT\tTOTAL\tTOK/S\tCOST\tSTATUS")
+	for _, result := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%v\t%v\t%.2f\t$%.6f\t%s\n",
+			result.Provider, result.Model, result.PromptFile,
+			result.TTFT.Round(time.Millisecond), result.TotalTime.Round(time.Millisecond),
+			result.TokensPerSecond, result.Cost, colorize(resultStatus(result), statusColor(result), color))
+	}
+	return tw.Flush()
+}
+
+// statusColor is green for a complete answer, yellow for a truncated or empty
+// one and red for a failure
+func statusColor(result benchmark.BenchmarkResult) string {
+	switch {
+	case !result.Success:
+		return ansiRed
+	case result.Truncated, result.EmptyResponse:
+		return ansiYellow
+	default:
+		return ansiGreen
+	}
+}
+
+// resultStatus summarizes whether a run succeeded, was cut off at max_tokens,
+// came back empty or failed, and why
+func resultStatus(result benchmark.BenchmarkResult) string {
+	switch {
+	case !result.Success:
+		if category := providers.ErrorCategory(result.Error); category != "" {
+			return "error (" + category + ")"
+		}
+		return "error"
+	case result.Truncated:
+		return "truncated"
+	case result.EmptyResponse:
+		return "empty"
+	default:
+		return "ok"
+	}
+}
@@ -0,0 +1,47 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/megzo/llm-latency-benchmark/internal/benchmark"
+	"github.com/megzo/llm-latency-benchmark/providers"
+)
+
+func TestWriteTable(t *testing.T) {
+	results := []benchmark.BenchmarkResult{
+		{
+			Provider:        "openai",
+			Model:           "gpt-4o-mini",
+			PromptFile:      "simple_chat",
+			TTFT:            250400 * time.Microsecond,
+			TotalTime:       1200 * time.Millisecond,
+			TokensPerSecond: 42.5,
+			Cost:            0.000125,
+			Success:         true,
+		},
+		{
+			Provider:   "groq",
+			Model:      "llama-3.1-8b-instant",
+			PromptFile: "code_review",
+			Error:      &providers.RateLimitError{Provider: "groq"},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteTable(&buf, results, false))
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, []string{"PROVIDER", "MODEL", "PROMPT", "TTFT", "TOTAL", "TOK/S", "COST", "STATUS"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"openai", "gpt-4o-mini", "simple_chat", "250ms", "1.2s", "42.50", "$0.000125", "ok"}, strings.Fields(lines[1]))
+	assert.True(t, strings.HasSuffix(lines[2], "error (rate_limit)"), lines[2])
+
+	// Columns line up
+	assert.Equal(t, strings.Index(lines[0], "TTFT"), strings.Index(lines[1], "250ms"))
+}
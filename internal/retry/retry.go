@@ -0,0 +1,86 @@
+// Package retry computes the backoff between attempts of a failed request.
+package retry
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Strategy selects how the delay grows between attempts
+type Strategy string
+
+// Strategies accepted by Parse
+const (
+	// Fixed waits BaseDelay before every attempt
+	Fixed Strategy = "fixed"
+	// Exponential doubles the delay each attempt, starting from BaseDelay
+	Exponential Strategy = "exponential"
+	// ExponentialFullJitter waits a random delay between 0 and the exponential
+	// delay, so clients that failed together don't retry together
+	ExponentialFullJitter Strategy = "exponential-full-jitter"
+)
+
+// Default is the strategy used when none is configured
+const Default = Exponential
+
+// BaseDelay is the delay before the first retry
+const BaseDelay = time.Second
+
+// MaxDelay caps the delay of every strategy unless a lower cap is configured
+const MaxDelay = 30 * time.Second
+
+// Parse returns the strategy with the given name; "" selects Default
+func Parse(name string) (Strategy, error) {
+	switch Strategy(name) {
+	case "":
+		return Default, nil
+	case Fixed, Exponential, ExponentialFullJitter:
+		return Strategy(name), nil
+	default:
+		return "", fmt.Errorf("unknown retry strategy %q (expected %q, %q or %q)", name, Fixed, Exponential, ExponentialFullJitter)
+	}
+}
+
+// Delay returns how long to wait before retry number attempt (1 for the first
+// retry), capped at MaxDelay. Unknown strategies behave like Default.
+func Delay(strategy Strategy, attempt int) time.Duration {
+	return DelayUpTo(strategy, attempt, MaxDelay)
+}
+
+// DelayUpTo is Delay capped at maxDelay instead of MaxDelay; maxDelay <= 0
+// uses MaxDelay
+func DelayUpTo(strategy Strategy, attempt int, maxDelay time.Duration) time.Duration {
+	if maxDelay <= 0 {
+		maxDelay = MaxDelay
+	}
+	switch strategy {
+	case Fixed:
+		return min(BaseDelay, maxDelay)
+	case ExponentialFullJitter:
+		return rand.N(CeilingUpTo(attempt, maxDelay) + 1)
+	default:
+		return CeilingUpTo(attempt, maxDelay)
+	}
+}
+
+// Ceiling is the exponential delay for attempt, BaseDelay doubled per attempt
+// and capped at MaxDelay; ExponentialFullJitter delays fall in [0, Ceiling]
+func Ceiling(attempt int) time.Duration {
+	return CeilingUpTo(attempt, MaxDelay)
+}
+
+// CeilingUpTo is Ceiling capped at maxDelay instead of MaxDelay
+func CeilingUpTo(attempt int, maxDelay time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := min(BaseDelay, maxDelay)
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return delay
+}
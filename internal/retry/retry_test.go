@@ -0,0 +1,89 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	strategy, err := Parse("")
+	require.NoError(t, err)
+	assert.Equal(t, Default, strategy)
+
+	for _, name := range []string{"fixed", "exponential", "exponential-full-jitter"} {
+		strategy, err := Parse(name)
+		require.NoError(t, err)
+		assert.Equal(t, Strategy(name), strategy)
+	}
+
+	_, err = Parse("linear")
+	assert.ErrorContains(t, err, "unknown retry strategy")
+}
+
+func TestDelay_Fixed(t *testing.T) {
+	for attempt := 1; attempt <= 10; attempt++ {
+		assert.Equal(t, BaseDelay, Delay(Fixed, attempt))
+	}
+}
+
+func TestDelay_Exponential(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, time.Second},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{5, 16 * time.Second},
+		{6, 30 * time.Second},
+		{100, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Delay(Exponential, tt.attempt), "attempt %d", tt.attempt)
+	}
+	assert.Equal(t, Delay(Exponential, 3), Delay("", 3), "unset strategy uses the default")
+}
+
+func TestDelay_ExponentialFullJitter(t *testing.T) {
+	for attempt := 1; attempt <= 8; attempt++ {
+		ceiling := Ceiling(attempt)
+		var sawBelowCeiling bool
+		for i := 0; i < 200; i++ {
+			delay := Delay(ExponentialFullJitter, attempt)
+			require.GreaterOrEqual(t, delay, time.Duration(0), "attempt %d", attempt)
+			require.LessOrEqual(t, delay, ceiling, "attempt %d", attempt)
+			require.LessOrEqual(t, delay, MaxDelay, "attempt %d", attempt)
+			if delay < ceiling/2 {
+				sawBelowCeiling = true
+			}
+		}
+		assert.True(t, sawBelowCeiling, "attempt %d delays should spread over [0, %v]", attempt, ceiling)
+	}
+}
+
+func TestDelayUpTo_NeverExceedsCap(t *testing.T) {
+	for _, maxDelay := range []time.Duration{500 * time.Millisecond, 5 * time.Second, time.Minute} {
+		for _, strategy := range []Strategy{Fixed, Exponential, ExponentialFullJitter} {
+			for attempt := 1; attempt <= 50; attempt++ {
+				delay := DelayUpTo(strategy, attempt, maxDelay)
+				require.GreaterOrEqual(t, delay, time.Duration(0), "%s attempt %d", strategy, attempt)
+				require.LessOrEqual(t, delay, maxDelay, "%s attempt %d with cap %v", strategy, attempt, maxDelay)
+			}
+		}
+	}
+
+	// The cap applies from the first retry, and growth continues past 30s up to a higher one
+	assert.Equal(t, 500*time.Millisecond, DelayUpTo(Exponential, 1, 500*time.Millisecond))
+	assert.Equal(t, 500*time.Millisecond, DelayUpTo(Fixed, 1, 500*time.Millisecond))
+	assert.Equal(t, 5*time.Second, DelayUpTo(Exponential, 10, 5*time.Second))
+	assert.Equal(t, time.Minute, DelayUpTo(Exponential, 10, time.Minute))
+
+	// An unset cap keeps the default
+	assert.Equal(t, Delay(Exponential, 10), DelayUpTo(Exponential, 10, 0))
+	assert.Equal(t, MaxDelay, DelayUpTo(Exponential, 10, 0))
+}
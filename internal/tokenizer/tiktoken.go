@@ -0,0 +1,82 @@
+package tokenizer
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// fallbackEncoding is used for models tiktoken doesn't know, such as non-OpenAI
+// models, where it is still a better estimate than the heuristic
+const fallbackEncoding = "o200k_base"
+
+// ReferenceEncoding is the encoding NewReference counts every model's text with
+const ReferenceEncoding = "o200k_base"
+
+// Tiktoken counts tokens with OpenAI's BPE encodings. Encoding data is downloaded
+// on first use (cached in TIKTOKEN_CACHE_DIR when set); if an encoding can't be
+// loaded, counts fall back to the heuristic.
+type Tiktoken struct {
+	mu        sync.Mutex
+	encodings map[string]*tiktoken.Tiktoken // by encoding name; nil records a failed load
+	fallback  Tokenizer
+	fixed     string // encoding used for every model, ignoring the model's own (empty = per model)
+}
+
+// NewTiktoken creates a tiktoken-backed tokenizer
+func NewTiktoken() *Tiktoken {
+	return &Tiktoken{
+		encodings: make(map[string]*tiktoken.Tiktoken),
+		fallback:  Heuristic{},
+	}
+}
+
+// NewReference creates a tokenizer that counts every model's text with
+// ReferenceEncoding, so counts are comparable across providers
+func NewReference() *Tiktoken {
+	t := NewTiktoken()
+	t.fixed = ReferenceEncoding
+	return t
+}
+
+// Count returns the number of tokens in text under the model's encoding
+func (t *Tiktoken) Count(model, text string) int {
+	if text == "" {
+		return 0
+	}
+	encoding := t.encoding(model)
+	if encoding == nil {
+		return t.fallback.Count(model, text)
+	}
+	return len(encoding.EncodeOrdinary(text))
+}
+
+// encoding returns the encoder for model, loading it once; nil if it can't be loaded
+func (t *Tiktoken) encoding(model string) *tiktoken.Tiktoken {
+	name := fallbackEncoding
+	if t.fixed != "" {
+		name = t.fixed
+	} else if known, ok := tiktoken.MODEL_TO_ENCODING[model]; ok {
+		name = known
+	} else {
+		for prefix, prefixEncoding := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+			if strings.HasPrefix(model, prefix) {
+				name = prefixEncoding
+				break
+			}
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if encoding, ok := t.encodings[name]; ok {
+		return encoding
+	}
+	encoding, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		encoding = nil
+	}
+	t.encodings[name] = encoding
+	return encoding
+}
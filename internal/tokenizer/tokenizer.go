@@ -0,0 +1,57 @@
+// Package tokenizer estimates token counts for prompts and responses.
+package tokenizer
+
+import (
+	"fmt"
+)
+
+// Tokenizer names accepted by New
+const (
+	NameHeuristic = "heuristic"
+	NameTiktoken  = "tiktoken"
+)
+
+// Tokenizer counts the tokens text occupies for a model. Implementations must be
+// safe for concurrent use.
+type Tokenizer interface {
+	Count(model, text string) int
+}
+
+// Heuristic estimates ~4 characters per token, which is close for English text
+// and needs no model-specific data
+type Heuristic struct{}
+
+// Count returns len(text)/4, at least 1 for non-empty text
+func (Heuristic) Count(model, text string) int {
+	if text == "" {
+		return 0
+	}
+	count := len(text) / 4
+	if count < 1 {
+		count = 1
+	}
+	return count
+}
+
+// Default is the tokenizer used when none is configured
+var Default Tokenizer = Heuristic{}
+
+// OrDefault returns t, or Default when t is nil
+func OrDefault(t Tokenizer) Tokenizer {
+	if t == nil {
+		return Default
+	}
+	return t
+}
+
+// New returns the tokenizer with the given name; "" selects the heuristic
+func New(name string) (Tokenizer, error) {
+	switch name {
+	case "", NameHeuristic:
+		return Heuristic{}, nil
+	case NameTiktoken:
+		return NewTiktoken(), nil
+	default:
+		return nil, fmt.Errorf("unknown tokenizer %q (expected %q or %q)", name, NameHeuristic, NameTiktoken)
+	}
+}
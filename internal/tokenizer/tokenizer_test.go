@@ -0,0 +1,91 @@
+package tokenizer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pkoukk/tiktoken-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeuristic_Count(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"Hi", 1},
+		{"Hello", 1},
+		{"This is a longer text with more tokens to count", 11},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Heuristic{}.Count("gpt-4.1", tt.text), "Count(%q)", tt.text)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, name := range []string{"", NameHeuristic} {
+		tok, err := New(name)
+		require.NoError(t, err)
+		assert.IsType(t, Heuristic{}, tok)
+	}
+
+	tok, err := New(NameTiktoken)
+	require.NoError(t, err)
+	assert.IsType(t, &Tiktoken{}, tok)
+
+	_, err = New("sentencepiece")
+	assert.Error(t, err)
+}
+
+func TestOrDefault(t *testing.T) {
+	assert.Equal(t, Default, OrDefault(nil))
+
+	custom := NewTiktoken()
+	assert.Same(t, custom, OrDefault(custom))
+}
+
+// byteLoader serves a byte-level vocabulary for o200k_base, so every byte is one
+// token, and fails for every other encoding
+type byteLoader struct{}
+
+func (byteLoader) LoadTiktokenBpe(file string) (map[string]int, error) {
+	if !strings.Contains(file, "o200k_base") {
+		return nil, errors.New("offline")
+	}
+	ranks := make(map[string]int, 256)
+	for b := 0; b < 256; b++ {
+		ranks[string([]byte{byte(b)})] = b
+	}
+	return ranks, nil
+}
+
+func TestTiktoken_Count(t *testing.T) {
+	tiktoken.SetBpeLoader(byteLoader{})
+	defer tiktoken.SetBpeLoader(tiktoken.NewDefaultBpeLoader())
+
+	tok := NewTiktoken()
+
+	// gpt-4o uses o200k_base, as do models tiktoken doesn't know
+	assert.Equal(t, 11, tok.Count("gpt-4o", "hello world"))
+	assert.Equal(t, 11, tok.Count("claude-sonnet-4", "hello world"))
+	assert.Equal(t, 0, tok.Count("gpt-4o", ""))
+
+	// gpt-4 uses cl100k_base, which fails to load, so counts fall back to the heuristic
+	assert.Equal(t, Heuristic{}.Count("gpt-4", "hello world"), tok.Count("gpt-4", "hello world"))
+}
+
+func TestNewReference(t *testing.T) {
+	tiktoken.SetBpeLoader(byteLoader{})
+	defer tiktoken.SetBpeLoader(tiktoken.NewDefaultBpeLoader())
+
+	// Every model is counted with o200k_base, including gpt-4, whose own
+	// cl100k_base encoding fails to load here
+	tok := NewReference()
+	for _, model := range []string{"gpt-4", "gpt-4o", "claude-sonnet-4", ""} {
+		assert.Equal(t, 11, tok.Count(model, "hello world"), "Count(%q)", model)
+	}
+}
@@ -4,41 +4,167 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"math/rand/v2"
 	"os"
 	"os/signal"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/megzo/llm-latency-benchmark/internal/benchmark"
 	"github.com/megzo/llm-latency-benchmark/internal/config"
+	"github.com/megzo/llm-latency-benchmark/internal/notify"
 	"github.com/megzo/llm-latency-benchmark/internal/output"
+	"github.com/megzo/llm-latency-benchmark/internal/retry"
 	"github.com/megzo/llm-latency-benchmark/providers"
 )
 
-const version = "0.1.0"
+// Build information, set at release time with
+//
+//	go build -ldflags "-X main.version=0.2.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// and otherwise filled in from what the go tool embeds in the binary
+var (
+	version   = ""
+	commit    = ""
+	buildDate = ""
+)
+
+// defaultVersion is reported when neither -ldflags nor the module set a version
+const defaultVersion = "0.1.0"
+
+// pseudoVersion matches the module versions go build stamps on untagged commits,
+// e.g. v0.0.0-20250601120000-1a2b3c4d5e6f+dirty, which aren't releases
+var pseudoVersion = regexp.MustCompile(`\d{14}-[0-9a-f]{12}(\+dirty)?$`)
+
+// exitRegression is the process exit code when a baseline comparison finds regressions
+const exitRegression = 2
+
+// exitThresholdBreached is the process exit code when -max-p95-ttft or -max-error-rate is exceeded
+const exitThresholdBreached = 3
+
+// exitInterrupted is the process exit code after SIGINT/SIGTERM, once partial results are written
+const exitInterrupted = 130
 
 func main() {
 	// Parse command line flags
 	var (
 		concurrent = flag.Int("concurrent", 1, "Number of concurrent requests")
+		providerConcurrency = flag.String("provider-concurrency", "", "Per-provider worker pool sizes overriding -concurrent, e.g. groq=2,openai=8")
+		concurrencySweep = flag.String("concurrency-sweep", "", "Comma-separated concurrency levels to run everything at in turn, e.g. 1,2,4,8")
+		adaptiveConcurrency = flag.Bool("adaptive-concurrency", false, "Start each provider at 1 concurrent request, growing to -concurrent and halving on rate limits")
 		runs       = flag.Int("runs", 1, "Number of runs per model per prompt")
-		promptsDir = flag.String("prompts", "prompts", "Directory containing prompt files")
-		outputFile = flag.String("output", "", "Output CSV file (default: results/benchmark_TIMESTAMP.csv)")
+		mode       = flag.String("mode", config.ModeChat, "What to benchmark: chat or embeddings (models under embeddings: in the models file)")
+		promptsDir = flag.String("prompts", "prompts", "Directory containing prompt files, or a YAML file of prompts separated by ---")
+		recursivePrompts = flag.Bool("recursive-prompts", false, "Also load prompt files from subdirectories of -prompts")
+		limitPrompts = flag.Int("limit-prompts", 0, "Run only the first N prompts (0 = all)")
+		shuffle = flag.Bool("shuffle", false, "Run prompts in a random order, shuffled before -limit-prompts picks the first N")
+		shuffleSeed = flag.Uint64("shuffle-seed", 0, "Seed for -shuffle, to repeat a shuffled order (0 = random)")
+		outputFile = flag.String("output", "", "Output CSV file, gzip-compressed when it ends in .gz (default: results/benchmark_TIMESTAMP.csv)")
+		outputFormat = flag.String("format", "csv", "Results format: csv, or table to print them aligned in the terminal (writing a CSV only with -output)")
+		incremental = flag.Bool("incremental", false, "Write each result to the -output CSV as soon as it completes so a crash keeps the finished rows")
+		appendOutput = flag.Bool("append", false, "Append results to an existing -output file instead of replacing it")
 		modelsFile = flag.String("models", "models.yaml", "Models configuration file (default: models.yaml)")
+		runConfigFile = flag.String("config", "", "Run configuration YAML file; explicitly passed flags override it")
+		secretsFile = flag.String("secrets", "", "YAML file mapping provider names to API keys, for keys not set in the environment")
+		providerList = flag.String("providers", "", "Comma-separated providers to benchmark (default: all with credentials)")
+		includeModels = flag.String("include-models", "", "Comma-separated regular expressions; only models whose name or provider/model matches one run")
+		excludeModels = flag.String("exclude-models", "", "Comma-separated regular expressions; models whose name or provider/model matches one are skipped")
+		warmup     = flag.Int("warmup", 0, "Unrecorded warm-up requests per model before measuring")
+		cooldown   = flag.Duration("cooldown", 0, "Pause after each request, per worker when concurrent, e.g. 500ms (0 = none)")
+		proxy      = flag.String("proxy", "", "Send every provider's requests through this proxy URL, overriding HTTP_PROXY/HTTPS_PROXY")
+		interleave = flag.Bool("interleave", false, "Rotate requests across models (A,B,C,A,B,C,...) instead of running each model's requests together")
 		verbose    = flag.Bool("verbose", false, "Enable verbose logging")
+		logFormat  = flag.String("log-format", "text", "Log record format on stderr: text or json")
+		colorMode  = flag.String("color", output.ColorAuto, "Colorize terminal output: auto, always or never")
+		logLevel   = flag.String("log-level", "info", "Minimum level of logged records: debug, info, warn or error")
+		noStream   = flag.Bool("no-stream", false, "Request non-streamed completions (TTFT equals total time)")
+		temperature = flag.Float64("temperature", 0, "Temperature for every request, overriding the sweep and model parameters (default: 0.7 unless configured)")
+		maxTokens  = flag.Int("max-tokens", 0, "max_tokens for every request, overriding the sweep and model parameters (default: 1000 unless configured)")
+		topP       = flag.Float64("top-p", 0, "top_p for every request, overriding model parameters (default: 1 unless configured)")
+		normalizeTokens = flag.Bool("normalize-tokens", false, "Recount every response's output tokens with one reference tokenizer (o200k_base) and compute tokens/sec from it")
+		visibleTPS = flag.Bool("visible-tps", false, "Compute tokens/sec over visible output only, excluding reasoning tokens")
+		measure    = flag.String("measure", config.MeasureCompletion, "Where total time stops: completion (stream close) or last-token")
+		tokenizerName = flag.String("tokenizer", "heuristic", "Token estimator: heuristic (~4 chars/token) or tiktoken")
+		retries    = flag.Int("retries", 3, "Extra attempts after a transient (rate limit, server, timeout) error")
+		retryStrategy = flag.String("retry-strategy", "exponential", "Backoff between retries: exponential, fixed or exponential-full-jitter")
+		retryMaxDelay = flag.Duration("retry-max-delay", retry.MaxDelay, "Cap on the backoff between retries")
+		timeout    = flag.Duration("timeout", 5*time.Minute, "Deadline for one benchmark including all retries (0 = none)")
+		providerTimeout = flag.String("provider-timeout", "", "Per-provider request timeouts overriding the default 60s, e.g. openai=120s,groq=30s")
+		traceDir   = flag.String("trace-dir", "", "Write each run's content chunk arrival times to this directory as NDJSON")
+		requestIDs = flag.Bool("request-ids", false, "Send a unique X-Request-Id with every run and record it in the results")
+		summaryJSON = flag.String("summary-json", "", "Also write the run summary, per-model summaries and run metadata to this JSON file")
+		printSummaryLine = flag.Bool("print-summary-line", false, "End stdout with one key=value line summarizing the run, for scripts")
+		maxCost    = flag.Float64("max-cost", 0, "Halt the run once accumulated cost exceeds this many dollars (0 = unlimited)")
+		maxDuration = flag.Duration("max-duration", 0, "Halt the run after this much wall-clock time, e.g. 10m, cancelling in-flight requests (0 = unlimited)")
+		maxP95TTFT = flag.Duration("max-p95-ttft", 0, "Exit with status 3 if p95 TTFT exceeds this (0 = unchecked)")
+		maxErrorRate = flag.Float64("max-error-rate", 0, "Exit with status 3 if the error rate exceeds this percentage (0 = unchecked)")
+		perModelThresholds = flag.Bool("per-model-thresholds", false, "Check -max-p95-ttft and -max-error-rate for every model instead of the whole run")
+		histogramBuckets = flag.String("histogram-buckets", "", "Comma-separated TTFT histogram boundaries, e.g. 100ms,250ms,500ms,1s (default: those)")
+		percentiles      = flag.String("percentiles", "", "Comma-separated TTFT and total time percentiles for the summary, e.g. 50,90,95,99,99.9 (default: 95,99)")
+		compareFile      = flag.String("compare", "", "Baseline CSV file to compare results against")
+		compareThreshold = flag.Float64("compare-threshold", 10, "Percent change that counts as a regression in -compare mode")
+		currentFile      = flag.String("current", "", "Compare this CSV file against -compare instead of running a benchmark")
+		notifyWebhook    = flag.String("notify-webhook", "", "Slack/Discord incoming-webhook URL to post the run summary to (default: NOTIFY_WEBHOOK_URL)")
+		listWork   = flag.Bool("list", false, "Print the provider/model/prompt combinations that would run, then exit")
+		strict     = flag.Bool("strict", false, "Fail on configuration problems that are otherwise warnings, such as models without pricing")
+		dryRun     = flag.Bool("dry-run", false, "Validate prompts, models, pricing and keys and print the plan without calling any API")
+		preflight  preflightMode
 		showHelp   = flag.Bool("help", false, "Show help message")
 		showVersion = flag.Bool("version", false, "Show version information")
 	)
+	flag.Var(&preflight, "preflight", "Ping every provider with a one-token request before running and abort if any fails; -preflight=warn only warns")
 	flag.Parse()
 
 	// Handle help and version flags
+	build := currentBuild()
 	if *showHelp {
-		printHelp()
+		printHelp(build.Version)
 		return
 	}
 
 	if *showVersion {
-		fmt.Printf("llm-benchmark v%s\n", version)
+		fmt.Println(build)
+		return
+	}
+
+	// Warnings, errors and verbose progress go to stderr through the logger; the
+	// human-readable summary stays on stdout
+	logger, err := newLogger(os.Stderr, *logFormat, *logLevel)
+	if err != nil {
+		fatal("Configuration error", "error", err)
+	}
+	slog.SetDefault(logger)
+
+	color, err := output.UseColor(*colorMode, os.Stdout)
+	if err != nil {
+		fatal("Configuration error", "error", err)
+	}
+
+	// Compare two existing result files without running a benchmark
+	if *currentFile != "" {
+		if *compareFile == "" {
+			fatal("-current requires -compare")
+		}
+		current, err := output.ReadResults(*currentFile)
+		if err != nil {
+			fatal("Failed to load results", "error", err)
+		}
+		_, regressed, err := compareWithBaseline(*compareFile, current, *compareThreshold)
+		if err != nil {
+			fatal("Comparison failed", "error", err)
+		}
+		if regressed {
+			os.Exit(exitRegression)
+		}
 		return
 	}
 
@@ -46,20 +172,148 @@ func main() {
 	fmt.Printf("Loading configuration from %s...\n", *modelsFile)
 	cfg, err := config.LoadConfig(*modelsFile)
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("Failed to load configuration", "error", err)
 	}
 	fmt.Printf("Configuration loaded successfully\n")
 
-	// Override config with CLI flags
-	cfg.Concurrent = *concurrent
-	cfg.Runs = *runs
-	cfg.PromptsDir = *promptsDir
-	cfg.OutputFile = *outputFile
+	// Keys from the secrets file only fill in those the environment left unset
+	if *secretsFile != "" {
+		secrets, err := config.LoadSecrets(*secretsFile)
+		if err != nil {
+			fatal("Failed to load secrets", "error", err)
+		}
+		if err := cfg.ApplySecrets(secrets); err != nil {
+			fatal("Failed to load secrets", "error", err)
+		}
+	}
+
+	// Apply the run config file, then the flags passed explicitly so they win
+	if *runConfigFile != "" {
+		runConfig, err := config.LoadRunConfig(*runConfigFile)
+		if err != nil {
+			fatal("Failed to load run config", "error", err)
+		}
+		cfg.ApplyRunConfig(runConfig)
+	}
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	flagConfig := &config.RunConfig{}
+	if setFlags["concurrent"] {
+		flagConfig.Concurrency = *concurrent
+	}
+	if setFlags["provider-concurrency"] {
+		pools, err := parseProviderConcurrency(*providerConcurrency)
+		if err != nil {
+			fatal("Configuration error", "error", err)
+		}
+		flagConfig.ProviderConcurrency = pools
+	}
+	if setFlags["provider-timeout"] {
+		timeouts, err := parseProviderTimeouts(*providerTimeout)
+		if err != nil {
+			fatal("Configuration error", "error", err)
+		}
+		flagConfig.ProviderTimeouts = timeouts
+	}
+	if setFlags["runs"] {
+		flagConfig.Runs = *runs
+	}
+	if setFlags["mode"] {
+		flagConfig.Mode = *mode
+	}
+	if setFlags["warmup"] {
+		flagConfig.Warmup = *warmup
+	}
+	if setFlags["prompts"] {
+		flagConfig.PromptsDir = *promptsDir
+	}
+	if setFlags["output"] {
+		flagConfig.Output = *outputFile
+	}
+	if setFlags["format"] {
+		flagConfig.OutputFormat = *outputFormat
+	}
+	if setFlags["providers"] {
+		flagConfig.Providers = splitList(*providerList)
+	}
+	cfg.ApplyRunConfig(flagConfig)
+
+	// Override config with the remaining CLI flags
+	cfg.RecursivePrompts = *recursivePrompts
+	cfg.LimitPrompts = *limitPrompts
+	cfg.Shuffle = *shuffle
+	cfg.ShuffleSeed = *shuffleSeed
+	if cfg.Shuffle && cfg.ShuffleSeed == 0 {
+		cfg.ShuffleSeed = rand.Uint64()
+	}
+	cfg.AdaptiveConcurrency = *adaptiveConcurrency
+	cfg.Interleave = *interleave
+	cfg.Cooldown = *cooldown
+	cfg.ProxyURL = *proxy
+	cfg.Strict = *strict
+	cfg.Incremental = *incremental
+	cfg.Append = *appendOutput
 	cfg.Verbose = *verbose
+	cfg.NoStream = *noStream
+	if setFlags["temperature"] {
+		cfg.Temperature = temperature
+	}
+	if setFlags["max-tokens"] {
+		cfg.MaxTokens = maxTokens
+	}
+	if setFlags["top-p"] {
+		cfg.TopP = topP
+	}
+	cfg.Tokenizer = *tokenizerName
+	cfg.VisibleTokensPerSecond = *visibleTPS
+	cfg.NormalizeTokens = *normalizeTokens
+	cfg.Measure = *measure
+	cfg.Retries = *retries
+	cfg.RetryStrategy = *retryStrategy
+	cfg.RetryMaxDelay = *retryMaxDelay
+	cfg.Timeout = *timeout
+	cfg.MaxCost = *maxCost
+	cfg.MaxDuration = *maxDuration
+	cfg.TraceDir = *traceDir
+	cfg.RequestIDs = *requestIDs
+	cfg.SummaryJSONFile = *summaryJSON
+	if cfg.IncludeModels, err = config.ParseModelPatterns(*includeModels); err != nil {
+		fatal("Configuration error", "error", err)
+	}
+	if cfg.ExcludeModels, err = config.ParseModelPatterns(*excludeModels); err != nil {
+		fatal("Configuration error", "error", err)
+	}
+	if *concurrencySweep != "" {
+		levels, err := benchmark.ParseConcurrencySweep(*concurrencySweep)
+		if err != nil {
+			fatal("Configuration error", "error", err)
+		}
+		cfg.ConcurrencySweep = levels
+	}
+	if *histogramBuckets != "" {
+		bounds, err := benchmark.ParseHistogramBuckets(*histogramBuckets)
+		if err != nil {
+			fatal("Configuration error", "error", err)
+		}
+		cfg.HistogramBuckets = bounds
+	}
+	if *percentiles != "" {
+		values, err := benchmark.ParsePercentiles(*percentiles)
+		if err != nil {
+			fatal("Configuration error", "error", err)
+		}
+		cfg.Percentiles = values
+	}
+	if *notifyWebhook != "" {
+		cfg.NotifyWebhookURL = *notifyWebhook
+	}
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
-		log.Fatalf("Configuration error: %v", err)
+		fatal("Configuration error", "error", err)
+	}
+	for _, warning := range cfg.Warnings() {
+		slog.Warn(warning)
 	}
 
 	// Set up graceful shutdown
@@ -75,6 +329,10 @@ func main() {
 		cancel()
 	}()
 
+	if cfg.Shuffle {
+		fmt.Printf("Shuffling prompts with seed %d (repeat the order with -shuffle-seed %d)\n", cfg.ShuffleSeed, cfg.ShuffleSeed)
+	}
+
 	// Initialize provider factory
 	fmt.Printf("Initializing provider factory...\n")
 	factory := providers.NewProviderFactory()
@@ -84,8 +342,20 @@ func main() {
 	factory.RegisterConfig("openai", cfg.GetOpenAIConfig())
 	factory.RegisterConfig("groq", cfg.GetGroqConfig())
 	factory.RegisterConfig("anthropic", cfg.GetAnthropicConfig())
-	factory.RegisterConfig("azure_openai", cfg.GetAzureOpenAIConfig())
+	azureConfig := cfg.GetAzureOpenAIConfig()
+	if azureConfig.AuthMode == providers.AzureAuthAAD {
+		credential, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			slog.Warn("Failed to create Azure AD credential", "provider", "azure_openai", "error", err)
+		} else {
+			azureConfig.Credential = credential
+		}
+	}
+	factory.RegisterConfig("azure_openai", azureConfig)
 	factory.RegisterConfig("gemini", cfg.GetGeminiConfig())
+	for _, name := range []string{"perplexity", "deepseek", "xai", "together", "cerebras", "sambanova"} {
+		factory.RegisterConfig(name, cfg.GetOpenAICompatibleConfig(name))
+	}
 	
 	// Create provider instances for all configured providers
 	providerMap := make(map[string]providers.Provider)
@@ -96,7 +366,7 @@ func main() {
 		fmt.Printf("OpenAI API key found, creating provider...\n")
 		provider, err := factory.GetProvider("openai")
 		if err != nil {
-			log.Printf("Warning: Failed to create OpenAI provider: %v", err)
+			slog.Warn("Failed to create provider", "provider", "openai", "error", err)
 		} else {
 			providerMap["openai"] = provider
 			fmt.Printf("OpenAI provider created successfully\n")
@@ -106,7 +376,7 @@ func main() {
 		fmt.Printf("Creating OpenAI Responses provider...\n")
 		respProvider, err := factory.GetProvider("openai_responses")
 		if err != nil {
-			log.Printf("Warning: Failed to create OpenAI Responses provider: %v", err)
+			slog.Warn("Failed to create provider", "provider", "openai_responses", "error", err)
 		} else {
 			providerMap["openai_responses"] = respProvider
 			fmt.Printf("OpenAI Responses provider created successfully\n")
@@ -121,7 +391,7 @@ func main() {
 		fmt.Printf("Groq API key found, creating provider...\n")
 		provider, err := factory.GetProvider("groq")
 		if err != nil {
-			log.Printf("Warning: Failed to create Groq provider: %v", err)
+			slog.Warn("Failed to create provider", "provider", "groq", "error", err)
 		} else {
 			providerMap["groq"] = provider
 			fmt.Printf("Groq provider created successfully\n")
@@ -136,7 +406,7 @@ func main() {
 		fmt.Printf("Anthropic API key found, creating provider...\n")
 		provider, err := factory.GetProvider("anthropic")
 		if err != nil {
-			log.Printf("Warning: Failed to create Anthropic provider: %v", err)
+			slog.Warn("Failed to create provider", "provider", "anthropic", "error", err)
 		} else {
 			providerMap["anthropic"] = provider
 			fmt.Printf("Anthropic provider created successfully\n")
@@ -147,87 +417,780 @@ func main() {
 	
 	// Initialize Azure OpenAI provider if configuration is available
 	fmt.Printf("Checking Azure OpenAI configuration...\n")
-	if cfg.AzureOpenAIAPIKey != "" && cfg.AzureOpenAIEndpoint != "" {
+	if cfg.AzureOpenAIEndpoint != "" && (cfg.AzureOpenAIAPIKey != "" || cfg.AzureOpenAIAuthMode == providers.AzureAuthAAD) {
 		fmt.Printf("Azure OpenAI configuration found, creating provider...\n")
 		provider, err := factory.GetProvider("azure_openai")
 		if err != nil {
-			log.Printf("Warning: Failed to create Azure OpenAI provider: %v", err)
+			slog.Warn("Failed to create provider", "provider", "azure_openai", "error", err)
 		} else {
 			providerMap["azure_openai"] = provider
 			fmt.Printf("Azure OpenAI provider created successfully\n")
 		}
 	} else {
-		fmt.Printf("No Azure OpenAI configuration found (requires AZURE_OPENAI_API_KEY and AZURE_OPENAI_ENDPOINT)\n")
+		fmt.Printf("No Azure OpenAI configuration found (requires AZURE_OPENAI_ENDPOINT and either AZURE_OPENAI_API_KEY or AZURE_OPENAI_AUTH_MODE=aad)\n")
 	}
 	
-	// Initialize Gemini provider if API key is available
+	// Initialize Gemini provider if an API key or Vertex AI project is available
 	fmt.Printf("Checking Google API key...\n")
-	if cfg.GoogleAPIKey != "" {
-		fmt.Printf("Google API key found, creating Gemini provider...\n")
+	if cfg.GoogleAPIKey != "" || cfg.GeminiUseVertex {
+		fmt.Printf("Google configuration found, creating Gemini provider...\n")
 		provider, err := factory.GetProvider("gemini")
 		if err != nil {
-			log.Printf("Warning: Failed to create Gemini provider: %v", err)
+			slog.Warn("Failed to create provider", "provider", "gemini", "error", err)
 		} else {
 			providerMap["gemini"] = provider
 			fmt.Printf("Gemini provider created successfully\n")
+			if reporter, ok := provider.(providers.BackendReporter); ok {
+				fmt.Printf("Gemini backend: %s\n", reporter.GetBackendInfo())
+			}
+		}
+	} else {
+		fmt.Printf("No Google configuration found (requires GOOGLE_API_KEY, or GOOGLE_GENAI_USE_VERTEXAI=true and GOOGLE_CLOUD_PROJECT)\n")
+	}
+	
+	// Initialize Perplexity provider if API key is available
+	fmt.Printf("Checking Perplexity API key...\n")
+	if cfg.PerplexityAPIKey != "" {
+		fmt.Printf("Perplexity API key found, creating provider...\n")
+		provider, err := factory.GetProvider("perplexity")
+		if err != nil {
+			slog.Warn("Failed to create provider", "provider", "perplexity", "error", err)
+		} else {
+			providerMap["perplexity"] = provider
+			fmt.Printf("Perplexity provider created successfully\n")
+		}
+	} else {
+		fmt.Printf("No Perplexity API key found\n")
+	}
+	
+	// Initialize DeepSeek provider if API key is available
+	fmt.Printf("Checking DeepSeek API key...\n")
+	if cfg.DeepSeekAPIKey != "" {
+		fmt.Printf("DeepSeek API key found, creating provider...\n")
+		provider, err := factory.GetProvider("deepseek")
+		if err != nil {
+			slog.Warn("Failed to create provider", "provider", "deepseek", "error", err)
+		} else {
+			providerMap["deepseek"] = provider
+			fmt.Printf("DeepSeek provider created successfully\n")
+		}
+	} else {
+		fmt.Printf("No DeepSeek API key found\n")
+	}
+	
+	// Initialize xAI provider if API key is available
+	fmt.Printf("Checking xAI API key...\n")
+	if cfg.XAIAPIKey != "" {
+		fmt.Printf("xAI API key found, creating provider...\n")
+		provider, err := factory.GetProvider("xai")
+		if err != nil {
+			slog.Warn("Failed to create provider", "provider", "xai", "error", err)
+		} else {
+			providerMap["xai"] = provider
+			fmt.Printf("xAI provider created successfully\n")
+		}
+	} else {
+		fmt.Printf("No xAI API key found\n")
+	}
+	
+	// Initialize Together AI provider if API key is available
+	fmt.Printf("Checking Together AI API key...\n")
+	if cfg.TogetherAPIKey != "" {
+		fmt.Printf("Together AI API key found, creating provider...\n")
+		provider, err := factory.GetProvider("together")
+		if err != nil {
+			slog.Warn("Failed to create provider", "provider", "together", "error", err)
+		} else {
+			providerMap["together"] = provider
+			fmt.Printf("Together AI provider created successfully\n")
 		}
 	} else {
-		fmt.Printf("No Google API key found\n")
+		fmt.Printf("No Together AI API key found\n")
 	}
 	
+	// Initialize Cerebras provider if API key is available
+	fmt.Printf("Checking Cerebras API key...\n")
+	if cfg.CerebrasAPIKey != "" {
+		fmt.Printf("Cerebras API key found, creating provider...\n")
+		provider, err := factory.GetProvider("cerebras")
+		if err != nil {
+			slog.Warn("Failed to create provider", "provider", "cerebras", "error", err)
+		} else {
+			providerMap["cerebras"] = provider
+			fmt.Printf("Cerebras provider created successfully\n")
+		}
+	} else {
+		fmt.Printf("No Cerebras API key found\n")
+	}
+	
+	// Initialize SambaNova provider if API key is available
+	fmt.Printf("Checking SambaNova API key...\n")
+	if cfg.SambaNovaAPIKey != "" {
+		fmt.Printf("SambaNova API key found, creating provider...\n")
+		provider, err := factory.GetProvider("sambanova")
+		if err != nil {
+			slog.Warn("Failed to create provider", "provider", "sambanova", "error", err)
+		} else {
+			providerMap["sambanova"] = provider
+			fmt.Printf("SambaNova provider created successfully\n")
+		}
+	} else {
+		fmt.Printf("No SambaNova API key found\n")
+	}
+	
+	// Keep only the providers selected with -providers or the run config
+	var unavailable []string
+	for _, name := range cfg.Providers {
+		if _, ok := providerMap[name]; !ok {
+			slog.Warn("Provider was selected but is not available", "provider", name)
+			unavailable = append(unavailable, name)
+		}
+	}
+	for name := range providerMap {
+		if !cfg.ProviderEnabled(name) {
+			delete(providerMap, name)
+		}
+	}
+
 	if len(providerMap) == 0 {
-		log.Fatal("No valid providers could be initialized")
+		fatal("No valid providers could be initialized")
 	}
 	
 	fmt.Printf("Providers initialized: %d\n", len(providerMap))
 	
 	// Create and run benchmark
 	runner := benchmark.NewRunner(cfg, providerMap, cfg.Verbose)
+	runner.SetLogger(logger)
+
+	// Show what would run without validating or running anything
+	if *listWork {
+		list, err := runner.WorkList()
+		if err != nil {
+			fatal("Failed to list work", "error", err)
+		}
+		printWorkList(list)
+		return
+	}
+
+	// Check everything the run would send, then stop before any network call
+	if *dryRun {
+		plan, err := runner.Plan()
+		if err != nil {
+			fatal("Dry run failed", "error", err)
+		}
+		for _, name := range unavailable {
+			plan.Problems = append(plan.Problems, benchmark.PlanProblem{Provider: name, Err: fmt.Errorf("selected but has no credentials configured")})
+		}
+		plan.Write(os.Stdout)
+		if !plan.Valid() {
+			os.Exit(1)
+		}
+		return
+	}
 	
-	fmt.Printf("LLM Benchmark Tool v%s\n", version)
+	fmt.Printf("LLM Benchmark Tool v%s\n", build.Version)
 	fmt.Printf("Configuration loaded successfully\n")
-	fmt.Printf("Concurrent requests: %d\n", cfg.Concurrent)
+	if len(cfg.ConcurrencySweep) > 0 {
+		fmt.Printf("Concurrency sweep: %s\n", *concurrencySweep)
+	} else {
+		fmt.Printf("Concurrent requests: %d\n", cfg.Concurrent)
+		for _, name := range sortedKeys(cfg.ProviderConcurrency) {
+			fmt.Printf("Concurrent requests for %s: %d\n", name, cfg.ProviderConcurrency[name])
+		}
+	}
+	for _, name := range sortedKeys(cfg.ProviderTimeouts) {
+		fmt.Printf("Request timeout for %s: %v\n", name, cfg.ProviderTimeouts[name])
+	}
 	fmt.Printf("Runs per model/prompt: %d\n", cfg.Runs)
+	fmt.Printf("Mode: %s\n", cfg.Mode)
 	fmt.Printf("Prompts directory: %s\n", cfg.PromptsDir)
 	fmt.Printf("Models file: %s\n", *modelsFile)
-	fmt.Printf("Output file: %s\n", cfg.GetOutputFile())
+	writeCSV := cfg.OutputFormat != config.OutputFormatTable || cfg.OutputFile != ""
+	outputPath := cfg.GetOutputFile()
+	if writeCSV {
+		fmt.Printf("Output file: %s\n", outputPath)
+	}
+	if cfg.OutputFormat == config.OutputFormatTable {
+		fmt.Printf("Output format: table\n")
+	}
 	fmt.Printf("Verbose mode: %t\n", cfg.Verbose)
+	if cfg.AdaptiveConcurrency {
+		fmt.Printf("Adaptive concurrency: enabled (up to %d per provider)\n", cfg.Concurrent)
+	}
+	if cfg.NoStream {
+		fmt.Printf("Streaming: disabled\n")
+	}
+	if cfg.TraceDir != "" {
+		fmt.Printf("Chunk traces: %s\n", cfg.TraceDir)
+	}
+	if cfg.MaxCost > 0 {
+		fmt.Printf("Cost budget: $%.6f\n", cfg.MaxCost)
+	}
+	if cfg.MaxDuration > 0 {
+		fmt.Printf("Max duration: %v\n", cfg.MaxDuration)
+	}
 	fmt.Printf("Providers initialized: %d\n", len(providerMap))
+
+	// Confirm every endpoint and key works before dispatching the whole run
+	if preflight != preflightOff {
+		results := runner.Preflight(ctx)
+		fmt.Println()
+		benchmark.WritePreflight(os.Stdout, results)
+		if benchmark.PreflightFailed(results) {
+			if preflight == preflightAbort {
+				fatal("Preflight failed; fix the providers above or rerun with -preflight=warn")
+			}
+			slog.Warn("Preflight failed for some providers; running anyway")
+		}
+	}
 	
 	// Run the benchmark
-	if err := runner.Run(ctx); err != nil {
-		log.Fatalf("Benchmark failed: %v", err)
+	metadata := benchmark.NewRunMetadata(build.Version, *modelsFile, cfg.Concurrent)
+	// With -incremental each result is written as soon as it completes, so a crash
+	// keeps the finished rows, rather than all of them once the run ends
+	var csvStream *output.CSVStream
+	if writeCSV && cfg.Incremental {
+		csvWriter := output.NewCSVWriter(outputPath)
+		csvWriter.SetMetadata(metadata)
+		csvWriter.SetAppend(cfg.Append)
+		if csvStream, err = csvWriter.Open(); err != nil {
+			fatal("Failed to open CSV results", "error", err)
+		}
+		runner.SetResultSink(csvStream)
+	}
+	results, summary, err := runner.Execute(ctx)
+	if csvStream != nil {
+		if err := csvStream.Close(); err != nil {
+			fatal("Failed to write CSV results", "error", err)
+		}
 	}
+	// An interrupted run still writes and summarizes the benchmarks that completed
+	interrupted := ctx.Err() != nil
+	if err != nil && !interrupted {
+		fatal("Benchmark failed", "error", err)
+	}
+	// Workers finish in no particular order; sorting keeps output comparable across runs
+	benchmark.SortResults(results)
 	
-	// Get results and write to CSV
-	results := runner.GetResults()
+	// Nothing to write if no run completed
 	if len(results) == 0 {
-		log.Println("No benchmark results generated")
+		slog.Warn("No benchmark results generated")
+		if interrupted {
+			os.Exit(exitInterrupted)
+		}
 		return
 	}
 	
-	// Write results to CSV
-	csvWriter := output.NewCSVWriter(cfg.GetOutputFile())
-	if err := csvWriter.WriteResults(results); err != nil {
-		log.Fatalf("Failed to write CSV results: %v", err)
+	// Write results to CSV, which table output only does when -output names a file
+	if writeCSV && csvStream == nil {
+		csvWriter := output.NewCSVWriter(outputPath)
+		csvWriter.SetMetadata(metadata)
+		csvWriter.SetAppend(cfg.Append)
+		if err := csvWriter.WriteResults(results); err != nil {
+			fatal("Failed to write CSV results", "error", err)
+		}
+	}
+	if cfg.OutputFormat == config.OutputFormatTable {
+		fmt.Println()
+		if err := output.WriteTable(os.Stdout, results, color); err != nil {
+			fatal("Failed to print results table", "error", err)
+		}
+	}
+	byModel := benchmark.CalculateSummaryByModelWithPercentiles(results, cfg.Percentiles)
+	if cfg.SummaryJSONFile != "" {
+		if err := output.WriteSummaryJSON(cfg.SummaryJSONFile, metadata, summary, byModel); err != nil {
+			fatal("Failed to write summary JSON", "file", cfg.SummaryJSONFile, "error", err)
+		}
 	}
 	
 	// Print summary
-	summary := runner.GetSummary()
-	fmt.Printf("\nBenchmark completed successfully!\n")
-	fmt.Printf("Results written to: %s\n", cfg.GetOutputFile())
+	if interrupted {
+		fmt.Printf("\nBenchmark interrupted: summary covers the %d completed runs only (partial results)\n", summary.TotalRuns)
+	} else if summary.HaltReason != "" {
+		fmt.Printf("\nBenchmark halted: %s\n", summary.HaltReason)
+	} else {
+		fmt.Printf("\nBenchmark completed successfully!\n")
+	}
+	if writeCSV {
+		fmt.Printf("Results written to: %s\n", outputPath)
+	}
+	if cfg.SummaryJSONFile != "" {
+		fmt.Printf("Summary written to: %s\n", cfg.SummaryJSONFile)
+	}
 	fmt.Printf("Total runs: %d\n", summary.TotalRuns)
 	fmt.Printf("Successful runs: %d\n", summary.SuccessfulRuns)
 	fmt.Printf("Failed runs: %d\n", summary.FailedRuns)
 	fmt.Printf("Error rate: %.2f%%\n", summary.ErrorRate*100)
+	if summary.TruncatedRuns > 0 {
+		fmt.Printf("Truncated runs: %d (hit max_tokens, latency and tokens/sec are understated)\n", summary.TruncatedRuns)
+	}
+	if summary.EmptyRuns > 0 {
+		fmt.Printf("Empty responses: %d (no answer text, left out of tokens/sec)\n", summary.EmptyRuns)
+	}
 	if summary.SuccessfulRuns > 0 {
-		fmt.Printf("Average TTFT: %v\n", summary.AvgTTFT)
-		fmt.Printf("Average total time: %v\n", summary.AvgTotalTime)
+		fmt.Print(formatLatencySummary(summary))
+		printHistogram("TTFT histogram:", summary.TTFTHistogram, summary.SuccessfulRuns)
+		fmt.Printf("Average tokens/sec: %.2f\n", summary.AvgTokensPerSecond)
+		fmt.Printf("Aggregate throughput: %.2f tokens/sec over %v\n", summary.AggregateThroughput, summary.WallTime.Round(time.Millisecond))
 		fmt.Printf("Total cost: $%.6f\n", summary.TotalCost)
+		fmt.Printf("Cost per run min/avg/max: $%.6f / $%.6f / $%.6f\n",
+			summary.MinCostPerRun, summary.AvgCostPerRun, summary.MaxCostPerRun)
+		fmt.Printf("Effective cost: $%.4f per 1M tokens\n", summary.EffectiveCostPer1MTokens)
+	}
+	if summary.WallTime > 0 {
+		fmt.Printf("Wall-clock duration: %v\n", summary.WallTime.Round(time.Millisecond))
+	}
+	printFingerprintChanges(summary.Fingerprints)
+
+	if limits := runner.ConcurrencyLimits(); len(limits) > 0 {
+		for _, name := range sortedKeys(limits) {
+			fmt.Printf("Final concurrency for %s: %d\n", name, limits[name])
+		}
+	}
+
+	if len(cfg.ConcurrencySweep) > 0 {
+		printConcurrencySweep(benchmark.SummarizeConcurrencyLevels(results))
+	}
+
+	// Break the summary down by model when more than one was benchmarked
+	if len(byModel) > 1 {
+		printModelSummaries(byModel)
+	}
+
+	// And by prompt, across models, when more than one prompt ran
+	if byPrompt := benchmark.CalculateSummaryByPromptWithPercentiles(results, cfg.Percentiles); len(byPrompt) > 1 {
+		printPromptSummaries(byPrompt)
+	}
+
+	// Compare against a baseline run, failing the process on regressions
+	var comparisons []benchmark.Comparison
+	regressed := false
+	if *compareFile != "" {
+		comparisons, regressed, err = compareWithBaseline(*compareFile, results, *compareThreshold)
+		if err != nil {
+			fatal("Comparison failed", "error", err)
+		}
+	}
+
+	// Check SLA thresholds for CI gating
+	thresholds := benchmark.Thresholds{MaxP95TTFT: *maxP95TTFT, MaxErrorRate: *maxErrorRate / 100, PerModel: *perModelThresholds}
+	var violations []benchmark.ThresholdViolation
+	if thresholds.Enabled() {
+		violations = benchmark.CheckThresholds(results, thresholds)
+		if len(violations) == 0 {
+			fmt.Printf("All thresholds met\n")
+		}
+		for _, violation := range violations {
+			fmt.Printf("Threshold breached: %s\n", violation)
+		}
+	}
+
+	// Notification failures shouldn't fail the run
+	if cfg.NotifyWebhookURL != "" {
+		notifier := notify.NewWebhookNotifier(cfg.NotifyWebhookURL)
+		if err := notifier.Notify(context.Background(), summary, comparisons); err != nil {
+			slog.Warn("Failed to send webhook notification", "error", err)
+		} else {
+			fmt.Printf("Summary posted to webhook\n")
+		}
+	}
+
+	// Last so scripts can read it with tail -n 1
+	if *printSummaryLine {
+		fmt.Println(formatSummaryLine(summary))
+	}
+
+	if code := exitCode(violations, regressed, interrupted); code != 0 {
+		os.Exit(code)
+	}
+}
+
+// buildInfo identifies the binary for -version and the run metadata
+type buildInfo struct {
+	Version   string
+	Commit    string // Abbreviated, with -dirty when built from a modified tree
+	Date      string
+	GoVersion string
+}
+
+// currentBuild resolves the build information of the running binary
+func currentBuild() buildInfo {
+	info, _ := debug.ReadBuildInfo()
+	return resolveBuild(version, commit, buildDate, info)
+}
+
+// resolveBuild takes the -ldflags build variables, filling in those left empty
+// from the module version go install embeds and the VCS stamp go build does (info
+// may be nil), and the version from defaultVersion as a last resort
+func resolveBuild(version, commit, buildDate string, info *debug.BuildInfo) buildInfo {
+	build := buildInfo{Version: version, Commit: commit, Date: buildDate}
+	if info != nil {
+		build.GoVersion = info.GoVersion
+		if build.Version == "" && info.Main.Version != "" && info.Main.Version != "(devel)" && !pseudoVersion.MatchString(info.Main.Version) {
+			build.Version = strings.TrimPrefix(info.Main.Version, "v")
+		}
+
+		var revision, revisionTime string
+		dirty := false
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				revision = setting.Value
+			case "vcs.time":
+				revisionTime = setting.Value
+			case "vcs.modified":
+				dirty = setting.Value == "true"
+			}
+		}
+		if build.Commit == "" && revision != "" {
+			build.Commit = revision[:min(len(revision), 12)]
+			if dirty {
+				build.Commit += "-dirty"
+			}
+		}
+		if build.Date == "" {
+			build.Date = revisionTime
+		}
+	}
+	if build.Version == "" {
+		build.Version = defaultVersion
+	}
+	return build
+}
+
+// String renders the -version line, e.g.
+// "llm-benchmark v0.2.0 (commit 1a2b3c4, built 2025-06-01T12:00:00Z, go1.24.4)"
+func (b buildInfo) String() string {
+	var details []string
+	if b.Commit != "" {
+		details = append(details, "commit "+b.Commit)
+	}
+	if b.Date != "" {
+		details = append(details, "built "+b.Date)
+	}
+	if b.GoVersion != "" {
+		details = append(details, b.GoVersion)
+	}
+
+	line := "llm-benchmark v" + b.Version
+	if len(details) > 0 {
+		line += " (" + strings.Join(details, ", ") + ")"
+	}
+	return line
+}
+
+// newLogger builds the logger for warnings, errors and verbose progress: format is
+// text or json, level the minimum level logged (debug, info, warn or error)
+func newLogger(w io.Writer, format, level string) (*slog.Logger, error) {
+	var minLevel slog.Level
+	if err := minLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q (expected debug, info, warn or error)", level)
+	}
+	options := &slog.HandlerOptions{Level: minLevel}
+
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	}
+	return nil, fmt.Errorf("unsupported log format %q (expected text or json)", format)
+}
+
+// fatal logs msg and its attributes at error level and exits with status 1
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// exitCode picks the process exit code once results are written: threshold breaches
+// first, then baseline regressions, then interruption
+func exitCode(violations []benchmark.ThresholdViolation, regressed, interrupted bool) int {
+	switch {
+	case len(violations) > 0:
+		return exitThresholdBreached
+	case regressed:
+		return exitRegression
+	case interrupted:
+		return exitInterrupted
+	}
+	return 0
+}
+
+// compareWithBaseline prints per-model deltas against a baseline CSV and returns them,
+// reporting whether any metric worsened beyond thresholdPct
+func compareWithBaseline(baselineFile string, current []benchmark.BenchmarkResult, thresholdPct float64) ([]benchmark.Comparison, bool, error) {
+	baseline, err := output.ReadResults(baselineFile)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load baseline: %w", err)
+	}
+
+	comparisons := benchmark.CompareResults(baseline, current, thresholdPct)
+	fmt.Printf("\nComparison against %s (regression threshold %.1f%%):\n", baselineFile, thresholdPct)
+	if len(comparisons) == 0 {
+		fmt.Println("No matching provider/model/prompt combinations found")
+		return nil, false, nil
+	}
+
+	regressed := false
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROVIDER\tMODEL\tPROMPT\tP95 TTFT\tΔ TTFT\tTOK/S\tΔ TOK/S\tAVG COST\tΔ COST\tSTATUS")
+	for _, c := range comparisons {
+		status := "ok"
+		if c.IsRegression() {
+			status = fmt.Sprintf("REGRESSION (%s)", strings.Join(c.Regressions, ", "))
+			regressed = true
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%v\t%+.1f%%\t%.2f\t%+.1f%%\t$%.6f\t%+.1f%%\t%s\n",
+			c.Provider, c.Model, c.PromptFile,
+			c.CurrentP95TTFT, c.P95TTFTDeltaPct,
+			c.CurrentTokensPerSecond, c.TokensPerSecondDeltaPct,
+			c.CurrentAvgCost, c.CostDeltaPct,
+			status)
+	}
+	tw.Flush()
+
+	return comparisons, regressed, nil
+}
+
+// printPromptSummaries prints one row per prompt file, sorted by name
+func printPromptSummaries(byPrompt map[string]benchmark.Summary) {
+	keys := make([]string, 0, len(byPrompt))
+	for key := range byPrompt {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("\nPer-prompt summary:\n")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROMPT\tRUNS\tERRORS\tAVG TTFT\tP95 TTFT\tP95 TOTAL\tAVG TOK/S\tTOTAL COST")
+	for _, key := range keys {
+		s := byPrompt[key]
+		fmt.Fprintf(tw, "%s\t%d\t%.1f%%\t%v\t%v\t%v\t%.2f\t$%.6f\n",
+			key, s.TotalRuns, s.ErrorRate*100,
+			s.AvgTTFT, s.P95TTFT, s.P95TotalTime, s.AvgTokensPerSecond, s.TotalCost)
+	}
+	tw.Flush()
+}
+
+// printFingerprintChanges warns about models whose backend build, as identified by the
+// system fingerprint, changed during the run, since that can shift latency
+func printFingerprintChanges(fingerprints map[string][]string) {
+	keys := make([]string, 0, len(fingerprints))
+	for key := range fingerprints {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Printf("Warning: %s reported %d system fingerprints (%s); its backend changed during the run\n",
+			key, len(fingerprints[key]), strings.Join(fingerprints[key], ", "))
+	}
+}
+
+// printModelSummaries prints one row per provider/model, sorted by key
+func printModelSummaries(byModel map[string]benchmark.Summary) {
+	keys := make([]string, 0, len(byModel))
+	for key := range byModel {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("\nPer-model summary:\n")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROVIDER\tMODEL\tRUNS\tSUCCESS\tTOP ERROR\tAVG TTFT\tP95 TTFT\tP95 TOTAL\tTOTAL COST")
+	for _, key := range keys {
+		s := byModel[key]
+		provider, model, _ := strings.Cut(key, "/")
+		topError := s.TopErrorCategory
+		if topError == "" {
+			topError = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.1f%%\t%s\t%v\t%v\t%v\t$%.6f\n",
+			provider, model, s.TotalRuns, s.SuccessRate*100, topError,
+			s.AvgTTFT, s.P95TTFT, s.P95TotalTime, s.TotalCost)
+	}
+	tw.Flush()
+}
+
+// printWorkList prints the combinations a run would execute and their request counts
+func printWorkList(list []benchmark.PlannedBenchmark) {
+	fmt.Printf("\nWork list:\n")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROVIDER\tMODEL\tPROMPT\tREQUESTS")
+	requests := 0
+	for _, item := range list {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", item.Provider, item.Model, item.Prompt, item.Requests)
+		requests += item.Requests
+	}
+	tw.Flush()
+	fmt.Printf("%d combinations, %d requests\n", len(list), requests)
+}
+
+// printConcurrencySweep prints latency and throughput at each level of a concurrency sweep
+func printConcurrencySweep(levels []benchmark.ConcurrencyLevel) {
+	fmt.Printf("\nConcurrency sweep:\n")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONCURRENCY\tRUNS\tERRORS\tP95 TTFT\tWALL TIME\tTHROUGHPUT (TOK/S)")
+	for _, level := range levels {
+		s := level.Summary
+		fmt.Fprintf(tw, "%d\t%d\t%.1f%%\t%v\t%v\t%.2f\n",
+			level.Concurrency, s.TotalRuns, s.ErrorRate*100,
+			s.P95TTFT, s.WallTime.Round(time.Millisecond), s.AggregateThroughput)
+	}
+	tw.Flush()
+}
+
+// formatLatencySummary renders the average TTFT and total time followed by
+// their min/p50/p95/p99/max distributions, so the tails show without opening
+// the results file
+func formatLatencySummary(summary benchmark.Summary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Average TTFT: %v\n", summary.AvgTTFT)
+	fmt.Fprintf(&b, "TTFT min/p50/p95/p99/max: %v / %v / %v / %v / %v\n",
+		summary.MinTTFT, summary.P50TTFT, summary.P95TTFT, summary.P99TTFT, summary.MaxTTFT)
+	fmt.Fprintf(&b, "TTFT std dev: %v (CV %.2f)\n", summary.StdDevTTFT, summary.CoeffVarTTFT)
+	fmt.Fprintf(&b, "Average total time: %v\n", summary.AvgTotalTime)
+	fmt.Fprintf(&b, "Total time min/p50/p95/p99/max: %v / %v / %v / %v / %v\n",
+		summary.MinTotalTime, summary.P50TotalTime, summary.P95TotalTime, summary.P99TotalTime, summary.MaxTotalTime)
+	if line := formatPercentiles("TTFT", summary.TTFTPercentiles); line != "" {
+		b.WriteString(line)
+	}
+	if line := formatPercentiles("Total time", summary.TotalTimePercentiles); line != "" {
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// formatPercentiles renders a -percentiles line such as "TTFT p90/p99.9: 420ms / 1.1s",
+// or "" when the percentiles add nothing to the fixed p50/p95/p99 line
+func formatPercentiles(name string, values map[float64]time.Duration) string {
+	percentiles := benchmark.SortedPercentiles(values)
+	extra := false
+	for _, percentile := range percentiles {
+		if percentile != 50 && percentile != 95 && percentile != 99 {
+			extra = true
+		}
+	}
+	if !extra {
+		return ""
 	}
+
+	labels := make([]string, len(percentiles))
+	durations := make([]string, len(percentiles))
+	for i, percentile := range percentiles {
+		labels[i] = benchmark.PercentileLabel(percentile)
+		durations[i] = values[percentile].String()
+	}
+	return fmt.Sprintf("%s %s: %s\n", name, strings.Join(labels, "/"), strings.Join(durations, " / "))
 }
 
-func printHelp() {
+// formatSummaryLine renders the run as space-separated key=value pairs with
+// fixed keys and precision, so scripts can grep or parse it
+func formatSummaryLine(summary benchmark.Summary) string {
+	return fmt.Sprintf("runs=%d success=%d error_rate=%.3f p95_ttft_ms=%d total_cost=%.4f",
+		summary.TotalRuns, summary.SuccessfulRuns, summary.ErrorRate,
+		summary.P95TTFT.Milliseconds(), summary.TotalCost)
+}
+
+// printHistogram prints bucket counts with their share of total and a proportional bar
+func printHistogram(title string, buckets []benchmark.HistogramBucket, total int) {
+	if total == 0 {
+		return
+	}
+	fmt.Println(title)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, bucket := range buckets {
+		share := float64(bucket.Count) / float64(total)
+		fmt.Fprintf(w, "  %s\t%d\t%5.1f%%\t%s\n", bucket.Label, bucket.Count, share*100, strings.Repeat("#", int(share*40+0.5)))
+	}
+	w.Flush()
+}
+
+// parseProviderConcurrency parses comma-separated provider=workers pairs
+func parseProviderConcurrency(value string) (map[string]int, error) {
+	pools := make(map[string]int)
+	for _, item := range splitList(value) {
+		name, workers, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid provider concurrency %q (expected provider=workers)", item)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(workers))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid provider concurrency %q (workers must be a positive integer)", item)
+		}
+		pools[strings.TrimSpace(name)] = n
+	}
+	return pools, nil
+}
+
+// parseProviderTimeouts parses comma-separated provider=duration pairs
+func parseProviderTimeouts(value string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, item := range splitList(value) {
+		name, timeout, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid provider timeout %q (expected provider=duration)", item)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(timeout))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid provider timeout %q (timeout must be a positive duration such as 120s)", item)
+		}
+		timeouts[strings.TrimSpace(name)] = d
+	}
+	return timeouts, nil
+}
+
+// preflightMode is the -preflight flag: off, abort on a failed ping, or only warn
+type preflightMode string
+
+const (
+	preflightOff   preflightMode = ""
+	preflightAbort preflightMode = "abort"
+	preflightWarn  preflightMode = "warn"
+)
+
+func (m *preflightMode) String() string { return string(*m) }
+
+// IsBoolFlag lets -preflight be given without a value
+func (m *preflightMode) IsBoolFlag() bool { return true }
+
+func (m *preflightMode) Set(value string) error {
+	switch strings.ToLower(value) {
+	case "true", "abort":
+		*m = preflightAbort
+	case "warn":
+		*m = preflightWarn
+	case "false", "off":
+		*m = preflightOff
+	default:
+		return fmt.Errorf("invalid preflight mode %q (expected true, false or warn)", value)
+	}
+	return nil
+}
+
+// sortedKeys returns a map's keys in order, for stable output
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func printHelp(version string) {
 	fmt.Printf(`LLM Benchmark Tool v%s
 
 A Go-based command-line tool for measuring LLM latency and performance metrics 
@@ -239,20 +1202,229 @@ Usage:
 Flags:
   -concurrent int
         Number of concurrent requests (default 1)
+  -provider-concurrency string
+        Comma-separated provider=workers pairs, e.g. groq=2,openai=8. Each
+        provider runs in its own worker pool, sized by -concurrent unless
+        overridden here, so a slow or rate-limited provider doesn't hold
+        back the others
+  -concurrency-sweep string
+        Comma-separated concurrency levels, e.g. 1,2,4,8. Runs every
+        prompt/model once per level, waiting for each level to finish before
+        the next, tags results with their level and prints p95 TTFT and
+        aggregate throughput against concurrency (overrides -concurrent)
+  -adaptive-concurrency
+        Treat -concurrent as a ceiling: each provider starts at 1 request in
+        flight, adds one as requests succeed and halves on rate limits (429)
   -runs int
         Number of runs per model per prompt (default 1)
+  -mode string
+        What to benchmark: chat, or embeddings to time /embeddings round trips
+        for the models under embeddings: in the models file, embedding each
+        prompt's text (openai only) (default "chat")
   -prompts string
-        Directory containing prompt files (default "prompts")
+        Directory containing prompt files, or a single YAML file whose
+        documents, separated by ---, are each a prompt (default "prompts")
+  -recursive-prompts
+        Also load prompt files from subdirectories; nested prompts are named
+        by their relative path (e.g. chat/short)
+  -limit-prompts int
+        Run only the first N prompts, in name order, for a quick smoke run
+        (default 0 = all)
+  -shuffle
+        Run prompts in a random order; shuffled before -limit-prompts applies,
+        so the two together run a random sample of N prompts
+  -shuffle-seed uint
+        Seed for -shuffle, printed at the start of every shuffled run, to
+        repeat its order (default 0 = random)
   -output string
-        Output CSV file (default: results/benchmark_TIMESTAMP.csv)
+        Output CSV file, gzip-compressed when the path ends in .gz
+        (default: results/benchmark_TIMESTAMP.csv)
+  -format string
+        Results format: csv (default) writes the CSV file; table prints one
+        aligned row per run (provider, model, prompt, TTFT, total time,
+        tokens/sec, cost, status) before the summary and writes the CSV
+        only when -output is given
+  -color string
+        Colorize the table's status column: auto (default) only when stdout
+        is a terminal and NO_COLOR is unset, always, or never for plain
+        CI logs
+  -append
+        Append results to an existing -output file instead of replacing it;
+        fails if the file's header doesn't match the current columns
+  -incremental
+        Write each result to the CSV file as soon as it completes instead of
+        all of them when the run ends, so a crash or out-of-memory kill keeps
+        the rows already measured
   -models string
         Models configuration file (default "models.yaml")
+  -config string
+        Run configuration YAML file (providers, models, concurrency, runs,
+        warmup, prompts, output, output_format, sweep); flags passed
+        explicitly override its values
+  -secrets string
+        YAML file of provider: API key entries (openai: sk-..., gemini: ...),
+        so keys stay out of .env and the process environment; keys set in
+        the environment or .env take precedence (default off)
+  -providers string
+        Comma-separated providers to benchmark (default: all with credentials)
+  -include-models string
+        Comma-separated regular expressions; only models whose name or
+        provider/model matches one of them in full are benchmarked,
+        e.g. 'gpt-4o.*,groq/llama-.*'
+  -exclude-models string
+        Comma-separated regular expressions; models whose name or
+        provider/model matches one of them in full are skipped, e.g. '.*-preview'
+  -warmup int
+        Unrecorded warm-up requests per model before measuring (default 0)
+  -cooldown duration
+        Pause after each request, including warm-up requests, for providers
+        that penalize bursts; concurrent workers each pause after their own
+        requests (default 0 = none)
+  -interleave
+        Rotate requests across models (A, B, C, A, B, C, ...) instead of running
+        each model's requests back to back, so every model is measured across
+        the whole run rather than in its own window of network conditions
+  -proxy string
+        Send every provider's requests through this proxy
+        (http://[user:pass@]host:port, https:// or socks5://). Takes
+        precedence over HTTP_PROXY, HTTPS_PROXY and NO_PROXY, which apply
+        when it's unset
   -verbose
-        Enable verbose logging
+        Enable verbose logging, including each run's queue wait, TTFT and
+        streaming time
+  -log-format string
+        Format of the log records written to stderr: text (default) or json,
+        one object per line with time, level, msg and fields such as
+        provider, model and error; the results summary stays on stdout
+  -log-level string
+        Minimum level of logged records: debug, info (default), warn or
+        error; verbose progress logs at info, warnings at warn
+  -no-stream
+        Request non-streamed completions so TTFT equals total time
+        (supported by openai and anthropic; other providers keep streaming)
+  -temperature float
+        Temperature for every request, overriding the default (0.7), the
+        models file's sweep and each model's parameters; not sent to OpenAI
+        models that reject it (by default gpt-5, gpt-4.1, gpt-4o, o3 and o4;
+        set fixed_sampling on a model in the models file to override)
+  -max-tokens int
+        max_tokens for every request, overriding the default (1000), the
+        models file's sweep and each model's parameters
+  -top-p float
+        top_p for every request, overriding the default (1) and each model's
+        parameters; not sent to the OpenAI models that reject temperature
+  -normalize-tokens
+        Recount the visible output of every response with one shared
+        reference tokenizer (tiktoken o200k_base, downloaded on first use)
+        whatever the provider, and compute tokens/sec from that count so it
+        is comparable across providers; the provider's own count is kept
+        for cost
+  -visible-tps
+        Compute tokens/sec over visible output only. By default reasoning
+        tokens reported by the provider (o-series, Responses API, Gemini
+        thinking) count as output, as they are billed
+  -measure string
+        Where each run's total time stops: completion, when the provider
+        closes the stream after its completion event, or last-token, at the
+        last streamed content token (the EndTime column keeps the stream
+        close either way) (default "completion")
+  -tokenizer string
+        Token estimator used for token counts and cost: heuristic (~4 chars
+        per token) or tiktoken (OpenAI BPE encodings, downloaded on first use
+        and cached in TIKTOKEN_CACHE_DIR) (default "heuristic")
+  -retries int
+        Extra attempts after a transient error (rate limit, 5xx, timeout) for
+        providers that classify errors; only the final attempt is recorded
+        (default 3)
+  -retry-max-delay duration
+        Cap on the delay before each retry, for runs that must not stall
+        long on a failing provider; a rate-limit response's own Retry-After
+        or reset hint is cut to it too (default 30s)
+  -retry-strategy string
+        Backoff before each retry, capped at -retry-max-delay: exponential
+        (1s, 2s, 4s, ...), fixed (1s every time) or exponential-full-jitter (a
+        random delay up to the exponential one, which spreads out clients
+        retrying together).
+        A rate-limit response's own Retry-After or reset hint takes precedence,
+        up to the same cap (default "exponential")
+  -timeout duration
+        Overall deadline for one benchmark, covering every attempt and the
+        backoff between them. Each attempt's streaming request is separately
+        limited by the request timeout (60s), which should not exceed this
+        (default 5m0s, 0 = none)
+  -provider-timeout string
+        Comma-separated provider=duration pairs, e.g. openai=120s,groq=30s,
+        replacing the 60s request timeout for those providers so slow
+        reasoning models aren't cut off while fast providers fail quickly
+  -histogram-buckets string
+        Comma-separated, ascending TTFT histogram boundaries printed in the
+        summary; bare numbers are milliseconds (default "100ms,250ms,500ms,1s")
+  -percentiles string
+        Comma-separated, ascending TTFT and total time percentiles for the
+        summary and the summary JSON, e.g. 50,90,95,99,99.9; printed on their
+        own line when they go beyond p50/p95/p99 (default "95,99")
+  -trace-dir string
+        Write the arrival time of every streamed content chunk to one NDJSON
+        file per run in this directory, as {"offset_ms", "chunk_len"} lines
+        (default off)
+  -request-ids
+        Generate a UUID for every run, send it as the X-Request-Id header and
+        record it in the RequestID column, to match runs with provider-side
+        logs; sent by every provider except gemini (default off)
+  -summary-json string
+        Also write the run summary, a summary per provider/model and the run
+        metadata to this JSON file for dashboards; durations are in
+        milliseconds; gzip-compressed when the path ends in .gz (default off)
+  -print-summary-line
+        End stdout with a single line of key=value pairs for scripts, e.g.
+        runs=120 success=118 error_rate=0.017 p95_ttft_ms=812 total_cost=0.0431;
+        printed whatever -format is chosen (default off)
+  -max-cost float
+        Halt the run once accumulated cost exceeds this many dollars (default 0 = unlimited)
+  -max-duration duration
+        Halt the run after this much wall-clock time, e.g. 10m; in-flight
+        requests are cancelled and the completed results are still written
+        (default 0 = unlimited)
+  -max-p95-ttft duration
+        Exit with status 3 if p95 TTFT exceeds this, e.g. 800ms (default 0 = unchecked)
+  -max-error-rate float
+        Exit with status 3 if the percentage of failed runs exceeds this
+        (default 0 = unchecked)
+  -per-model-thresholds
+        Check -max-p95-ttft and -max-error-rate for every model rather than
+        across the whole run
+  -compare string
+        Baseline CSV file to compare results against; exits with status 2 on regressions
+  -compare-threshold float
+        Percent change that counts as a regression in -compare mode (default 10)
+  -current string
+        Compare this CSV file against -compare instead of running a benchmark
+  -notify-webhook string
+        Slack/Discord incoming-webhook URL to post the run summary (and any
+        regressions) to; notification failures are logged, not fatal
+        (default: NOTIFY_WEBHOOK_URL)
+  -list
+        Print the provider/model/prompt combinations that would run given the
+        API keys present, -providers, -include-models, -exclude-models and the
+        -config models filter, and how many requests each makes, then exit
+        without validating or calling any API
+  -dry-run
+        Load prompts and models, validate every request against its provider,
+        check pricing and API keys, and print the planned request count and
+        estimated cost range without calling any API; exits with status 1 if
+        anything is invalid
+  -preflight
+        Before running, ping every provider with a one-token request to its
+        first model and abort if any endpoint or key fails; -preflight=warn
+        prints the failures and runs anyway (default off)
+  -strict
+        Exit with a configuration error instead of warning about problems
+        that would skew results, such as benchmarked models with no
+        token_price in models.yaml, whose cost would be reported as $0
   -help
         Show this help message
   -version
-        Show version information
+        Show the version, git commit, build date and Go version
 
 Examples:
   # Basic usage (sequential)
@@ -261,6 +1433,18 @@ Examples:
   # Concurrent execution
   llm-benchmark -concurrent 4
 
+  # Keep Groq within its rate limit without slowing OpenAI down
+  llm-benchmark -concurrent 8 -provider-concurrency groq=2 -runs 10
+
+  # Give OpenAI's reasoning models longer than the 60s default per request
+  llm-benchmark -provider-timeout openai=180s
+
+  # See how latency and throughput change as concurrency rises
+  llm-benchmark -concurrency-sweep 1,2,4,8 -runs 5 -providers groq
+
+  # Find each provider's sustainable concurrency, up to 16
+  llm-benchmark -concurrent 16 -adaptive-concurrency -runs 10
+
   # Multiple runs per model/prompt for latency variance
   llm-benchmark -runs 5
 
@@ -270,24 +1454,100 @@ Examples:
   # Specify prompts directory
   llm-benchmark -prompts ./custom-prompts
 
+  # Embeddings latency instead of chat
+  llm-benchmark -mode embeddings -runs 10
+
+  # Include prompts organized into subdirectories
+  llm-benchmark -prompts ./custom-prompts -recursive-prompts
+
+  # Smoke-test a config change on two prompts
+  llm-benchmark -limit-prompts 2 -runs 1
+
+  # Run a random sample of five prompts, repeatable with its printed seed
+  llm-benchmark -shuffle -limit-prompts 5
+
   # Custom output file
   llm-benchmark -output results/my-benchmark.csv
 
+  # Accumulate results from several runs in one rolling file
+  llm-benchmark -output results/nightly.csv -append
+
   # Use custom models file
   llm-benchmark -models mymodels.yaml
 
   # Verbose logging
   llm-benchmark -verbose
 
+  # JSON log records for automated environments
+  llm-benchmark -verbose -log-format json 2> benchmark.log
+
+  # Check configuration and estimate cost before a long run
+  llm-benchmark -runs 20 -dry-run
+
+  # Confirm every provider's endpoint and key work before a long run
+  llm-benchmark -runs 100 -preflight
+
+  # List what would run for the selected providers
+  llm-benchmark -list -providers groq,openai
+
+  # Only the GPT-4o family, without previews
+  llm-benchmark -include-models 'gpt-4o.*' -exclude-models '.*-preview'
+
+  # Stop after spending $0.50
+  llm-benchmark -max-cost 0.5
+
+  # Stop a long sweep after 10 minutes, keeping the results so far
+  llm-benchmark -concurrency-sweep 1,2,4,8 -max-duration 10m
+
+  # Run and fail if p95 TTFT, tokens/sec or cost worsened >15%% vs a baseline
+  llm-benchmark -compare results/yesterday.csv -compare-threshold 15
+
+  # Capture per-chunk arrival times to analyze streaming smoothness
+  llm-benchmark -trace-dir traces
+
+  # Tag every request with an X-Request-Id recorded in the results
+  llm-benchmark -request-ids
+
+  # Retry up to 5 times but never back off more than 5s in between
+  llm-benchmark -retries 5 -retry-max-delay 5s
+
+  # Track p90 and p99.9 latency alongside the usual percentiles
+  llm-benchmark -runs 100 -percentiles 50,90,95,99,99.9
+
+  # Write a JSON summary alongside the CSV for dashboards
+  llm-benchmark -output results/run.csv -summary-json results/run.json
+
+  # End the output with a key=value line to grep in scripts
+  llm-benchmark -print-summary-line | tail -n 1
+
+  # Fail CI if any model's p95 TTFT exceeds 800ms or more than 5%% of its runs fail
+  llm-benchmark -runs 20 -max-p95-ttft 800ms -max-error-rate 5 -per-model-thresholds
+
+  # Read API keys from a secrets file instead of the environment
+  llm-benchmark -secrets ~/.config/llm-benchmark/secrets.yaml
+
+  # Compare two existing result files without running
+  llm-benchmark -compare results/yesterday.csv -current results/today.csv
+
 Configuration:
   Create a .env file with your API keys:
     OPENAI_API_KEY=sk-...
     GROQ_API_KEY=gsk_...
+    # OPENAI_API_KEY and GROQ_API_KEY take a comma-separated list of keys to rotate
     ANTHROPIC_API_KEY=sk-ant-...
     AZURE_OPENAI_API_KEY=your-azure-api-key
     AZURE_OPENAI_ENDPOINT=https://your-resource.openai.azure.com/
     # AZURE_OPENAI_API_VERSION=2024-02-15-preview
+    # AZURE_OPENAI_AUTH_MODE=aad   (use Azure AD credentials instead of an API key)
     GOOGLE_API_KEY=your-google-api-key
+    PERPLEXITY_API_KEY=pplx-...
+    DEEPSEEK_API_KEY=sk-...
+    XAI_API_KEY=xai-...
+    TOGETHER_API_KEY=...
+    CEREBRAS_API_KEY=csk-...
+    SAMBANOVA_API_KEY=...
+    # NOTIFY_WEBHOOK_URL=https://hooks.slack.com/services/...
+  or pass them in a -secrets file instead
 
   The models.yaml file contains pricing information for different models.
 `, version)
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"runtime/debug"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/megzo/llm-latency-benchmark/internal/benchmark"
+)
+
+func TestExitCode(t *testing.T) {
+	results := []benchmark.BenchmarkResult{
+		{Provider: "openai", Model: "gpt-4.1", Success: true, TTFT: 300 * time.Millisecond},
+		{Provider: "openai", Model: "gpt-4.1", Success: true, TTFT: 900 * time.Millisecond},
+	}
+	breached := benchmark.CheckThresholds(results, benchmark.Thresholds{MaxP95TTFT: 500 * time.Millisecond})
+	met := benchmark.CheckThresholds(results, benchmark.Thresholds{MaxP95TTFT: time.Second})
+
+	tests := []struct {
+		name        string
+		violations  []benchmark.ThresholdViolation
+		regressed   bool
+		interrupted bool
+		want        int
+	}{
+		{name: "thresholds met", violations: met, want: 0},
+		{name: "threshold breached", violations: breached, want: exitThresholdBreached},
+		{name: "breach beats regression", violations: breached, regressed: true, want: exitThresholdBreached},
+		{name: "regression", violations: met, regressed: true, want: exitRegression},
+		{name: "interrupted", interrupted: true, want: exitInterrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.violations, tt.regressed, tt.interrupted); got != tt.want {
+				t.Errorf("exitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatLatencySummary(t *testing.T) {
+	var results []benchmark.BenchmarkResult
+	for i := 1; i <= 5; i++ {
+		results = append(results, benchmark.BenchmarkResult{
+			Success:   true,
+			TTFT:      time.Duration(i) * 100 * time.Millisecond,
+			TotalTime: time.Duration(i) * time.Second,
+		})
+	}
+
+	got := formatLatencySummary(benchmark.CalculateSummary(results))
+
+	for _, want := range []string{
+		"Average TTFT: 300ms\n",
+		"TTFT min/p50/p95/p99/max: 100ms / 300ms / 500ms / 500ms / 500ms\n",
+		"Average total time: 3s\n",
+		"Total time min/p50/p95/p99/max: 1s / 3s / 5s / 5s / 5s\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("formatLatencySummary() missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestFormatLatencySummary_Percentiles(t *testing.T) {
+	summary := benchmark.Summary{
+		TTFTPercentiles:      map[float64]time.Duration{99.9: 800 * time.Millisecond, 90: 300 * time.Millisecond},
+		TotalTimePercentiles: map[float64]time.Duration{95: 2 * time.Second, 99: 3 * time.Second},
+	}
+
+	got := formatLatencySummary(summary)
+	if want := "TTFT p90/p99.9: 300ms / 800ms\n"; !strings.Contains(got, want) {
+		t.Errorf("formatLatencySummary() missing %q in:\n%s", want, got)
+	}
+	// The default p95/p99 are already on the fixed line
+	if strings.Contains(got, "Total time p95/p99") {
+		t.Errorf("formatLatencySummary() repeated the default percentiles:\n%s", got)
+	}
+}
+
+func TestFormatSummaryLine(t *testing.T) {
+	summary := benchmark.Summary{
+		TotalRuns:      120,
+		SuccessfulRuns: 118,
+		FailedRuns:     2,
+		ErrorRate:      2.0 / 120,
+		P95TTFT:        812400 * time.Microsecond,
+		TotalCost:      0.043125,
+	}
+
+	want := "runs=120 success=118 error_rate=0.017 p95_ttft_ms=812 total_cost=0.0431"
+	if got := formatSummaryLine(summary); got != want {
+		t.Errorf("formatSummaryLine() = %q, want %q", got, want)
+	}
+
+	// An empty run keeps every key, so the line parses the same way
+	want = "runs=0 success=0 error_rate=0.000 p95_ttft_ms=0 total_cost=0.0000"
+	if got := formatSummaryLine(benchmark.Summary{}); got != want {
+		t.Errorf("formatSummaryLine() = %q, want %q", got, want)
+	}
+}
+
+func TestResolveBuild(t *testing.T) {
+	stamped := &debug.BuildInfo{
+		GoVersion: "go1.24.4",
+		Main:      debug.Module{Path: "github.com/megzo/llm-latency-benchmark", Version: "v0.0.0-20250601120000-1a2b3c4d5e6f+dirty"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b"},
+			{Key: "vcs.time", Value: "2025-06-01T12:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	tests := []struct {
+		name                  string
+		version, commit, date string
+		info                  *debug.BuildInfo
+		want                  string
+	}{
+		{
+			name:    "ldflags",
+			version: "0.2.0", commit: "9f8e7d6", date: "2025-07-01T08:30:00Z",
+			info: stamped,
+			want: "llm-benchmark v0.2.0 (commit 9f8e7d6, built 2025-07-01T08:30:00Z, go1.24.4)",
+		},
+		{
+			name: "go build from a modified checkout",
+			info: stamped,
+			want: "llm-benchmark v0.1.0 (commit 1a2b3c4d5e6f-dirty, built 2025-06-01T12:00:00Z, go1.24.4)",
+		},
+		{
+			name: "go install of a release",
+			info: &debug.BuildInfo{GoVersion: "go1.24.4", Main: debug.Module{Version: "v0.3.1"}},
+			want: "llm-benchmark v0.3.1 (go1.24.4)",
+		},
+		{
+			name: "no build info",
+			want: "llm-benchmark v0.1.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveBuild(tt.version, tt.commit, tt.date, tt.info).String(); got != tt.want {
+				t.Errorf("resolveBuild().String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProviderConcurrency(t *testing.T) {
+	pools, err := parseProviderConcurrency("groq=2, openai = 8")
+	if err != nil {
+		t.Fatalf("parseProviderConcurrency() error = %v", err)
+	}
+	if len(pools) != 2 || pools["groq"] != 2 || pools["openai"] != 8 {
+		t.Errorf("parseProviderConcurrency() = %v, want groq=2 openai=8", pools)
+	}
+
+	for _, value := range []string{"groq", "groq=0", "groq=two"} {
+		if _, err := parseProviderConcurrency(value); err == nil {
+			t.Errorf("parseProviderConcurrency(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestParseProviderTimeouts(t *testing.T) {
+	timeouts, err := parseProviderTimeouts("openai=2m, groq = 30s")
+	if err != nil {
+		t.Fatalf("parseProviderTimeouts() error = %v", err)
+	}
+	if len(timeouts) != 2 || timeouts["openai"] != 2*time.Minute || timeouts["groq"] != 30*time.Second {
+		t.Errorf("parseProviderTimeouts() = %v, want openai=2m groq=30s", timeouts)
+	}
+
+	for _, value := range []string{"openai", "openai=0s", "openai=120", "openai=-1s"} {
+		if _, err := parseProviderTimeouts(value); err == nil {
+			t.Errorf("parseProviderTimeouts(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestPreflightMode(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var mode preflightMode
+	fs.Var(&mode, "preflight", "")
+
+	tests := []struct {
+		args []string
+		want preflightMode
+	}{
+		{nil, preflightOff},
+		{[]string{"-preflight"}, preflightAbort},
+		{[]string{"-preflight=warn"}, preflightWarn},
+		{[]string{"-preflight=false"}, preflightOff},
+	}
+	for _, tt := range tests {
+		mode = preflightOff
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatalf("Parse(%v) error = %v", tt.args, err)
+		}
+		if mode != tt.want {
+			t.Errorf("Parse(%v) mode = %q, want %q", tt.args, mode, tt.want)
+		}
+	}
+
+	if err := mode.Set("sometimes"); err == nil {
+		t.Error("Set(\"sometimes\") succeeded, want an error")
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, "json", "warn")
+	if err != nil {
+		t.Fatalf("newLogger() error = %v", err)
+	}
+	logger.Info("hidden below warn")
+	logger.Warn("Failed to create provider", "provider", "groq")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("log output %q is not one JSON record: %v", buf.String(), err)
+	}
+	if record["level"] != "WARN" || record["msg"] != "Failed to create provider" || record["provider"] != "groq" {
+		t.Errorf("record = %v, want a WARN record for provider groq", record)
+	}
+
+	if _, err := newLogger(&buf, "xml", "info"); err == nil {
+		t.Error("expected an error for an unknown log format")
+	}
+	if _, err := newLogger(&buf, "text", "loud"); err == nil {
+		t.Error("expected an error for an unknown log level")
+	}
+}
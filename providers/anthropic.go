@@ -2,24 +2,40 @@ package providers
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/anthropics/anthropic-sdk-go/packages/param"
+
+	"github.com/megzo/llm-latency-benchmark/internal/retry"
+	"github.com/megzo/llm-latency-benchmark/internal/tokenizer"
 )
 
 // AnthropicProvider implements the Provider interface for Anthropic
 type AnthropicProvider struct {
-	client anthropic.Client
-	config *AnthropicConfig
+	client        anthropic.Client
+	config        *AnthropicConfig
+	tokenizer     tokenizer.Tokenizer
+	retryStrategy retry.Strategy
+	retryMaxDelay time.Duration
 }
 
 // AnthropicConfig holds Anthropic-specific configuration
 type AnthropicConfig struct {
 	APIKey  string
 	BaseURL string
+	// Tokenizer estimates token counts; nil uses the default heuristic
+	Tokenizer tokenizer.Tokenizer
+	// RetryStrategy selects the backoff between retries; "" uses retry.Default
+	RetryStrategy retry.Strategy
+	// RetryMaxDelay caps the backoff between retries; 0 uses retry.MaxDelay
+	RetryMaxDelay time.Duration
+	// ProxyURL sends requests through this proxy instead of the one HTTP_PROXY or
+	// HTTPS_PROXY name; "" honors those variables
+	ProxyURL string
 }
 
 // NewAnthropicProvider creates a new Anthropic provider instance
@@ -36,14 +52,23 @@ func NewAnthropicProvider(config *AnthropicConfig) (*AnthropicProvider, error) {
 		config.BaseURL = "https://api.anthropic.com"
 	}
 
+	httpClient, err := newHTTPClient(config.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+
 	client := anthropic.NewClient(
 		option.WithAPIKey(config.APIKey),
 		option.WithBaseURL(config.BaseURL),
+		option.WithHTTPClient(httpClient),
 	)
 
 	return &AnthropicProvider{
-		client: client,
-		config: config,
+		client:        client,
+		config:        config,
+		tokenizer:     tokenizer.OrDefault(config.Tokenizer),
+		retryStrategy: config.RetryStrategy,
+		retryMaxDelay: config.RetryMaxDelay,
 	}, nil
 }
 
@@ -54,15 +79,14 @@ func (p *AnthropicProvider) Name() string {
 
 // StreamChat performs a streaming chat completion
 func (p *AnthropicProvider) StreamChat(ctx context.Context, req ChatRequest) (<-chan ChatResponse, error) {
+	thinkingBudget, err := anthropicThinkingBudget(req.ExtraParams)
+	if err != nil {
+		return nil, err
+	}
+
 	responseChan := make(chan ChatResponse)
 
-	// Build messages for Anthropic API
-	messages := []anthropic.MessageParam{}
-	
-	// Add user message
-	messages = append(messages, anthropic.NewUserMessage(
-		anthropic.NewTextBlock(req.UserPrompt),
-	))
+	messages, system := anthropicMessages(req)
 
 	// Create the request parameters
 	params := anthropic.MessageNewParams{
@@ -71,32 +95,42 @@ func (p *AnthropicProvider) StreamChat(ctx context.Context, req ChatRequest) (<-
 		MaxTokens: int64(req.MaxTokens),
 	}
 	
-	if req.Temperature > 0 {
-		params.Temperature = param.NewOpt(req.Temperature)
-	}
-	if req.TopP > 0 {
-		params.TopP = param.NewOpt(req.TopP)
+	if thinkingBudget > 0 {
+		// Extended thinking only runs at the default sampling settings, so the
+		// request's temperature and top_p are left out rather than rejected
+		params.Thinking = anthropic.ThinkingConfigParamOfEnabled(thinkingBudget)
+	} else {
+		if req.Temperature != nil {
+			params.Temperature = param.NewOpt(*req.Temperature)
+		}
+		if req.TopP > 0 {
+			params.TopP = param.NewOpt(req.TopP)
+		}
 	}
+	params.StopSequences = req.StopSequences()
 
 	// Add system prompt if provided
-	if req.SystemPrompt != "" {
-		params.System = []anthropic.TextBlockParam{
-			{Text: req.SystemPrompt},
-		}
+	if len(system) > 0 {
+		params.System = system
+	}
+
+	if req.NoStream {
+		go p.completeChat(ctx, params, anthropicRequestIDOptions(req.RequestID), responseChan)
+		return responseChan, nil
 	}
 
 	go func() {
 		defer close(responseChan)
 		
 		// Create streaming completion
-		stream := p.client.Messages.NewStreaming(ctx, params)
+		stream := p.client.Messages.NewStreaming(ctx, params, anthropicRequestIDOptions(req.RequestID)...)
 		
 		message := anthropic.Message{}
 		for stream.Next() {
 			event := stream.Current()
 			err := message.Accumulate(event)
 			if err != nil {
-				responseChan <- ChatResponse{
+				sendResponse(ctx, responseChan, ChatResponse{
 					Content:    "",
 					IsComplete: true,
 					Timestamp:  time.Now(),
@@ -105,36 +139,61 @@ func (p *AnthropicProvider) StreamChat(ctx context.Context, req ChatRequest) (<-
 						Message:  "failed to accumulate stream event",
 						Cause:    err,
 					},
-				}
+				})
 				return
 			}
 			
 			// Handle different types of content
 			switch eventVariant := event.AsAny().(type) {
+			case anthropic.ContentBlockStartEvent:
+				// Redacted thinking arrives whole and encrypted, with no deltas to time
+				if eventVariant.ContentBlock.Type == "redacted_thinking" {
+					if !sendResponse(ctx, responseChan, ChatResponse{
+						RedactedReasoning: true,
+						IsComplete:        false,
+						Timestamp:         time.Now(),
+					}) {
+						return
+					}
+				}
 			case anthropic.ContentBlockDeltaEvent:
 				switch deltaVariant := eventVariant.Delta.AsAny().(type) {
+				case anthropic.ThinkingDelta:
+					if deltaVariant.Thinking != "" {
+						if !sendResponse(ctx, responseChan, ChatResponse{
+							ReasoningContent: deltaVariant.Thinking,
+							IsComplete:       false,
+							Timestamp:        time.Now(),
+						}) {
+							return
+						}
+					}
 				case anthropic.TextDelta:
 					if deltaVariant.Text != "" {
-						responseChan <- ChatResponse{
+						if !sendResponse(ctx, responseChan, ChatResponse{
 							Content:    deltaVariant.Text,
 							IsComplete: false,
 							Timestamp:  time.Now(),
+						}) {
+							return
 						}
 					}
 				}
 			case anthropic.MessageStopEvent:
-				responseChan <- ChatResponse{
-					Content:    "",
-					IsComplete: true,
-					Timestamp:  time.Now(),
-				}
+				sendResponse(ctx, responseChan, ChatResponse{
+					Content:      "",
+					IsComplete:   true,
+					Timestamp:    time.Now(),
+					FinishReason: string(message.StopReason),
+					Usage:        anthropicUsage(message.Usage),
+				})
 				return
 			}
 		}
 		
 		// Check for errors
 		if err := stream.Err(); err != nil {
-			responseChan <- ChatResponse{
+			sendResponse(ctx, responseChan, ChatResponse{
 				Content:    "",
 				IsComplete: true,
 				Timestamp:  time.Now(),
@@ -143,47 +202,183 @@ func (p *AnthropicProvider) StreamChat(ctx context.Context, req ChatRequest) (<-
 					Message:  "failed to receive stream response",
 					Cause:    err,
 				},
-			}
+			})
 			return
 		}
 		
 		// Stream completed successfully
-		responseChan <- ChatResponse{
+		sendResponse(ctx, responseChan, ChatResponse{
 			Content:    "",
 			IsComplete: true,
 			Timestamp:  time.Now(),
-		}
+		})
 	}()
 	return responseChan, nil
 }
 
-// TokenCount returns the token counts for a response
-// Note: Anthropic doesn't provide token counts in streaming responses
-// This is a simplified implementation - in practice, you might want to
-// use a tokenizer library for accurate counting
-func (p *AnthropicProvider) TokenCount(response ChatResponse) (input, output, total int) {
-	// This is a rough estimation - for production use, consider using
-	// a proper tokenizer like tiktoken or similar
-	if response.Content != "" {
-		// Rough estimation: ~4 characters per token for English text
-		output = len(response.Content) / 4
-		if output < 1 {
-			output = 1
+// anthropicMessages builds the request's messages; system messages go into
+// Anthropic's separate system field
+func anthropicMessages(req ChatRequest) ([]anthropic.MessageParam, []anthropic.TextBlockParam) {
+	messages := []anthropic.MessageParam{}
+	system := []anthropic.TextBlockParam{}
+	for _, message := range req.ChatMessages() {
+		switch message.Role {
+		case "system":
+			system = append(system, anthropic.TextBlockParam{Text: message.Content})
+		case "assistant":
+			messages = append(messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(message.Content)))
+		default:
+			messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(message.Content)))
 		}
 	}
-	
+	// The image goes ahead of the last message's text, as Anthropic recommends;
+	// the last message is always the user's
+	if req.Image != nil && len(messages) > 0 {
+		last := &messages[len(messages)-1]
+		last.Content = append([]anthropic.ContentBlockParamUnion{anthropicImageBlock(req.Image)}, last.Content...)
+	}
+	return messages, system
+}
+
+// anthropicImageBlock sends the image by URL, or inline as base64 data
+func anthropicImageBlock(image *Image) anthropic.ContentBlockParamUnion {
+	if image.URL != "" {
+		return anthropic.NewImageBlock(anthropic.URLImageSourceParam{URL: image.URL})
+	}
+	return anthropic.NewImageBlockBase64(image.MediaType, image.Base64())
+}
+
+// CountInputTokens counts the request's prompt tokens with the /v1/messages/count_tokens endpoint
+func (p *AnthropicProvider) CountInputTokens(ctx context.Context, req ChatRequest) (int, error) {
+	messages, system := anthropicMessages(req)
+	params := anthropic.MessageCountTokensParams{
+		Model:    anthropic.Model(req.Model),
+		Messages: messages,
+	}
+	if len(system) > 0 {
+		params.System = anthropic.MessageCountTokensParamsSystemUnion{OfTextBlockArray: system}
+	}
+
+	count, err := p.client.Messages.CountTokens(ctx, params)
+	if err != nil {
+		return 0, &ProviderError{
+			Provider: "anthropic",
+			Message:  "failed to count input tokens",
+			Cause:    err,
+		}
+	}
+	return int(count.InputTokens), nil
+}
+
+// anthropicMinThinkingBudget is the smallest budget_tokens the API accepts
+const anthropicMinThinkingBudget = 1024
+
+// anthropicThinkingBudget returns budget_tokens from a "thinking" parameter
+// enabling extended thinking ({type: enabled, budget_tokens: N}), or 0 when the
+// parameter is absent or disables it
+func anthropicThinkingBudget(params map[string]interface{}) (int64, error) {
+	thinking, ok := params["thinking"].(map[string]interface{})
+	if !ok || thinking["type"] != "enabled" {
+		return 0, nil
+	}
+
+	var budget int64
+	switch value := thinking["budget_tokens"].(type) {
+	case int:
+		budget = int64(value)
+	case int64:
+		budget = value
+	case float64:
+		budget = int64(value)
+	}
+	if budget < anthropicMinThinkingBudget {
+		return 0, &ValidationError{
+			Field:   "thinking",
+			Message: fmt.Sprintf("thinking budget_tokens must be at least %d", anthropicMinThinkingBudget),
+		}
+	}
+	return budget, nil
+}
+
+// anthropicRequestIDOptions sends id as the request ID header on an SDK request;
+// an empty id adds nothing
+func anthropicRequestIDOptions(id string) []option.RequestOption {
+	if id == "" {
+		return nil
+	}
+	return []option.RequestOption{option.WithHeader(RequestIDHeader, id)}
+}
+
+// completeChat performs a non-streaming message request and emits the full text
+// as a single complete response
+func (p *AnthropicProvider) completeChat(ctx context.Context, params anthropic.MessageNewParams, opts []option.RequestOption, responseChan chan<- ChatResponse) {
+	defer close(responseChan)
+
+	message, err := p.client.Messages.New(ctx, params, opts...)
+	if err != nil {
+		sendResponse(ctx, responseChan, ChatResponse{
+			Content:    "",
+			IsComplete: true,
+			Timestamp:  time.Now(),
+			Error: &ProviderError{
+				Provider: "anthropic",
+				Message:  "failed to create message",
+				Cause:    err,
+			},
+		})
+		return
+	}
+
+	var content, thinking strings.Builder
+	for _, block := range message.Content {
+		switch block.Type {
+		case "text":
+			content.WriteString(block.Text)
+		case "thinking":
+			thinking.WriteString(block.Thinking)
+		}
+	}
+	sendResponse(ctx, responseChan, ChatResponse{
+		Content:          content.String(),
+		ReasoningContent: thinking.String(),
+		IsComplete:       true,
+		Timestamp:    time.Now(),
+		FinishReason: string(message.StopReason),
+		Usage:        anthropicUsage(message.Usage),
+	})
+}
+
+// anthropicUsage converts message usage, returning nil when none was reported.
+// Anthropic's input_tokens excludes cache reads and writes, so they're added back
+// to give the full prompt size like other providers report.
+func anthropicUsage(u anthropic.Usage) *Usage {
+	input := u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens
+	if input == 0 && u.OutputTokens == 0 {
+		return nil
+	}
+	return &Usage{
+		InputTokens:       int(input),
+		OutputTokens:      int(u.OutputTokens),
+		CachedInputTokens: int(u.CacheReadInputTokens),
+	}
+}
+
+// TokenCount returns the token counts for a response
+// Note: streaming responses don't include token counts, so output tokens are
+// estimated with the provider's tokenizer
+func (p *AnthropicProvider) TokenCount(response ChatResponse) (input, output, total int) {
+	output = p.Tokenizer().Count("", response.Content)
 	return 0, output, output
 }
 
 // GetTokenCount estimates token count for input text
-// This is a simplified implementation - consider using a proper tokenizer
 func (p *AnthropicProvider) GetTokenCount(text string) int {
-	// Rough estimation: ~4 characters per token for English text
-	count := len(text) / 4
-	if count < 1 {
-		count = 1
-	}
-	return count
+	return p.Tokenizer().Count("", text)
+}
+
+// Tokenizer returns the tokenizer used for token estimates
+func (p *AnthropicProvider) Tokenizer() tokenizer.Tokenizer {
+	return tokenizer.OrDefault(p.tokenizer)
 }
 
 // ValidateRequest validates the chat request
@@ -195,7 +390,7 @@ func (p *AnthropicProvider) ValidateRequest(req ChatRequest) error {
 		}
 	}
 
-	if req.UserPrompt == "" {
+	if req.UserPrompt == "" && len(req.Messages) == 0 {
 		return &ValidationError{
 			Field:   "user_prompt",
 			Message: "user prompt is required",
@@ -209,7 +404,7 @@ func (p *AnthropicProvider) ValidateRequest(req ChatRequest) error {
 		}
 	}
 
-	if req.Temperature < 0 || req.Temperature > 1 {
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 1) {
 		return &ValidationError{
 			Field:   "temperature",
 			Message: "temperature must be between 0 and 1",
@@ -223,6 +418,18 @@ func (p *AnthropicProvider) ValidateRequest(req ChatRequest) error {
 		}
 	}
 
+	// The thinking budget is part of max_tokens, leaving the rest for the answer
+	budget, err := anthropicThinkingBudget(req.ExtraParams)
+	if err != nil {
+		return err
+	}
+	if budget > 0 && budget >= int64(req.MaxTokens) {
+		return &ValidationError{
+			Field:   "thinking",
+			Message: "thinking budget_tokens must be less than max_tokens",
+		}
+	}
+
 	return nil
 }
 
@@ -264,16 +471,5 @@ func (p *AnthropicProvider) IsRetryableError(err error) bool {
 
 // GetRetryDelay calculates the delay before retrying
 func (p *AnthropicProvider) GetRetryDelay(attempt int, err error) time.Duration {
-	// Base delay with exponential backoff
-	baseDelay := time.Duration(attempt*attempt) * time.Second
-	
-	// Cap at 30 seconds
-	if baseDelay > 30*time.Second {
-		baseDelay = 30 * time.Second
-	}
-	
-	// Add jitter to prevent thundering herd
-	jitter := time.Duration(attempt) * 100 * time.Millisecond
-	
-	return baseDelay + jitter
+	return retryDelay(p.retryStrategy, p.retryMaxDelay, attempt, err)
 } 
\ No newline at end of file
@@ -1,6 +1,11 @@
 package providers
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -106,7 +111,7 @@ func TestAnthropicProvider_ValidateRequest(t *testing.T) {
 			request: ChatRequest{
 				Model:       "claude-3-sonnet",
 				UserPrompt:  "Hello, world!",
-				Temperature: 1.5,
+				Temperature: float64Ptr(1.5),
 			},
 			wantErr: true,
 		},
@@ -115,7 +120,7 @@ func TestAnthropicProvider_ValidateRequest(t *testing.T) {
 			request: ChatRequest{
 				Model:       "claude-3-sonnet",
 				UserPrompt:  "Hello, world!",
-				Temperature: -0.1,
+				Temperature: float64Ptr(-0.1),
 			},
 			wantErr: true,
 		},
@@ -178,7 +183,7 @@ func TestAnthropicProvider_TokenCount(t *testing.T) {
 				Content: "This is a longer response with more tokens to count",
 			},
 			wantInput:  0,
-			wantOutput: 12, // 48 chars / 4 = 12
+			wantOutput: 12, // 51 chars / 4 = 12
 			wantTotal:  12,
 		},
 	}
@@ -226,7 +231,7 @@ func TestAnthropicProvider_GetTokenCount(t *testing.T) {
 		{
 			name: "longer text",
 			text: "This is a longer text with more tokens to count",
-			want: 11, // 44 chars / 4 = 11
+			want: 11, // 47 chars / 4 = 11
 		},
 	}
 
@@ -328,8 +333,8 @@ func TestAnthropicProvider_GetRetryDelay(t *testing.T) {
 			name:     "second attempt",
 			attempt:  2,
 			err:      &ProviderError{Provider: "anthropic", Message: "500 error"},
-			wantMin:  4 * time.Second,
-			wantMax:  5 * time.Second,
+			wantMin:  2 * time.Second,
+			wantMax:  3 * time.Second,
 		},
 		{
 			name:     "high attempt (should be capped)",
@@ -348,4 +353,415 @@ func TestAnthropicProvider_GetRetryDelay(t *testing.T) {
 			}
 		})
 	}
-} 
\ No newline at end of file
+} 
+func TestAnthropicProvider_NoStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"Hello "},{"type":"text","text":"there!"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":4}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewAnthropicProvider(&AnthropicConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:      "claude-sonnet-4-20250514",
+		UserPrompt: "Hello",
+		MaxTokens:  100,
+		NoStream:   true,
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+
+	responses := collectResponses(t, responseChan)
+	if len(responses) != 1 {
+		t.Fatalf("expected a single response, got %d", len(responses))
+	}
+	if responses[0].Error != nil {
+		t.Fatalf("unexpected error: %v", responses[0].Error)
+	}
+	if !responses[0].IsComplete || responses[0].Content != "Hello there!" {
+		t.Errorf("response = %+v, want complete response with full content", responses[0])
+	}
+	if responses[0].FinishReason != "end_turn" {
+		t.Errorf("FinishReason = %q, want %q", responses[0].FinishReason, "end_turn")
+	}
+}
+
+func TestAnthropicProvider_StopReasonMaxTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`event: message_start` + "\n" + `data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"stop_reason":null,"usage":{"input_tokens":10,"output_tokens":1}}}`,
+			`event: content_block_start` + "\n" + `data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`event: content_block_delta` + "\n" + `data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+			`event: content_block_stop` + "\n" + `data: {"type":"content_block_stop","index":0}`,
+			`event: message_delta` + "\n" + `data: {"type":"message_delta","delta":{"stop_reason":"max_tokens","stop_sequence":null},"usage":{"output_tokens":1}}`,
+			`event: message_stop` + "\n" + `data: {"type":"message_stop"}`,
+		} {
+			w.Write([]byte(event + "\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewAnthropicProvider(&AnthropicConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:      "claude-sonnet-4-20250514",
+		UserPrompt: "Hello",
+		MaxTokens:  1,
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+
+	responses := collectResponses(t, responseChan)
+	if len(responses) != 2 {
+		t.Fatalf("expected 2 responses, got %d: %+v", len(responses), responses)
+	}
+	if responses[0].Content != "Hello" {
+		t.Errorf("Content = %q, want %q", responses[0].Content, "Hello")
+	}
+	last := responses[1]
+	if last.Error != nil {
+		t.Fatalf("unexpected error: %v", last.Error)
+	}
+	if !last.IsComplete || last.FinishReason != "max_tokens" {
+		t.Errorf("final response = %+v, want complete with FinishReason max_tokens", last)
+	}
+	if !IsTruncated(last.FinishReason) {
+		t.Errorf("IsTruncated(%q) = false, want true", last.FinishReason)
+	}
+}
+
+func TestAnthropicProvider_SendsImage(t *testing.T) {
+	tests := []struct {
+		name       string
+		image      *Image
+		wantSource map[string]string
+	}{
+		{"local file", &Image{MediaType: "image/png", Data: []byte("png")}, map[string]string{"type": "base64", "media_type": "image/png", "data": "cG5n"}},
+		{"URL", &Image{URL: "https://example.com/chart.png", MediaType: "image/png"}, map[string]string{"type": "url", "url": "https://example.com/chart.png"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body struct {
+				Messages []struct {
+					Content []struct {
+						Type   string            `json:"type"`
+						Text   string            `json:"text"`
+						Source map[string]string `json:"source"`
+					} `json:"content"`
+				} `json:"messages"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&body)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"A bar chart."}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":4}}`))
+			}))
+			defer server.Close()
+
+			provider, err := NewAnthropicProvider(&AnthropicConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:      "claude-sonnet-4-20250514",
+				UserPrompt: "Describe this chart.",
+				Image:      tt.image,
+				MaxTokens:  100,
+				NoStream:   true,
+			})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			collectResponses(t, responseChan)
+
+			// The image block comes ahead of the text
+			if len(body.Messages) != 1 || len(body.Messages[0].Content) != 2 {
+				t.Fatalf("messages = %+v, want one message with an image and a text block", body.Messages)
+			}
+			image, text := body.Messages[0].Content[0], body.Messages[0].Content[1]
+			if image.Type != "image" || !reflect.DeepEqual(image.Source, tt.wantSource) {
+				t.Errorf("image block = %+v, want source %v", image, tt.wantSource)
+			}
+			if text.Type != "text" || text.Text != "Describe this chart." {
+				t.Errorf("text block = %+v, want the prompt text", text)
+			}
+		})
+	}
+}
+
+func TestAnthropicProvider_ForwardsMessages(t *testing.T) {
+	var body struct {
+		System []struct {
+			Text string `json:"text"`
+		} `json:"system"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"Rome."}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewAnthropicProvider(&AnthropicConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:        "claude-sonnet-4-20250514",
+		SystemPrompt: "Be brief.",
+		Messages: []Message{
+			{Role: "user", Content: "What is the capital of France?"},
+			{Role: "assistant", Content: "Paris."},
+			{Role: "user", Content: "And of Italy?"},
+		},
+		MaxTokens: 100,
+		NoStream:  true,
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	collectResponses(t, responseChan)
+
+	if len(body.System) != 1 || body.System[0].Text != "Be brief." {
+		t.Errorf("system = %+v, want a single \"Be brief.\" block", body.System)
+	}
+
+	want := []Message{
+		{Role: "user", Content: "What is the capital of France?"},
+		{Role: "assistant", Content: "Paris."},
+		{Role: "user", Content: "And of Italy?"},
+	}
+	if len(body.Messages) != len(want) {
+		t.Fatalf("sent %d messages, want %d", len(body.Messages), len(want))
+	}
+	for i, message := range body.Messages {
+		if message.Role != want[i].Role || len(message.Content) != 1 || message.Content[0].Text != want[i].Content {
+			t.Errorf("message %d = %+v, want %+v", i, message, want[i])
+		}
+	}
+}
+
+func TestAnthropicProvider_StreamUsageWithCacheHits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`event: message_start` + "\n" + `data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"stop_reason":null,"usage":{"input_tokens":20,"cache_read_input_tokens":1800,"cache_creation_input_tokens":200,"output_tokens":1}}}`,
+			`event: content_block_start` + "\n" + `data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`event: content_block_delta` + "\n" + `data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi!"}}`,
+			`event: content_block_stop` + "\n" + `data: {"type":"content_block_stop","index":0}`,
+			`event: message_delta` + "\n" + `data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`,
+			`event: message_stop` + "\n" + `data: {"type":"message_stop"}`,
+		} {
+			w.Write([]byte(event + "\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewAnthropicProvider(&AnthropicConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:      "claude-sonnet-4-20250514",
+		UserPrompt: "Hello",
+		MaxTokens:  100,
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+
+	responses := collectResponses(t, responseChan)
+	final := responses[len(responses)-1]
+	if final.Error != nil {
+		t.Fatalf("unexpected error: %v", final.Error)
+	}
+	// input_tokens excludes cache reads and writes, which are added back
+	want := Usage{InputTokens: 2020, OutputTokens: 5, CachedInputTokens: 1800}
+	if final.Usage == nil || *final.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", final.Usage, want)
+	}
+}
+
+func TestAnthropicProvider_ExtendedThinking(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&gotBody)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		write := func(events ...string) {
+			for _, event := range events {
+				w.Write([]byte(event + "\n\n"))
+			}
+			w.(http.Flusher).Flush()
+		}
+		write(
+			`event: message_start`+"\n"+`data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"stop_reason":null,"usage":{"input_tokens":20,"output_tokens":1}}}`,
+			`event: content_block_start`+"\n"+`data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":"","signature":""}}`,
+			`event: content_block_delta`+"\n"+`data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"The user said hello."}}`,
+			`event: content_block_delta`+"\n"+`data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"EqQBCgIYAhIM"}}`,
+			`event: content_block_stop`+"\n"+`data: {"type":"content_block_stop","index":0}`,
+			`event: content_block_start`+"\n"+`data: {"type":"content_block_start","index":1,"content_block":{"type":"redacted_thinking","data":"EmwKAhgBEgy3va3pzix"}}`,
+			`event: content_block_stop`+"\n"+`data: {"type":"content_block_stop","index":1}`,
+		)
+		// Thinking takes a while before the answer starts
+		time.Sleep(30 * time.Millisecond)
+		write(
+			`event: content_block_start`+"\n"+`data: {"type":"content_block_start","index":2,"content_block":{"type":"text","text":""}}`,
+			`event: content_block_delta`+"\n"+`data: {"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":"Hi!"}}`,
+			`event: content_block_stop`+"\n"+`data: {"type":"content_block_stop","index":2}`,
+			`event: message_delta`+"\n"+`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":120}}`,
+			`event: message_stop`+"\n"+`data: {"type":"message_stop"}`,
+		)
+	}))
+	defer server.Close()
+
+	provider, err := NewAnthropicProvider(&AnthropicConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:       "claude-sonnet-4-20250514",
+		UserPrompt:  "Hello",
+		MaxTokens:   4096,
+		Temperature: float64Ptr(0.7),
+		ExtraParams: map[string]interface{}{
+			"thinking": map[string]interface{}{"type": "enabled", "budget_tokens": 2048},
+		},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	responses := collectResponses(t, responseChan)
+
+	thinking, _ := gotBody["thinking"].(map[string]interface{})
+	if thinking["type"] != "enabled" || thinking["budget_tokens"] != float64(2048) {
+		t.Errorf("thinking = %v, want enabled with a 2048 token budget", gotBody["thinking"])
+	}
+	if _, ok := gotBody["temperature"]; ok {
+		t.Errorf("temperature = %v, want it left out with thinking enabled", gotBody["temperature"])
+	}
+
+	// Thinking is kept apart from the answer, and timed on its own
+	var reasoning, content string
+	var firstThinking, firstText time.Time
+	var redacted bool
+	for _, response := range responses {
+		if response.Error != nil {
+			t.Fatalf("unexpected error: %v", response.Error)
+		}
+		if response.ReasoningContent != "" && firstThinking.IsZero() {
+			firstThinking = response.Timestamp
+		}
+		if response.Content != "" && firstText.IsZero() {
+			firstText = response.Timestamp
+		}
+		reasoning += response.ReasoningContent
+		content += response.Content
+		redacted = redacted || response.RedactedReasoning
+	}
+	if reasoning != "The user said hello." || content != "Hi!" {
+		t.Errorf("reasoning = %q, content = %q, want the thinking and the answer apart", reasoning, content)
+	}
+	if !redacted {
+		t.Error("the redacted thinking block wasn't reported")
+	}
+	// The server pauses 30ms between the bursts; allow for the first one being read late
+	if firstThinking.IsZero() || !firstText.After(firstThinking) || firstText.Sub(firstThinking) < 15*time.Millisecond {
+		t.Errorf("first text %v after first thinking, want at least 15ms", firstText.Sub(firstThinking))
+	}
+}
+
+func TestAnthropicProvider_ThinkingBudgetValidation(t *testing.T) {
+	provider, err := NewAnthropicProvider(&AnthropicConfig{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	thinking := func(budget interface{}) map[string]interface{} {
+		return map[string]interface{}{"thinking": map[string]interface{}{"type": "enabled", "budget_tokens": budget}}
+	}
+
+	tests := []struct {
+		name    string
+		request ChatRequest
+		wantErr bool
+	}{
+		{"budget within max_tokens", ChatRequest{Model: "claude-sonnet-4-20250514", UserPrompt: "Hello", MaxTokens: 4096, ExtraParams: thinking(2048)}, false},
+		{"budget from JSON", ChatRequest{Model: "claude-sonnet-4-20250514", UserPrompt: "Hello", MaxTokens: 4096, ExtraParams: thinking(float64(2048))}, false},
+		{"thinking disabled", ChatRequest{Model: "claude-sonnet-4-20250514", UserPrompt: "Hello", MaxTokens: 100, ExtraParams: map[string]interface{}{"thinking": map[string]interface{}{"type": "disabled"}}}, false},
+		{"budget below the minimum", ChatRequest{Model: "claude-sonnet-4-20250514", UserPrompt: "Hello", MaxTokens: 4096, ExtraParams: thinking(512)}, true},
+		{"budget not below max_tokens", ChatRequest{Model: "claude-sonnet-4-20250514", UserPrompt: "Hello", MaxTokens: 1000, ExtraParams: thinking(2048)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := provider.ValidateRequest(tt.request); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAnthropicProvider_CountInputTokens(t *testing.T) {
+	var path string
+	var body struct {
+		Model  string `json:"model"`
+		System []struct {
+			Text string `json:"text"`
+		} `json:"system"`
+		Messages []json.RawMessage `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"input_tokens":42}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewAnthropicProvider(&AnthropicConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	tokens, err := provider.CountInputTokens(context.Background(), ChatRequest{
+		Model:        "claude-sonnet-4-20250514",
+		SystemPrompt: "Be brief.",
+		UserPrompt:   "What is the capital of France?",
+		MaxTokens:    100,
+	})
+	if err != nil {
+		t.Fatalf("CountInputTokens() error = %v", err)
+	}
+	if tokens != 42 {
+		t.Errorf("CountInputTokens() = %d, want 42", tokens)
+	}
+	if path != "/v1/messages/count_tokens" {
+		t.Errorf("request path = %s, want /v1/messages/count_tokens", path)
+	}
+	if body.Model != "claude-sonnet-4-20250514" || len(body.System) != 1 || body.System[0].Text != "Be brief." || len(body.Messages) != 1 {
+		t.Errorf("request body = %+v, want the model, system prompt and one message", body)
+	}
+}
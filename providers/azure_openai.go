@@ -2,25 +2,45 @@ package providers
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
+    "github.com/Azure/azure-sdk-for-go/sdk/azcore"
     "github.com/openai/openai-go/v2"
     "github.com/openai/openai-go/v2/azure"
     "github.com/openai/openai-go/v2/option"
+
+    "github.com/megzo/llm-latency-benchmark/internal/retry"
+    "github.com/megzo/llm-latency-benchmark/internal/tokenizer"
 )
 
 // AzureOpenAIProvider implements the Provider interface for Azure OpenAI
 type AzureOpenAIProvider struct {
-	client openai.Client
-	config *AzureOpenAIConfig
+	client        openai.Client
+	config        *AzureOpenAIConfig
+	tokenizer     tokenizer.Tokenizer
+	retryStrategy retry.Strategy
+	retryMaxDelay time.Duration
 }
 
+// Azure OpenAI authentication modes
+const (
+	AzureAuthAPIKey = "api_key" // static API key (default)
+	AzureAuthAAD    = "aad"     // Azure AD (Entra) token credential
+)
+
 // AzureOpenAIConfig holds Azure OpenAI-specific configuration
 type AzureOpenAIConfig struct {
 	Endpoint        string
 	APIKey          string
 	APIVersion      string
+	AuthMode        string                 // AzureAuthAPIKey (default) or AzureAuthAAD
+	Credential      azcore.TokenCredential // required when AuthMode is AzureAuthAAD
+	Tokenizer       tokenizer.Tokenizer    // estimates token counts; nil uses the default heuristic
+	RetryStrategy   retry.Strategy         // backoff between retries; "" uses retry.Default
+	RetryMaxDelay   time.Duration          // cap on the backoff between retries; 0 uses retry.MaxDelay
+	ProxyURL        string                 // proxy for requests instead of HTTP(S)_PROXY; "" honors those
 }
 
 // NewAzureOpenAIProvider creates a new Azure OpenAI provider instance
@@ -32,10 +52,28 @@ func NewAzureOpenAIProvider(config *AzureOpenAIConfig) (*AzureOpenAIProvider, er
 		}
 	}
 
-	if config.APIKey == "" {
+	var authOption option.RequestOption
+	switch config.AuthMode {
+	case "", AzureAuthAPIKey:
+		if config.APIKey == "" {
+			return nil, &ConfigurationError{
+				Field:   "AZURE_OPENAI_API_KEY",
+				Message: "Azure OpenAI API key is required",
+			}
+		}
+		authOption = option.WithAPIKey(config.APIKey)
+	case AzureAuthAAD:
+		if config.Credential == nil {
+			return nil, &ConfigurationError{
+				Field:   "azure_credential",
+				Message: "Azure AD auth mode requires a token credential",
+			}
+		}
+		authOption = azure.WithTokenCredential(config.Credential)
+	default:
 		return nil, &ConfigurationError{
-			Field:   "AZURE_OPENAI_API_KEY",
-			Message: "Azure OpenAI API key is required",
+			Field:   "AZURE_OPENAI_AUTH_MODE",
+			Message: fmt.Sprintf("unknown auth mode %q (expected %q or %q)", config.AuthMode, AzureAuthAPIKey, AzureAuthAAD),
 		}
 	}
 
@@ -44,15 +82,24 @@ func NewAzureOpenAIProvider(config *AzureOpenAIConfig) (*AzureOpenAIProvider, er
 		config.APIVersion = "2024-02-15-preview"
 	}
 
+	httpClient, err := newHTTPClient(config.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create client with Azure OpenAI configuration
 	client := openai.NewClient(
-		option.WithAPIKey(config.APIKey),
+		authOption,
 		azure.WithEndpoint(config.Endpoint, config.APIVersion),
+		option.WithHTTPClient(httpClient),
 	)
 
 	return &AzureOpenAIProvider{
-		client: client,
-		config: config,
+		client:        client,
+		config:        config,
+		tokenizer:     tokenizer.OrDefault(config.Tokenizer),
+		retryStrategy: config.RetryStrategy,
+		retryMaxDelay: config.RetryMaxDelay,
 	}, nil
 }
 
@@ -66,11 +113,7 @@ func (p *AzureOpenAIProvider) StreamChat(ctx context.Context, req ChatRequest) (
 	responseChan := make(chan ChatResponse)
 
 	// Build messages for Azure OpenAI API
-	messages := []openai.ChatCompletionMessageParamUnion{}
-	if req.SystemPrompt != "" {
-		messages = append(messages, openai.SystemMessage(req.SystemPrompt))
-	}
-	messages = append(messages, openai.UserMessage(req.UserPrompt))
+	messages := openAIMessages(req.ChatMessages())
 
 	// Create chat completion request
 	chatReq := openai.ChatCompletionNewParams{
@@ -80,28 +123,37 @@ func (p *AzureOpenAIProvider) StreamChat(ctx context.Context, req ChatRequest) (
 	if req.MaxTokens > 0 {
 		chatReq.MaxTokens = openai.Int(int64(req.MaxTokens))
 	}
-	if req.Temperature > 0 {
-		chatReq.Temperature = openai.Float(req.Temperature)
+	if req.Temperature != nil {
+		chatReq.Temperature = openai.Float(*req.Temperature)
 	}
 	if req.TopP > 0 {
 		chatReq.TopP = openai.Float(req.TopP)
 	}
+	if stops := req.StopSequences(); len(stops) > 0 {
+		chatReq.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: stops}
+	}
 
 	go func() {
 		defer close(responseChan)
 
 		// Create streaming completion
-		stream := p.client.Chat.Completions.NewStreaming(ctx, chatReq)
+		stream := p.client.Chat.Completions.NewStreaming(ctx, chatReq, requestIDOptions(req.RequestID)...)
 		
+		finishReason := ""
 		for stream.Next() {
 			resp := stream.Current()
 			if len(resp.Choices) > 0 {
 				choice := resp.Choices[0]
+				if choice.FinishReason != "" {
+					finishReason = choice.FinishReason
+				}
 				if choice.Delta.Content != "" {
-					responseChan <- ChatResponse{
+					if !sendResponse(ctx, responseChan, ChatResponse{
 						Content:    choice.Delta.Content,
 						IsComplete: false,
 						Timestamp:  time.Now(),
+					}) {
+						return
 					}
 				}
 			}
@@ -109,7 +161,7 @@ func (p *AzureOpenAIProvider) StreamChat(ctx context.Context, req ChatRequest) (
 		
 		// Check for errors
 		if err := stream.Err(); err != nil {
-			responseChan <- ChatResponse{
+			sendResponse(ctx, responseChan, ChatResponse{
 				Content:    "",
 				IsComplete: true,
 				Timestamp:  time.Now(),
@@ -118,48 +170,38 @@ func (p *AzureOpenAIProvider) StreamChat(ctx context.Context, req ChatRequest) (
 					Message:  "failed to receive stream response",
 					Cause:    err,
 				},
-			}
+			})
 			return
 		}
 		
 		// Stream completed successfully
-		responseChan <- ChatResponse{
-			Content:    "",
-			IsComplete: true,
-			Timestamp:  time.Now(),
-		}
+		sendResponse(ctx, responseChan, ChatResponse{
+			Content:      "",
+			IsComplete:   true,
+			Timestamp:    time.Now(),
+			FinishReason: finishReason,
+		})
 	}()
 
 	return responseChan, nil
 }
 
 // TokenCount returns the token counts for a response
-// Note: Azure OpenAI doesn't provide token counts in streaming responses
-// This is a simplified implementation - in practice, you might want to
-// use a tokenizer library for accurate counting
+// Note: streaming responses don't include token counts, so output tokens are
+// estimated with the provider's tokenizer
 func (p *AzureOpenAIProvider) TokenCount(response ChatResponse) (input, output, total int) {
-	// This is a rough estimation - for production use, consider using
-	// a proper tokenizer like tiktoken or similar
-	if response.Content != "" {
-		// Rough estimation: ~4 characters per token for English text
-		output = len(response.Content) / 4
-		if output < 1 {
-			output = 1
-		}
-	}
-
+	output = p.Tokenizer().Count("", response.Content)
 	return 0, output, output
 }
 
 // GetTokenCount estimates token count for input text
-// This is a simplified implementation - consider using a proper tokenizer
 func (p *AzureOpenAIProvider) GetTokenCount(text string) int {
-	// Rough estimation: ~4 characters per token for English text
-	count := len(text) / 4
-	if count < 1 {
-		count = 1
-	}
-	return count
+	return p.Tokenizer().Count("", text)
+}
+
+// Tokenizer returns the tokenizer used for token estimates
+func (p *AzureOpenAIProvider) Tokenizer() tokenizer.Tokenizer {
+	return tokenizer.OrDefault(p.tokenizer)
 }
 
 // ValidateRequest validates the chat request
@@ -171,7 +213,7 @@ func (p *AzureOpenAIProvider) ValidateRequest(req ChatRequest) error {
 		}
 	}
 
-	if req.UserPrompt == "" {
+	if req.UserPrompt == "" && len(req.Messages) == 0 {
 		return &ValidationError{
 			Field:   "user_prompt",
 			Message: "user prompt is required",
@@ -185,7 +227,7 @@ func (p *AzureOpenAIProvider) ValidateRequest(req ChatRequest) error {
 		}
 	}
 
-	if req.Temperature < 0 || req.Temperature > 2 {
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
 		return &ValidationError{
 			Field:   "temperature",
 			Message: "temperature must be between 0 and 2",
@@ -243,15 +285,5 @@ func (p *AzureOpenAIProvider) IsRetryableError(err error) bool {
 
 // GetRetryDelay calculates the delay before retrying
 func (p *AzureOpenAIProvider) GetRetryDelay(attempt int, err error) time.Duration {
-	// Base delay with exponential backoff
-	baseDelay := time.Duration(attempt*attempt) * time.Second
-
-	// Cap at 30 seconds
-	if baseDelay > 30*time.Second {
-		baseDelay = 30 * time.Second
-	}
-
-	// Add jitter to prevent thundering herd
-	jitter := time.Duration(attempt) * 100 * time.Millisecond
-	return baseDelay + jitter
+	return retryDelay(p.retryStrategy, p.retryMaxDelay, attempt, err)
 } 
\ No newline at end of file
@@ -2,10 +2,15 @@ package providers
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -19,43 +24,31 @@ func TestNewAzureOpenAIProvider(t *testing.T) {
 		{
 			name: "valid configuration",
 			config: &AzureOpenAIConfig{
-				Endpoint:       "https://test.openai.azure.com/",
-				APIKey:         "test-key",
-				APIVersion:     "2024-02-15-preview",
-				DeploymentName: "test-deployment",
+				Endpoint:   "https://test.openai.azure.com/",
+				APIKey:     "test-key",
+				APIVersion: "2024-02-15-preview",
 			},
 			wantErr: false,
 		},
 		{
 			name: "missing endpoint",
 			config: &AzureOpenAIConfig{
-				APIKey:         "test-key",
-				APIVersion:     "2024-02-15-preview",
-				DeploymentName: "test-deployment",
+				APIKey:     "test-key",
+				APIVersion: "2024-02-15-preview",
 			},
 			wantErr: true,
 		},
 		{
 			name: "missing API key",
-			config: &AzureOpenAIConfig{
-				Endpoint:       "https://test.openai.azure.com/",
-				APIVersion:     "2024-02-15-preview",
-				DeploymentName: "test-deployment",
-			},
-			wantErr: true,
-		},
-		{
-			name: "missing deployment name",
 			config: &AzureOpenAIConfig{
 				Endpoint:   "https://test.openai.azure.com/",
-				APIKey:     "test-key",
 				APIVersion: "2024-02-15-preview",
 			},
 			wantErr: true,
 		},
 		{
-			name: "empty configuration",
-			config: &AzureOpenAIConfig{},
+			name:    "empty configuration",
+			config:  &AzureOpenAIConfig{},
 			wantErr: true,
 		},
 	}
@@ -77,10 +70,9 @@ func TestNewAzureOpenAIProvider(t *testing.T) {
 
 func TestAzureOpenAIProvider_Name(t *testing.T) {
 	config := &AzureOpenAIConfig{
-		Endpoint:       "https://test.openai.azure.com/",
-		APIKey:         "test-key",
-		APIVersion:     "2024-02-15-preview",
-		DeploymentName: "test-deployment",
+		Endpoint:   "https://test.openai.azure.com/",
+		APIKey:     "test-key",
+		APIVersion: "2024-02-15-preview",
 	}
 
 	provider, err := NewAzureOpenAIProvider(config)
@@ -88,12 +80,98 @@ func TestAzureOpenAIProvider_Name(t *testing.T) {
 	assert.Equal(t, "azure_openai", provider.Name())
 }
 
+// staticTokenCredential is a fake azcore.TokenCredential returning a fixed token
+type staticTokenCredential struct {
+	token string
+}
+
+func (c staticTokenCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: c.token, ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func TestNewAzureOpenAIProvider_AuthModes(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *AzureOpenAIConfig
+		wantErr bool
+	}{
+		{
+			name:   "api key is the default",
+			config: &AzureOpenAIConfig{Endpoint: "https://test.openai.azure.com/", APIKey: "test-key"},
+		},
+		{
+			name:   "explicit api key mode",
+			config: &AzureOpenAIConfig{Endpoint: "https://test.openai.azure.com/", APIKey: "test-key", AuthMode: AzureAuthAPIKey},
+		},
+		{
+			name:   "aad with credential",
+			config: &AzureOpenAIConfig{Endpoint: "https://test.openai.azure.com/", AuthMode: AzureAuthAAD, Credential: staticTokenCredential{token: "aad-token"}},
+		},
+		{
+			name:    "aad without credential",
+			config:  &AzureOpenAIConfig{Endpoint: "https://test.openai.azure.com/", APIKey: "test-key", AuthMode: AzureAuthAAD},
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			config:  &AzureOpenAIConfig{Endpoint: "https://test.openai.azure.com/", APIKey: "test-key", AuthMode: "oauth"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewAzureOpenAIProvider(tt.config)
+			if tt.wantErr {
+				var configErr *ConfigurationError
+				assert.ErrorAs(t, err, &configErr)
+				assert.Nil(t, provider)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, provider)
+			}
+		})
+	}
+}
+
+func TestAzureOpenAIProvider_AADSendsBearerToken(t *testing.T) {
+	var authorization, apiKey string
+	// Bearer tokens are only sent over TLS, so trust the test server's certificate
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		apiKey = r.Header.Get("Api-Key")
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"1","object":"chat.completion.chunk","created":1,"model":"gpt-4","choices":[{"index":0,"delta":{"content":"hi"},"finish_reason":"stop"}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	defaultTransport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = server.Client().Transport
+	defer func() { http.DefaultClient.Transport = defaultTransport }()
+
+	provider, err := NewAzureOpenAIProvider(&AzureOpenAIConfig{
+		Endpoint:   server.URL,
+		AuthMode:   AzureAuthAAD,
+		Credential: staticTokenCredential{token: "aad-token"},
+	})
+	require.NoError(t, err)
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{Model: "gpt-4", UserPrompt: "Hello"})
+	require.NoError(t, err)
+	for _, resp := range collectResponses(t, responseChan) {
+		require.NoError(t, resp.Error)
+	}
+
+	assert.Equal(t, "Bearer aad-token", authorization)
+	assert.Empty(t, apiKey)
+}
+
 func TestAzureOpenAIProvider_ValidateRequest(t *testing.T) {
 	config := &AzureOpenAIConfig{
-		Endpoint:       "https://test.openai.azure.com/",
-		APIKey:         "test-key",
-		APIVersion:     "2024-02-15-preview",
-		DeploymentName: "test-deployment",
+		Endpoint:   "https://test.openai.azure.com/",
+		APIKey:     "test-key",
+		APIVersion: "2024-02-15-preview",
 	}
 
 	provider, err := NewAzureOpenAIProvider(config)
@@ -140,7 +218,7 @@ func TestAzureOpenAIProvider_ValidateRequest(t *testing.T) {
 			request: ChatRequest{
 				Model:       "gpt-4",
 				UserPrompt:  "Hello, world!",
-				Temperature: 3.0,
+				Temperature: float64Ptr(3.0),
 			},
 			wantErr: true,
 		},
@@ -169,21 +247,20 @@ func TestAzureOpenAIProvider_ValidateRequest(t *testing.T) {
 
 func TestAzureOpenAIProvider_TokenCount(t *testing.T) {
 	config := &AzureOpenAIConfig{
-		Endpoint:       "https://test.openai.azure.com/",
-		APIKey:         "test-key",
-		APIVersion:     "2024-02-15-preview",
-		DeploymentName: "test-deployment",
+		Endpoint:   "https://test.openai.azure.com/",
+		APIKey:     "test-key",
+		APIVersion: "2024-02-15-preview",
 	}
 
 	provider, err := NewAzureOpenAIProvider(config)
 	require.NoError(t, err)
 
 	tests := []struct {
-		name     string
-		response ChatResponse
-		wantInput int
+		name       string
+		response   ChatResponse
+		wantInput  int
 		wantOutput int
-		wantTotal int
+		wantTotal  int
 	}{
 		{
 			name: "empty response",
@@ -209,8 +286,8 @@ func TestAzureOpenAIProvider_TokenCount(t *testing.T) {
 				Content: "This is a longer response with more tokens to count",
 			},
 			wantInput:  0,
-			wantOutput: 11, // 44 characters / 4 = 11
-			wantTotal:  11,
+			wantOutput: 12, // 51 characters / 4 = 12
+			wantTotal:  12,
 		},
 	}
 
@@ -226,10 +303,9 @@ func TestAzureOpenAIProvider_TokenCount(t *testing.T) {
 
 func TestAzureOpenAIProvider_GetTokenCount(t *testing.T) {
 	config := &AzureOpenAIConfig{
-		Endpoint:       "https://test.openai.azure.com/",
-		APIKey:         "test-key",
-		APIVersion:     "2024-02-15-preview",
-		DeploymentName: "test-deployment",
+		Endpoint:   "https://test.openai.azure.com/",
+		APIKey:     "test-key",
+		APIVersion: "2024-02-15-preview",
 	}
 
 	provider, err := NewAzureOpenAIProvider(config)
@@ -253,7 +329,7 @@ func TestAzureOpenAIProvider_GetTokenCount(t *testing.T) {
 		{
 			name: "longer text",
 			text: "This is a longer text with more tokens to count",
-			want: 9, // 36 characters / 4 = 9
+			want: 11, // 47 characters / 4 = 11
 		},
 	}
 
@@ -267,10 +343,9 @@ func TestAzureOpenAIProvider_GetTokenCount(t *testing.T) {
 
 func TestAzureOpenAIProvider_IsRetryableError(t *testing.T) {
 	config := &AzureOpenAIConfig{
-		Endpoint:       "https://test.openai.azure.com/",
-		APIKey:         "test-key",
-		APIVersion:     "2024-02-15-preview",
-		DeploymentName: "test-deployment",
+		Endpoint:   "https://test.openai.azure.com/",
+		APIKey:     "test-key",
+		APIVersion: "2024-02-15-preview",
 	}
 
 	provider, err := NewAzureOpenAIProvider(config)
@@ -318,10 +393,9 @@ func TestAzureOpenAIProvider_IsRetryableError(t *testing.T) {
 
 func TestAzureOpenAIProvider_GetRetryDelay(t *testing.T) {
 	config := &AzureOpenAIConfig{
-		Endpoint:       "https://test.openai.azure.com/",
-		APIKey:         "test-key",
-		APIVersion:     "2024-02-15-preview",
-		DeploymentName: "test-deployment",
+		Endpoint:   "https://test.openai.azure.com/",
+		APIKey:     "test-key",
+		APIVersion: "2024-02-15-preview",
 	}
 
 	provider, err := NewAzureOpenAIProvider(config)
@@ -345,8 +419,8 @@ func TestAzureOpenAIProvider_GetRetryDelay(t *testing.T) {
 			name:    "second attempt",
 			attempt: 2,
 			err:     &ProviderError{Message: "500 error"},
-			wantMin: 4 * time.Second,
-			wantMax: 5 * time.Second,
+			wantMin: 2 * time.Second,
+			wantMax: 3 * time.Second,
 		},
 		{
 			name:    "high attempt number",
@@ -377,10 +451,9 @@ func TestAzureOpenAIProvider_Integration(t *testing.T) {
 	}
 
 	config := &AzureOpenAIConfig{
-		Endpoint:       endpoint,
-		APIKey:         apiKey,
-		APIVersion:     "2024-02-15-preview",
-		DeploymentName: deploymentName,
+		Endpoint:   endpoint,
+		APIKey:     apiKey,
+		APIVersion: "2024-02-15-preview",
 	}
 
 	provider, err := NewAzureOpenAIProvider(config)
@@ -419,4 +492,4 @@ func TestAzureOpenAIProvider_Integration(t *testing.T) {
 	assert.True(t, isComplete)
 	assert.NotEmpty(t, response)
 	assert.LessOrEqual(t, len(response), 50) // Should be short due to max tokens
-} 
\ No newline at end of file
+}
@@ -0,0 +1,163 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewCerebrasProvider(t *testing.T) {
+	if _, err := NewOpenAICompatibleProvider("cerebras", &OpenAICompatibleConfig{}); err == nil {
+		t.Error("NewOpenAICompatibleProvider(\"cerebras\") without an API key should fail")
+	}
+
+	provider, err := NewOpenAICompatibleProvider("cerebras", &OpenAICompatibleConfig{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("NewOpenAICompatibleProvider(\"cerebras\") error = %v", err)
+	}
+	if provider.Name() != "cerebras" {
+		t.Errorf("Name() = %s, want 'cerebras'", provider.Name())
+	}
+	if provider.config.BaseURL != "https://api.cerebras.ai/v1" {
+		t.Errorf("BaseURL = %s, want the default", provider.config.BaseURL)
+	}
+}
+
+func TestCerebrasProvider_ValidateRequest(t *testing.T) {
+	provider, err := NewOpenAICompatibleProvider("cerebras", &OpenAICompatibleConfig{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		request ChatRequest
+		wantErr bool
+	}{
+		{"valid request", ChatRequest{Model: "llama3.1-8b", UserPrompt: "Hello"}, false},
+		{"conversation without user prompt", ChatRequest{Model: "llama3.1-8b", Messages: []Message{{Role: "user", Content: "Hello"}}}, false},
+		{"maximum temperature", ChatRequest{Model: "llama3.1-8b", UserPrompt: "Hello", Temperature: float64Ptr(1.5)}, false},
+		{"missing model", ChatRequest{UserPrompt: "Hello"}, true},
+		{"missing user prompt", ChatRequest{Model: "llama3.1-8b"}, true},
+		{"negative max tokens", ChatRequest{Model: "llama3.1-8b", UserPrompt: "Hello", MaxTokens: -1}, true},
+		{"temperature above 1.5", ChatRequest{Model: "llama3.1-8b", UserPrompt: "Hello", Temperature: float64Ptr(1.8)}, true},
+		{"invalid top_p", ChatRequest{Model: "llama3.1-8b", UserPrompt: "Hello", TopP: 1.5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := provider.ValidateRequest(tt.request); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// StreamChat rejects invalid requests before sending anything
+	if _, err := provider.StreamChat(context.Background(), ChatRequest{Model: "llama3.1-8b"}); err == nil {
+		t.Error("StreamChat() accepted a request without a prompt")
+	}
+}
+
+func TestCerebrasProvider_IsRetryableError(t *testing.T) {
+	provider, err := NewOpenAICompatibleProvider("cerebras", &OpenAICompatibleConfig{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil error", nil, false},
+		{"rate limit error", &RateLimitError{Provider: "cerebras"}, true},
+		{"server error", &ServerError{Provider: "cerebras", StatusCode: 503}, true},
+		{"timeout error", &TimeoutError{Operation: "streaming response", Duration: time.Second}, true},
+		{"network error", &NetworkError{Provider: "cerebras", Cause: errors.New("connection refused")}, true},
+		{"auth error", &AuthError{Provider: "cerebras", StatusCode: 401}, false},
+		{"bad request", &ProviderError{Provider: "cerebras", Message: "HTTP error 400: model not found"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := provider.IsRetryableError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := provider.GetRetryDelay(1, &RateLimitError{Provider: "cerebras", RetryAfter: 7 * time.Second}); got != 7*time.Second {
+		t.Errorf("GetRetryDelay() = %v, want the 7s reset hint", got)
+	}
+}
+
+func TestCerebrasProvider_StreamChat(t *testing.T) {
+	var gotBody compatChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("request path = %s, want /chat/completions", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q, want the API key as a bearer token", got)
+		}
+		json.NewDecoder(r.Body).Decode(&gotBody)
+
+		// Recorded from the API: usage and timing arrive on the chunk that finishes the choice
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"chatcmpl-4b7e2c1d","choices":[{"delta":{"role":"assistant"},"index":0}],"created":1736900000,"model":"llama3.1-8b","system_fingerprint":"fp_70185065a4","object":"chat.completion.chunk"}` + "\n\n"))
+		w.Write([]byte(`data: {"id":"chatcmpl-4b7e2c1d","choices":[{"delta":{"content":"Hello"},"index":0}],"created":1736900000,"model":"llama3.1-8b","system_fingerprint":"fp_70185065a4","object":"chat.completion.chunk"}` + "\n\n"))
+		w.Write([]byte(`data: {"id":"chatcmpl-4b7e2c1d","choices":[{"delta":{"content":"!"},"index":0}],"created":1736900000,"model":"llama3.1-8b","system_fingerprint":"fp_70185065a4","object":"chat.completion.chunk"}` + "\n\n"))
+		w.Write([]byte(`data: {"id":"chatcmpl-4b7e2c1d","choices":[{"delta":{},"finish_reason":"stop","index":0}],"created":1736900000,"model":"llama3.1-8b","system_fingerprint":"fp_70185065a4","object":"chat.completion.chunk","usage":{"prompt_tokens":38,"completion_tokens":3,"total_tokens":41},"time_info":{"queue_time":0.000094,"prompt_time":0.00189,"completion_time":0.00142,"total_time":0.00512,"created":1736900000}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAICompatibleProvider("cerebras", &OpenAICompatibleConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:      "llama3.1-8b",
+		UserPrompt: "Say hello",
+		MaxTokens:  16,
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	responses := collectResponses(t, responseChan)
+
+	if !gotBody.Stream || gotBody.MaxTokens == nil || *gotBody.MaxTokens != 16 {
+		t.Errorf("request body = %+v, want a stream with max_tokens 16", gotBody)
+	}
+
+	var content string
+	for _, response := range responses {
+		if response.Error != nil {
+			t.Fatalf("unexpected error: %v", response.Error)
+		}
+		content += response.Content
+	}
+	if content != "Hello!" {
+		t.Errorf("content = %q, want %q", content, "Hello!")
+	}
+
+	final := responses[len(responses)-1]
+	if !final.IsComplete || final.FinishReason != "stop" {
+		t.Errorf("final response = %+v, want complete with finish reason stop", final)
+	}
+	want := Usage{InputTokens: 38, OutputTokens: 3}
+	if final.Usage == nil || *final.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", final.Usage, want)
+	}
+	if input, output, total := provider.TokenCount(final); input != 38 || output != 3 || total != 41 {
+		t.Errorf("TokenCount() = %d, %d, %d, want 38, 3, 41", input, output, total)
+	}
+	if final.SystemFingerprint != "fp_70185065a4" {
+		t.Errorf("SystemFingerprint = %q, want the reported fingerprint", final.SystemFingerprint)
+	}
+}
@@ -0,0 +1,98 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewDeepSeekProvider(t *testing.T) {
+	if _, err := NewOpenAICompatibleProvider("deepseek", &OpenAICompatibleConfig{}); err == nil {
+		t.Error("NewOpenAICompatibleProvider(\"deepseek\") without an API key should fail")
+	}
+
+	provider, err := NewOpenAICompatibleProvider("deepseek", &OpenAICompatibleConfig{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("NewOpenAICompatibleProvider(\"deepseek\") error = %v", err)
+	}
+	if provider.Name() != "deepseek" {
+		t.Errorf("Name() = %s, want 'deepseek'", provider.Name())
+	}
+	if provider.config.BaseURL != "https://api.deepseek.com" {
+		t.Errorf("BaseURL = %s, want the default", provider.config.BaseURL)
+	}
+}
+
+func TestDeepSeekProvider_StreamChat(t *testing.T) {
+	var gotBody compatChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("request path = %s, want /chat/completions", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&gotBody)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"model":"deepseek-reasoner","system_fingerprint":"fp_5417b77867_prod0225","choices":[{"index":0,"delta":{"role":"assistant","content":null,"reasoning_content":""}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":null,"reasoning_content":"The user wants"}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":null,"reasoning_content":" a greeting."}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":"Hello","reasoning_content":null}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":"!","reasoning_content":null},"finish_reason":"stop"}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":9,"prompt_cache_hit_tokens":8,"completion_tokens_details":{"reasoning_tokens":6}}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAICompatibleProvider("deepseek", &OpenAICompatibleConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{Model: "deepseek-reasoner", UserPrompt: "Say hello"})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	responses := collectResponses(t, responseChan)
+
+	if !gotBody.Stream || gotBody.StreamOptions == nil || !gotBody.StreamOptions.IncludeUsage {
+		t.Errorf("request body = %+v, want a stream that includes usage", gotBody)
+	}
+
+	var reasoning, content string
+	var reasoningDone bool
+	for _, response := range responses {
+		if response.Error != nil {
+			t.Fatalf("unexpected error: %v", response.Error)
+		}
+		if response.ReasoningContent != "" && response.Content != "" {
+			t.Errorf("chunk mixes reasoning and answer: %+v", response)
+		}
+		if response.ReasoningContent != "" && reasoningDone {
+			t.Errorf("reasoning arrived after the answer started: %+v", response)
+		}
+		if response.Content != "" {
+			reasoningDone = true
+		}
+		reasoning += response.ReasoningContent
+		content += response.Content
+	}
+	if reasoning != "The user wants a greeting." {
+		t.Errorf("reasoning = %q, want %q", reasoning, "The user wants a greeting.")
+	}
+	if content != "Hello!" {
+		t.Errorf("content = %q, want %q", content, "Hello!")
+	}
+
+	final := responses[len(responses)-1]
+	if !final.IsComplete || final.FinishReason != "stop" {
+		t.Errorf("final response = %+v, want complete with finish reason stop", final)
+	}
+	want := Usage{InputTokens: 12, OutputTokens: 9, CachedInputTokens: 8, ReasoningTokens: 6}
+	if final.Usage == nil || *final.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", final.Usage, want)
+	}
+	if final.ResponseModel != "deepseek-reasoner" || final.SystemFingerprint != "fp_5417b77867_prod0225" {
+		t.Errorf("model version = %q/%q, want deepseek-reasoner/fp_5417b77867_prod0225", final.ResponseModel, final.SystemFingerprint)
+	}
+}
@@ -118,6 +118,16 @@ func (f *ProviderFactory) createProvider(providerName string) (Provider, error)
 		return NewGeminiProvider(config)
 
 	default:
+		if vendor, ok := compatVendors[providerName]; ok {
+			config, ok := f.configs[providerName].(*OpenAICompatibleConfig)
+			if !ok {
+				return nil, &ConfigurationError{
+					Field:   providerName + "_config",
+					Message: vendor.displayName + " configuration not found or invalid",
+				}
+			}
+			return NewOpenAICompatibleProvider(providerName, config)
+		}
 		return nil, &ConfigurationError{
 			Field:   "provider_name",
 			Message: fmt.Sprintf("unknown provider: %s", providerName),
@@ -141,5 +151,11 @@ func (f *ProviderFactory) GetAvailableProviders() []string {
 		"anthropic",
 		"azure_openai",
 		"gemini",
+		"perplexity",
+		"deepseek",
+		"xai",
+		"together",
+		"cerebras",
+		"sambanova",
 	}
 } 
\ No newline at end of file
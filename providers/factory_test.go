@@ -18,7 +18,7 @@ func TestProviderFactory_RegisterConfig(t *testing.T) {
 	// Test registering the same provider again (should overwrite)
 	config2 := &OpenAIConfig{APIKey: "test-key-2"}
 	factory.RegisterConfig("test-provider", config2)
-	
+
 	// Should not error, just overwrite
 }
 
@@ -66,10 +66,9 @@ func TestProviderFactory_GetAzureOpenAIProvider(t *testing.T) {
 
 	// Register Azure OpenAI config
 	config := &AzureOpenAIConfig{
-		Endpoint:       "https://test.openai.azure.com/",
-		APIKey:         "test-key",
-		APIVersion:     "2024-02-15-preview",
-		DeploymentName: "test-deployment",
+		Endpoint:   "https://test.openai.azure.com/",
+		APIKey:     "test-key",
+		APIVersion: "2024-02-15-preview",
 	}
 	factory.RegisterConfig("azure_openai", config)
 
@@ -86,18 +85,38 @@ func TestProviderFactory_GetAzureOpenAIProvider(t *testing.T) {
 	assert.Equal(t, provider, provider2) // Should be the same instance
 }
 
+func TestProviderFactory_GetPerplexityProvider(t *testing.T) {
+	factory := NewProviderFactory()
+
+	// Without a registered config the provider can't be created
+	provider, err := factory.GetProvider("perplexity")
+	assert.Error(t, err)
+	assert.Nil(t, provider)
+
+	factory.RegisterConfig("perplexity", &OpenAICompatibleConfig{APIKey: "test-key"})
+	provider, err = factory.GetProvider("perplexity")
+	assert.NoError(t, err)
+	assert.Equal(t, "perplexity", provider.Name())
+}
+
 func TestProviderFactory_GetAvailableProviders(t *testing.T) {
 	factory := NewProviderFactory()
 
 	// Check available providers
-    providers := factory.GetAvailableProviders()
-    assert.Len(t, providers, 6)
-    assert.Contains(t, providers, "openai")
-    assert.Contains(t, providers, "openai_responses")
-    assert.Contains(t, providers, "groq")
-    assert.Contains(t, providers, "anthropic")
-    assert.Contains(t, providers, "azure_openai")
-    assert.Contains(t, providers, "gemini")
+	providers := factory.GetAvailableProviders()
+	assert.Len(t, providers, 12)
+	assert.Contains(t, providers, "openai")
+	assert.Contains(t, providers, "openai_responses")
+	assert.Contains(t, providers, "groq")
+	assert.Contains(t, providers, "anthropic")
+	assert.Contains(t, providers, "azure_openai")
+	assert.Contains(t, providers, "gemini")
+	assert.Contains(t, providers, "perplexity")
+	assert.Contains(t, providers, "deepseek")
+	assert.Contains(t, providers, "xai")
+	assert.Contains(t, providers, "together")
+	assert.Contains(t, providers, "cerebras")
+	assert.Contains(t, providers, "sambanova")
 }
 
 func TestProviderFactory_ClearProviders(t *testing.T) {
@@ -135,7 +154,7 @@ func (m *MockProvider) Name() string {
 
 func (m *MockProvider) StreamChat(ctx context.Context, req ChatRequest) (<-chan ChatResponse, error) {
 	responseChan := make(chan ChatResponse)
-	
+
 	go func() {
 		defer close(responseChan)
 		responseChan <- ChatResponse{
@@ -144,7 +163,7 @@ func (m *MockProvider) StreamChat(ctx context.Context, req ChatRequest) (<-chan
 			Timestamp:  time.Now(),
 		}
 	}()
-	
+
 	return responseChan, nil
 }
 
@@ -169,4 +188,4 @@ func (m *MockProvider) IsRetryableError(err error) bool {
 
 func (m *MockProvider) GetRetryDelay(attempt int, err error) time.Duration {
 	return time.Second
-} 
\ No newline at end of file
+}
@@ -6,32 +6,54 @@ import (
 	"time"
 
 	"google.golang.org/genai"
+
+	"github.com/megzo/llm-latency-benchmark/internal/retry"
+	"github.com/megzo/llm-latency-benchmark/internal/tokenizer"
 )
 
 // GeminiProvider implements the Provider interface for Google Gemini
 type GeminiProvider struct {
-	client *genai.Client
-	config *GeminiConfig
+	client        *genai.Client
+	config        *GeminiConfig
+	tokenizer     tokenizer.Tokenizer
+	retryStrategy retry.Strategy
+	retryMaxDelay time.Duration
 }
 
 // GeminiConfig holds Gemini-specific configuration
 type GeminiConfig struct {
 	APIKey string
-	// Gemini supports both Gemini API and Vertex AI backends
-	// The client will automatically detect which one to use based on the API key
+	// UseVertex selects the Vertex AI backend in Project, authenticating with
+	// application default credentials instead of APIKey; otherwise requests go
+	// to the public Gemini API
+	UseVertex bool
+	Project   string
+	// Location is the Vertex AI region; "" uses DefaultVertexLocation
+	Location string
+
+	// Tokenizer estimates token counts; nil uses the default heuristic
+	Tokenizer tokenizer.Tokenizer
+	// RetryStrategy selects the backoff between retries; "" uses retry.Default
+	RetryStrategy retry.Strategy
+	// RetryMaxDelay caps the backoff between retries; 0 uses retry.MaxDelay
+	RetryMaxDelay time.Duration
+	// ProxyURL sends requests through this proxy instead of the one HTTP_PROXY or
+	// HTTPS_PROXY name; "" honors those variables
+	ProxyURL string
 }
 
+// DefaultVertexLocation is the Vertex AI region used when none is configured
+const DefaultVertexLocation = "us-central1"
+
 // NewGeminiProvider creates a new Gemini provider instance
 func NewGeminiProvider(config *GeminiConfig) (*GeminiProvider, error) {
-	if config.APIKey == "" {
-		return nil, &ConfigurationError{
-			Field:   "GOOGLE_API_KEY",
-			Message: "Google API key is required for Gemini",
-		}
+	clientConfig, err := geminiClientConfig(config)
+	if err != nil {
+		return nil, err
 	}
 
 	ctx := context.Background()
-	client, err := genai.NewClient(ctx, nil)
+	client, err := genai.NewClient(ctx, clientConfig)
 	if err != nil {
 		return nil, &ProviderError{
 			Provider: "gemini",
@@ -41,8 +63,59 @@ func NewGeminiProvider(config *GeminiConfig) (*GeminiProvider, error) {
 	}
 
 	return &GeminiProvider{
-		client: client,
-		config: config,
+		client:        client,
+		config:        config,
+		tokenizer:     tokenizer.OrDefault(config.Tokenizer),
+		retryStrategy: config.RetryStrategy,
+		retryMaxDelay: config.RetryMaxDelay,
+	}, nil
+}
+
+// geminiClientConfig builds the client configuration for the public Gemini API or,
+// with UseVertex, for Vertex AI
+func geminiClientConfig(config *GeminiConfig) (*genai.ClientConfig, error) {
+	if config.UseVertex {
+		if config.Project == "" {
+			return nil, &ConfigurationError{
+				Field:   "GOOGLE_CLOUD_PROJECT",
+				Message: "a Google Cloud project is required for Vertex AI",
+			}
+		}
+		// The SDK only authenticates requests with application default credentials
+		// on a client it creates itself, so a custom proxy client can't be used
+		if config.ProxyURL != "" {
+			return nil, &ConfigurationError{
+				Field:   "proxy",
+				Message: "a proxy URL is not supported with Vertex AI; set HTTPS_PROXY instead",
+			}
+		}
+		location := config.Location
+		if location == "" {
+			location = DefaultVertexLocation
+		}
+		return &genai.ClientConfig{
+			Backend:  genai.BackendVertexAI,
+			Project:  config.Project,
+			Location: location,
+		}, nil
+	}
+
+	if config.APIKey == "" {
+		return nil, &ConfigurationError{
+			Field:   "GOOGLE_API_KEY",
+			Message: "Google API key is required for Gemini",
+		}
+	}
+
+	httpClient, err := newHTTPClient(config.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &genai.ClientConfig{
+		APIKey:     config.APIKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: httpClient,
 	}, nil
 }
 
@@ -69,17 +142,25 @@ func (p *GeminiProvider) StreamChat(ctx context.Context, req ChatRequest) (<-cha
 				ThinkingBudget: genai.Ptr[int32](0), // Disable thinking mode for faster responses
 			},
 		}
-		if req.Temperature > 0 {
-			config.Temperature = genai.Ptr[float32](float32(req.Temperature))
+		if req.Temperature != nil {
+			config.Temperature = genai.Ptr[float32](float32(*req.Temperature))
 		}
 		if req.TopP > 0 {
 			config.TopP = genai.Ptr[float32](float32(req.TopP))
 		}
+		config.StopSequences = req.StopSequences()
+		if mimeType, schema := geminiResponseFormat(req.ExtraParams); mimeType != "" {
+			config.ResponseMIMEType = mimeType
+			config.ResponseJsonSchema = schema
+		}
+
+		// Earlier turns become the chat history, the last one is sent
+		history, message := geminiConversation(req)
 
 		// Create a new chat session
-		chat, err := p.client.Chats.Create(ctx, req.Model, config, nil)
+		chat, err := p.client.Chats.Create(ctx, req.Model, config, history)
 		if err != nil {
-			responseChan <- ChatResponse{
+			sendResponse(ctx, responseChan, ChatResponse{
 				Content:    "",
 				IsComplete: true,
 				Timestamp:  time.Now(),
@@ -88,24 +169,22 @@ func (p *GeminiProvider) StreamChat(ctx context.Context, req ChatRequest) (<-cha
 					Message:  "failed to create chat session",
 					Cause:    err,
 				},
-			}
+			})
 			return
 		}
 
-		// Prepare the message content
-		messageContent := req.UserPrompt
-		if req.SystemPrompt != "" {
-			// Gemini doesn't have a separate system prompt, so we prepend it
-			messageContent = req.SystemPrompt + "\n\n" + req.UserPrompt
+		// SendMessageStream takes the parts by value
+		parts := make([]genai.Part, len(message))
+		for i, part := range message {
+			parts[i] = *part
 		}
 
-		// Create the message part
-		part := genai.Part{Text: messageContent}
-
 		// Send message and stream response
-		for result, err := range chat.SendMessageStream(ctx, part) {
+		finishReason := ""
+		var usage *Usage
+		for result, err := range chat.SendMessageStream(ctx, parts...) {
 			if err != nil {
-				responseChan <- ChatResponse{
+				sendResponse(ctx, responseChan, ChatResponse{
 					Content:    "",
 					IsComplete: true,
 					Timestamp:  time.Now(),
@@ -114,59 +193,151 @@ func (p *GeminiProvider) StreamChat(ctx context.Context, req ChatRequest) (<-cha
 						Message:  "failed to receive stream response",
 						Cause:    err,
 					},
-				}
+				})
 				return
 			}
 
+			if len(result.Candidates) > 0 && result.Candidates[0].FinishReason != "" {
+				finishReason = string(result.Candidates[0].FinishReason)
+			}
+			if result.UsageMetadata != nil {
+				usage = geminiUsage(result.UsageMetadata)
+			}
+
 			// Extract text content from the result
 			text := result.Text()
 			if text != "" {
-				responseChan <- ChatResponse{
+				if !sendResponse(ctx, responseChan, ChatResponse{
 					Content:    text,
 					IsComplete: false,
 					Timestamp:  time.Now(),
+				}) {
+					return
 				}
 			}
 		}
 
 		// Stream completed successfully
-		responseChan <- ChatResponse{
-			Content:    "",
-			IsComplete: true,
-			Timestamp:  time.Now(),
-		}
+		sendResponse(ctx, responseChan, ChatResponse{
+			Content:      "",
+			IsComplete:   true,
+			Timestamp:    time.Now(),
+			FinishReason: finishReason,
+			Usage:        usage,
+		})
 	}()
 
 	return responseChan, nil
 }
 
-// TokenCount returns the token counts for a response
-// Note: Gemini doesn't provide token counts in streaming responses
-// This is a simplified implementation - in practice, you might want to
-// use a tokenizer library for accurate counting
-func (p *GeminiProvider) TokenCount(response ChatResponse) (input, output, total int) {
-	// This is a rough estimation - for production use, consider using
-	// a proper tokenizer like tiktoken or similar
-	if response.Content != "" {
-		// Rough estimation: ~4 characters per token for English text
-		output = len(response.Content) / 4
-		if output < 1 {
-			output = 1
+// geminiResponseFormat maps an OpenAI-style response_format parameter to Gemini's
+// structured output: json_object and json_schema both ask for application/json, the
+// latter constrained by its JSON schema. Other formats return "" and leave text output.
+func geminiResponseFormat(params map[string]interface{}) (mimeType string, schema any) {
+	format, _ := params["response_format"].(map[string]interface{})
+	switch format["type"] {
+	case "json_object":
+		return "application/json", nil
+	case "json_schema":
+		jsonSchema, _ := format["json_schema"].(map[string]interface{})
+		return "application/json", jsonSchema["schema"]
+	}
+	return "", nil
+}
+
+// geminiUsage converts usage metadata, returning nil when it has no counts.
+// Gemini reports thinking tokens apart from candidate tokens but bills both as output.
+func geminiUsage(u *genai.GenerateContentResponseUsageMetadata) *Usage {
+	output := u.CandidatesTokenCount + u.ThoughtsTokenCount
+	if u.PromptTokenCount == 0 && output == 0 {
+		return nil
+	}
+	return &Usage{
+		InputTokens:       int(u.PromptTokenCount),
+		OutputTokens:      int(output),
+		CachedInputTokens: int(u.CachedContentTokenCount),
+		ReasoningTokens:   int(u.ThoughtsTokenCount),
+	}
+}
+
+// geminiConversation splits the request into chat history and the final message's
+// parts: its text, followed by the request's image if it has one
+func geminiConversation(req ChatRequest) ([]*genai.Content, []*genai.Part) {
+	var systemParts []string
+	var turns []Message
+	for _, message := range req.ChatMessages() {
+		if message.Role == "system" {
+			systemParts = append(systemParts, message.Content)
+			continue
 		}
+		turns = append(turns, message)
+	}
+	if len(turns) == 0 {
+		return nil, geminiMessageParts(strings.Join(systemParts, "\n\n"), req.Image)
 	}
 
+	// Gemini doesn't have a separate system prompt, so we prepend it to the first turn
+	if len(systemParts) > 0 {
+		turns[0].Content = strings.Join(systemParts, "\n\n") + "\n\n" + turns[0].Content
+	}
+
+	history := make([]*genai.Content, 0, len(turns)-1)
+	for _, turn := range turns[:len(turns)-1] {
+		var role genai.Role = genai.RoleUser
+		if turn.Role == "assistant" {
+			role = genai.RoleModel
+		}
+		history = append(history, genai.NewContentFromText(turn.Content, role))
+	}
+	return history, geminiMessageParts(turns[len(turns)-1].Content, req.Image)
+}
+
+// geminiMessageParts builds a message from its text and an optional image, sent
+// by URI or inline as bytes
+func geminiMessageParts(text string, image *Image) []*genai.Part {
+	parts := []*genai.Part{genai.NewPartFromText(text)}
+	switch {
+	case image == nil:
+	case image.URL != "":
+		parts = append(parts, genai.NewPartFromURI(image.URL, image.MediaType))
+	default:
+		parts = append(parts, genai.NewPartFromBytes(image.Data, image.MediaType))
+	}
+	return parts
+}
+
+// CountInputTokens counts the request's prompt tokens with the CountTokens API
+func (p *GeminiProvider) CountInputTokens(ctx context.Context, req ChatRequest) (int, error) {
+	history, message := geminiConversation(req)
+	contents := append(history, genai.NewContentFromParts(message, genai.RoleUser))
+
+	count, err := p.client.Models.CountTokens(ctx, req.Model, contents, nil)
+	if err != nil {
+		return 0, &ProviderError{
+			Provider: "gemini",
+			Message:  "failed to count input tokens",
+			Cause:    err,
+		}
+	}
+	return int(count.TotalTokens), nil
+}
+
+// TokenCount returns the token counts for a response
+// Note: streaming responses don't include token counts, so output tokens are
+// estimated with the provider's tokenizer
+func (p *GeminiProvider) TokenCount(response ChatResponse) (input, output, total int) {
+	output = p.Tokenizer().Count("", response.Content)
 	return 0, output, output
 }
 
 // GetTokenCount estimates token count for input text
-// This is a simplified implementation - consider using a proper tokenizer
 func (p *GeminiProvider) GetTokenCount(text string) int {
-	// Rough estimation: ~4 characters per token for English text
-	count := len(text) / 4
-	if count < 1 {
-		count = 1
-	}
-	return count
+	return p.Tokenizer().Count("", text)
+}
+
+// Tokenizer returns the tokenizer used for token estimates
+func (p *GeminiProvider) Tokenizer() tokenizer.Tokenizer {
+	return tokenizer.OrDefault(p.tokenizer)
 }
 
 // ValidateRequest validates the chat request
@@ -178,7 +349,7 @@ func (p *GeminiProvider) ValidateRequest(req ChatRequest) error {
 		}
 	}
 
-	if req.UserPrompt == "" {
+	if req.UserPrompt == "" && len(req.Messages) == 0 {
 		return &ValidationError{
 			Field:   "user_prompt",
 			Message: "user prompt is required",
@@ -192,7 +363,7 @@ func (p *GeminiProvider) ValidateRequest(req ChatRequest) error {
 		}
 	}
 
-	if req.Temperature < 0 || req.Temperature > 2 {
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
 		return &ValidationError{
 			Field:   "temperature",
 			Message: "temperature must be between 0 and 2",
@@ -250,20 +421,10 @@ func (p *GeminiProvider) IsRetryableError(err error) bool {
 
 // GetRetryDelay returns the delay before retrying
 func (p *GeminiProvider) GetRetryDelay(attempt int, err error) time.Duration {
-	// Base delay with exponential backoff
-	baseDelay := time.Second * time.Duration(1<<uint(attempt))
-	
-	// Cap the maximum delay at 30 seconds
-	if baseDelay > 30*time.Second {
-		baseDelay = 30 * time.Second
-	}
-
-	// Add jitter to prevent thundering herd
-	jitter := time.Duration(attempt*100) * time.Millisecond
-	return baseDelay + jitter
+	return retryDelay(p.retryStrategy, p.retryMaxDelay, attempt, err)
 }
 
-// GetBackendInfo returns information about which backend is being used
+// GetBackendInfo returns which backend serves requests: "gemini_api" or "vertex_ai"
 func (p *GeminiProvider) GetBackendInfo() string {
 	if p.client == nil {
 		return "unknown"
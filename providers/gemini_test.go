@@ -2,9 +2,18 @@ package providers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"google.golang.org/genai"
 )
 
 func TestNewGeminiProvider(t *testing.T) {
@@ -94,7 +103,7 @@ func TestGeminiProvider_ValidateRequest(t *testing.T) {
 			req: ChatRequest{
 				Model:       "gemini-2.0-flash",
 				UserPrompt:  "Hello, world!",
-				Temperature: 3.0,
+				Temperature: float64Ptr(3.0),
 			},
 			wantErr: true,
 		},
@@ -151,8 +160,8 @@ func TestGeminiProvider_TokenCount(t *testing.T) {
 				Content: "This is a longer response with more tokens to count",
 			},
 			wantInput:  0,
-			wantOutput: 11, // 44 chars / 4 = 11
-			wantTotal:  11,
+			wantOutput: 12, // 51 chars / 4 = 12
+			wantTotal:  12,
 		},
 	}
 
@@ -193,7 +202,7 @@ func TestGeminiProvider_GetTokenCount(t *testing.T) {
 		{
 			name: "longer text",
 			text: "This is a longer text with more tokens to count",
-			want: 10, // 40 chars / 4 = 10
+			want: 11, // 47 chars / 4 = 11
 		},
 	}
 
@@ -291,4 +300,298 @@ func TestGeminiProvider_GetRetryDelay(t *testing.T) {
 			}
 		})
 	}
-} 
\ No newline at end of file
+} 
+
+func TestGeminiUsage(t *testing.T) {
+	got := geminiUsage(&genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:        100,
+		CachedContentTokenCount: 64,
+		CandidatesTokenCount:    20,
+		ThoughtsTokenCount:      180,
+	})
+	want := Usage{InputTokens: 100, OutputTokens: 200, CachedInputTokens: 64, ReasoningTokens: 180}
+	if got == nil || *got != want {
+		t.Errorf("geminiUsage() = %+v, want %+v", got, want)
+	}
+
+	if got := geminiUsage(&genai.GenerateContentResponseUsageMetadata{}); got != nil {
+		t.Errorf("geminiUsage(empty) = %+v, want nil", got)
+	}
+}
+
+func TestGeminiProvider_CountInputTokens(t *testing.T) {
+	var path string
+	var body struct {
+		Contents []struct {
+			Role  string `json:"role"`
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"contents"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"totalTokens":17}`))
+	}))
+	defer server.Close()
+
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	provider := &GeminiProvider{client: client, config: &GeminiConfig{APIKey: "test-key"}}
+
+	tokens, err := provider.CountInputTokens(context.Background(), ChatRequest{
+		Model:        "gemini-2.0-flash",
+		SystemPrompt: "Be brief.",
+		Messages: []Message{
+			{Role: "user", Content: "What is the capital of France?"},
+			{Role: "assistant", Content: "Paris."},
+			{Role: "user", Content: "And of Italy?"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CountInputTokens() error = %v", err)
+	}
+	if tokens != 17 {
+		t.Errorf("CountInputTokens() = %d, want 17", tokens)
+	}
+	if !strings.HasSuffix(path, "/models/gemini-2.0-flash:countTokens") {
+		t.Errorf("request path = %s, want the model's countTokens endpoint", path)
+	}
+
+	// The whole conversation is counted, with the system prompt folded into the first turn
+	if len(body.Contents) != 3 {
+		t.Fatalf("counted %d contents, want 3", len(body.Contents))
+	}
+	if first := body.Contents[0]; first.Role != "user" || len(first.Parts) != 1 || first.Parts[0].Text != "Be brief.\n\nWhat is the capital of France?" {
+		t.Errorf("first content = %+v, want the system prompt prepended to the first user turn", first)
+	}
+	if last := body.Contents[2]; last.Role != "user" || len(last.Parts) != 1 || last.Parts[0].Text != "And of Italy?" {
+		t.Errorf("last content = %+v, want the final user turn", last)
+	}
+}
+
+func TestGeminiProvider_ResponseFormat(t *testing.T) {
+	var body struct {
+		GenerationConfig struct {
+			ResponseMIMEType   string                 `json:"responseMimeType"`
+			ResponseJSONSchema map[string]interface{} `json:"responseJsonSchema"`
+		} `json:"generationConfig"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"{\"city\":\"Paris\"}"}]},"finishReason":"STOP"}]}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	provider := &GeminiProvider{client: client, config: &GeminiConfig{APIKey: "test-key"}}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+	}
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:      "gemini-2.0-flash",
+		UserPrompt: "Capital of France as JSON",
+		ExtraParams: map[string]interface{}{
+			"response_format": map[string]interface{}{
+				"type":        "json_schema",
+				"json_schema": map[string]interface{}{"name": "capital", "schema": schema},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	for _, response := range collectResponses(t, responseChan) {
+		if response.Error != nil {
+			t.Fatalf("unexpected error: %v", response.Error)
+		}
+	}
+
+	if body.GenerationConfig.ResponseMIMEType != "application/json" {
+		t.Errorf("responseMimeType = %q, want application/json", body.GenerationConfig.ResponseMIMEType)
+	}
+	if !reflect.DeepEqual(body.GenerationConfig.ResponseJSONSchema, schema) {
+		t.Errorf("responseJsonSchema = %v, want %v", body.GenerationConfig.ResponseJSONSchema, schema)
+	}
+}
+
+func TestGeminiProvider_SendsImage(t *testing.T) {
+	type part struct {
+		Text       string `json:"text"`
+		InlineData *struct {
+			MIMEType string `json:"mimeType"`
+			Data     string `json:"data"`
+		} `json:"inlineData"`
+		FileData *struct {
+			MIMEType string `json:"mimeType"`
+			FileURI  string `json:"fileUri"`
+		} `json:"fileData"`
+	}
+
+	tests := []struct {
+		name  string
+		image *Image
+		check func(t *testing.T, image part)
+	}{
+		{
+			name:  "inline bytes",
+			image: &Image{MediaType: "image/png", Data: []byte("png")},
+			check: func(t *testing.T, image part) {
+				if image.InlineData == nil || image.InlineData.MIMEType != "image/png" || image.InlineData.Data != "cG5n" {
+					t.Errorf("image part = %+v, want the PNG inline and base64-encoded", image)
+				}
+			},
+		},
+		{
+			name:  "URI",
+			image: &Image{URL: "https://example.com/cat.jpg", MediaType: "image/jpeg"},
+			check: func(t *testing.T, image part) {
+				if image.FileData == nil || image.FileData.MIMEType != "image/jpeg" || image.FileData.FileURI != "https://example.com/cat.jpg" {
+					t.Errorf("image part = %+v, want the URL with its media type", image)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body struct {
+				Contents []struct {
+					Role  string `json:"role"`
+					Parts []part `json:"parts"`
+				} `json:"contents"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&body)
+
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte(`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"A cat."}]},"finishReason":"STOP"}]}` + "\n\n"))
+			}))
+			defer server.Close()
+
+			client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+				APIKey:      "test-key",
+				Backend:     genai.BackendGeminiAPI,
+				HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			provider := &GeminiProvider{client: client, config: &GeminiConfig{APIKey: "test-key"}}
+
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:      "gemini-2.0-flash",
+				UserPrompt: "What is in this image?",
+				Image:      tt.image,
+			})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			for _, response := range collectResponses(t, responseChan) {
+				if response.Error != nil {
+					t.Fatalf("unexpected error: %v", response.Error)
+				}
+			}
+
+			// The image follows the prompt text in the final user message
+			if len(body.Contents) != 1 || len(body.Contents[0].Parts) != 2 {
+				t.Fatalf("contents = %+v, want one message with a text and an image part", body.Contents)
+			}
+			if text := body.Contents[0].Parts[0].Text; text != "What is in this image?" {
+				t.Errorf("first part text = %q, want the prompt", text)
+			}
+			tt.check(t, body.Contents[0].Parts[1])
+		})
+	}
+}
+
+func TestGeminiResponseFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   map[string]interface{}
+		wantMIME string
+	}{
+		{"none", nil, ""},
+		{"text", map[string]interface{}{"response_format": map[string]interface{}{"type": "text"}}, ""},
+		{"json object", map[string]interface{}{"response_format": map[string]interface{}{"type": "json_object"}}, "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mimeType, schema := geminiResponseFormat(tt.params)
+			if mimeType != tt.wantMIME || schema != nil {
+				t.Errorf("geminiResponseFormat() = %q, %v, want %q and no schema", mimeType, schema, tt.wantMIME)
+			}
+		})
+	}
+}
+
+func TestGeminiProvider_GetBackendInfo(t *testing.T) {
+	// The backend is chosen by the configuration, not the SDK's environment variable
+	t.Setenv("GOOGLE_GENAI_USE_VERTEXAI", "true")
+
+	provider, err := NewGeminiProvider(&GeminiConfig{APIKey: "test-api-key"})
+	if err != nil {
+		t.Fatalf("NewGeminiProvider() error = %v", err)
+	}
+	if got := provider.GetBackendInfo(); got != "gemini_api" {
+		t.Errorf("GetBackendInfo() = %q, want gemini_api", got)
+	}
+
+	// Vertex AI authenticates with application default credentials
+	credentials := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(credentials, []byte(`{"type":"authorized_user","client_id":"id","client_secret":"secret","refresh_token":"token","quota_project_id":"bench-project"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credentials)
+
+	provider, err = NewGeminiProvider(&GeminiConfig{APIKey: "test-api-key", UseVertex: true, Project: "bench-project"})
+	if err != nil {
+		t.Fatalf("NewGeminiProvider() error = %v", err)
+	}
+	if got := provider.GetBackendInfo(); got != "vertex_ai" {
+		t.Errorf("GetBackendInfo() = %q, want vertex_ai", got)
+	}
+	if got := provider.client.ClientConfig(); got.Project != "bench-project" || got.Location != DefaultVertexLocation {
+		t.Errorf("Project, Location = %q, %q, want bench-project, %q", got.Project, got.Location, DefaultVertexLocation)
+	}
+
+	provider, err = NewGeminiProvider(&GeminiConfig{UseVertex: true, Project: "bench-project", Location: "europe-west4"})
+	if err != nil {
+		t.Fatalf("NewGeminiProvider() error = %v", err)
+	}
+	if got := provider.client.ClientConfig().Location; got != "europe-west4" {
+		t.Errorf("Location = %q, want europe-west4", got)
+	}
+
+	for _, config := range []*GeminiConfig{
+		{UseVertex: true},
+		{UseVertex: true, Project: "bench-project", ProxyURL: "http://proxy.example.com:8080"},
+	} {
+		if _, err := NewGeminiProvider(config); err == nil {
+			t.Errorf("NewGeminiProvider(%+v) succeeded, want a ConfigurationError", *config)
+		} else if _, ok := err.(*ConfigurationError); !ok {
+			t.Errorf("NewGeminiProvider(%+v) error = %v, want a ConfigurationError", *config, err)
+		}
+	}
+}
@@ -1,30 +1,47 @@
 package providers
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"time"
 
     "github.com/openai/openai-go/v2"
     "github.com/openai/openai-go/v2/option"
+
+    "github.com/megzo/llm-latency-benchmark/internal/retry"
+    "github.com/megzo/llm-latency-benchmark/internal/tokenizer"
 )
 
 // GroqProvider implements the Provider interface for Groq
 type GroqProvider struct {
-	client openai.Client
-	config *GroqConfig
+	client        openai.Client
+	httpClient    *http.Client // For requests made directly rather than through the SDK
+	config        *GroqConfig
+	keys          *keyPool
+	tokenizer     tokenizer.Tokenizer
+	retryStrategy retry.Strategy
+	retryMaxDelay time.Duration
 }
 
 // GroqConfig holds Groq-specific configuration
 type GroqConfig struct {
 	APIKey  string
+	// APIKeys are further keys requests rotate across along with APIKey, to get
+	// past one key's rate limit
+	APIKeys []string
 	BaseURL string
+	// Tokenizer estimates token counts; nil uses the default heuristic
+	Tokenizer tokenizer.Tokenizer
+	// RetryStrategy selects the backoff between retries; "" uses retry.Default
+	RetryStrategy retry.Strategy
+	// RetryMaxDelay caps the backoff between retries; 0 uses retry.MaxDelay
+	RetryMaxDelay time.Duration
+	// ProxyURL sends requests through this proxy instead of the one HTTP_PROXY or
+	// HTTPS_PROXY name; "" honors those variables
+	ProxyURL string
 }
 
 // GroqChatRequest represents the Groq-specific chat completion request
@@ -37,12 +54,9 @@ type GroqChatRequest struct {
 	Stream              bool      `json:"stream"`
 	ReasoningEffort     *string   `json:"reasoning_effort,omitempty"`
 	Stop                []string  `json:"stop,omitempty"`
-}
-
-// Message represents a chat message
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	StreamOptions       struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
 }
 
 // GroqChatResponse represents the Groq streaming response
@@ -58,6 +72,12 @@ type GroqChatResponse struct {
 		} `json:"delta"`
 		FinishReason *string `json:"finish_reason"`
 	} `json:"choices"`
+	// Usage arrives on the final chunk when stream_options.include_usage is set;
+	// older responses only report it under x_groq
+	Usage *openAIUsage `json:"usage"`
+	XGroq struct {
+		Usage *openAIUsage `json:"usage"`
+	} `json:"x_groq"`
 }
 
 // NewGroqProvider creates a new Groq provider instance
@@ -74,14 +94,25 @@ func NewGroqProvider(config *GroqConfig) (*GroqProvider, error) {
 		config.BaseURL = "https://api.groq.com/openai/v1"
 	}
 
+	httpClient, err := newHTTPClient(config.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+
 	client := openai.NewClient(
 		option.WithAPIKey(config.APIKey),
 		option.WithBaseURL(config.BaseURL),
+		option.WithHTTPClient(httpClient),
 	)
 
 	return &GroqProvider{
-		client: client,
-		config: config,
+		client:        client,
+		httpClient:    httpClient,
+		config:        config,
+		keys:          newKeyPool(append([]string{config.APIKey}, config.APIKeys...)...),
+		tokenizer:     tokenizer.OrDefault(config.Tokenizer),
+		retryStrategy: config.RetryStrategy,
+		retryMaxDelay: config.RetryMaxDelay,
 	}, nil
 }
 
@@ -120,11 +151,7 @@ func (p *GroqProvider) streamChatDirect(ctx context.Context, req ChatRequest, re
 	defer close(responseChan)
 
 	// Build messages
-	messages := []Message{}
-	if req.SystemPrompt != "" {
-		messages = append(messages, Message{Role: "system", Content: req.SystemPrompt})
-	}
-	messages = append(messages, Message{Role: "user", Content: req.UserPrompt})
+	messages := req.ChatMessages()
 
     // Build Groq-specific request
     groqReq := GroqChatRequest{
@@ -132,12 +159,14 @@ func (p *GroqProvider) streamChatDirect(ctx context.Context, req ChatRequest, re
 		Messages: messages,
 		Stream:  true,
 	}
+	// Ask for a final usage chunk so token counts aren't estimated
+	groqReq.StreamOptions.IncludeUsage = true
 
 	if req.MaxTokens > 0 {
 		groqReq.MaxCompletionTokens = &req.MaxTokens
 	}
-	if req.Temperature > 0 {
-		groqReq.Temperature = &req.Temperature
+	if req.Temperature != nil {
+		groqReq.Temperature = req.Temperature
 	}
 	if req.TopP > 0 {
 		groqReq.TopP = &req.TopP
@@ -150,17 +179,7 @@ func (p *GroqProvider) streamChatDirect(ctx context.Context, req ChatRequest, re
     if req.ExtraParams != nil {
         // response_format -> ignore for now (Groq may not support)
         // tools -> ignore unless Groq supports
-        if stops, ok := req.ExtraParams["stop"].([]string); ok {
-            groqReq.Stop = stops
-        }
-        if stopsIface, ok := req.ExtraParams["stop"].([]interface{}); ok {
-            // Convert []interface{} to []string
-            ss := make([]string, 0, len(stopsIface))
-            for _, s := range stopsIface {
-                if str, ok := s.(string); ok { ss = append(ss, str) }
-            }
-            if len(ss) > 0 { groqReq.Stop = ss }
-        }
+        groqReq.Stop = req.StopSequences()
         if t, ok := req.ExtraParams["temperature"].(float64); ok { groqReq.Temperature = &t }
         if tp, ok := req.ExtraParams["top_p"].(float64); ok { groqReq.TopP = &tp }
         if mct, ok := req.ExtraParams["max_completion_tokens"].(int); ok { groqReq.MaxCompletionTokens = &mct }
@@ -169,7 +188,7 @@ func (p *GroqProvider) streamChatDirect(ctx context.Context, req ChatRequest, re
 	// Marshal request
 	reqBody, err := json.Marshal(groqReq)
 	if err != nil {
-		responseChan <- ChatResponse{
+		sendResponse(ctx, responseChan, ChatResponse{
 			Content:    "",
 			IsComplete: true,
 			Timestamp:  time.Now(),
@@ -178,14 +197,14 @@ func (p *GroqProvider) streamChatDirect(ctx context.Context, req ChatRequest, re
 				Message:  "failed to marshal request",
 				Cause:    err,
 			},
-		}
+		})
 		return
 	}
 
 	// Create HTTP request
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/chat/completions", bytes.NewBuffer(reqBody))
 	if err != nil {
-		responseChan <- ChatResponse{
+		sendResponse(ctx, responseChan, ChatResponse{
 			Content:    "",
 			IsComplete: true,
 			Timestamp:  time.Now(),
@@ -194,54 +213,57 @@ func (p *GroqProvider) streamChatDirect(ctx context.Context, req ChatRequest, re
 				Message:  "failed to create HTTP request",
 				Cause:    err,
 			},
-		}
+		})
 		return
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	key := p.keys.Next()
+	httpReq.Header.Set("Authorization", "Bearer "+key)
+	setRequestID(httpReq, req.RequestID)
 
 	// Make request
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	httpReq, tracer := withNetworkTrace(httpReq)
+	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		responseChan <- ChatResponse{
+		sendResponse(ctx, responseChan, ChatResponse{
 			Content:    "",
 			IsComplete: true,
 			Timestamp:  time.Now(),
-			Error: &ProviderError{
+			Error: &NetworkError{
 				Provider: "groq",
-				Message:  "failed to make HTTP request",
 				Cause:    err,
 			},
-		}
+		})
 		return
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		responseChan <- ChatResponse{
+		err := newHTTPError("groq", resp, body)
+		p.keys.Observe(key, err)
+		sendResponse(ctx, responseChan, ChatResponse{
 			Content:    "",
 			IsComplete: true,
 			Timestamp:  time.Now(),
-			Error: &ProviderError{
-				Provider: "groq",
-				Message:  fmt.Sprintf("HTTP error %d: %s", resp.StatusCode, string(body)),
-			},
-		}
+			Error:      err,
+			RateLimit:  parseRateLimitHeaders(resp.Header),
+		})
 		return
 	}
 
 	// Read streaming response
-	reader := bufio.NewReader(resp.Body)
+	finishReason := ""
+	var usage *Usage
+	events := newSSEReader(resp.Body)
 	for {
-		line, err := reader.ReadString('\n')
+		data, err := events.Next()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			responseChan <- ChatResponse{
+			sendResponse(ctx, responseChan, ChatResponse{
 				Content:    "",
 				IsComplete: true,
 				Timestamp:  time.Now(),
@@ -250,46 +272,51 @@ func (p *GroqProvider) streamChatDirect(ctx context.Context, req ChatRequest, re
 					Message:  "failed to read response stream",
 					Cause:    err,
 				},
-			}
+			})
 			return
 		}
 
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+		if data == "[DONE]" {
+			break
 		}
 
-		// Handle SSE format: "data: {...}"
-		if strings.HasPrefix(line, "data: ") {
-			data := strings.TrimPrefix(line, "data: ")
-			if data == "[DONE]" {
-				break
-			}
+		var groqResp GroqChatResponse
+		if err := json.Unmarshal([]byte(data), &groqResp); err != nil {
+			continue // Skip malformed JSON
+		}
+		if groqResp.Usage != nil {
+			usage = groqResp.Usage.toUsage()
+		} else if groqResp.XGroq.Usage != nil {
+			usage = groqResp.XGroq.Usage.toUsage()
+		}
 
-			var groqResp GroqChatResponse
-			if err := json.Unmarshal([]byte(data), &groqResp); err != nil {
-				continue // Skip malformed JSON
+		if len(groqResp.Choices) > 0 {
+			choice := groqResp.Choices[0]
+			if choice.FinishReason != nil && *choice.FinishReason != "" {
+				finishReason = *choice.FinishReason
 			}
-
-			if len(groqResp.Choices) > 0 {
-				choice := groqResp.Choices[0]
-				if choice.Delta.Content != "" {
-					responseChan <- ChatResponse{
-						Content:    choice.Delta.Content,
-						IsComplete: false,
-						Timestamp:  time.Now(),
-					}
+			if choice.Delta.Content != "" {
+				if !sendResponse(ctx, responseChan, ChatResponse{
+					Content:    choice.Delta.Content,
+					IsComplete: false,
+					Timestamp:  time.Now(),
+				}) {
+					return
 				}
 			}
 		}
 	}
 
 	// Stream completed successfully
-	responseChan <- ChatResponse{
-		Content:    "",
-		IsComplete: true,
-		Timestamp:  time.Now(),
-	}
+	sendResponse(ctx, responseChan, ChatResponse{
+		Content:       "",
+		IsComplete:    true,
+		Timestamp:     time.Now(),
+		FinishReason:  finishReason,
+		NetworkTiming: tracer.Timing(),
+		Usage:         usage,
+		RateLimit:     parseRateLimitHeaders(resp.Header),
+	})
 }
 
 // streamChatOpenAI performs streaming chat using OpenAI library
@@ -297,11 +324,7 @@ func (p *GroqProvider) streamChatOpenAI(ctx context.Context, req ChatRequest, re
 	defer close(responseChan)
 
 	// Build messages for Groq API (OpenAI-compatible)
-	messages := []openai.ChatCompletionMessageParamUnion{}
-	if req.SystemPrompt != "" {
-		messages = append(messages, openai.SystemMessage(req.SystemPrompt))
-	}
-	messages = append(messages, openai.UserMessage(req.UserPrompt))
+	messages := openAIMessages(req.ChatMessages())
 
 	chatReq := openai.ChatCompletionNewParams{
 		Model:    openai.ChatModel(req.Model),
@@ -310,25 +333,41 @@ func (p *GroqProvider) streamChatOpenAI(ctx context.Context, req ChatRequest, re
 	if req.MaxTokens > 0 {
 		chatReq.MaxTokens = openai.Int(int64(req.MaxTokens))
 	}
-	if req.Temperature > 0 {
-		chatReq.Temperature = openai.Float(req.Temperature)
+	if req.Temperature != nil {
+		chatReq.Temperature = openai.Float(*req.Temperature)
 	}
 	if req.TopP > 0 {
 		chatReq.TopP = openai.Float(req.TopP)
 	}
+	if stops := req.StopSequences(); len(stops) > 0 {
+		chatReq.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: stops}
+	}
 
-	// Create streaming completion
-	stream := p.client.Chat.Completions.NewStreaming(ctx, chatReq)
+	// Create streaming completion, asking for a final usage chunk
+	chatReq.StreamOptions.IncludeUsage = openai.Bool(true)
+	key := p.keys.Next()
+	opts := append(requestIDOptions(req.RequestID), option.WithAPIKey(key))
+	stream := p.client.Chat.Completions.NewStreaming(ctx, chatReq, opts...)
 	
+	finishReason := ""
+	var usage *Usage
 	for stream.Next() {
 		resp := stream.Current()
+		if resp.Usage.PromptTokens > 0 {
+			usage = openAISDKUsage(resp.Usage)
+		}
 		if len(resp.Choices) > 0 {
 			choice := resp.Choices[0]
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
 			if choice.Delta.Content != "" {
-				responseChan <- ChatResponse{
+				if !sendResponse(ctx, responseChan, ChatResponse{
 					Content:    choice.Delta.Content,
 					IsComplete: false,
 					Timestamp:  time.Now(),
+				}) {
+					return
 				}
 			}
 		}
@@ -336,7 +375,8 @@ func (p *GroqProvider) streamChatOpenAI(ctx context.Context, req ChatRequest, re
 	
 	// Check for errors
 	if err := stream.Err(); err != nil {
-		responseChan <- ChatResponse{
+		p.keys.Observe(key, err)
+		sendResponse(ctx, responseChan, ChatResponse{
 			Content:    "",
 			IsComplete: true,
 			Timestamp:  time.Now(),
@@ -345,45 +385,38 @@ func (p *GroqProvider) streamChatOpenAI(ctx context.Context, req ChatRequest, re
 				Message:  "failed to receive stream response",
 				Cause:    err,
 			},
-		}
+		})
 		return
 	}
 	
 	// Stream completed successfully
-	responseChan <- ChatResponse{
-		Content:    "",
-		IsComplete: true,
-		Timestamp:  time.Now(),
-	}
+	sendResponse(ctx, responseChan, ChatResponse{
+		Content:      "",
+		IsComplete:   true,
+		Timestamp:    time.Now(),
+		FinishReason: finishReason,
+		Usage:        usage,
+	})
 }
 
-// TokenCount returns the token counts for a response
-// Note: Groq doesn't provide token counts in streaming responses
-// This is a simplified implementation - in practice, you might want to
-// use a tokenizer library for accurate counting
+// TokenCount returns the token counts for a response, estimating output tokens
+// with the provider's tokenizer when the stream reported no usage
 func (p *GroqProvider) TokenCount(response ChatResponse) (input, output, total int) {
-	// This is a rough estimation - for production use, consider using
-	// a proper tokenizer like tiktoken or similar
-	if response.Content != "" {
-		// Rough estimation: ~4 characters per token for English text
-		output = len(response.Content) / 4
-		if output < 1 {
-			output = 1
-		}
+	if response.Usage != nil {
+		return response.Usage.InputTokens, response.Usage.OutputTokens, response.Usage.InputTokens + response.Usage.OutputTokens
 	}
-	
+	output = p.Tokenizer().Count("", response.Content)
 	return 0, output, output
 }
 
 // GetTokenCount estimates token count for input text
-// This is a simplified implementation - consider using a proper tokenizer
 func (p *GroqProvider) GetTokenCount(text string) int {
-	// Rough estimation: ~4 characters per token for English text
-	count := len(text) / 4
-	if count < 1 {
-		count = 1
-	}
-	return count
+	return p.Tokenizer().Count("", text)
+}
+
+// Tokenizer returns the tokenizer used for token estimates
+func (p *GroqProvider) Tokenizer() tokenizer.Tokenizer {
+	return tokenizer.OrDefault(p.tokenizer)
 }
 
 // ValidateRequest validates the chat request
@@ -395,7 +428,7 @@ func (p *GroqProvider) ValidateRequest(req ChatRequest) error {
 		}
 	}
 
-	if req.UserPrompt == "" {
+	if req.UserPrompt == "" && len(req.Messages) == 0 {
 		return &ValidationError{
 			Field:   "user_prompt",
 			Message: "user prompt is required",
@@ -409,7 +442,7 @@ func (p *GroqProvider) ValidateRequest(req ChatRequest) error {
 		}
 	}
 
-	if req.Temperature < 0 || req.Temperature > 2 {
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
 		return &ValidationError{
 			Field:   "temperature",
 			Message: "temperature must be between 0 and 2",
@@ -428,52 +461,10 @@ func (p *GroqProvider) ValidateRequest(req ChatRequest) error {
 
 // IsRetryableError checks if an error is retryable
 func (p *GroqProvider) IsRetryableError(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	// Check for rate limit errors
-	if strings.Contains(err.Error(), "rate_limit") || 
-	   strings.Contains(err.Error(), "429") {
-		return true
-	}
-
-	// Check for server errors
-	if strings.Contains(err.Error(), "500") ||
-	   strings.Contains(err.Error(), "502") ||
-	   strings.Contains(err.Error(), "503") ||
-	   strings.Contains(err.Error(), "504") {
-		return true
-	}
-
-	// Check for timeout errors
-	if strings.Contains(err.Error(), "timeout") ||
-	   strings.Contains(err.Error(), "deadline exceeded") {
-		return true
-	}
-
-	// Check for connection errors
-	if strings.Contains(err.Error(), "connection refused") ||
-	   strings.Contains(err.Error(), "no route to host") ||
-	   strings.Contains(err.Error(), "network is unreachable") {
-		return true
-	}
-
-	return false
+	return isRetryableOpenAIError(err)
 }
 
 // GetRetryDelay calculates the delay before retrying
 func (p *GroqProvider) GetRetryDelay(attempt int, err error) time.Duration {
-	// Base delay with exponential backoff
-	baseDelay := time.Duration(attempt*attempt) * time.Second
-	
-	// Cap at 30 seconds
-	if baseDelay > 30*time.Second {
-		baseDelay = 30 * time.Second
-	}
-	
-	// Add jitter to prevent thundering herd
-	jitter := time.Duration(attempt) * 100 * time.Millisecond
-	
-	return baseDelay + jitter
+	return retryDelay(p.retryStrategy, p.retryMaxDelay, attempt, err)
 } 
\ No newline at end of file
@@ -1,8 +1,16 @@
 package providers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/openai/openai-go/v2"
 )
 
 func TestNewGroqProvider(t *testing.T) {
@@ -106,7 +114,7 @@ func TestGroqProvider_ValidateRequest(t *testing.T) {
 			request: ChatRequest{
 				Model:       "llama-3-70b",
 				UserPrompt:  "Hello, world!",
-				Temperature: 3.0,
+				Temperature: float64Ptr(3.0),
 			},
 			wantErr: true,
 		},
@@ -169,8 +177,8 @@ func TestGroqProvider_TokenCount(t *testing.T) {
 				Content: "This is a longer response with more tokens to count",
 			},
 			wantInput:  0,
-			wantOutput: 11, // 44 chars / 4 = 11
-			wantTotal:  11,
+			wantOutput: 12, // 51 chars / 4 = 12
+			wantTotal:  12,
 		},
 	}
 
@@ -217,7 +225,7 @@ func TestGroqProvider_GetTokenCount(t *testing.T) {
 		{
 			name: "longer text",
 			text: "This is a longer text with more tokens to count",
-			want: 10, // 40 chars / 4 = 10
+			want: 11, // 47 chars / 4 = 11
 		},
 	}
 
@@ -252,29 +260,34 @@ func TestGroqProvider_IsRetryableError(t *testing.T) {
 		},
 		{
 			name: "rate limit error",
-			err:  &ProviderError{Provider: "groq", Message: "rate_limit exceeded"},
+			err:  &RateLimitError{Provider: "groq", RetryAfter: time.Second},
 			want: true,
 		},
 		{
-			name: "429 error",
-			err:  &ProviderError{Provider: "groq", Message: "429 too many requests"},
+			name: "SDK 429 error",
+			err:  &ProviderError{Provider: "groq", Message: "stream error", Cause: &openai.Error{StatusCode: http.StatusTooManyRequests}},
 			want: true,
 		},
 		{
 			name: "server error",
-			err:  &ProviderError{Provider: "groq", Message: "500 internal server error"},
+			err:  &ServerError{Provider: "groq", StatusCode: http.StatusInternalServerError},
 			want: true,
 		},
 		{
 			name: "timeout error",
-			err:  &ProviderError{Provider: "groq", Message: "timeout after 30s"},
+			err:  &TimeoutError{Operation: "stream", Duration: 30 * time.Second},
 			want: true,
 		},
 		{
 			name: "connection error",
-			err:  &ProviderError{Provider: "groq", Message: "connection refused"},
+			err:  &NetworkError{Provider: "groq", Cause: errors.New("connection refused")},
 			want: true,
 		},
+		{
+			name: "auth error",
+			err:  &AuthError{Provider: "groq", StatusCode: http.StatusUnauthorized},
+			want: false,
+		},
 		{
 			name: "non-retryable error",
 			err:  &ProviderError{Provider: "groq", Message: "invalid API key"},
@@ -319,8 +332,15 @@ func TestGroqProvider_GetRetryDelay(t *testing.T) {
 			name:     "second attempt",
 			attempt:  2,
 			err:      &ProviderError{Provider: "groq", Message: "500 error"},
-			wantMin:  4 * time.Second,
-			wantMax:  5 * time.Second,
+			wantMin:  2 * time.Second,
+			wantMax:  3 * time.Second,
+		},
+		{
+			name:     "rate limit with a reset hint",
+			attempt:  1,
+			err:      &ProviderError{Provider: "groq", Message: "error in streaming response", Cause: &RateLimitError{Provider: "groq", RetryAfter: 7 * time.Second}},
+			wantMin:  7 * time.Second,
+			wantMax:  7 * time.Second,
 		},
 		{
 			name:     "high attempt (should be capped)",
@@ -339,4 +359,103 @@ func TestGroqProvider_GetRetryDelay(t *testing.T) {
 			}
 		})
 	}
-} 
\ No newline at end of file
+} 
+func TestGroqProvider_StreamUsage(t *testing.T) {
+	// A recorded Groq stream: usage arrives on a final chunk without choices
+	stream := []string{
+		`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1750000000,"model":"qwen/qwen3-32b","choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}`,
+		`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1750000000,"model":"qwen/qwen3-32b","choices":[{"index":0,"delta":{"content":"Hello"},"finish_reason":null}]}`,
+		`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1750000000,"model":"qwen/qwen3-32b","choices":[{"index":0,"delta":{"content":" there!"},"finish_reason":"stop"}]}`,
+		`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1750000000,"model":"qwen/qwen3-32b","choices":[],"usage":{"queue_time":0.02,"prompt_tokens":18,"prompt_time":0.003,"completion_tokens":3,"completion_time":0.01,"total_tokens":21,"total_time":0.013},"x_groq":{"id":"req_01"}}`,
+	}
+
+	tests := []struct {
+		name  string
+		extra map[string]interface{}
+	}{
+		{"SDK path", nil},
+		{"direct path", map[string]interface{}{"reasoning_effort": "none"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&gotBody)
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, chunk := range stream {
+					w.Write([]byte("data: " + chunk + "\n\n"))
+				}
+				w.Write([]byte("data: [DONE]\n\n"))
+			}))
+			defer server.Close()
+
+			provider, err := NewGroqProvider(&GroqConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{Model: "qwen/qwen3-32b", UserPrompt: "Say hello", ExtraParams: tt.extra})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			responses := collectResponses(t, responseChan)
+
+			if options, _ := gotBody["stream_options"].(map[string]interface{}); options["include_usage"] != true {
+				t.Errorf("stream_options = %v, want include_usage", gotBody["stream_options"])
+			}
+
+			final := responses[len(responses)-1]
+			if final.Error != nil {
+				t.Fatalf("unexpected error: %v", final.Error)
+			}
+			if final.Usage == nil || final.Usage.InputTokens != 18 || final.Usage.OutputTokens != 3 {
+				t.Fatalf("usage = %+v, want 18 input and 3 output tokens", final.Usage)
+			}
+			if input, output, total := provider.TokenCount(final); input != 18 || output != 3 || total != 21 {
+				t.Errorf("TokenCount() = %d, %d, %d, want 18, 3, 21", input, output, total)
+			}
+		})
+	}
+}
+
+func TestGroqProvider_StopSequences(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra map[string]interface{}
+	}{
+		{"SDK path", map[string]interface{}{"stop": []string{"\n\n", "END"}}},
+		{"direct path", map[string]interface{}{"stop": []interface{}{"\n\n", "END"}, "reasoning_effort": "none"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&gotBody)
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte(`data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"qwen/qwen3-32b","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}` + "\n\n"))
+				w.Write([]byte("data: [DONE]\n\n"))
+			}))
+			defer server.Close()
+
+			provider, err := NewGroqProvider(&GroqConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{Model: "qwen/qwen3-32b", UserPrompt: "Say hello", ExtraParams: tt.extra})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			if responses := collectResponses(t, responseChan); responses[len(responses)-1].Error != nil {
+				t.Fatalf("unexpected error: %v", responses[len(responses)-1].Error)
+			}
+
+			want := []interface{}{"\n\n", "END"}
+			if got, _ := gotBody["stop"].([]interface{}); !reflect.DeepEqual(got, want) {
+				t.Errorf("stop = %#v, want %#v", gotBody["stop"], want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestProxyURL_RoutesRequestsThroughProxy(t *testing.T) {
+	// A bogus environment proxy shows the configured one takes precedence
+	t.Setenv("HTTP_PROXY", "http://127.0.0.1:1")
+
+	var mu sync.Mutex
+	var proxied []*http.Request
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"1","model":"grok-3","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer proxy.Close()
+
+	// The API host doesn't resolve, so the request only succeeds through the proxy
+	provider, err := NewOpenAICompatibleProvider("xai", &OpenAICompatibleConfig{
+		APIKey:   "test-key",
+		BaseURL:  "http://api.example.invalid/v1",
+		ProxyURL: proxy.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{Model: "grok-3", UserPrompt: "Hello"})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	responses := collectResponses(t, responseChan)
+
+	final := responses[len(responses)-1]
+	if final.Error != nil {
+		t.Fatalf("unexpected error: %v", final.Error)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(proxied) != 1 {
+		t.Fatalf("proxy received %d requests, want 1", len(proxied))
+	}
+	if got := proxied[0]; got.Host != "api.example.invalid" || got.URL.Path != "/v1/chat/completions" {
+		t.Errorf("proxied request for %s%s, want api.example.invalid/v1/chat/completions", got.Host, got.URL.Path)
+	}
+}
+
+func TestProxyURL_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		proxyURL string
+	}{
+		{"unsupported scheme", "ftp://proxy.example.com:21"},
+		{"missing host", "http://"},
+		{"unparseable", "http://proxy.example.com:port"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewOpenAICompatibleProvider("xai", &OpenAICompatibleConfig{APIKey: "test-key", ProxyURL: tt.proxyURL})
+			if err == nil {
+				t.Fatal("expected an error for an invalid proxy URL")
+			}
+			if _, ok := err.(*ConfigurationError); !ok {
+				t.Errorf("error = %T, want *ConfigurationError", err)
+			}
+		})
+	}
+}
+
+func TestRequestIDHeader(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent = append(sent, r.Header.Get(RequestIDHeader))
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	xai, err := NewOpenAICompatibleProvider("xai", &OpenAICompatibleConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	openAI, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		provider  Provider
+		requestID string
+	}{
+		{"direct HTTP", xai, "req-direct"},
+		{"SDK", openAI, "req-sdk"},
+		{"no ID", xai, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			sent = nil
+			mu.Unlock()
+
+			responseChan, err := tt.provider.StreamChat(context.Background(), ChatRequest{Model: "m", UserPrompt: "Hello", RequestID: tt.requestID})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			if final := collectResponses(t, responseChan); final[len(final)-1].Error != nil {
+				t.Fatalf("unexpected error: %v", final[len(final)-1].Error)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(sent) != 1 || sent[0] != tt.requestID {
+				t.Errorf("%s headers sent = %q, want [%q]", RequestIDHeader, sent, tt.requestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package providers
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v2"
+)
+
+// keyCooldown is how long a rate-limited key is passed over when the response
+// gave no hint of when its limit resets
+const keyCooldown = 30 * time.Second
+
+// keyPool spreads a provider's requests across several API keys so one key's
+// rate limit doesn't cap a concurrent run. Keys are handed out in turn, passing
+// over any that were rate limited until their limit resets. It's safe for
+// concurrent use.
+type keyPool struct {
+	mu           sync.Mutex
+	keys         []string
+	limitedUntil []time.Time
+	next         int
+	now          func() time.Time
+}
+
+// newKeyPool creates a pool of keys, which must not be empty
+func newKeyPool(keys ...string) *keyPool {
+	return &keyPool{
+		keys:         keys,
+		limitedUntil: make([]time.Time, len(keys)),
+		now:          time.Now,
+	}
+}
+
+// Next returns the key for the next request: the next in turn that isn't rate
+// limited or, when all of them are, the one whose limit resets first
+func (p *keyPool) Next() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	chosen := -1
+	for i := range p.keys {
+		index := (p.next + i) % len(p.keys)
+		if !p.limitedUntil[index].After(now) {
+			chosen = index
+			break
+		}
+	}
+	if chosen < 0 {
+		chosen = p.next
+		for index, until := range p.limitedUntil {
+			if until.Before(p.limitedUntil[chosen]) {
+				chosen = index
+			}
+		}
+	}
+
+	p.next = (chosen + 1) % len(p.keys)
+	return p.keys[chosen]
+}
+
+// Observe passes over key until its limit resets if the request made with it
+// failed with a rate limit
+func (p *keyPool) Observe(key string, err error) {
+	retryAfter, limited := rateLimitedFor(err)
+	if !limited {
+		return
+	}
+	if retryAfter <= 0 {
+		retryAfter = keyCooldown
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for index, k := range p.keys {
+		if k == key {
+			p.limitedUntil[index] = p.now().Add(retryAfter)
+		}
+	}
+}
+
+// rateLimitedFor reports whether err is a rate limit, from this package's
+// errors or the OpenAI SDK's, and how long it asked to wait (0 when unknown)
+func rateLimitedFor(err error) (time.Duration, bool) {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr.RetryAfter, true
+	}
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests {
+		if apiErr.Response == nil {
+			return 0, true
+		}
+		retryAfter := parseRetryAfter(apiErr.Response.Header.Get("Retry-After"), time.Now())
+		if retryAfter == 0 {
+			retryAfter = parseRateLimitHeaders(apiErr.Response.Header).ResetDelay()
+		}
+		return retryAfter, true
+	}
+	return 0, false
+}
@@ -0,0 +1,160 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v2"
+)
+
+func TestKeyPool_RoundRobin(t *testing.T) {
+	pool := newKeyPool("key-a", "key-b", "key-c")
+
+	var got []string
+	for i := 0; i < 6; i++ {
+		got = append(got, pool.Next())
+	}
+	if want := "key-a key-b key-c key-a key-b key-c"; strings.Join(got, " ") != want {
+		t.Errorf("Next() = %v, want %s", got, want)
+	}
+
+	// Concurrent requests still share the keys evenly
+	counts := make(map[string]int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 300; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := pool.Next()
+			mu.Lock()
+			counts[key]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	for _, key := range []string{"key-a", "key-b", "key-c"} {
+		if counts[key] != 100 {
+			t.Errorf("%s handed out %d times, want 100 (counts %v)", key, counts[key], counts)
+		}
+	}
+}
+
+func TestKeyPool_DeprioritizesRateLimitedKey(t *testing.T) {
+	now := time.Now()
+	pool := newKeyPool("key-a", "key-b", "key-c")
+	pool.now = func() time.Time { return now }
+
+	// Errors other than rate limits leave the key in rotation
+	pool.Observe("key-b", &ServerError{Provider: "groq", StatusCode: 503})
+	pool.Observe("key-b", &RateLimitError{Provider: "groq", RetryAfter: 10 * time.Second})
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, pool.Next())
+	}
+	if want := "key-a key-c key-a key-c"; strings.Join(got, " ") != want {
+		t.Errorf("Next() while key-b is limited = %v, want %s", got, want)
+	}
+
+	// Once its limit resets, the key is back in turn
+	now = now.Add(11 * time.Second)
+	if got := []string{pool.Next(), pool.Next()}; strings.Join(got, " ") != "key-a key-b" {
+		t.Errorf("Next() after the reset = %v, want key-a then key-b", got)
+	}
+
+	// With every key limited, the one that resets first is used; a 429 from the
+	// SDK without a hint sidelines its key for keyCooldown
+	pool.Observe("key-a", &RateLimitError{Provider: "groq", RetryAfter: 5 * time.Second})
+	pool.Observe("key-b", &ProviderError{Provider: "openai", Cause: &openai.Error{StatusCode: http.StatusTooManyRequests}})
+	pool.Observe("key-c", &RateLimitError{Provider: "groq", RetryAfter: 2 * time.Second})
+	if got := pool.Next(); got != "key-c" {
+		t.Errorf("Next() with every key limited = %s, want key-c, the first to reset", got)
+	}
+	if until := pool.limitedUntil[1]; !until.Equal(now.Add(keyCooldown)) {
+		t.Errorf("key-b limited until %v, want keyCooldown after the 429", until)
+	}
+	now = now.Add(6 * time.Second)
+	if got := []string{pool.Next(), pool.Next(), pool.Next()}; strings.Join(got, " ") != "key-a key-c key-a" {
+		t.Errorf("Next() with key-b still limited = %v, want key-a key-c key-a", got)
+	}
+}
+
+func TestGroqProvider_RotatesAPIKeys(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	limitKeyB := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		mu.Lock()
+		seen[key]++
+		limited := limitKeyB && key == "key-b"
+		mu.Unlock()
+
+		if limited {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1750000000,"model":"llama-3.1-8b-instant","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := NewGroqProvider(&GroqConfig{APIKey: "key-a", APIKeys: []string{"key-b", "key-c"}, BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	// The direct path is used for the rate limit, as the SDK retries 429s itself
+	direct := map[string]interface{}{"reasoning_effort": "none"}
+	run := func(extra map[string]interface{}) error {
+		responseChan, err := provider.StreamChat(context.Background(), ChatRequest{Model: "llama-3.1-8b-instant", UserPrompt: "Hello", ExtraParams: extra})
+		if err != nil {
+			return err
+		}
+		responses := collectResponses(t, responseChan)
+		return responses[len(responses)-1].Error
+	}
+
+	// Both the SDK and the direct path spread requests across all three keys
+	for i := 0; i < 3; i++ {
+		if err := run(nil); err != nil {
+			t.Fatalf("SDK request failed: %v", err)
+		}
+		if err := run(direct); err != nil {
+			t.Fatalf("direct request failed: %v", err)
+		}
+	}
+	for _, key := range []string{"key-a", "key-b", "key-c"} {
+		if seen[key] != 2 {
+			t.Errorf("%s sent %d requests, want 2 (seen %v)", key, seen[key], seen)
+		}
+	}
+
+	// Once key-b is rate limited, the following requests go to the other keys
+	mu.Lock()
+	limitKeyB = true
+	clear(seen)
+	mu.Unlock()
+	var rateLimited int
+	for i := 0; i < 6; i++ {
+		if err := run(direct); err != nil {
+			var rateLimitErr *RateLimitError
+			if !errors.As(err, &rateLimitErr) {
+				t.Fatalf("request failed: %v", err)
+			}
+			rateLimited++
+		}
+	}
+	if rateLimited != 1 || seen["key-b"] != 1 || seen["key-a"]+seen["key-c"] != 5 {
+		t.Errorf("after a 429 on key-b: %d rate limited, keys used %v; want key-b tried once", rateLimited, seen)
+	}
+}
@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// NetworkTiming breaks down where time went before the first response byte, so
+// network latency can be told apart from model latency. Phases that didn't happen
+// (e.g. DNS/connect/TLS on a reused connection) are zero.
+type NetworkTiming struct {
+	DNSLookup       time.Duration `json:"dns_lookup"`
+	Connect         time.Duration `json:"connect"`
+	TLSHandshake    time.Duration `json:"tls_handshake"`
+	TimeToFirstByte time.Duration `json:"time_to_first_byte"` // from starting the request, including the phases above
+}
+
+// networkTracer collects NetworkTiming for a single HTTP request via httptrace
+type networkTracer struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timing       NetworkTiming
+}
+
+// withNetworkTrace returns req instrumented with a tracer; call it right before sending
+func withNetworkTrace(req *http.Request) (*http.Request, *networkTracer) {
+	t := &networkTracer{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if !t.dnsStart.IsZero() {
+				t.timing.DNSLookup = time.Since(t.dnsStart)
+			}
+		},
+		ConnectStart: func(network, addr string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// Dual-stack dialing may start several attempts; time from the first
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil && !t.connectStart.IsZero() {
+				t.timing.Connect = time.Since(t.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if !t.tlsStart.IsZero() {
+				t.timing.TLSHandshake = time.Since(t.tlsStart)
+			}
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timing.TimeToFirstByte = time.Since(t.start)
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), t
+}
+
+// Timing returns a snapshot of the phases measured so far
+func (t *networkTracer) Timing() *NetworkTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timing := t.timing
+	return &timing
+}
@@ -0,0 +1,78 @@
+package providers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestOpenAIProvider_DirectNetworkTiming(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":"hi"},"finish_reason":"stop"}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse server URL: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(serverURL.Host)
+
+	// Route a hostname through a resolver hook so the DNS phase runs, and trust the
+	// test certificate (issued for example.com) so the TLS phase completes
+	var resolved []string
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.ServerName = "example.com"
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, host)
+		dialer := &net.Dialer{}
+		return dialer.DialContext(ctx, network, net.JoinHostPort("localhost", port))
+	}
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = transport
+	defer func() { http.DefaultTransport = defaultTransport }()
+
+	provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: "https://bench.test:" + port})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:       "gpt-4.1",
+		UserPrompt:  "Hello",
+		ExtraParams: map[string]interface{}{"user": "bench"},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+
+	responses := collectResponses(t, responseChan)
+	final := responses[len(responses)-1]
+	if final.Error != nil {
+		t.Fatalf("unexpected error: %v", final.Error)
+	}
+	if len(resolved) == 0 || resolved[0] != "bench.test" {
+		t.Errorf("resolver hook saw %q, want bench.test", resolved)
+	}
+
+	timing := final.NetworkTiming
+	if timing == nil {
+		t.Fatal("final response has no NetworkTiming")
+	}
+	if timing.DNSLookup <= 0 || timing.Connect <= 0 || timing.TLSHandshake <= 0 || timing.TimeToFirstByte <= 0 {
+		t.Fatalf("expected every phase to be measured, got %+v", timing)
+	}
+	// Phases run one after another, so together they fit within the time to first byte
+	if sum := timing.DNSLookup + timing.Connect + timing.TLSHandshake; sum > timing.TimeToFirstByte {
+		t.Errorf("DNS+connect+TLS = %v exceeds time to first byte %v", sum, timing.TimeToFirstByte)
+	}
+}
@@ -1,10 +1,10 @@
 package providers
 
 import (
-    "bufio"
     "bytes"
     "context"
     "encoding/json"
+    "errors"
     "io"
     "net/http"
     "strings"
@@ -12,18 +12,41 @@ import (
 
     "github.com/openai/openai-go/v2"
     "github.com/openai/openai-go/v2/option"
+
+    "github.com/megzo/llm-latency-benchmark/internal/retry"
+    "github.com/megzo/llm-latency-benchmark/internal/tokenizer"
 )
 
 // OpenAIProvider implements the Provider interface for OpenAI
 type OpenAIProvider struct {
-	client openai.Client
-	config *OpenAIConfig
+	client        openai.Client
+	httpClient    *http.Client // For requests made directly rather than through the SDK
+	config        *OpenAIConfig
+	keys          *keyPool
+	tokenizer     tokenizer.Tokenizer
+	retryStrategy retry.Strategy
+	retryMaxDelay time.Duration
 }
 
 // OpenAIConfig holds OpenAI-specific configuration
 type OpenAIConfig struct {
 	APIKey  string
+	// APIKeys are further keys chat completions rotate across along with APIKey,
+	// to get past one key's rate limit
+	APIKeys []string
 	BaseURL string
+	// Optional organization and project, sent as OpenAI-Organization/OpenAI-Project headers
+	Organization string
+	Project      string
+	// Tokenizer estimates token counts; nil uses the default heuristic
+	Tokenizer tokenizer.Tokenizer
+	// RetryStrategy selects the backoff between retries; "" uses retry.Default
+	RetryStrategy retry.Strategy
+	// RetryMaxDelay caps the backoff between retries; 0 uses retry.MaxDelay
+	RetryMaxDelay time.Duration
+	// ProxyURL sends requests through this proxy instead of the one HTTP_PROXY or
+	// HTTPS_PROXY name; "" honors those variables
+	ProxyURL string
 }
 
 // NewOpenAIProvider creates a new OpenAI provider instance
@@ -35,11 +58,31 @@ func NewOpenAIProvider(config *OpenAIConfig) (*OpenAIProvider, error) {
 		}
 	}
 
-	client := openai.NewClient(option.WithAPIKey(config.APIKey))
+	httpClient, err := newHTTPClient(config.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []option.RequestOption{option.WithAPIKey(config.APIKey), option.WithHTTPClient(httpClient)}
+	if config.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(config.BaseURL))
+	}
+	if config.Organization != "" {
+		opts = append(opts, option.WithOrganization(config.Organization))
+	}
+	if config.Project != "" {
+		opts = append(opts, option.WithProject(config.Project))
+	}
+	client := openai.NewClient(opts...)
 
 	return &OpenAIProvider{
-		client: client,
-		config: config,
+		client:        client,
+		httpClient:    httpClient,
+		config:        config,
+		keys:          newKeyPool(append([]string{config.APIKey}, config.APIKeys...)...),
+		tokenizer:     tokenizer.OrDefault(config.Tokenizer),
+		retryStrategy: config.RetryStrategy,
+		retryMaxDelay: config.RetryMaxDelay,
 	}, nil
 }
 
@@ -59,47 +102,70 @@ func (p *OpenAIProvider) StreamChat(ctx context.Context, req ChatRequest) (<-cha
     }
 
     // Build messages for OpenAI API (SDK path)
-    messages := []openai.ChatCompletionMessageParamUnion{}
-    if req.SystemPrompt != "" {
-        messages = append(messages, openai.SystemMessage(req.SystemPrompt))
-    }
-    messages = append(messages, openai.UserMessage(req.UserPrompt))
+    messages := openAIRequestMessages(req)
 
     chatReq := openai.ChatCompletionNewParams{
         Model:    openai.ChatModel(req.Model),
         Messages: messages,
     }
     if req.MaxTokens > 0 {
-        if !requiresMaxCompletionTokens(req.Model) {
+        if !usesMaxCompletionTokens(req) {
             chatReq.MaxTokens = openai.Int(int64(req.MaxTokens))
         }
     }
-    if req.Temperature > 0 {
-        if !disallowsSamplingParameters(req.Model) {
-            chatReq.Temperature = openai.Float(req.Temperature)
+    if req.Temperature != nil {
+        if !fixedSampling(req) {
+            chatReq.Temperature = openai.Float(*req.Temperature)
         }
     }
     if req.TopP > 0 {
-        if !disallowsSamplingParameters(req.Model) {
+        if !fixedSampling(req) {
             chatReq.TopP = openai.Float(req.TopP)
         }
     }
+    if req.Seed != nil {
+        chatReq.Seed = openai.Int(*req.Seed)
+    }
+
+    key := p.keys.Next()
+    opts := append(requestIDOptions(req.RequestID), option.WithAPIKey(key))
+    if req.NoStream {
+        go p.completeChat(ctx, chatReq, key, opts, responseChan)
+        return responseChan, nil
+    }
 
     go func() {
         defer close(responseChan)
 
-        // Create streaming completion
-        stream := p.client.Chat.Completions.NewStreaming(ctx, chatReq)
+        // Create streaming completion, asking for a final usage chunk
+        chatReq.StreamOptions.IncludeUsage = openai.Bool(true)
+        stream := p.client.Chat.Completions.NewStreaming(ctx, chatReq, opts...)
 
+        finishReason, responseModel, fingerprint := "", "", ""
+        var usage *Usage
         for stream.Next() {
             resp := stream.Current()
+            if resp.Model != "" {
+                responseModel = resp.Model
+            }
+            if resp.SystemFingerprint != "" {
+                fingerprint = resp.SystemFingerprint
+            }
+            if resp.Usage.PromptTokens > 0 {
+                usage = openAISDKUsage(resp.Usage)
+            }
             if len(resp.Choices) > 0 {
                 choice := resp.Choices[0]
+                if choice.FinishReason != "" {
+                    finishReason = choice.FinishReason
+                }
                 if choice.Delta.Content != "" {
-                    responseChan <- ChatResponse{
+                    if !sendResponse(ctx, responseChan, ChatResponse{
                         Content:    choice.Delta.Content,
                         IsComplete: false,
                         Timestamp:  time.Now(),
+                    }) {
+                        return
                     }
                 }
             }
@@ -107,7 +173,8 @@ func (p *OpenAIProvider) StreamChat(ctx context.Context, req ChatRequest) (<-cha
 
         // Check for errors
         if err := stream.Err(); err != nil {
-            responseChan <- ChatResponse{
+            p.keys.Observe(key, err)
+            sendResponse(ctx, responseChan, ChatResponse{
                 Content:    "",
                 IsComplete: true,
                 Timestamp:  time.Now(),
@@ -116,20 +183,148 @@ func (p *OpenAIProvider) StreamChat(ctx context.Context, req ChatRequest) (<-cha
                     Message:  "failed to receive stream response",
                     Cause:    err,
                 },
-            }
+            })
             return
         }
 
         // Stream completed successfully
-        responseChan <- ChatResponse{
-            Content:    "",
-            IsComplete: true,
-            Timestamp:  time.Now(),
-        }
+        sendResponse(ctx, responseChan, ChatResponse{
+            Content:           "",
+            IsComplete:        true,
+            Timestamp:         time.Now(),
+            FinishReason:      finishReason,
+            Usage:             usage,
+            ResponseModel:     responseModel,
+            SystemFingerprint: fingerprint,
+        })
     }()
     return responseChan, nil
 }
 
+// completeChat performs a non-streaming chat completion and emits the full content
+// as a single complete response; key is the API key opts send
+func (p *OpenAIProvider) completeChat(ctx context.Context, chatReq openai.ChatCompletionNewParams, key string, opts []option.RequestOption, responseChan chan<- ChatResponse) {
+    defer close(responseChan)
+
+    completion, err := p.client.Chat.Completions.New(ctx, chatReq, opts...)
+    if err != nil {
+        p.keys.Observe(key, err)
+        sendResponse(ctx, responseChan, ChatResponse{
+            IsComplete: true,
+            Timestamp:  time.Now(),
+            Error: &ProviderError{
+                Provider: "openai",
+                Message:  "failed to create chat completion",
+                Cause:    err,
+            },
+        })
+        return
+    }
+
+    content, finishReason := "", ""
+    if len(completion.Choices) > 0 {
+        content = completion.Choices[0].Message.Content
+        finishReason = completion.Choices[0].FinishReason
+    }
+    sendResponse(ctx, responseChan, ChatResponse{
+        Content:           content,
+        IsComplete:        true,
+        Timestamp:         time.Now(),
+        FinishReason:      finishReason,
+        Usage:             openAISDKUsage(completion.Usage),
+        ResponseModel:     completion.Model,
+        SystemFingerprint: completion.SystemFingerprint,
+    })
+}
+
+// openAIUsage is the usage object of Chat Completions responses and final stream chunks
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+// toUsage converts the usage object, returning nil when it's absent
+func (u *openAIUsage) toUsage() *Usage {
+	if u == nil {
+		return nil
+	}
+	return &Usage{
+		InputTokens:       u.PromptTokens,
+		OutputTokens:      u.CompletionTokens,
+		CachedInputTokens: u.PromptTokensDetails.CachedTokens,
+		ReasoningTokens:   u.CompletionTokensDetails.ReasoningTokens,
+	}
+}
+
+// openAISDKUsage converts SDK usage, returning nil when the response carried none
+func openAISDKUsage(u openai.CompletionUsage) *Usage {
+	if u.PromptTokens == 0 && u.CompletionTokens == 0 {
+		return nil
+	}
+	return &Usage{
+		InputTokens:       int(u.PromptTokens),
+		OutputTokens:      int(u.CompletionTokens),
+		CachedInputTokens: int(u.PromptTokensDetails.CachedTokens),
+		ReasoningTokens:   int(u.CompletionTokensDetails.ReasoningTokens),
+	}
+}
+
+// openAIMessages converts chat messages to OpenAI SDK message params; unknown roles are sent as user
+func openAIMessages(messages []Message) []openai.ChatCompletionMessageParamUnion {
+	params := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages))
+	for _, message := range messages {
+		switch message.Role {
+		case "system":
+			params = append(params, openai.SystemMessage(message.Content))
+		case "assistant":
+			params = append(params, openai.AssistantMessage(message.Content))
+		default:
+			params = append(params, openai.UserMessage(message.Content))
+		}
+	}
+	return params
+}
+
+// openAIRequestMessages converts the request's messages to SDK params, sending
+// the last user message as text and image content parts when there's an image
+func openAIRequestMessages(req ChatRequest) []openai.ChatCompletionMessageParamUnion {
+	messages := req.ChatMessages()
+	params := openAIMessages(messages)
+	if req.Image != nil {
+		params[len(params)-1] = openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
+			openai.TextContentPart(messages[len(messages)-1].Content),
+			openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: req.Image.DataURL()}),
+		})
+	}
+	return params
+}
+
+// openAIPayloadMessages returns the request's messages for a direct HTTP payload,
+// with the image as an image_url content part of the last user message
+func openAIPayloadMessages(req ChatRequest) []interface{} {
+	messages := req.ChatMessages()
+	payload := make([]interface{}, len(messages))
+	for i, message := range messages {
+		payload[i] = message
+	}
+	if req.Image != nil {
+		payload[len(payload)-1] = map[string]interface{}{
+			"role": "user",
+			"content": []map[string]interface{}{
+				{"type": "text", "text": messages[len(messages)-1].Content},
+				{"type": "image_url", "image_url": map[string]string{"url": req.Image.DataURL()}},
+			},
+		}
+	}
+	return payload
+}
+
 // requiresMaxCompletionTokens returns true for models that reject the legacy
 // "max_tokens" parameter on the Chat Completions API.
 func requiresMaxCompletionTokens(model string) bool {
@@ -141,6 +336,34 @@ func requiresMaxCompletionTokens(model string) bool {
 		strings.HasPrefix(m, "o4")
 }
 
+// usesMaxCompletionTokens reports whether the request's model rejects max_tokens,
+// from its configured capabilities or else its name
+func usesMaxCompletionTokens(req ChatRequest) bool {
+	if req.Capabilities.UsesMaxCompletionTokens != nil {
+		return *req.Capabilities.UsesMaxCompletionTokens
+	}
+	return requiresMaxCompletionTokens(req.Model)
+}
+
+// fixedSampling reports whether the request's model rejects temperature and top_p,
+// from its configured capabilities or else its name
+func fixedSampling(req ChatRequest) bool {
+	if req.Capabilities.FixedSampling != nil {
+		return *req.Capabilities.FixedSampling
+	}
+	return disallowsSamplingParameters(req.Model)
+}
+
+// AcceptsSamplingParameters reports whether a provider sends temperature and
+// top_p for a request's model; OpenAI's reasoning-era models take only the defaults
+func AcceptsSamplingParameters(provider string, req ChatRequest) bool {
+	switch provider {
+	case "openai", "openai_responses":
+		return !fixedSampling(req)
+	}
+	return true
+}
+
 // disallowsSamplingParameters returns true for models that do not accept
 // temperature/top_p overrides and require default values.
 func disallowsSamplingParameters(model string) bool {
@@ -160,29 +383,32 @@ func (p *OpenAIProvider) streamChatDirect(ctx context.Context, req ChatRequest,
     endpoint := baseURL + "/chat/completions"
 
     // Build messages array
-    messages := []map[string]interface{}{}
-    if strings.TrimSpace(req.SystemPrompt) != "" {
-        messages = append(messages, map[string]interface{}{"role": "system", "content": req.SystemPrompt})
-    }
-    messages = append(messages, map[string]interface{}{"role": "user", "content": req.UserPrompt})
+    messages := openAIPayloadMessages(req)
 
     // Base payload
     payloadMap := map[string]interface{}{
         "model":   req.Model,
         "messages": messages,
-        "stream":  true,
+        "stream":  !req.NoStream,
     }
 
     // Standard params
-    if req.MaxTokens > 0 && !requiresMaxCompletionTokens(req.Model) {
+    if req.MaxTokens > 0 && !usesMaxCompletionTokens(req) {
         payloadMap["max_tokens"] = req.MaxTokens
     }
-    if req.Temperature > 0 && !disallowsSamplingParameters(req.Model) {
-        payloadMap["temperature"] = req.Temperature
+    if req.Temperature != nil && !fixedSampling(req) {
+        payloadMap["temperature"] = *req.Temperature
     }
-    if req.TopP > 0 && !disallowsSamplingParameters(req.Model) {
+    if req.TopP > 0 && !fixedSampling(req) {
         payloadMap["top_p"] = req.TopP
     }
+    if req.Seed != nil {
+        payloadMap["seed"] = *req.Seed
+    }
+    // Ask for a final usage chunk so prompt-cache hits can be measured
+    if !req.NoStream {
+        payloadMap["stream_options"] = map[string]interface{}{"include_usage": true}
+    }
 
     // Merge ExtraParams
     if req.ExtraParams != nil {
@@ -197,65 +423,155 @@ func (p *OpenAIProvider) streamChatDirect(ctx context.Context, req ChatRequest,
     // Marshal
     body, err := json.Marshal(payloadMap)
     if err != nil {
-        responseChan <- ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to marshal request", Cause: err}}
+        sendResponse(ctx, responseChan, ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to marshal request", Cause: err}})
         return
     }
 
     // HTTP request
     httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
     if err != nil {
-        responseChan <- ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to create HTTP request", Cause: err}}
+        sendResponse(ctx, responseChan, ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to create HTTP request", Cause: err}})
         return
     }
     httpReq.Header.Set("Content-Type", "application/json")
-    httpReq.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+    key := p.keys.Next()
+    httpReq.Header.Set("Authorization", "Bearer "+key)
     httpReq.Header.Set("Accept", "text/event-stream")
+    setOpenAIAccountHeaders(httpReq, p.config)
+    setRequestID(httpReq, req.RequestID)
 
-    client := &http.Client{}
-    resp, err := client.Do(httpReq)
+    httpReq, tracer := withNetworkTrace(httpReq)
+    resp, err := p.httpClient.Do(httpReq)
     if err != nil {
-        responseChan <- ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to make HTTP request", Cause: err}}
+        sendResponse(ctx, responseChan, ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &NetworkError{Provider: p.Name(), Cause: err}})
         return
     }
     defer resp.Body.Close()
 
     if resp.StatusCode != http.StatusOK {
         b, _ := io.ReadAll(resp.Body)
-        responseChan <- ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: strings.TrimSpace(string(b))}}
+        err := newHTTPError(p.Name(), resp, b)
+        p.keys.Observe(key, err)
+        sendResponse(ctx, responseChan, ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: err, RateLimit: parseRateLimitHeaders(resp.Header)})
         return
     }
 
-    reader := bufio.NewReader(resp.Body)
+    // Non-streaming responses carry the full completion in a single JSON body
+    if req.NoStream {
+        var completion struct {
+            Choices []struct {
+                Message struct {
+                    Content   string           `json:"content"`
+                    ToolCalls []openAIToolCall `json:"tool_calls"`
+                } `json:"message"`
+                FinishReason string `json:"finish_reason"`
+            } `json:"choices"`
+            Usage             *openAIUsage `json:"usage"`
+            Model             string       `json:"model"`
+            SystemFingerprint string       `json:"system_fingerprint"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+            sendResponse(ctx, responseChan, ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to decode response", Cause: err}})
+            return
+        }
+        content, finishReason := "", ""
+        var toolCalls []ToolCallDelta
+        if len(completion.Choices) > 0 {
+            content = completion.Choices[0].Message.Content
+            finishReason = completion.Choices[0].FinishReason
+            for i, call := range completion.Choices[0].Message.ToolCalls {
+                toolCalls = append(toolCalls, ToolCallDelta{Index: i, Name: call.Function.Name, Arguments: call.Function.Arguments})
+            }
+        }
+        sendResponse(ctx, responseChan, ChatResponse{Content: content, ToolCalls: toolCalls, IsComplete: true, Timestamp: time.Now(), FinishReason: finishReason, NetworkTiming: tracer.Timing(), Usage: completion.Usage.toUsage(), RateLimit: parseRateLimitHeaders(resp.Header), ResponseModel: completion.Model, SystemFingerprint: completion.SystemFingerprint})
+        return
+    }
+
+    finishReason, responseModel, fingerprint := "", "", ""
+    var usage *Usage
+    events := newSSEReader(resp.Body)
     for {
-        line, err := reader.ReadString('\n')
+        data, err := events.Next()
         if err != nil {
             if err == io.EOF { break }
-            responseChan <- ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to read response stream", Cause: err}}
+            sendResponse(ctx, responseChan, ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to read response stream", Cause: err}})
             return
         }
-        line = strings.TrimSpace(line)
-        if line == "" { continue }
-        if strings.HasPrefix(line, "data: ") {
-            data := strings.TrimPrefix(line, "data: ")
-            if data == "[DONE]" { break }
-            // Parse minimal fields from Chat API delta
-            var s struct {
-                Choices []struct {
-                    Delta struct {
-                        Content string `json:"content"`
-                    } `json:"delta"`
-                } `json:"choices"`
+        if data == "[DONE]" { break }
+        // Parse minimal fields from Chat API delta
+        var s struct {
+            Choices []struct {
+                Delta struct {
+                    Content   string           `json:"content"`
+                    ToolCalls []openAIToolCall `json:"tool_calls"`
+                } `json:"delta"`
+                FinishReason *string `json:"finish_reason"`
+            } `json:"choices"`
+            Usage             *openAIUsage `json:"usage"`
+            Model             string       `json:"model"`
+            SystemFingerprint string       `json:"system_fingerprint"`
+        }
+        if err := json.Unmarshal([]byte(data), &s); err == nil {
+            if s.Model != "" {
+                responseModel = s.Model
+            }
+            if s.SystemFingerprint != "" {
+                fingerprint = s.SystemFingerprint
             }
-            if err := json.Unmarshal([]byte(data), &s); err == nil {
-                if len(s.Choices) > 0 {
-                    if c := s.Choices[0].Delta.Content; c != "" {
-                        responseChan <- ChatResponse{Content: c, IsComplete: false, Timestamp: time.Now()}
+            if s.Usage != nil {
+                usage = s.Usage.toUsage()
+            }
+            if len(s.Choices) > 0 {
+                if fr := s.Choices[0].FinishReason; fr != nil && *fr != "" {
+                    finishReason = *fr
+                }
+                if c := s.Choices[0].Delta.Content; c != "" {
+                    if !sendResponse(ctx, responseChan, ChatResponse{Content: c, IsComplete: false, Timestamp: time.Now()}) {
+                        return
+                    }
+                }
+                if calls := s.Choices[0].Delta.ToolCalls; len(calls) > 0 {
+                    toolCalls := make([]ToolCallDelta, 0, len(calls))
+                    for _, call := range calls {
+                        toolCalls = append(toolCalls, ToolCallDelta{Index: call.Index, Name: call.Function.Name, Arguments: call.Function.Arguments})
+                    }
+                    if !sendResponse(ctx, responseChan, ChatResponse{ToolCalls: toolCalls, IsComplete: false, Timestamp: time.Now()}) {
+                        return
                     }
                 }
             }
         }
     }
-    responseChan <- ChatResponse{IsComplete: true, Timestamp: time.Now()}
+    sendResponse(ctx, responseChan, ChatResponse{IsComplete: true, Timestamp: time.Now(), FinishReason: finishReason, NetworkTiming: tracer.Timing(), Usage: usage, RateLimit: parseRateLimitHeaders(resp.Header), ResponseModel: responseModel, SystemFingerprint: fingerprint})
+}
+
+// openAIToolCall is a tool call in a Chat Completions message or a fragment of one in a
+// streamed delta, where only the first fragment of each call carries the name
+type openAIToolCall struct {
+	Index    int `json:"index"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// requestIDOptions sends id as the request ID header on an SDK request; an empty
+// id adds nothing
+func requestIDOptions(id string) []option.RequestOption {
+	if id == "" {
+		return nil
+	}
+	return []option.RequestOption{option.WithHeader(RequestIDHeader, id)}
+}
+
+// setOpenAIAccountHeaders adds the optional organization/project headers to a direct request
+func setOpenAIAccountHeaders(httpReq *http.Request, config *OpenAIConfig) {
+	if config.Organization != "" {
+		httpReq.Header.Set("OpenAI-Organization", config.Organization)
+	}
+	if config.Project != "" {
+		httpReq.Header.Set("OpenAI-Project", config.Project)
+	}
 }
 
 func (p *OpenAIProvider) getBaseURL() string {
@@ -266,32 +582,21 @@ func (p *OpenAIProvider) getBaseURL() string {
 }
 
 // TokenCount returns the token counts for a response
-// Note: OpenAI doesn't provide token counts in streaming responses
-// This is a simplified implementation - in practice, you might want to
-// use a tokenizer library for accurate counting
+// Note: streaming responses don't include token counts, so output tokens are
+// estimated with the provider's tokenizer
 func (p *OpenAIProvider) TokenCount(response ChatResponse) (input, output, total int) {
-	// This is a rough estimation - for production use, consider using
-	// a proper tokenizer like tiktoken or similar
-	if response.Content != "" {
-		// Rough estimation: ~4 characters per token for English text
-		output = len(response.Content) / 4
-		if output < 1 {
-			output = 1
-		}
-	}
-
+	output = p.Tokenizer().Count("", response.Content)
 	return 0, output, output
 }
 
 // GetTokenCount estimates token count for input text
-// This is a simplified implementation - consider using a proper tokenizer
 func (p *OpenAIProvider) GetTokenCount(text string) int {
-	// Rough estimation: ~4 characters per token for English text
-	count := len(text) / 4
-	if count < 1 {
-		count = 1
-	}
-	return count
+	return p.Tokenizer().Count("", text)
+}
+
+// Tokenizer returns the tokenizer used for token estimates
+func (p *OpenAIProvider) Tokenizer() tokenizer.Tokenizer {
+	return tokenizer.OrDefault(p.tokenizer)
 }
 
 // ValidateRequest validates the chat request
@@ -303,7 +608,7 @@ func (p *OpenAIProvider) ValidateRequest(req ChatRequest) error {
 		}
 	}
 
-	if req.UserPrompt == "" {
+	if req.UserPrompt == "" && len(req.Messages) == 0 {
 		return &ValidationError{
 			Field:   "user_prompt",
 			Message: "user prompt is required",
@@ -317,7 +622,7 @@ func (p *OpenAIProvider) ValidateRequest(req ChatRequest) error {
 		}
 	}
 
-	if req.Temperature < 0 || req.Temperature > 2 {
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
 		return &ValidationError{
 			Field:   "temperature",
 			Message: "temperature must be between 0 and 2",
@@ -336,44 +641,21 @@ func (p *OpenAIProvider) ValidateRequest(req ChatRequest) error {
 
 // IsRetryableError checks if an error is retryable
 func (p *OpenAIProvider) IsRetryableError(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	// Check for rate limit errors
-	if strings.Contains(err.Error(), "rate_limit") ||
-		strings.Contains(err.Error(), "429") {
-		return true
-	}
-
-	// Check for server errors
-	if strings.Contains(err.Error(), "500") ||
-		strings.Contains(err.Error(), "502") ||
-		strings.Contains(err.Error(), "503") ||
-		strings.Contains(err.Error(), "504") {
-		return true
-	}
+	return isRetryableOpenAIError(err)
+}
 
-	// Check for timeout errors
-	if strings.Contains(err.Error(), "timeout") ||
-		strings.Contains(err.Error(), "context deadline exceeded") {
+// isRetryableOpenAIError extends isRetryableCategory to the SDK's *openai.Error,
+// which carries the HTTP status of a failed request on the SDK paths
+func isRetryableOpenAIError(err error) bool {
+	if isRetryableCategory(err) {
 		return true
 	}
-
-	return false
+	var apiErr *openai.Error
+	return errors.As(err, &apiErr) &&
+		(apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError)
 }
 
 // GetRetryDelay calculates the delay before retrying
 func (p *OpenAIProvider) GetRetryDelay(attempt int, err error) time.Duration {
-	// Base delay with exponential backoff
-	baseDelay := time.Duration(attempt*attempt) * time.Second
-
-	// Cap at 30 seconds
-	if baseDelay > 30*time.Second {
-		baseDelay = 30 * time.Second
-	}
-
-	// Add jitter to prevent thundering herd
-	jitter := time.Duration(attempt) * 100 * time.Millisecond
-	return baseDelay + jitter
+	return retryDelay(p.retryStrategy, p.retryMaxDelay, attempt, err)
 }
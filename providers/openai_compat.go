@@ -0,0 +1,500 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/megzo/llm-latency-benchmark/internal/retry"
+	"github.com/megzo/llm-latency-benchmark/internal/tokenizer"
+)
+
+// compatVendor describes a vendor served through an OpenAI-compatible chat
+// completions API; everything else about its provider is shared
+type compatVendor struct {
+	displayName    string
+	apiKeyEnv      string
+	defaultBaseURL string
+	// maxTemperature is the highest temperature accepted, or the lowest rejected
+	// one when temperatureBelowMax is set
+	maxTemperature      float64
+	temperatureBelowMax bool
+	// seed and stop are set for vendors that accept a sampling seed and stop sequences
+	seed bool
+	stop bool
+	// streamUsage is set for vendors that send usage only when asked for it with
+	// stream_options; the others send it on the last chunk of every stream
+	streamUsage bool
+}
+
+// compatVendors lists the providers NewOpenAICompatibleProvider creates, by name
+var compatVendors = map[string]compatVendor{
+	"perplexity": {
+		displayName:         "Perplexity",
+		apiKeyEnv:           "PERPLEXITY_API_KEY",
+		defaultBaseURL:      "https://api.perplexity.ai",
+		maxTemperature:      2,
+		temperatureBelowMax: true,
+	},
+	"deepseek": {
+		displayName:    "DeepSeek",
+		apiKeyEnv:      "DEEPSEEK_API_KEY",
+		defaultBaseURL: "https://api.deepseek.com",
+		maxTemperature: 2,
+		stop:           true,
+		streamUsage:    true,
+	},
+	"xai": {
+		displayName:    "xAI",
+		apiKeyEnv:      "XAI_API_KEY",
+		defaultBaseURL: "https://api.x.ai/v1",
+		maxTemperature: 2,
+		seed:           true,
+		stop:           true,
+		streamUsage:    true,
+	},
+	"together": {
+		displayName:    "Together AI",
+		apiKeyEnv:      "TOGETHER_API_KEY",
+		defaultBaseURL: "https://api.together.xyz/v1",
+		maxTemperature: 2,
+		seed:           true,
+		stop:           true,
+	},
+	"cerebras": {
+		displayName:    "Cerebras",
+		apiKeyEnv:      "CEREBRAS_API_KEY",
+		defaultBaseURL: "https://api.cerebras.ai/v1",
+		maxTemperature: 1.5,
+		seed:           true,
+		stop:           true,
+	},
+	"sambanova": {
+		displayName:    "SambaNova",
+		apiKeyEnv:      "SAMBANOVA_API_KEY",
+		defaultBaseURL: "https://api.sambanova.ai/v1",
+		maxTemperature: 1,
+		stop:           true,
+		streamUsage:    true,
+	},
+}
+
+// OpenAICompatibleConfig holds the configuration of a provider served through an
+// OpenAI-compatible chat completions API
+type OpenAICompatibleConfig struct {
+	APIKey  string
+	BaseURL string
+	// Tokenizer estimates token counts; nil uses the default heuristic
+	Tokenizer tokenizer.Tokenizer
+	// RetryStrategy selects the backoff between retries; "" uses retry.Default
+	RetryStrategy retry.Strategy
+	// RetryMaxDelay caps the backoff between retries; 0 uses retry.MaxDelay
+	RetryMaxDelay time.Duration
+	// ProxyURL sends requests through this proxy instead of the one HTTP_PROXY or
+	// HTTPS_PROXY name; "" honors those variables
+	ProxyURL string
+}
+
+// OpenAICompatibleProvider implements the Provider interface for the vendors in
+// compatVendors: Perplexity, DeepSeek, xAI, Together AI, Cerebras and SambaNova
+type OpenAICompatibleProvider struct {
+	name          string
+	vendor        compatVendor
+	config        *OpenAICompatibleConfig
+	client        *http.Client
+	tokenizer     tokenizer.Tokenizer
+	retryStrategy retry.Strategy
+	retryMaxDelay time.Duration
+}
+
+// compatChatRequest is the body of a chat completion request to an
+// OpenAI-compatible API
+type compatChatRequest struct {
+	Model         string               `json:"model"`
+	Messages      []Message            `json:"messages"`
+	MaxTokens     *int                 `json:"max_tokens,omitempty"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	TopP          *float64             `json:"top_p,omitempty"`
+	Seed          *int64               `json:"seed,omitempty"`
+	Stop          []string             `json:"stop,omitempty"`
+	Stream        bool                 `json:"stream"`
+	StreamOptions *compatStreamOptions `json:"stream_options,omitempty"`
+}
+
+// compatStreamOptions asks for usage on the last chunk of the stream
+type compatStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// NewOpenAICompatibleProvider creates the named provider from compatVendors
+func NewOpenAICompatibleProvider(name string, config *OpenAICompatibleConfig) (*OpenAICompatibleProvider, error) {
+	vendor, ok := compatVendors[name]
+	if !ok {
+		return nil, &ConfigurationError{
+			Field:   "provider_name",
+			Message: fmt.Sprintf("unknown OpenAI-compatible provider: %s", name),
+		}
+	}
+	if config.APIKey == "" {
+		return nil, &ConfigurationError{
+			Field:   vendor.apiKeyEnv,
+			Message: vendor.displayName + " API key is required",
+		}
+	}
+
+	// Set default base URL if not provided
+	if config.BaseURL == "" {
+		config.BaseURL = vendor.defaultBaseURL
+	}
+
+	httpClient, err := newHTTPClient(config.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAICompatibleProvider{
+		name:          name,
+		vendor:        vendor,
+		config:        config,
+		client:        httpClient,
+		tokenizer:     tokenizer.OrDefault(config.Tokenizer),
+		retryStrategy: config.RetryStrategy,
+		retryMaxDelay: config.RetryMaxDelay,
+	}, nil
+}
+
+// Name returns the provider name
+func (p *OpenAICompatibleProvider) Name() string {
+	return p.name
+}
+
+// StreamChat performs a streaming chat completion
+func (p *OpenAICompatibleProvider) StreamChat(ctx context.Context, req ChatRequest) (<-chan ChatResponse, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return nil, err
+	}
+
+	responseChan := make(chan ChatResponse)
+	go p.streamChat(ctx, req, responseChan)
+	return responseChan, nil
+}
+
+// streamChat sends the request and relays the SSE stream
+func (p *OpenAICompatibleProvider) streamChat(ctx context.Context, req ChatRequest, responseChan chan<- ChatResponse) {
+	defer close(responseChan)
+
+	chatReq := compatChatRequest{
+		Model:       req.Model,
+		Messages:    req.ChatMessages(),
+		Temperature: req.Temperature,
+		Stream:      true,
+	}
+	if req.MaxTokens > 0 {
+		chatReq.MaxTokens = &req.MaxTokens
+	}
+	if req.TopP > 0 {
+		chatReq.TopP = &req.TopP
+	}
+	if p.vendor.seed {
+		chatReq.Seed = req.Seed
+	}
+	if p.vendor.stop {
+		chatReq.Stop = req.StopSequences()
+	}
+	if p.vendor.streamUsage {
+		chatReq.StreamOptions = &compatStreamOptions{IncludeUsage: true}
+	}
+
+	streamOpenAICompatible(ctx, p.name, p.client, p.config.BaseURL, p.config.APIKey, req, chatReq, responseChan)
+}
+
+// TokenCount returns the token counts for a response; reasoning streamed apart
+// from the answer (DeepSeek, xAI) counts as output
+func (p *OpenAICompatibleProvider) TokenCount(response ChatResponse) (input, output, total int) {
+	if response.Usage != nil {
+		return response.Usage.InputTokens, response.Usage.OutputTokens, response.Usage.InputTokens + response.Usage.OutputTokens
+	}
+	output = p.Tokenizer().Count("", response.ReasoningContent+response.Content)
+	return 0, output, output
+}
+
+// GetTokenCount estimates token count for input text
+func (p *OpenAICompatibleProvider) GetTokenCount(text string) int {
+	return p.Tokenizer().Count("", text)
+}
+
+// Tokenizer returns the tokenizer used for token estimates
+func (p *OpenAICompatibleProvider) Tokenizer() tokenizer.Tokenizer {
+	return tokenizer.OrDefault(p.tokenizer)
+}
+
+// ValidateRequest validates the chat request
+func (p *OpenAICompatibleProvider) ValidateRequest(req ChatRequest) error {
+	if req.Model == "" {
+		return &ValidationError{
+			Field:   "model",
+			Message: "model name is required",
+		}
+	}
+
+	if req.UserPrompt == "" && len(req.Messages) == 0 {
+		return &ValidationError{
+			Field:   "user_prompt",
+			Message: "user prompt is required",
+		}
+	}
+
+	if req.MaxTokens < 0 {
+		return &ValidationError{
+			Field:   "max_tokens",
+			Message: "max_tokens must be non-negative",
+		}
+	}
+
+	if t := req.Temperature; t != nil {
+		maxTemperature := p.vendor.maxTemperature
+		if p.vendor.temperatureBelowMax && (*t < 0 || *t >= maxTemperature) {
+			return &ValidationError{
+				Field:   "temperature",
+				Message: fmt.Sprintf("temperature must be at least 0 and below %g", maxTemperature),
+			}
+		}
+		if *t < 0 || *t > maxTemperature {
+			return &ValidationError{
+				Field:   "temperature",
+				Message: fmt.Sprintf("temperature must be between 0 and %g", maxTemperature),
+			}
+		}
+	}
+
+	if req.TopP < 0 || req.TopP > 1 {
+		return &ValidationError{
+			Field:   "top_p",
+			Message: "top_p must be between 0 and 1",
+		}
+	}
+
+	return nil
+}
+
+// IsRetryableError checks if an error is retryable
+func (p *OpenAICompatibleProvider) IsRetryableError(err error) bool {
+	return isRetryableCategory(err)
+}
+
+// GetRetryDelay calculates the delay before retrying
+func (p *OpenAICompatibleProvider) GetRetryDelay(attempt int, err error) time.Duration {
+	return retryDelay(p.retryStrategy, p.retryMaxDelay, attempt, err)
+}
+
+// compatChatChunk is one streamed chunk from an OpenAI-compatible chat completions
+// API, with the extensions some of them add: reasoning_content (DeepSeek, xAI),
+// citations (Perplexity) and a usage object on the last chunk or every chunk
+type compatChatChunk struct {
+	ID                string   `json:"id"`
+	Model             string   `json:"model"`
+	SystemFingerprint string   `json:"system_fingerprint"`
+	Citations         []string `json:"citations"`
+	Choices           []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *compatUsage `json:"usage"`
+}
+
+// compatUsage is the OpenAI usage object plus DeepSeek's context-cache count
+type compatUsage struct {
+	openAIUsage
+	PromptCacheHitTokens int `json:"prompt_cache_hit_tokens"`
+}
+
+// toUsage converts the usage object, returning nil when it's absent
+func (u *compatUsage) toUsage() *Usage {
+	if u == nil {
+		return nil
+	}
+	usage := u.openAIUsage.toUsage()
+	if usage.CachedInputTokens == 0 {
+		usage.CachedInputTokens = u.PromptCacheHitTokens
+	}
+	return usage
+}
+
+// streamOpenAICompatible posts body, built from req, to baseURL's /chat/completions
+// endpoint with req's request ID and headers, and relays the SSE stream to
+// responseChan. Reasoning and answer deltas are sent apart; usage, citations, the
+// served model version, network timing and rate-limit headers go on the final
+// response. The caller closes responseChan.
+func streamOpenAICompatible(ctx context.Context, provider string, client *http.Client, baseURL, apiKey string, req ChatRequest, body interface{}, responseChan chan<- ChatResponse) {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		sendResponse(ctx, responseChan, ChatResponse{
+			IsComplete: true,
+			Timestamp:  time.Now(),
+			Error: &ProviderError{
+				Provider: provider,
+				Message:  "failed to marshal request",
+				Cause:    err,
+			},
+		})
+		return
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(baseURL, "/")+"/chat/completions", bytes.NewBuffer(reqBody))
+	if err != nil {
+		sendResponse(ctx, responseChan, ChatResponse{
+			IsComplete: true,
+			Timestamp:  time.Now(),
+			Error: &ProviderError{
+				Provider: provider,
+				Message:  "failed to create HTTP request",
+				Cause:    err,
+			},
+		})
+		return
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	setRequestHeaders(httpReq, apiKey, req.Headers)
+	setRequestID(httpReq, req.RequestID)
+
+	httpReq, tracer := withNetworkTrace(httpReq)
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		sendResponse(ctx, responseChan, ChatResponse{
+			IsComplete: true,
+			Timestamp:  time.Now(),
+			Error: &NetworkError{
+				Provider: provider,
+				Cause:    err,
+			},
+		})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		sendResponse(ctx, responseChan, ChatResponse{
+			IsComplete: true,
+			Timestamp:  time.Now(),
+			Error:      newHTTPError(provider, resp, body),
+			RateLimit:  parseRateLimitHeaders(resp.Header),
+		})
+		return
+	}
+
+	finishReason, responseModel, fingerprint := "", "", ""
+	var usage *Usage
+	var citations []string
+	events := newSSEReader(resp.Body)
+	for {
+		data, err := events.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			sendResponse(ctx, responseChan, ChatResponse{
+				IsComplete: true,
+				Timestamp:  time.Now(),
+				Error: &ProviderError{
+					Provider: provider,
+					Message:  "failed to read response stream",
+					Cause:    err,
+				},
+			})
+			return
+		}
+
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk compatChatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue // Skip malformed JSON
+		}
+
+		// Perplexity repeats the citations on every chunk; later chunks carry the complete list
+		if len(chunk.Citations) > 0 {
+			citations = chunk.Citations
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage.toUsage()
+		}
+		if chunk.Model != "" {
+			responseModel = chunk.Model
+		}
+		if chunk.SystemFingerprint != "" {
+			fingerprint = chunk.SystemFingerprint
+		}
+
+		if len(chunk.Choices) > 0 {
+			choice := chunk.Choices[0]
+			if choice.FinishReason != nil && *choice.FinishReason != "" {
+				finishReason = *choice.FinishReason
+			}
+			if choice.Delta.ReasoningContent != "" || choice.Delta.Content != "" {
+				if !sendResponse(ctx, responseChan, ChatResponse{
+					Content:          choice.Delta.Content,
+					ReasoningContent: choice.Delta.ReasoningContent,
+					IsComplete:       false,
+					Timestamp:        time.Now(),
+				}) {
+					return
+				}
+			}
+		}
+	}
+
+	// Stream completed successfully
+	sendResponse(ctx, responseChan, ChatResponse{
+		IsComplete:        true,
+		Timestamp:         time.Now(),
+		FinishReason:      finishReason,
+		NetworkTiming:     tracer.Timing(),
+		Usage:             usage,
+		RateLimit:         parseRateLimitHeaders(resp.Header),
+		Citations:         citations,
+		ResponseModel:     responseModel,
+		SystemFingerprint: fingerprint,
+	})
+}
+
+// setRequestHeaders sets the API key, as "Authorization: Bearer" unless headers
+// say otherwise, and then headers' extra headers
+func setRequestHeaders(httpReq *http.Request, apiKey string, headers RequestHeaders) {
+	name, scheme := "Authorization", "Bearer"
+	if headers.AuthHeader != "" {
+		name, scheme = headers.AuthHeader, ""
+	}
+	if headers.AuthScheme != "" {
+		scheme = headers.AuthScheme
+	}
+	if scheme != "" {
+		apiKey = scheme + " " + apiKey
+	}
+	httpReq.Header.Set(name, apiKey)
+	for name, value := range headers.Extra {
+		httpReq.Header.Set(name, value)
+	}
+}
+
+// isRetryableCategory reports whether an error is a transient rate-limit, server,
+// timeout or network failure
+func isRetryableCategory(err error) bool {
+	switch ErrorCategory(err) {
+	case ErrorCategoryRateLimit, ErrorCategoryServer, ErrorCategoryTimeout, ErrorCategoryNetwork:
+		return true
+	}
+	return false
+}
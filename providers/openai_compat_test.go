@@ -0,0 +1,145 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewOpenAICompatibleProvider_UnknownVendor(t *testing.T) {
+	if _, err := NewOpenAICompatibleProvider("acme", &OpenAICompatibleConfig{APIKey: "test-key"}); err == nil {
+		t.Error("NewOpenAICompatibleProvider() accepted a provider without a vendor entry")
+	}
+}
+
+func TestOpenAICompatibleProvider_VendorRequestBodies(t *testing.T) {
+	tests := []struct {
+		name            string
+		wantSeed        bool
+		wantStop        bool
+		wantStreamUsage bool
+	}{
+		{name: "perplexity"},
+		{name: "deepseek", wantStop: true, wantStreamUsage: true},
+		{name: "xai", wantSeed: true, wantStop: true, wantStreamUsage: true},
+		{name: "together", wantSeed: true, wantStop: true},
+		{name: "cerebras", wantSeed: true, wantStop: true},
+		{name: "sambanova", wantStop: true, wantStreamUsage: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&payload)
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte(`data: {"id":"1","model":"test-model","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}` + "\n\n"))
+				w.Write([]byte("data: [DONE]\n\n"))
+			}))
+			defer server.Close()
+
+			provider, err := NewOpenAICompatibleProvider(tt.name, &OpenAICompatibleConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+			if provider.Name() != tt.name {
+				t.Errorf("Name() = %s, want %s", provider.Name(), tt.name)
+			}
+
+			seed := int64(42)
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:       "test-model",
+				UserPrompt:  "Hello",
+				Seed:        &seed,
+				ExtraParams: map[string]interface{}{"stop": "END"},
+			})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			collectResponses(t, responseChan)
+
+			// Each vendor gets only the parameters its API accepts
+			if _, ok := payload["seed"]; ok != tt.wantSeed {
+				t.Errorf("seed sent = %v, want %v (payload %v)", ok, tt.wantSeed, payload)
+			}
+			if _, ok := payload["stop"]; ok != tt.wantStop {
+				t.Errorf("stop sent = %v, want %v (payload %v)", ok, tt.wantStop, payload)
+			}
+			if _, ok := payload["stream_options"]; ok != tt.wantStreamUsage {
+				t.Errorf("stream_options sent = %v, want %v (payload %v)", ok, tt.wantStreamUsage, payload)
+			}
+		})
+	}
+}
+
+func TestOpenAICompatibleProvider_RequestHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers RequestHeaders
+		want    map[string]string
+	}{
+		{
+			name: "default bearer token",
+			want: map[string]string{"Authorization": "Bearer test-key"},
+		},
+		{
+			name:    "auth scheme",
+			headers: RequestHeaders{AuthScheme: "Token"},
+			want:    map[string]string{"Authorization": "Token test-key"},
+		},
+		{
+			name:    "auth header",
+			headers: RequestHeaders{AuthHeader: "X-Api-Key"},
+			want:    map[string]string{"X-Api-Key": "test-key", "Authorization": ""},
+		},
+		{
+			name: "extra headers",
+			headers: RequestHeaders{
+				AuthHeader: "Api-Key",
+				AuthScheme: "Signed",
+				Extra:      map[string]string{"X-Gateway-Token": "gw-secret", "X-Team": "bench"},
+			},
+			want: map[string]string{"Api-Key": "Signed test-key", "X-Gateway-Token": "gw-secret", "X-Team": "bench", "Authorization": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sent = r.Header.Clone()
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte(`data: {"id":"1","model":"test-model","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}` + "\n\n"))
+				w.Write([]byte("data: [DONE]\n\n"))
+			}))
+			defer server.Close()
+
+			provider, err := NewOpenAICompatibleProvider("together", &OpenAICompatibleConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:      "test-model",
+				UserPrompt: "Hello",
+				Headers:    tt.headers,
+			})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			for _, response := range collectResponses(t, responseChan) {
+				if response.Error != nil {
+					t.Fatalf("unexpected error: %v", response.Error)
+				}
+			}
+
+			for name, want := range tt.want {
+				if got := sent.Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
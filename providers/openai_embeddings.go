@@ -0,0 +1,82 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Embed creates embeddings with the /embeddings endpoint and times the round trip
+func (p *OpenAIProvider) Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	if req.Model == "" {
+		return nil, &ValidationError{Field: "model", Message: "model name is required"}
+	}
+	if len(req.Input) == 0 {
+		return nil, &ValidationError{Field: "input", Message: "input is required"}
+	}
+
+	payloadMap := map[string]interface{}{
+		"model": req.Model,
+		"input": req.Input,
+	}
+	for k, v := range req.ExtraParams {
+		if k == "model" || k == "input" {
+			continue
+		}
+		payloadMap[k] = v
+	}
+	body, err := json.Marshal(payloadMap)
+	if err != nil {
+		return nil, &ProviderError{Provider: p.Name(), Message: "failed to marshal request", Cause: err}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.getBaseURL()+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, &ProviderError{Provider: p.Name(), Message: "failed to create HTTP request", Cause: err}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	setOpenAIAccountHeaders(httpReq, p.config)
+
+	httpReq, tracer := withNetworkTrace(httpReq)
+	start := time.Now()
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, &NetworkError{Provider: p.Name(), Cause: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, newHTTPError(p.Name(), resp, b)
+	}
+
+	var embeddings struct {
+		Data []struct {
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&embeddings); err != nil {
+		return nil, &ProviderError{Provider: p.Name(), Message: "failed to decode response", Cause: err}
+	}
+
+	// The round trip ends once the whole body has been read
+	result := &EmbeddingResponse{
+		Count:         len(embeddings.Data),
+		Latency:       time.Since(start),
+		NetworkTiming: tracer.Timing(),
+	}
+	if len(embeddings.Data) > 0 {
+		result.Dimensions = len(embeddings.Data[0].Embedding)
+	}
+	if embeddings.Usage.PromptTokens > 0 {
+		result.Usage = &Usage{InputTokens: embeddings.Usage.PromptTokens}
+	}
+	return result, nil
+}
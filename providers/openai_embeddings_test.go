@@ -0,0 +1,80 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIProvider_Embed(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("path = %s, want /embeddings", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.1,-0.2,0.3,0.4]}],` +
+			`"model":"text-embedding-3-small","usage":{"prompt_tokens":7,"total_tokens":7}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	resp, err := provider.Embed(context.Background(), EmbeddingRequest{
+		Model:       "text-embedding-3-small",
+		Input:       []string{"Hello, world!"},
+		ExtraParams: map[string]interface{}{"dimensions": 4},
+	})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	if payload["model"] != "text-embedding-3-small" || payload["dimensions"] != float64(4) {
+		t.Errorf("payload = %v", payload)
+	}
+	if resp.Count != 1 || resp.Dimensions != 4 {
+		t.Errorf("Count, Dimensions = %d, %d, want 1, 4", resp.Count, resp.Dimensions)
+	}
+	if resp.Usage == nil || resp.Usage.InputTokens != 7 {
+		t.Errorf("Usage = %+v, want 7 input tokens", resp.Usage)
+	}
+	if resp.Latency <= 0 {
+		t.Errorf("Latency = %v, want > 0", resp.Latency)
+	}
+	if resp.NetworkTiming == nil || resp.NetworkTiming.TimeToFirstByte <= 0 {
+		t.Errorf("NetworkTiming = %+v, want a time to first byte", resp.NetworkTiming)
+	}
+}
+
+func TestOpenAIProvider_EmbedErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"message":"Rate limit reached"}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	_, err = provider.Embed(context.Background(), EmbeddingRequest{Model: "text-embedding-3-small", Input: []string{"Hello"}})
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Errorf("error = %v, want *RateLimitError", err)
+	}
+
+	var validationErr *ValidationError
+	if _, err := provider.Embed(context.Background(), EmbeddingRequest{Model: "text-embedding-3-small"}); !errors.As(err, &validationErr) {
+		t.Errorf("error = %v, want *ValidationError for missing input", err)
+	}
+}
@@ -1,7 +1,6 @@
 package providers
 
 import (
-    "bufio"
     "bytes"
     "context"
     "encoding/json"
@@ -9,11 +8,15 @@ import (
     "net/http"
     "strings"
     "time"
+
+    "github.com/megzo/llm-latency-benchmark/internal/tokenizer"
 )
 
 // OpenAIResponsesProvider implements the Provider interface using OpenAI Responses API (v1/responses)
 type OpenAIResponsesProvider struct {
-	config *OpenAIConfig
+	config     *OpenAIConfig
+	httpClient *http.Client
+	tokenizer  tokenizer.Tokenizer
 }
 
 // NewOpenAIResponsesProvider creates a new provider instance for the Responses API
@@ -24,7 +27,11 @@ func NewOpenAIResponsesProvider(config *OpenAIConfig) (*OpenAIResponsesProvider,
 			Message: "OpenAI API key is required",
 		}
 	}
-	return &OpenAIResponsesProvider{config: config}, nil
+	httpClient, err := newHTTPClient(config.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAIResponsesProvider{config: config, httpClient: httpClient, tokenizer: tokenizer.OrDefault(config.Tokenizer)}, nil
 }
 
 // Name returns the provider name
@@ -40,6 +47,39 @@ type responsesStreamEvent struct {
 	Delta *string `json:"delta,omitempty"`
 	// Some events may include a "message" or other fields when errors occur
 	Message string `json:"message,omitempty"`
+	// Terminal events (response.completed/response.incomplete) carry the final response
+	Response *struct {
+		Status            string `json:"status"`
+		IncompleteDetails *struct {
+			Reason string `json:"reason"`
+		} `json:"incomplete_details"`
+		Usage *responsesUsage `json:"usage"`
+	} `json:"response,omitempty"`
+}
+
+// responsesUsage is the usage object of a completed Responses API response
+type responsesUsage struct {
+	InputTokens        int `json:"input_tokens"`
+	InputTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"input_tokens_details"`
+	OutputTokens        int `json:"output_tokens"`
+	OutputTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"output_tokens_details"`
+}
+
+// toUsage converts the usage object, returning nil when it's absent
+func (u *responsesUsage) toUsage() *Usage {
+	if u == nil {
+		return nil
+	}
+	return &Usage{
+		InputTokens:       u.InputTokens,
+		OutputTokens:      u.OutputTokens,
+		CachedInputTokens: u.InputTokensDetails.CachedTokens,
+		ReasoningTokens:   u.OutputTokensDetails.ReasoningTokens,
+	}
 }
 
 // StreamChat performs a streaming call using the Responses API
@@ -53,18 +93,27 @@ func (p *OpenAIResponsesProvider) StreamChat(ctx context.Context, req ChatReques
 		baseURL := strings.TrimRight(p.getBaseURL(), "/")
 		endpoint := baseURL + "/responses"
 
-		// Build input string. Responses API accepts string for simple use-cases.
-		inputBuilder := strings.Builder{}
-		if strings.TrimSpace(req.SystemPrompt) != "" {
-			inputBuilder.WriteString(req.SystemPrompt)
-			inputBuilder.WriteString("\n\n")
+		// Build input string. Responses API accepts string for simple use-cases
+		// and a list of role/content messages for conversations.
+		var input interface{}
+		if req.Image != nil {
+			input = responsesImageInput(req)
+		} else if len(req.Messages) > 0 {
+			input = req.ChatMessages()
+		} else {
+			inputBuilder := strings.Builder{}
+			if strings.TrimSpace(req.SystemPrompt) != "" {
+				inputBuilder.WriteString(req.SystemPrompt)
+				inputBuilder.WriteString("\n\n")
+			}
+			inputBuilder.WriteString(req.UserPrompt)
+			input = inputBuilder.String()
 		}
-		inputBuilder.WriteString(req.UserPrompt)
 
         // Build request body (flexible map to allow arbitrary parameters)
         payloadMap := map[string]interface{}{
             "model":  req.Model,
-            "input":  inputBuilder.String(),
+            "input":  input,
             "stream": true,
         }
 
@@ -72,10 +121,10 @@ func (p *OpenAIResponsesProvider) StreamChat(ctx context.Context, req ChatReques
         if req.MaxTokens > 0 {
             payloadMap["max_output_tokens"] = req.MaxTokens
         }
-        if req.Temperature > 0 && !disallowsSamplingParameters(req.Model) {
-            payloadMap["temperature"] = req.Temperature
+        if req.Temperature != nil && !fixedSampling(req) {
+            payloadMap["temperature"] = *req.Temperature
         }
-        if req.TopP > 0 && !disallowsSamplingParameters(req.Model) {
+        if req.TopP > 0 && !fixedSampling(req) {
             payloadMap["top_p"] = req.TopP
         }
 
@@ -86,6 +135,10 @@ func (p *OpenAIResponsesProvider) StreamChat(ctx context.Context, req ChatReques
                 if k == "model" || k == "stream" {
                     continue
                 }
+                // The Responses API has no stop sequences
+                if k == "stop" {
+                    continue
+                }
                 // Allow overriding input if provided
                 payloadMap[k] = v
             }
@@ -94,101 +147,126 @@ func (p *OpenAIResponsesProvider) StreamChat(ctx context.Context, req ChatReques
         // Marshal to JSON
         payload, err := json.Marshal(payloadMap)
 		if err != nil {
-			responseChan <- ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to marshal request", Cause: err}}
+			sendResponse(ctx, responseChan, ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to marshal request", Cause: err}})
 			return
 		}
 
 		// Prepare HTTP request
 		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
 		if err != nil {
-			responseChan <- ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to create HTTP request", Cause: err}}
+			sendResponse(ctx, responseChan, ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to create HTTP request", Cause: err}})
 			return
 		}
 		httpReq.Header.Set("Content-Type", "application/json")
         httpReq.Header.Set("Authorization", "Bearer "+p.config.APIKey)
         httpReq.Header.Set("Accept", "text/event-stream")
+        setOpenAIAccountHeaders(httpReq, p.config)
+        setRequestID(httpReq, req.RequestID)
 
 		// Execute
-		client := &http.Client{}
-		resp, err := client.Do(httpReq)
+		httpReq, tracer := withNetworkTrace(httpReq)
+		resp, err := p.httpClient.Do(httpReq)
 		if err != nil {
-			responseChan <- ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to make HTTP request", Cause: err}}
+			sendResponse(ctx, responseChan, ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &NetworkError{Provider: p.Name(), Cause: err}})
 			return
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
 			body, _ := io.ReadAll(resp.Body)
-			responseChan <- ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: strings.TrimSpace(string(body))}}
+			sendResponse(ctx, responseChan, ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: newHTTPError(p.Name(), resp, body), RateLimit: parseRateLimitHeaders(resp.Header)})
 			return
 		}
 
 		// Parse SSE stream (data: {json}) lines
-		reader := bufio.NewReader(resp.Body)
+		finishReason := ""
+		var usage *Usage
+		events := newSSEReader(resp.Body)
 		for {
-			line, err := reader.ReadString('\n')
+			data, err := events.Next()
 			if err != nil {
 				if err == io.EOF {
 					break
 				}
-				responseChan <- ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to read response stream", Cause: err}}
+				sendResponse(ctx, responseChan, ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: "failed to read response stream", Cause: err}})
 				return
 			}
+			if data == "[DONE]" {
+				break
+			}
 
-			line = strings.TrimSpace(line)
-			if line == "" {
+			var event responsesStreamEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				// Skip malformed JSON lines
 				continue
 			}
-			if strings.HasPrefix(line, "data: ") {
-				data := strings.TrimPrefix(line, "data: ")
-				if data == "[DONE]" {
-					break
-				}
 
-				var event responsesStreamEvent
-				if err := json.Unmarshal([]byte(data), &event); err != nil {
-					// Skip malformed JSON lines
-					continue
+			// Emit deltas for textual output
+			if strings.HasSuffix(event.Type, "output_text.delta") && event.Delta != nil && *event.Delta != "" {
+				if !sendResponse(ctx, responseChan, ChatResponse{Content: *event.Delta, IsComplete: false, Timestamp: time.Now()}) {
+					return
 				}
+			}
 
-				// Emit deltas for textual output
-				if strings.HasSuffix(event.Type, "output_text.delta") && event.Delta != nil && *event.Delta != "" {
-					responseChan <- ChatResponse{Content: *event.Delta, IsComplete: false, Timestamp: time.Now()}
+			// Record why the response ended; incomplete responses report a reason
+			if event.Response != nil && event.Response.Status != "" && event.Response.Status != "in_progress" {
+				finishReason = event.Response.Status
+				if event.Response.IncompleteDetails != nil && event.Response.IncompleteDetails.Reason != "" {
+					finishReason = event.Response.IncompleteDetails.Reason
 				}
-
-				// If there's an error-type event, surface it
-				if strings.Contains(event.Type, "error") && event.Message != "" {
-					responseChan <- ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: event.Message}}
-					return
+				if event.Response.Usage != nil {
+					usage = event.Response.Usage.toUsage()
 				}
 			}
+
+			// If there's an error-type event, surface it
+			if strings.Contains(event.Type, "error") && event.Message != "" {
+				sendResponse(ctx, responseChan, ChatResponse{IsComplete: true, Timestamp: time.Now(), Error: &ProviderError{Provider: p.Name(), Message: event.Message}})
+				return
+			}
 		}
 
 		// Completed
-		responseChan <- ChatResponse{IsComplete: true, Timestamp: time.Now()}
+		sendResponse(ctx, responseChan, ChatResponse{IsComplete: true, Timestamp: time.Now(), FinishReason: finishReason, NetworkTiming: tracer.Timing(), Usage: usage, RateLimit: parseRateLimitHeaders(resp.Header)})
 	}()
 
 	return responseChan, nil
 }
 
 // TokenCount returns the token counts for a response
+// Note: streaming responses don't include token counts, so output tokens are
+// estimated with the provider's tokenizer
 func (p *OpenAIResponsesProvider) TokenCount(response ChatResponse) (input, output, total int) {
-	if response.Content != "" {
-		output = len(response.Content) / 4
-		if output < 1 {
-			output = 1
-		}
-	}
+	output = p.Tokenizer().Count("", response.Content)
 	return 0, output, output
 }
 
+// responsesImageInput returns the request's messages as Responses API input,
+// with the image as an input_image content part of the last user message
+func responsesImageInput(req ChatRequest) []interface{} {
+	messages := req.ChatMessages()
+	input := make([]interface{}, len(messages))
+	for i, message := range messages {
+		input[i] = message
+	}
+	input[len(input)-1] = map[string]interface{}{
+		"role": "user",
+		"content": []map[string]interface{}{
+			{"type": "input_text", "text": messages[len(messages)-1].Content},
+			{"type": "input_image", "image_url": req.Image.DataURL()},
+		},
+	}
+	return input
+}
+
 // GetTokenCount estimates token count for input text
 func (p *OpenAIResponsesProvider) GetTokenCount(text string) int {
-	count := len(text) / 4
-	if count < 1 {
-		count = 1
-	}
-	return count
+	return p.Tokenizer().Count("", text)
+}
+
+// Tokenizer returns the tokenizer used for token estimates
+func (p *OpenAIResponsesProvider) Tokenizer() tokenizer.Tokenizer {
+	return tokenizer.OrDefault(p.tokenizer)
 }
 
 // Helper to determine base URL for Responses API
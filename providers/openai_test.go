@@ -1,8 +1,15 @@
 package providers
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/openai/openai-go/v2"
 )
 
 func TestNewOpenAIProvider(t *testing.T) {
@@ -106,7 +113,7 @@ func TestOpenAIProvider_ValidateRequest(t *testing.T) {
 			request: ChatRequest{
 				Model:       "gpt-3.5-turbo",
 				UserPrompt:  "Hello, world!",
-				Temperature: 3.0,
+				Temperature: float64Ptr(3.0),
 			},
 			wantErr: true,
 		},
@@ -164,7 +171,7 @@ func TestOpenAIProvider_TokenCount(t *testing.T) {
 			response: ChatResponse{
 				Content: "This is a longer response with more tokens to count.",
 			},
-			want: 12, // 48 characters / 4 = 12 tokens
+			want: 13, // 52 characters / 4 = 13 tokens
 		},
 	}
 
@@ -202,27 +209,37 @@ func TestOpenAIProvider_IsRetryableError(t *testing.T) {
 		},
 		{
 			name:    "rate limit error",
-			err:     &ProviderError{Provider: "openai", Message: "rate_limit exceeded"},
+			err:     &RateLimitError{Provider: "openai", RetryAfter: time.Second},
 			want:    true,
 		},
 		{
-			name:    "429 error",
-			err:     &ProviderError{Provider: "openai", Message: "429 too many requests"},
+			name:    "SDK 429 error",
+			err:     &ProviderError{Provider: "openai", Message: "stream error", Cause: &openai.Error{StatusCode: http.StatusTooManyRequests}},
 			want:    true,
 		},
 		{
 			name:    "500 error",
-			err:     &ProviderError{Provider: "openai", Message: "500 internal server error"},
+			err:     &ServerError{Provider: "openai", StatusCode: http.StatusInternalServerError},
+			want:    true,
+		},
+		{
+			name:    "SDK 500 error",
+			err:     &ProviderError{Provider: "openai", Message: "stream error", Cause: &openai.Error{StatusCode: http.StatusInternalServerError}},
 			want:    true,
 		},
 		{
 			name:    "timeout error",
-			err:     &ProviderError{Provider: "openai", Message: "timeout"},
+			err:     &TimeoutError{Operation: "stream", Duration: 30 * time.Second},
 			want:    true,
 		},
 		{
 			name:    "authentication error",
-			err:     &ProviderError{Provider: "openai", Message: "401 unauthorized"},
+			err:     &AuthError{Provider: "openai", StatusCode: http.StatusUnauthorized},
+			want:    false,
+		},
+		{
+			name:    "SDK 400 error",
+			err:     &ProviderError{Provider: "openai", Message: "stream error", Cause: &openai.Error{StatusCode: http.StatusBadRequest}},
 			want:    false,
 		},
 	}
@@ -261,8 +278,8 @@ func TestOpenAIProvider_GetRetryDelay(t *testing.T) {
 		{
 			name:     "second attempt",
 			attempt:  2,
-			wantMin:  4 * time.Second,
-			wantMax:  5 * time.Second,
+			wantMin:  2 * time.Second,
+			wantMax:  3 * time.Second,
 		},
 		{
 			name:     "high attempt",
@@ -280,4 +297,928 @@ func TestOpenAIProvider_GetRetryDelay(t *testing.T) {
 			}
 		})
 	}
-} 
\ No newline at end of file
+} 
+// collectResponses drains a provider response channel, failing the test if it doesn't close in time
+func collectResponses(t *testing.T, responseChan <-chan ChatResponse) []ChatResponse {
+	t.Helper()
+
+	var responses []ChatResponse
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case resp, ok := <-responseChan:
+			if !ok {
+				return responses
+			}
+			responses = append(responses, resp)
+		case <-timeout:
+			t.Fatal("timed out waiting for response channel to close")
+		}
+	}
+}
+
+func TestOpenAIProvider_NoStream(t *testing.T) {
+	tests := []struct {
+		name        string
+		extraParams map[string]interface{}
+	}{
+		{name: "SDK path"},
+		{name: "direct path", extraParams: map[string]interface{}{"user": "bench"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotStream interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]interface{}
+				json.NewDecoder(r.Body).Decode(&body)
+				gotStream = body["stream"]
+
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4.1","choices":[{"index":0,"message":{"role":"assistant","content":"Hello there!"},"finish_reason":"stop"}]}`))
+			}))
+			defer server.Close()
+
+			provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:       "gpt-4.1",
+				UserPrompt:  "Hello",
+				ExtraParams: tt.extraParams,
+				NoStream:    true,
+			})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+
+			responses := collectResponses(t, responseChan)
+			if len(responses) != 1 {
+				t.Fatalf("expected a single response, got %d", len(responses))
+			}
+			if responses[0].Error != nil {
+				t.Fatalf("unexpected error: %v", responses[0].Error)
+			}
+			if !responses[0].IsComplete || responses[0].Content != "Hello there!" {
+				t.Errorf("response = %+v, want complete response with full content", responses[0])
+			}
+			if gotStream == true {
+				t.Errorf("request stream = %v, want false or omitted", gotStream)
+			}
+		})
+	}
+}
+
+func TestOpenAIProvider_FinishReasonLength(t *testing.T) {
+	tests := []struct {
+		name        string
+		extraParams map[string]interface{}
+	}{
+		{name: "SDK path"},
+		{name: "direct path", extraParams: map[string]interface{}{"user": "bench"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte(`data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4.1","choices":[{"index":0,"delta":{"content":"Hello"},"finish_reason":null}]}` + "\n\n"))
+				w.Write([]byte(`data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4.1","choices":[{"index":0,"delta":{},"finish_reason":"length"}]}` + "\n\n"))
+				w.Write([]byte("data: [DONE]\n\n"))
+			}))
+			defer server.Close()
+
+			provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:       "gpt-4.1",
+				UserPrompt:  "Hello",
+				MaxTokens:   1,
+				ExtraParams: tt.extraParams,
+			})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+
+			responses := collectResponses(t, responseChan)
+			if len(responses) == 0 {
+				t.Fatal("expected at least one response")
+			}
+			last := responses[len(responses)-1]
+			if last.Error != nil {
+				t.Fatalf("unexpected error: %v", last.Error)
+			}
+			if last.FinishReason != "length" {
+				t.Errorf("FinishReason = %q, want %q", last.FinishReason, "length")
+			}
+			if !IsTruncated(last.FinishReason) {
+				t.Errorf("IsTruncated(%q) = false, want true", last.FinishReason)
+			}
+		})
+	}
+}
+
+func TestOpenAIProvider_ForwardsMessages(t *testing.T) {
+	tests := []struct {
+		name        string
+		extraParams map[string]interface{}
+	}{
+		{name: "SDK path"},
+		{name: "direct path", extraParams: map[string]interface{}{"user": "bench"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Message
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Messages []Message `json:"messages"`
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				got = body.Messages
+
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4.1","choices":[{"index":0,"message":{"role":"assistant","content":"Rome."},"finish_reason":"stop"}]}`))
+			}))
+			defer server.Close()
+
+			provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:        "gpt-4.1",
+				SystemPrompt: "Be brief.",
+				Messages: []Message{
+					{Role: "user", Content: "What is the capital of France?"},
+					{Role: "assistant", Content: "Paris."},
+					{Role: "user", Content: "And of Italy?"},
+				},
+				ExtraParams: tt.extraParams,
+				NoStream:    true,
+			})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			collectResponses(t, responseChan)
+
+			want := []Message{
+				{Role: "system", Content: "Be brief."},
+				{Role: "user", Content: "What is the capital of France?"},
+				{Role: "assistant", Content: "Paris."},
+				{Role: "user", Content: "And of Italy?"},
+			}
+			if len(got) != len(want) {
+				t.Fatalf("sent %d messages, want %d: %+v", len(got), len(want), got)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("message %d = %+v, want %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAIProvider_SendsImage(t *testing.T) {
+	tests := []struct {
+		name        string
+		extraParams map[string]interface{}
+	}{
+		{name: "SDK path"},
+		{name: "direct path", extraParams: map[string]interface{}{"user": "bench"}},
+	}
+
+	type contentPart struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		ImageURL struct {
+			URL string `json:"url"`
+		} `json:"image_url"`
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []contentPart
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Messages []struct {
+						Role    string        `json:"role"`
+						Content []contentPart `json:"content"`
+					} `json:"messages"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				if len(body.Messages) == 1 && body.Messages[0].Role == "user" {
+					got = body.Messages[0].Content
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4.1","choices":[{"index":0,"message":{"role":"assistant","content":"A bar chart."},"finish_reason":"stop"}]}`))
+			}))
+			defer server.Close()
+
+			provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:       "gpt-4.1",
+				UserPrompt:  "Describe this chart.",
+				Image:       &Image{MediaType: "image/png", Data: []byte("png")},
+				ExtraParams: tt.extraParams,
+				NoStream:    true,
+			})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			collectResponses(t, responseChan)
+
+			// Local images are sent inline as a base64 data URL
+			if len(got) != 2 {
+				t.Fatalf("user message content = %+v, want a text and an image part", got)
+			}
+			if got[0].Type != "text" || got[0].Text != "Describe this chart." {
+				t.Errorf("first part = %+v, want the prompt text", got[0])
+			}
+			if got[1].Type != "image_url" || got[1].ImageURL.URL != "data:image/png;base64,cG5n" {
+				t.Errorf("second part = %+v, want the image as a data URL", got[1])
+			}
+		})
+	}
+}
+
+func TestOpenAIProvider_AccountHeaders(t *testing.T) {
+	tests := []struct {
+		name        string
+		extraParams map[string]interface{}
+	}{
+		{name: "SDK path"},
+		{name: "direct path", extraParams: map[string]interface{}{"user": "bench"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				headers = r.Header.Clone()
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4.1","choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`))
+			}))
+			defer server.Close()
+
+			provider, err := NewOpenAIProvider(&OpenAIConfig{
+				APIKey:       "test-key",
+				BaseURL:      server.URL,
+				Organization: "org-123",
+				Project:      "proj_456",
+			})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:       "gpt-4.1",
+				UserPrompt:  "Hello",
+				ExtraParams: tt.extraParams,
+				NoStream:    true,
+			})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			collectResponses(t, responseChan)
+
+			if got := headers.Get("OpenAI-Organization"); got != "org-123" {
+				t.Errorf("OpenAI-Organization = %q, want %q", got, "org-123")
+			}
+			if got := headers.Get("OpenAI-Project"); got != "proj_456" {
+				t.Errorf("OpenAI-Project = %q, want %q", got, "proj_456")
+			}
+		})
+	}
+}
+
+func TestOpenAIProvider_AccountHeadersOmittedByDefault(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:       "gpt-4.1",
+		UserPrompt:  "Hello",
+		ExtraParams: map[string]interface{}{"user": "bench"},
+		NoStream:    true,
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	collectResponses(t, responseChan)
+
+	if _, ok := headers["Openai-Organization"]; ok {
+		t.Errorf("unexpected OpenAI-Organization header: %v", headers)
+	}
+	if _, ok := headers["Openai-Project"]; ok {
+		t.Errorf("unexpected OpenAI-Project header: %v", headers)
+	}
+}
+
+func TestOpenAIProvider_Seed(t *testing.T) {
+	seed := int64(42)
+	tests := []struct {
+		name        string
+		seed        *int64
+		extraParams map[string]interface{}
+	}{
+		{name: "SDK path", seed: &seed},
+		{name: "direct path", seed: &seed, extraParams: map[string]interface{}{"user": "bench"}},
+		{name: "direct path seed parameter", extraParams: map[string]interface{}{"seed": 42}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&payload)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4.1","choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`))
+			}))
+			defer server.Close()
+
+			provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:       "gpt-4.1",
+				UserPrompt:  "Hello",
+				Seed:        tt.seed,
+				ExtraParams: tt.extraParams,
+				NoStream:    true,
+			})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			collectResponses(t, responseChan)
+
+			if got, ok := payload["seed"]; !ok || got != float64(42) {
+				t.Errorf("payload seed = %v, want 42 (payload %v)", got, payload)
+			}
+		})
+	}
+}
+
+func TestOpenAIProvider_ZeroTemperature(t *testing.T) {
+	tests := []struct {
+		name        string
+		extraParams map[string]interface{}
+	}{
+		{name: "SDK path"},
+		{name: "direct path", extraParams: map[string]interface{}{"user": "bench"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&payload)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-3.5-turbo","choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`))
+			}))
+			defer server.Close()
+
+			provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			// An explicit 0 asks for deterministic sampling and must be sent, not dropped
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:       "gpt-3.5-turbo",
+				UserPrompt:  "Hello",
+				Temperature: float64Ptr(0),
+				ExtraParams: tt.extraParams,
+				NoStream:    true,
+			})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			collectResponses(t, responseChan)
+
+			if got, ok := payload["temperature"]; !ok || got != float64(0) {
+				t.Errorf("payload temperature = %v, want 0 (payload %v)", got, payload)
+			}
+		})
+	}
+}
+
+func TestOpenAIProvider_ModelCapabilities(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name         string
+		model        string
+		capabilities ModelCapabilities
+		extraParams  map[string]interface{}
+		wantSampling bool
+		wantMax      bool
+	}{
+		{name: "prefix default", model: "gpt-4o-mini"},
+		{name: "gpt-4o accepting sampling", model: "gpt-4o-mini", capabilities: ModelCapabilities{FixedSampling: &no, UsesMaxCompletionTokens: &no}, wantSampling: true, wantMax: true},
+		{name: "unknown reasoning model", model: "acme-reasoner", capabilities: ModelCapabilities{FixedSampling: &yes, UsesMaxCompletionTokens: &yes}},
+		{name: "unknown model default", model: "acme-chat", wantSampling: true, wantMax: true},
+		{name: "direct path", model: "gpt-4o-mini", capabilities: ModelCapabilities{FixedSampling: &no}, extraParams: map[string]interface{}{"user": "bench"}, wantSampling: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&payload)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`))
+			}))
+			defer server.Close()
+
+			provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:        tt.model,
+				UserPrompt:   "Hello",
+				MaxTokens:    256,
+				Temperature:  float64Ptr(0.2),
+				TopP:         0.9,
+				ExtraParams:  tt.extraParams,
+				NoStream:     true,
+				Capabilities: tt.capabilities,
+			})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			collectResponses(t, responseChan)
+
+			_, hasTemperature := payload["temperature"]
+			_, hasTopP := payload["top_p"]
+			if hasTemperature != tt.wantSampling || hasTopP != tt.wantSampling {
+				t.Errorf("payload temperature/top_p sent = %v/%v, want %v (payload %v)", hasTemperature, hasTopP, tt.wantSampling, payload)
+			}
+			if _, hasMax := payload["max_tokens"]; hasMax != tt.wantMax {
+				t.Errorf("payload max_tokens sent = %v, want %v (payload %v)", hasMax, tt.wantMax, payload)
+			}
+			if got := AcceptsSamplingParameters("openai", ChatRequest{Model: tt.model, Capabilities: tt.capabilities}); got != tt.wantSampling {
+				t.Errorf("AcceptsSamplingParameters() = %v, want %v", got, tt.wantSampling)
+			}
+		})
+	}
+}
+
+func TestOpenAIProvider_SeedOmittedByDefault(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:       "gpt-4.1",
+		UserPrompt:  "Hello",
+		ExtraParams: map[string]interface{}{"user": "bench"},
+		NoStream:    true,
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	collectResponses(t, responseChan)
+
+	if _, ok := payload["seed"]; ok {
+		t.Errorf("unexpected seed in payload: %v", payload)
+	}
+}
+
+func TestOpenAIProvider_ResponseFormat(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"1","object":"chat.completion.chunk","created":1,"model":"gpt-4.1","choices":[{"index":0,"delta":{"content":"{\"ok\":true}"},"finish_reason":"stop"}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	format := map[string]interface{}{"type": "json_object"}
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:       "gpt-4.1",
+		UserPrompt:  "Reply in JSON",
+		ExtraParams: map[string]interface{}{"response_format": format},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	collectResponses(t, responseChan)
+
+	if got := payload["response_format"]; !reflect.DeepEqual(got, format) {
+		t.Errorf("response_format = %v, want %v", got, format)
+	}
+}
+
+func TestOpenAIProvider_DirectUsageWithCachedTokens(t *testing.T) {
+	const usage = `"usage":{"prompt_tokens":2048,"completion_tokens":12,"prompt_tokens_details":{"cached_tokens":1920}}`
+	tests := []struct {
+		name     string
+		noStream bool
+		body     string
+	}{
+		{
+			name: "stream",
+			body: `data: {"choices":[{"index":0,"delta":{"content":"Hi"}}],"usage":null}` + "\n\n" +
+				`data: {"choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"usage":null}` + "\n\n" +
+				`data: {"choices":[],` + usage + `}` + "\n\n" +
+				"data: [DONE]\n\n",
+		},
+		{
+			name:     "no stream",
+			noStream: true,
+			body:     `{"choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}],` + usage + `}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&payload)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:       "gpt-4.1",
+				UserPrompt:  "Hello",
+				ExtraParams: map[string]interface{}{"user": "bench"},
+				NoStream:    tt.noStream,
+			})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+
+			responses := collectResponses(t, responseChan)
+			final := responses[len(responses)-1]
+			if final.Error != nil {
+				t.Fatalf("unexpected error: %v", final.Error)
+			}
+			want := Usage{InputTokens: 2048, OutputTokens: 12, CachedInputTokens: 1920}
+			if final.Usage == nil || *final.Usage != want {
+				t.Errorf("Usage = %+v, want %+v", final.Usage, want)
+			}
+
+			_, requested := payload["stream_options"]
+			if requested == tt.noStream {
+				t.Errorf("stream_options present = %v, want %v", requested, !tt.noStream)
+			}
+		})
+	}
+}
+
+func TestOpenAIProvider_ModelVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		extraParams map[string]interface{}
+		noStream    bool
+	}{
+		{name: "SDK path"},
+		{name: "SDK path without streaming", noStream: true},
+		{name: "direct path", extraParams: map[string]interface{}{"user": "bench"}},
+		{name: "direct path without streaming", extraParams: map[string]interface{}{"user": "bench"}, noStream: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.noStream {
+					w.Header().Set("Content-Type", "application/json")
+					w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4.1-2025-04-14","system_fingerprint":"fp_b42ea4cc6f",` +
+						`"choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`))
+					return
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte(`data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4.1-2025-04-14","system_fingerprint":"fp_b42ea4cc6f","choices":[{"index":0,"delta":{"content":"Hello"},"finish_reason":null}]}` + "\n\n"))
+				w.Write([]byte(`data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4.1-2025-04-14","system_fingerprint":"fp_b42ea4cc6f","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}` + "\n\n"))
+				w.Write([]byte("data: [DONE]\n\n"))
+			}))
+			defer server.Close()
+
+			provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:       "gpt-4.1",
+				UserPrompt:  "Hello",
+				ExtraParams: tt.extraParams,
+				NoStream:    tt.noStream,
+			})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+
+			responses := collectResponses(t, responseChan)
+			last := responses[len(responses)-1]
+			if last.Error != nil {
+				t.Fatalf("unexpected error: %v", last.Error)
+			}
+			if last.ResponseModel != "gpt-4.1-2025-04-14" {
+				t.Errorf("ResponseModel = %q, want %q", last.ResponseModel, "gpt-4.1-2025-04-14")
+			}
+			if last.SystemFingerprint != "fp_b42ea4cc6f" {
+				t.Errorf("SystemFingerprint = %q, want %q", last.SystemFingerprint, "fp_b42ea4cc6f")
+			}
+		})
+	}
+}
+
+func TestOpenAIProvider_DirectUsageWithReasoningTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"42"},"finish_reason":"stop"}],` +
+			`"usage":{"prompt_tokens":30,"completion_tokens":450,"completion_tokens_details":{"reasoning_tokens":448}}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:       "o4-mini",
+		UserPrompt:  "What is 6*7?",
+		ExtraParams: map[string]interface{}{"reasoning_effort": "low"},
+		NoStream:    true,
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+
+	responses := collectResponses(t, responseChan)
+	want := Usage{InputTokens: 30, OutputTokens: 450, ReasoningTokens: 448}
+	if got := responses[len(responses)-1].Usage; got == nil || *got != want {
+		t.Errorf("Usage = %+v, want %+v", got, want)
+	}
+}
+
+func TestOpenAIResponsesProvider_Usage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"type":"response.output_text.delta","delta":"42"}` + "\n\n"))
+		w.Write([]byte(`data: {"type":"response.completed","response":{"status":"completed","usage":{` +
+			`"input_tokens":1200,"input_tokens_details":{"cached_tokens":1024},` +
+			`"output_tokens":300,"output_tokens_details":{"reasoning_tokens":256}}}}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIResponsesProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{Model: "gpt-5-mini", UserPrompt: "What is 6*7?"})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+
+	responses := collectResponses(t, responseChan)
+	final := responses[len(responses)-1]
+	if final.Error != nil {
+		t.Fatalf("unexpected error: %v", final.Error)
+	}
+	want := Usage{InputTokens: 1200, OutputTokens: 300, CachedInputTokens: 1024, ReasoningTokens: 256}
+	if final.Usage == nil || *final.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", final.Usage, want)
+	}
+}
+
+func TestOpenAIResponsesProvider_SendsImage(t *testing.T) {
+	type contentPart struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		ImageURL string `json:"image_url"`
+	}
+
+	var got []contentPart
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input []struct {
+				Role    string          `json:"role"`
+				Content json.RawMessage `json:"content"`
+			} `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		// The system message keeps its plain string content
+		if len(body.Input) == 2 && body.Input[1].Role == "user" {
+			if err := json.Unmarshal(body.Input[1].Content, &got); err != nil {
+				t.Errorf("failed to decode user content: %v", err)
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"type":"response.output_text.delta","delta":"A cat."}` + "\n\n"))
+		w.Write([]byte(`data: {"type":"response.completed","response":{"status":"completed"}}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIResponsesProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:        "gpt-5-mini",
+		SystemPrompt: "Be brief.",
+		UserPrompt:   "What is in this photo?",
+		Image:        &Image{URL: "https://example.com/cat.jpg", MediaType: "image/jpeg"},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	collectResponses(t, responseChan)
+
+	// The image goes with the last user message as an input_image part
+	if len(got) != 2 {
+		t.Fatalf("user message content = %+v, want a text and an image part", got)
+	}
+	if got[0].Type != "input_text" || got[0].Text != "What is in this photo?" {
+		t.Errorf("first part = %+v, want the prompt text", got[0])
+	}
+	if got[1].Type != "input_image" || got[1].ImageURL != "https://example.com/cat.jpg" {
+		t.Errorf("second part = %+v, want the image URL", got[1])
+	}
+}
+
+func TestOpenAIProvider_DirectToolCallStream(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"choices":[{"index":0,"delta":{"role":"assistant","content":"Checking."}}]}`,
+			`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+			`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+			`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}`,
+			`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		} {
+			w.Write([]byte("data: " + chunk + "\n\n"))
+		}
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	tools := []map[string]interface{}{{
+		"type":     "function",
+		"function": map[string]interface{}{"name": "get_weather", "parameters": map[string]interface{}{"type": "object"}},
+	}}
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:       "gpt-4.1",
+		UserPrompt:  "Weather in Paris?",
+		ExtraParams: map[string]interface{}{"tools": tools, "tool_choice": "auto"},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+
+	var content, name, arguments string
+	var final ChatResponse
+	for _, resp := range collectResponses(t, responseChan) {
+		if resp.Error != nil {
+			t.Fatalf("unexpected error: %v", resp.Error)
+		}
+		content += resp.Content
+		for _, call := range resp.ToolCalls {
+			name += call.Name
+			arguments += call.Arguments
+		}
+		final = resp
+	}
+
+	if payload["tool_choice"] != "auto" || len(payload["tools"].([]interface{})) != 1 {
+		t.Errorf("payload tools = %v, tool_choice = %v", payload["tools"], payload["tool_choice"])
+	}
+	if content != "Checking." {
+		t.Errorf("content = %q, want only the text deltas", content)
+	}
+	if name != "get_weather" || arguments != `{"city":"Paris"}` {
+		t.Errorf("tool call = %s(%s), want get_weather({\"city\":\"Paris\"})", name, arguments)
+	}
+	if final.FinishReason != "tool_calls" {
+		t.Errorf("FinishReason = %q, want tool_calls", final.FinishReason)
+	}
+}
+
+func TestOpenAIProvider_DirectToolCallNoStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[` +
+			`{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},` +
+			`"finish_reason":"tool_calls"}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:       "gpt-4.1",
+		UserPrompt:  "Weather in Paris?",
+		ExtraParams: map[string]interface{}{"tool_choice": "required"},
+		NoStream:    true,
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+
+	responses := collectResponses(t, responseChan)
+	want := []ToolCallDelta{{Index: 0, Name: "get_weather", Arguments: `{"city":"Paris"}`}}
+	if got := responses[0].ToolCalls; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("ToolCalls = %+v, want %+v", got, want)
+	}
+	if responses[0].Content != "" {
+		t.Errorf("Content = %q, want empty", responses[0].Content)
+	}
+}
+
+func TestOpenAIProvider_StopSequences(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4.1","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	// A stop list is an extra parameter, so it goes out on the direct path
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:       "gpt-4.1",
+		UserPrompt:  "Say hello",
+		ExtraParams: map[string]interface{}{"stop": []string{"\n\n", "END"}},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	if responses := collectResponses(t, responseChan); responses[len(responses)-1].Error != nil {
+		t.Fatalf("unexpected error: %v", responses[len(responses)-1].Error)
+	}
+
+	want := []interface{}{"\n\n", "END"}
+	if got, _ := gotBody["stop"].([]interface{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("stop = %#v, want %#v", gotBody["stop"], want)
+	}
+}
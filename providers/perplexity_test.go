@@ -0,0 +1,143 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNewPerplexityProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *OpenAICompatibleConfig
+		wantErr bool
+		wantURL string
+	}{
+		{
+			name:    "valid config",
+			config:  &OpenAICompatibleConfig{APIKey: "test-key", BaseURL: "https://example.test"},
+			wantURL: "https://example.test",
+		},
+		{
+			name:    "missing API key",
+			config:  &OpenAICompatibleConfig{BaseURL: "https://api.perplexity.ai"},
+			wantErr: true,
+		},
+		{
+			name:    "empty base URL (should use default)",
+			config:  &OpenAICompatibleConfig{APIKey: "test-key"},
+			wantURL: "https://api.perplexity.ai",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewOpenAICompatibleProvider("perplexity", tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewOpenAICompatibleProvider(\"perplexity\") error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if provider.Name() != "perplexity" {
+				t.Errorf("Name() = %s, want 'perplexity'", provider.Name())
+			}
+			if provider.config.BaseURL != tt.wantURL {
+				t.Errorf("BaseURL = %s, want %s", provider.config.BaseURL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestPerplexityProvider_ValidateRequest(t *testing.T) {
+	provider, err := NewOpenAICompatibleProvider("perplexity", &OpenAICompatibleConfig{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		request ChatRequest
+		wantErr bool
+	}{
+		{"valid request", ChatRequest{Model: "sonar", UserPrompt: "Hello"}, false},
+		{"conversation without user prompt", ChatRequest{Model: "sonar", Messages: []Message{{Role: "user", Content: "Hello"}}}, false},
+		{"missing model", ChatRequest{UserPrompt: "Hello"}, true},
+		{"missing user prompt", ChatRequest{Model: "sonar"}, true},
+		{"negative max tokens", ChatRequest{Model: "sonar", UserPrompt: "Hello", MaxTokens: -1}, true},
+		{"temperature of 2", ChatRequest{Model: "sonar", UserPrompt: "Hello", Temperature: float64Ptr(2)}, true},
+		{"invalid top_p", ChatRequest{Model: "sonar", UserPrompt: "Hello", TopP: 1.5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := provider.ValidateRequest(tt.request); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPerplexityProvider_StreamChat(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotBody compatChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&gotBody)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"1","model":"sonar","citations":["https://a.example"],"choices":[{"index":0,"delta":{"content":"Paris"}}],"usage":{"prompt_tokens":7,"completion_tokens":1}}` + "\n\n"))
+		w.Write([]byte(`data: {"id":"1","model":"sonar","citations":["https://a.example","https://b.example"],"choices":[{"index":0,"delta":{"content":" is the capital."},"finish_reason":"stop"}],"usage":{"prompt_tokens":7,"completion_tokens":5}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAICompatibleProvider("perplexity", &OpenAICompatibleConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{Model: "sonar", UserPrompt: "Capital of France?", MaxTokens: 50})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	responses := collectResponses(t, responseChan)
+
+	if gotPath != "/chat/completions" || gotAuth != "Bearer test-key" {
+		t.Errorf("request to %s with auth %q, want /chat/completions with the bearer key", gotPath, gotAuth)
+	}
+	if !gotBody.Stream || gotBody.Model != "sonar" || gotBody.MaxTokens == nil || *gotBody.MaxTokens != 50 {
+		t.Errorf("request body = %+v, want a streamed sonar request with max_tokens 50", gotBody)
+	}
+
+	var content string
+	for _, response := range responses {
+		if response.Error != nil {
+			t.Fatalf("unexpected error: %v", response.Error)
+		}
+		content += response.Content
+	}
+	if content != "Paris is the capital." {
+		t.Errorf("content = %q, want %q", content, "Paris is the capital.")
+	}
+
+	final := responses[len(responses)-1]
+	if !final.IsComplete || final.FinishReason != "stop" {
+		t.Errorf("final response = %+v, want complete with finish reason stop", final)
+	}
+	if want := []string{"https://a.example", "https://b.example"}; !reflect.DeepEqual(final.Citations, want) {
+		t.Errorf("Citations = %v, want %v", final.Citations, want)
+	}
+	if final.Usage == nil || final.Usage.InputTokens != 7 || final.Usage.OutputTokens != 5 {
+		t.Errorf("Usage = %+v, want the last chunk's 7 input and 5 output tokens", final.Usage)
+	}
+	for _, response := range responses[:len(responses)-1] {
+		if len(response.Citations) > 0 {
+			t.Errorf("content chunk carried citations: %+v", response)
+		}
+	}
+}
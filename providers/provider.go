@@ -2,8 +2,17 @@ package providers
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/megzo/llm-latency-benchmark/internal/retry"
+	"github.com/megzo/llm-latency-benchmark/internal/tokenizer"
 )
 
 // Provider defines the interface for LLM providers
@@ -21,23 +30,264 @@ type Provider interface {
 	GetTokenCount(text string) int
 }
 
+// RetryPolicy is implemented by providers that can tell transient failures apart
+// from permanent ones; the runner retries only errors IsRetryableError accepts
+type RetryPolicy interface {
+	IsRetryableError(err error) bool
+	GetRetryDelay(attempt int, err error) time.Duration
+}
+
+// TokenizerProvider is implemented by providers that estimate tokens with a
+// pluggable tokenizer, so callers can count tokens for a specific model
+type TokenizerProvider interface {
+	Tokenizer() tokenizer.Tokenizer
+}
+
+// InputTokenCounter is implemented by providers whose API counts a request's
+// prompt tokens exactly (Anthropic's and Gemini's count-tokens endpoints)
+type InputTokenCounter interface {
+	CountInputTokens(ctx context.Context, req ChatRequest) (int, error)
+}
+
+// BackendReporter is implemented by providers that can serve requests from more
+// than one backend (Gemini's public API or Vertex AI), naming the one in use
+type BackendReporter interface {
+	GetBackendInfo() string
+}
+
+// Ping checks that a provider's endpoint and credentials work by streaming a
+// minimal one-token chat request to model, returning the first error it reports
+func Ping(ctx context.Context, provider Provider, model string) error {
+	responses, err := provider.StreamChat(ctx, ChatRequest{Model: model, UserPrompt: "ping", MaxTokens: 1})
+	if err != nil {
+		return err
+	}
+	var pingErr error
+	for response := range responses {
+		if response.Error != nil && pingErr == nil {
+			pingErr = response.Error
+		}
+	}
+	return pingErr
+}
+
+// EmbeddingProvider is implemented by providers that can create embeddings
+type EmbeddingProvider interface {
+	Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error)
+}
+
+// EmbeddingRequest represents an embeddings request
+type EmbeddingRequest struct {
+	Model       string                 `json:"model"`
+	Input       []string               `json:"input"`
+	ExtraParams map[string]interface{} `json:"extra_params,omitempty"` // e.g. "dimensions"
+}
+
+// EmbeddingResponse summarizes an embeddings response. Embeddings aren't streamed,
+// so Latency is the full round trip and stands in for TTFT.
+type EmbeddingResponse struct {
+	Count         int            // Number of vectors returned
+	Dimensions    int            // Length of each vector
+	Latency       time.Duration
+	Usage         *Usage         // Provider-reported token usage, if any
+	NetworkTiming *NetworkTiming
+}
+
+// RequestValidator is implemented by providers that can check a request locally
+// before sending it
+type RequestValidator interface {
+	ValidateRequest(req ChatRequest) error
+}
+
 // ChatRequest represents a chat completion request
 type ChatRequest struct {
 	Model       string                 `json:"model"`
 	SystemPrompt string                `json:"system_prompt,omitempty"`
 	UserPrompt  string                 `json:"user_prompt"`
+	// Messages holds a multi-turn conversation. When set it replaces UserPrompt;
+	// SystemPrompt, if any, is still sent first.
+	Messages    []Message              `json:"messages,omitempty"`
 	MaxTokens   int                    `json:"max_tokens,omitempty"`
-	Temperature float64                `json:"temperature,omitempty"`
+	// Temperature is sent whenever set, including 0 (nil = provider default)
+	Temperature *float64               `json:"temperature,omitempty"`
 	TopP        float64                `json:"top_p,omitempty"`
+	// Seed pins the sampling seed for providers that support it (nil = provider default)
+	Seed        *int64                 `json:"seed,omitempty"`
 	ExtraParams map[string]interface{} `json:"extra_params,omitempty"`
+	// NoStream requests a single non-streamed completion. Providers that support it
+	// emit one complete ChatResponse with the full content; others keep streaming.
+	NoStream    bool                   `json:"no_stream,omitempty"`
+	// RequestID is sent as the X-Request-Id header (by every provider but Gemini)
+	// so a run can be matched with the provider's logs; "" sends none
+	RequestID   string                 `json:"request_id,omitempty"`
+	// Capabilities overrides what providers infer about the model from its name
+	Capabilities ModelCapabilities     `json:"-"`
+	// Headers customizes the HTTP headers sent by the OpenAI-compatible providers
+	Headers     RequestHeaders         `json:"-"`
+	// Image is attached to the last user message by the providers that accept
+	// image input (OpenAI Chat Completions and Responses, Anthropic and Gemini);
+	// the others send the text alone
+	Image       *Image                 `json:"-"`
+}
+
+// Image is a picture sent along with a prompt to benchmark vision models
+type Image struct {
+	// URL is fetched by the provider itself; when empty, Data is sent inline
+	URL       string
+	// MediaType is the image's MIME type (e.g. "image/png")
+	MediaType string
+	Data      []byte
+}
+
+// Base64 returns the image data base64-encoded
+func (i *Image) Base64() string {
+	return base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// DataURL returns the image's URL, or its data inlined as a data: URL
+func (i *Image) DataURL() string {
+	if i.URL != "" {
+		return i.URL
+	}
+	return "data:" + i.MediaType + ";base64," + i.Base64()
+}
+
+// ModelCapabilities describes which request parameters a model accepts where
+// that can't be told from its name alone; nil fields fall back to name prefixes
+type ModelCapabilities struct {
+	// UsesMaxCompletionTokens is set for models that reject max_tokens, which is
+	// then left out of the request
+	UsesMaxCompletionTokens *bool
+	// FixedSampling is set for models that reject temperature and top_p
+	FixedSampling *bool
+}
+
+// RequestHeaders customizes how a model's requests authenticate, for gateways in
+// front of an OpenAI-compatible API that need more than "Authorization: Bearer"
+type RequestHeaders struct {
+	// Extra headers are added to every request, after the API key
+	Extra      map[string]string
+	// AuthHeader carries the API key instead of Authorization ("" = Authorization)
+	AuthHeader string
+	// AuthScheme replaces "Bearer" before the API key. A key sent in AuthHeader has
+	// no scheme unless one is set here.
+	AuthScheme string
+}
+
+// Message represents a chat message
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatMessages returns the conversation to send, built from Messages when present
+// and from the system/user prompt pair otherwise
+func (r ChatRequest) ChatMessages() []Message {
+	var messages []Message
+	if r.SystemPrompt != "" {
+		messages = append(messages, Message{Role: "system", Content: r.SystemPrompt})
+	}
+	if len(r.Messages) > 0 {
+		return append(messages, r.Messages...)
+	}
+	return append(messages, Message{Role: "user", Content: r.UserPrompt})
+}
+
+// StopSequences returns the "stop" parameter as a list, accepting one string or a
+// list of them as decoded from YAML or JSON (nil if unset)
+func (r ChatRequest) StopSequences() []string {
+	switch stop := r.ExtraParams["stop"].(type) {
+	case string:
+		return []string{stop}
+	case []string:
+		return stop
+	case []interface{}:
+		var stops []string
+		for _, s := range stop {
+			if str, ok := s.(string); ok {
+				stops = append(stops, str)
+			}
+		}
+		return stops
+	}
+	return nil
+}
+
+// PromptText returns the concatenated content of all request messages, for token estimation
+func (r ChatRequest) PromptText() string {
+	var text strings.Builder
+	for _, message := range r.ChatMessages() {
+		text.WriteString(message.Content)
+	}
+	return text.String()
 }
 
 // ChatResponse represents a streaming chat response
 type ChatResponse struct {
 	Content     string    `json:"content"`
+	// ReasoningContent carries visible chain-of-thought that some models (e.g.
+	// DeepSeek's reasoner) stream separately before the answer in Content
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	// RedactedReasoning marks the arrival of reasoning the provider returned
+	// encrypted (Anthropic's redacted_thinking), which has no text to carry
+	RedactedReasoning bool `json:"redacted_reasoning,omitempty"`
 	IsComplete  bool      `json:"is_complete"`
 	Timestamp   time.Time `json:"timestamp"`
 	Error       error     `json:"error,omitempty"`
+	// FinishReason is the provider's finish/stop reason, set on the final response
+	FinishReason string   `json:"finish_reason,omitempty"`
+	// NetworkTiming is set on the final response by providers that make HTTP requests directly
+	NetworkTiming *NetworkTiming `json:"network_timing,omitempty"`
+	// Usage is set on the final response by providers that report token usage
+	Usage        *Usage    `json:"usage,omitempty"`
+	// ToolCalls carries tool-call fragments, kept apart from Content
+	ToolCalls    []ToolCallDelta `json:"tool_calls,omitempty"`
+	// RateLimit is set on the final response by direct-HTTP providers whose
+	// responses carry rate-limit headers, including failed requests
+	RateLimit    *RateLimitInfo  `json:"rate_limit,omitempty"`
+	// Citations lists the source URLs a search-backed model cited, set on the final response
+	Citations    []string        `json:"citations,omitempty"`
+	// ResponseModel is the model version the API reported serving, and
+	// SystemFingerprint its backend configuration (OpenAI-compatible APIs);
+	// both are set on the final response
+	ResponseModel     string `json:"response_model,omitempty"`
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+}
+
+// ToolCallDelta is a fragment of a tool call. Name is set on the first fragment of
+// each call and Arguments carries the next piece of its JSON arguments.
+type ToolCallDelta struct {
+	Index     int    `json:"index"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// Usage holds the token counts a provider reported for a request
+type Usage struct {
+	InputTokens       int `json:"input_tokens"`        // All prompt tokens, including cached ones
+	OutputTokens      int `json:"output_tokens"`       // All billed output tokens, including reasoning
+	CachedInputTokens int `json:"cached_input_tokens"` // Prompt tokens served from the provider's prompt cache
+	ReasoningTokens   int `json:"reasoning_tokens"`    // Output tokens spent on hidden reasoning
+}
+
+// sendResponse delivers a response unless ctx is cancelled first, so streaming goroutines
+// don't block forever once the caller stops reading. It returns false if ctx was cancelled.
+func sendResponse(ctx context.Context, responseChan chan<- ChatResponse, response ChatResponse) bool {
+	select {
+	case responseChan <- response:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// IsTruncated reports whether a finish reason means the output was cut off by the token limit
+func IsTruncated(finishReason string) bool {
+	switch strings.ToLower(finishReason) {
+	case "length", "max_tokens", "max_output_tokens":
+		return true
+	}
+	return false
 }
 
 // BenchmarkResult holds the complete result of a benchmark run
@@ -139,4 +389,136 @@ type RateLimitError struct {
 
 func (e *RateLimitError) Error() string {
 	return fmt.Sprintf("rate limit exceeded for provider %s, retry after %v", e.Provider, e.RetryAfter)
+}
+
+type AuthError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("authentication failed for provider %s (HTTP %d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+type ServerError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("server error from provider %s (HTTP %d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+type NetworkError struct {
+	Provider string
+	Cause    error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("network error for provider %s: %v", e.Provider, e.Cause)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Cause
+}
+
+// Error categories reported in benchmark output
+const (
+	ErrorCategoryAuth      = "auth"
+	ErrorCategoryRateLimit = "rate_limit"
+	ErrorCategoryServer    = "server"
+	ErrorCategoryNetwork   = "network"
+	ErrorCategoryTimeout   = "timeout"
+	ErrorCategoryOther     = "other"
+)
+
+// ErrorCategory classifies an error for reporting, or returns "" for a nil error
+func ErrorCategory(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var authErr *AuthError
+	var rateLimitErr *RateLimitError
+	var serverErr *ServerError
+	var networkErr *NetworkError
+	var timeoutErr *TimeoutError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &authErr):
+		return ErrorCategoryAuth
+	case errors.As(err, &rateLimitErr):
+		return ErrorCategoryRateLimit
+	case errors.As(err, &serverErr):
+		return ErrorCategoryServer
+	case errors.As(err, &timeoutErr), errors.Is(err, context.DeadlineExceeded):
+		return ErrorCategoryTimeout
+	case errors.As(err, &networkErr), errors.As(err, &netErr):
+		return ErrorCategoryNetwork
+	}
+	return ErrorCategoryOther
+}
+
+// newHTTPError maps a non-200 HTTP response to a typed error
+func newHTTPError(provider string, resp *http.Response, body []byte) error {
+	message := strings.TrimSpace(string(body))
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return &AuthError{Provider: provider, StatusCode: resp.StatusCode, Message: message}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		// Without Retry-After, wait for the exhausted rate-limit bucket to reset
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if retryAfter == 0 {
+			retryAfter = parseRateLimitHeaders(resp.Header).ResetDelay()
+		}
+		return &RateLimitError{Provider: provider, RetryAfter: retryAfter}
+	case resp.StatusCode >= 500:
+		return &ServerError{Provider: provider, StatusCode: resp.StatusCode, Message: message}
+	}
+	return &ProviderError{Provider: provider, Message: fmt.Sprintf("HTTP error %d: %s", resp.StatusCode, message)}
+}
+
+// rateLimitRetryDelay returns how long a rate-limit error asked to wait before
+// retrying, or 0 if err isn't one or gave no hint
+func rateLimitRetryDelay(err error) time.Duration {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr.RetryAfter
+	}
+	return 0
+}
+
+// retryDelay returns the delay before retry number attempt: as long as a
+// rate-limit error asked to wait, otherwise the strategy's backoff, either way
+// capped at maxDelay (retry.MaxDelay when 0)
+func retryDelay(strategy retry.Strategy, maxDelay time.Duration, attempt int, err error) time.Duration {
+	if maxDelay <= 0 {
+		maxDelay = retry.MaxDelay
+	}
+	if delay := rateLimitRetryDelay(err); delay > 0 {
+		return min(delay, maxDelay)
+	}
+	return retry.DelayUpTo(strategy, attempt, maxDelay)
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
 } 
\ No newline at end of file
@@ -0,0 +1,546 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/megzo/llm-latency-benchmark/internal/retry"
+	"github.com/megzo/llm-latency-benchmark/internal/tokenizer"
+)
+
+// float64Ptr returns a pointer to v, for optional request fields
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+func TestIsTruncated(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   bool
+	}{
+		{"length", true},
+		{"max_tokens", true},
+		{"MAX_TOKENS", true},
+		{"max_output_tokens", true},
+		{"stop", false},
+		{"end_turn", false},
+		{"STOP", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsTruncated(tt.reason); got != tt.want {
+			t.Errorf("IsTruncated(%q) = %v, want %v", tt.reason, got, tt.want)
+		}
+	}
+}
+
+func TestNewHTTPError_Categories(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusUnauthorized, ErrorCategoryAuth},
+		{http.StatusForbidden, ErrorCategoryAuth},
+		{http.StatusTooManyRequests, ErrorCategoryRateLimit},
+		{http.StatusInternalServerError, ErrorCategoryServer},
+		{http.StatusBadGateway, ErrorCategoryServer},
+		{http.StatusServiceUnavailable, ErrorCategoryServer},
+		{http.StatusBadRequest, ErrorCategoryOther},
+		{http.StatusNotFound, ErrorCategoryOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			err := newHTTPError("openai", resp, []byte(`{"error":"boom"}`))
+			if got := ErrorCategory(err); got != tt.want {
+				t.Errorf("ErrorCategory(%d) = %q, want %q", tt.status, got, tt.want)
+			}
+
+			// Categories survive wrapping, as the runner wraps stream errors
+			wrapped := &ProviderError{Provider: "openai", Message: "error in streaming response", Cause: err}
+			if got := ErrorCategory(wrapped); got != tt.want {
+				t.Errorf("ErrorCategory(wrapped %d) = %q, want %q", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorCategory(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "timeout", err: &TimeoutError{Operation: "streaming response", Duration: time.Second}, want: ErrorCategoryTimeout},
+		{name: "deadline", err: fmt.Errorf("request failed: %w", context.DeadlineExceeded), want: ErrorCategoryTimeout},
+		{name: "network", err: &NetworkError{Provider: "groq", Cause: errors.New("connection refused")}, want: ErrorCategoryNetwork},
+		{name: "generic", err: errors.New("something else"), want: ErrorCategoryOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorCategory(tt.err); got != tt.want {
+				t.Errorf("ErrorCategory() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"7", 7 * time.Second},
+		{"-1", 0},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{now.Add(-30 * time.Second).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestProviders_RetryStrategy(t *testing.T) {
+	groq, err := NewGroqProvider(&GroqConfig{APIKey: "test-key", RetryStrategy: retry.ExponentialFullJitter})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	serverErr := &ServerError{Provider: "groq", StatusCode: 503}
+	for i := 0; i < 100; i++ {
+		if delay := groq.GetRetryDelay(3, serverErr); delay < 0 || delay > 4*time.Second {
+			t.Fatalf("GetRetryDelay(3) = %v, want within [0, 4s]", delay)
+		}
+	}
+
+	// A rate-limit hint wins over the configured strategy
+	rateLimited := &RateLimitError{Provider: "groq", RetryAfter: 7 * time.Second}
+	if delay := groq.GetRetryDelay(1, rateLimited); delay != 7*time.Second {
+		t.Errorf("GetRetryDelay() = %v, want the 7s hint", delay)
+	}
+
+	fixed, err := NewAnthropicProvider(&AnthropicConfig{APIKey: "test-key", RetryStrategy: retry.Fixed})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if delay := fixed.GetRetryDelay(5, serverErr); delay != retry.BaseDelay {
+		t.Errorf("fixed GetRetryDelay(5) = %v, want %v", delay, retry.BaseDelay)
+	}
+
+	// A configured cap lowers both the backoff and a longer rate-limit hint
+	capped, err := NewOpenAICompatibleProvider("xai", &OpenAICompatibleConfig{APIKey: "test-key", RetryMaxDelay: 3 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	for attempt := 1; attempt <= 20; attempt++ {
+		if delay := capped.GetRetryDelay(attempt, serverErr); delay > 3*time.Second {
+			t.Fatalf("GetRetryDelay(%d) = %v, want at most the 3s cap", attempt, delay)
+		}
+	}
+	if delay := capped.GetRetryDelay(1, rateLimited); delay != 3*time.Second {
+		t.Errorf("capped GetRetryDelay() = %v, want the 7s hint cut to the 3s cap", delay)
+	}
+
+	// Without a configured cap, retry.MaxDelay bounds a server's Retry-After
+	stalled := &RateLimitError{Provider: "groq", RetryAfter: time.Hour}
+	if delay := groq.GetRetryDelay(1, stalled); delay != retry.MaxDelay {
+		t.Errorf("GetRetryDelay() = %v, want the 1h hint cut to %v", delay, retry.MaxDelay)
+	}
+}
+
+func TestOpenAIProvider_DirectRateLimitError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "12")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"message":"Rate limit reached"}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:       "gpt-4.1",
+		UserPrompt:  "Hello",
+		ExtraParams: map[string]interface{}{"user": "bench"},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+
+	responses := collectResponses(t, responseChan)
+	if len(responses) != 1 {
+		t.Fatalf("expected a single response, got %d", len(responses))
+	}
+
+	var rateLimitErr *RateLimitError
+	if !errors.As(responses[0].Error, &rateLimitErr) {
+		t.Fatalf("error = %v, want *RateLimitError", responses[0].Error)
+	}
+	if rateLimitErr.RetryAfter != 12*time.Second {
+		t.Errorf("RetryAfter = %v, want %v", rateLimitErr.RetryAfter, 12*time.Second)
+	}
+}
+
+func TestParseRateLimitHeaders(t *testing.T) {
+	if info := parseRateLimitHeaders(http.Header{}); info != nil {
+		t.Errorf("parseRateLimitHeaders(no headers) = %+v, want nil", info)
+	}
+
+	header := http.Header{}
+	header.Set("x-ratelimit-limit-requests", "10000")
+	header.Set("x-ratelimit-limit-tokens", "150000")
+	header.Set("x-ratelimit-remaining-requests", "9999")
+	header.Set("x-ratelimit-remaining-tokens", "0")
+	header.Set("x-ratelimit-reset-requests", "6ms")
+	header.Set("x-ratelimit-reset-tokens", "2m59.56s")
+
+	want := RateLimitInfo{
+		LimitRequests:     10000,
+		LimitTokens:       150000,
+		RemainingRequests: 9999,
+		RemainingTokens:   0,
+		ResetRequests:     6 * time.Millisecond,
+		ResetTokens:       2*time.Minute + 59560*time.Millisecond,
+	}
+	info := parseRateLimitHeaders(header)
+	if info == nil || *info != want {
+		t.Fatalf("parseRateLimitHeaders() = %+v, want %+v", info, want)
+	}
+	// Only the token bucket is exhausted
+	if got := info.ResetDelay(); got != want.ResetTokens {
+		t.Errorf("ResetDelay() = %v, want %v", got, want.ResetTokens)
+	}
+}
+
+func TestParseReset(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"1s", time.Second},
+		{"6m0s", 6 * time.Minute},
+		{"20ms", 20 * time.Millisecond},
+		{"1.5", 1500 * time.Millisecond},
+		{"0s", 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseReset(tt.value); got != tt.want {
+			t.Errorf("parseReset(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestOpenAIProvider_DirectRateLimitHeaders(t *testing.T) {
+	exhausted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ratelimit-limit-requests", "500")
+		w.Header().Set("x-ratelimit-reset-requests", "1.5s")
+		if exhausted {
+			w.Header().Set("x-ratelimit-remaining-requests", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"Rate limit reached"}}`))
+			return
+		}
+		w.Header().Set("x-ratelimit-remaining-requests", "499")
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":"Hi"}}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	req := ChatRequest{Model: "gpt-4.1", UserPrompt: "Hello", ExtraParams: map[string]interface{}{"user": "bench"}}
+
+	// The final response of a successful stream carries the headers
+	responseChan, err := provider.StreamChat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	responses := collectResponses(t, responseChan)
+	final := responses[len(responses)-1]
+	if final.RateLimit == nil || final.RateLimit.LimitRequests != 500 || final.RateLimit.RemainingRequests != 499 {
+		t.Fatalf("RateLimit = %+v, want limit 500 and 499 remaining", final.RateLimit)
+	}
+
+	// A 429 without Retry-After waits for the exhausted bucket's reset
+	exhausted = true
+	responseChan, err = provider.StreamChat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	responses = collectResponses(t, responseChan)
+	if len(responses) != 1 || responses[0].RateLimit == nil || responses[0].RateLimit.RemainingRequests != 0 {
+		t.Fatalf("responses = %+v, want one error response with rate-limit headers", responses)
+	}
+	var rateLimitErr *RateLimitError
+	if !errors.As(responses[0].Error, &rateLimitErr) {
+		t.Fatalf("error = %v, want *RateLimitError", responses[0].Error)
+	}
+	if rateLimitErr.RetryAfter != 1500*time.Millisecond {
+		t.Errorf("RetryAfter = %v, want 1.5s", rateLimitErr.RetryAfter)
+	}
+	if got := provider.GetRetryDelay(1, responses[0].Error); got != 1500*time.Millisecond {
+		t.Errorf("GetRetryDelay() = %v, want the 1.5s reset", got)
+	}
+}
+
+func TestSendResponse(t *testing.T) {
+	// With a reader waiting, the response is delivered
+	ch := make(chan ChatResponse, 1)
+	if !sendResponse(context.Background(), ch, ChatResponse{Content: "Hello"}) {
+		t.Fatal("sendResponse() = false with a reader ready")
+	}
+	if got := <-ch; got.Content != "Hello" {
+		t.Errorf("delivered %q, want Hello", got.Content)
+	}
+
+	// Once the context is cancelled nobody reads, and the send gives up at once
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan bool)
+	go func() {
+		done <- sendResponse(ctx, make(chan ChatResponse), ChatResponse{Content: "Hello"})
+	}()
+	select {
+	case sent := <-done:
+		if sent {
+			t.Error("sendResponse() = true on a cancelled context with no reader")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("sendResponse() blocked on a cancelled context")
+	}
+}
+
+func TestDirectStreaming_CancelMidStreamReleasesGoroutine(t *testing.T) {
+	tests := []struct {
+		name      string
+		newStream func(baseURL string) (<-chan ChatResponse, context.CancelFunc, error)
+	}{
+		{
+			name: "openai",
+			newStream: func(baseURL string) (<-chan ChatResponse, context.CancelFunc, error) {
+				provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: baseURL})
+				if err != nil {
+					return nil, nil, err
+				}
+				ctx, cancel := context.WithCancel(context.Background())
+				ch, err := provider.StreamChat(ctx, ChatRequest{Model: "gpt-4.1", UserPrompt: "Hello", ExtraParams: map[string]interface{}{"user": "bench"}})
+				return ch, cancel, err
+			},
+		},
+		{
+			name: "groq",
+			newStream: func(baseURL string) (<-chan ChatResponse, context.CancelFunc, error) {
+				provider, err := NewGroqProvider(&GroqConfig{APIKey: "test-key", BaseURL: baseURL})
+				if err != nil {
+					return nil, nil, err
+				}
+				ctx, cancel := context.WithCancel(context.Background())
+				ch, err := provider.StreamChat(ctx, ChatRequest{Model: "qwen/qwen3-32b", UserPrompt: "Hello", ExtraParams: map[string]interface{}{"reasoning_effort": "none"}})
+				return ch, cancel, err
+			},
+		},
+		{
+			name: "openai_responses",
+			newStream: func(baseURL string) (<-chan ChatResponse, context.CancelFunc, error) {
+				provider, err := NewOpenAIResponsesProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: baseURL})
+				if err != nil {
+					return nil, nil, err
+				}
+				ctx, cancel := context.WithCancel(context.Background())
+				ch, err := provider.StreamChat(ctx, ChatRequest{Model: "gpt-5-mini", UserPrompt: "Hello"})
+				return ch, cancel, err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlerDone := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer close(handlerDone)
+				w.Header().Set("Content-Type", "text/event-stream")
+				// Stream deltas until the client goes away
+				for {
+					w.Write([]byte(`data: {"type":"response.output_text.delta","delta":"tok","choices":[{"index":0,"delta":{"content":"tok"}}]}` + "\n\n"))
+					w.(http.Flusher).Flush()
+					select {
+					case <-r.Context().Done():
+						return
+					case <-time.After(5 * time.Millisecond):
+					}
+				}
+			}))
+			defer server.Close()
+
+			responseChan, cancel, err := tt.newStream(server.URL)
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+
+			// Read one delta, then cancel and stop reading like the runner does on timeout
+			select {
+			case <-responseChan:
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the first delta")
+			}
+			cancel()
+
+			select {
+			case <-handlerDone:
+			case <-time.After(5 * time.Second):
+				t.Fatal("server never saw the request cancelled")
+			}
+
+			// Give the streaming goroutine time to exit; a leaked goroutine would
+			// still be blocked on a send and deliver a value here instead of closing
+			time.Sleep(100 * time.Millisecond)
+			select {
+			case resp, ok := <-responseChan:
+				if ok {
+					t.Fatalf("streaming goroutine still sending after cancellation: %+v", resp)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("response channel was not closed after cancellation")
+			}
+		})
+	}
+}
+
+// fixedTokenizer counts every text as the same number of tokens
+type fixedTokenizer int
+
+func (f fixedTokenizer) Count(model, text string) int {
+	return int(f)
+}
+
+func TestProviders_Tokenizer(t *testing.T) {
+	// The Gemini SDK client reads its key from the environment
+	t.Setenv("GOOGLE_API_KEY", "test-key")
+
+	newProviders := func(tok tokenizer.Tokenizer) map[string]func() (Provider, error) {
+		return map[string]func() (Provider, error){
+			"openai": func() (Provider, error) {
+				return NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", Tokenizer: tok})
+			},
+			"openai_responses": func() (Provider, error) {
+				return NewOpenAIResponsesProvider(&OpenAIConfig{APIKey: "test-key", Tokenizer: tok})
+			},
+			"groq": func() (Provider, error) {
+				return NewGroqProvider(&GroqConfig{APIKey: "test-key", Tokenizer: tok})
+			},
+			"anthropic": func() (Provider, error) {
+				return NewAnthropicProvider(&AnthropicConfig{APIKey: "test-key", Tokenizer: tok})
+			},
+			"azure_openai": func() (Provider, error) {
+				return NewAzureOpenAIProvider(&AzureOpenAIConfig{Endpoint: "https://test.openai.azure.com/", APIKey: "test-key", Tokenizer: tok})
+			},
+			"gemini": func() (Provider, error) {
+				return NewGeminiProvider(&GeminiConfig{APIKey: "test-key", Tokenizer: tok})
+			},
+		}
+	}
+
+	text := "This is a longer text with more tokens to count"
+
+	for name, newProvider := range newProviders(nil) {
+		t.Run(name+"/default", func(t *testing.T) {
+			provider, err := newProvider()
+			if err != nil {
+				t.Fatalf("failed to create provider: %v", err)
+			}
+			if got := provider.(TokenizerProvider).Tokenizer(); got != tokenizer.Default {
+				t.Errorf("Tokenizer() = %T, want the default heuristic", got)
+			}
+			want := tokenizer.Heuristic{}.Count("", text)
+			if got := provider.GetTokenCount(text); got != want {
+				t.Errorf("GetTokenCount() = %d, want %d", got, want)
+			}
+		})
+	}
+
+	for name, newProvider := range newProviders(fixedTokenizer(42)) {
+		t.Run(name+"/injected", func(t *testing.T) {
+			provider, err := newProvider()
+			if err != nil {
+				t.Fatalf("failed to create provider: %v", err)
+			}
+			if got := provider.GetTokenCount(text); got != 42 {
+				t.Errorf("GetTokenCount() = %d, want 42", got)
+			}
+			if _, output, _ := provider.TokenCount(ChatResponse{Content: text}); output != 42 {
+				t.Errorf("TokenCount() output = %d, want 42", output)
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	var status int
+	var gotMaxTokens int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body compatChatRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.MaxTokens != nil {
+			gotMaxTokens = *body.MaxTokens
+		}
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":{"message":"Authentication Fails (no such user)"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"length"}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAICompatibleProvider("deepseek", &OpenAICompatibleConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	status = http.StatusOK
+	if err := Ping(context.Background(), provider, "deepseek-chat"); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+	if gotMaxTokens != 1 {
+		t.Errorf("max_tokens = %d, want 1", gotMaxTokens)
+	}
+
+	status = http.StatusUnauthorized
+	err = Ping(context.Background(), provider, "deepseek-chat")
+	var authErr *AuthError
+	if !errors.As(err, &authErr) {
+		t.Errorf("Ping() error = %v, want an AuthError", err)
+	}
+
+	// Request validation fails before anything is sent
+	if err := Ping(context.Background(), provider, ""); err == nil {
+		t.Error("Ping() with no model succeeded, want a validation error")
+	}
+}
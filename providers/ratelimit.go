@@ -0,0 +1,83 @@
+package providers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitInfo is the rate-limit state a provider reported in its response headers
+// (x-ratelimit-*, as sent by OpenAI and Groq). Resets are how long until the bucket
+// refills; fields whose header was absent are zero.
+type RateLimitInfo struct {
+	LimitRequests     int           `json:"limit_requests"`
+	LimitTokens       int           `json:"limit_tokens"`
+	RemainingRequests int           `json:"remaining_requests"`
+	RemainingTokens   int           `json:"remaining_tokens"`
+	ResetRequests     time.Duration `json:"reset_requests"`
+	ResetTokens       time.Duration `json:"reset_tokens"`
+}
+
+// parseRateLimitHeaders reads x-ratelimit-* headers, returning nil when there are none
+func parseRateLimitHeaders(header http.Header) *RateLimitInfo {
+	found := false
+	intHeader := func(name string) int {
+		value := strings.TrimSpace(header.Get(name))
+		if value == "" {
+			return 0
+		}
+		found = true
+		n, _ := strconv.Atoi(value)
+		return n
+	}
+	resetHeader := func(name string) time.Duration {
+		value := strings.TrimSpace(header.Get(name))
+		if value == "" {
+			return 0
+		}
+		found = true
+		return parseReset(value)
+	}
+
+	info := &RateLimitInfo{
+		LimitRequests:     intHeader("x-ratelimit-limit-requests"),
+		LimitTokens:       intHeader("x-ratelimit-limit-tokens"),
+		RemainingRequests: intHeader("x-ratelimit-remaining-requests"),
+		RemainingTokens:   intHeader("x-ratelimit-remaining-tokens"),
+		ResetRequests:     resetHeader("x-ratelimit-reset-requests"),
+		ResetTokens:       resetHeader("x-ratelimit-reset-tokens"),
+	}
+	if !found {
+		return nil
+	}
+	return info
+}
+
+// parseReset parses a reset hint given as a Go-style duration ("6m0s", "20ms",
+// "2m59.56s") or as bare seconds
+func parseReset(value string) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return 0
+}
+
+// ResetDelay returns how long until the exhausted bucket(s) refill, or 0 when
+// neither requests nor tokens have run out
+func (r *RateLimitInfo) ResetDelay() time.Duration {
+	if r == nil {
+		return 0
+	}
+	var delay time.Duration
+	if r.RemainingRequests == 0 && r.ResetRequests > delay {
+		delay = r.ResetRequests
+	}
+	if r.RemainingTokens == 0 && r.ResetTokens > delay {
+		delay = r.ResetTokens
+	}
+	return delay
+}
@@ -0,0 +1,136 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewSambaNovaProvider(t *testing.T) {
+	if _, err := NewOpenAICompatibleProvider("sambanova", &OpenAICompatibleConfig{}); err == nil {
+		t.Error("NewOpenAICompatibleProvider(\"sambanova\") without an API key should fail")
+	}
+
+	provider, err := NewOpenAICompatibleProvider("sambanova", &OpenAICompatibleConfig{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("NewOpenAICompatibleProvider(\"sambanova\") error = %v", err)
+	}
+	if provider.Name() != "sambanova" {
+		t.Errorf("Name() = %s, want 'sambanova'", provider.Name())
+	}
+	if provider.config.BaseURL != "https://api.sambanova.ai/v1" {
+		t.Errorf("BaseURL = %s, want the default", provider.config.BaseURL)
+	}
+
+	// StreamChat rejects invalid requests before sending anything
+	if _, err := provider.StreamChat(context.Background(), ChatRequest{Model: "Meta-Llama-3.1-8B-Instruct"}); err == nil {
+		t.Error("StreamChat() accepted a request without a prompt")
+	}
+	if err := provider.ValidateRequest(ChatRequest{Model: "Meta-Llama-3.1-8B-Instruct", UserPrompt: "Hello", Temperature: float64Ptr(1.5)}); err == nil {
+		t.Error("ValidateRequest() accepted a temperature above 1")
+	}
+}
+
+func TestSambaNovaProvider_StreamChat(t *testing.T) {
+	var gotBody compatChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("request path = %s, want /chat/completions", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q, want the API key as a bearer token", got)
+		}
+		json.NewDecoder(r.Body).Decode(&gotBody)
+
+		// Recorded from the API: usage, with SambaNova's own timing fields, arrives on
+		// a final chunk without choices
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"Hello","role":"assistant"},"finish_reason":null,"index":0,"logprobs":null}],"created":1736900000,"id":"6a1f0c2e","model":"Meta-Llama-3.1-8B-Instruct","object":"chat.completion.chunk","system_fingerprint":"fastcoe"}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"!"},"finish_reason":"stop","index":0,"logprobs":null}],"created":1736900000,"id":"6a1f0c2e","model":"Meta-Llama-3.1-8B-Instruct","object":"chat.completion.chunk","system_fingerprint":"fastcoe"}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[],"created":1736900000,"id":"6a1f0c2e","model":"Meta-Llama-3.1-8B-Instruct","object":"chat.completion.chunk","system_fingerprint":"fastcoe","usage":{"completion_tokens":3,"prompt_tokens":39,"total_tokens":42,"completion_tokens_after_first_per_sec":612.4,"time_to_first_token":0.081,"total_latency":0.0843,"total_tokens_per_sec":498.2}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAICompatibleProvider("sambanova", &OpenAICompatibleConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:      "Meta-Llama-3.1-8B-Instruct",
+		UserPrompt: "Say hello",
+		MaxTokens:  16,
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	responses := collectResponses(t, responseChan)
+
+	if !gotBody.Stream || gotBody.StreamOptions == nil || !gotBody.StreamOptions.IncludeUsage || gotBody.MaxTokens == nil || *gotBody.MaxTokens != 16 {
+		t.Errorf("request body = %+v, want a stream including usage with max_tokens 16", gotBody)
+	}
+
+	var content string
+	for _, response := range responses {
+		if response.Error != nil {
+			t.Fatalf("unexpected error: %v", response.Error)
+		}
+		content += response.Content
+	}
+	if content != "Hello!" {
+		t.Errorf("content = %q, want %q", content, "Hello!")
+	}
+
+	final := responses[len(responses)-1]
+	if !final.IsComplete || final.FinishReason != "stop" {
+		t.Errorf("final response = %+v, want complete with finish reason stop", final)
+	}
+	if input, output, total := provider.TokenCount(final); input != 39 || output != 3 || total != 42 {
+		t.Errorf("TokenCount() = %d, %d, %d, want 39, 3, 42", input, output, total)
+	}
+}
+
+func TestSambaNovaProvider_RetryableStatuses(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{"rate limited", http.StatusTooManyRequests, `{"error":{"message":"Rate limit exceeded","type":"rate_limit_exceeded"}}`, true},
+		{"overloaded", http.StatusServiceUnavailable, `{"error":{"message":"Service Unavailable: model is overloaded"}}`, true},
+		{"unknown model", http.StatusBadRequest, `{"error":{"message":"Model not found"}}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			provider, err := NewOpenAICompatibleProvider("sambanova", &OpenAICompatibleConfig{APIKey: "test-key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+			responseChan, err := provider.StreamChat(context.Background(), ChatRequest{Model: "Meta-Llama-3.1-8B-Instruct", UserPrompt: "Hello"})
+			if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			responses := collectResponses(t, responseChan)
+
+			final := responses[len(responses)-1]
+			if final.Error == nil {
+				t.Fatalf("HTTP %d produced no error", tt.status)
+			}
+			if got := provider.IsRetryableError(final.Error); got != tt.want {
+				t.Errorf("IsRetryableError(%v) = %v, want %v", final.Error, got, tt.want)
+			}
+		})
+	}
+}
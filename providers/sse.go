@@ -0,0 +1,56 @@
+package providers
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// maxSSELineSize bounds a single SSE line; large single-chunk deltas can exceed bufio defaults
+const maxSSELineSize = 1 << 20
+
+// sseReader reads server-sent events and returns the data payload of each event.
+// Multiple data lines within one event are joined with newlines; comment lines
+// (starting with ':') and event/id/retry fields are skipped.
+type sseReader struct {
+	scanner *bufio.Scanner
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELineSize)
+	return &sseReader{scanner: scanner}
+}
+
+// Next returns the data of the next event, or io.EOF once the stream ends
+func (r *sseReader) Next() (string, error) {
+	var data []string
+	for r.scanner.Scan() {
+		line := strings.TrimRight(r.scanner.Text(), "\r")
+
+		// A blank line ends the event
+		if line == "" {
+			if len(data) > 0 {
+				return strings.Join(data, "\n"), nil
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		if strings.HasPrefix(line, "data:") {
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		// event:, id: and retry: fields carry nothing we need
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return "", err
+	}
+	// Flush a final event that wasn't followed by a blank line
+	if len(data) > 0 {
+		return strings.Join(data, "\n"), nil
+	}
+	return "", io.EOF
+}
@@ -0,0 +1,107 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSSEReader(t *testing.T) {
+	stream := strings.Join([]string{
+		": keep-alive comment",
+		"event: message",
+		"id: 1",
+		`data: {"a":1}`,
+		"",
+		"retry: 1000",
+		"data: first line",
+		"data:second line",
+		"",
+		"",
+		"data: crlf\r",
+		"\r",
+		"data: [DONE]",
+	}, "\n")
+
+	reader := newSSEReader(strings.NewReader(stream))
+	var events []string
+	for {
+		data, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		events = append(events, data)
+	}
+
+	want := []string{`{"a":1}`, "first line\nsecond line", "crlf", "[DONE]"}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events %q, want %d", len(events), events, len(want))
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %q, want %q", i, events[i], want[i])
+		}
+	}
+}
+
+func TestSSEReader_LineTooLong(t *testing.T) {
+	reader := newSSEReader(strings.NewReader("data: " + strings.Repeat("x", maxSSELineSize+1) + "\n\n"))
+	if _, err := reader.Next(); err == nil || err == io.EOF {
+		t.Errorf("Next() error = %v, want a scanner error for an oversized line", err)
+	}
+}
+
+func TestOpenAIProvider_DirectLargeDelta(t *testing.T) {
+	large := strings.Repeat("abcdefghij", 20*1024) // 200KB in a single delta
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := func(content string) string {
+			data, _ := json.Marshal(map[string]interface{}{
+				"choices": []map[string]interface{}{{"index": 0, "delta": map[string]string{"content": content}}},
+			})
+			return "data: " + string(data) + "\n\n"
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(": OPENROUTER PROCESSING\n\n"))
+		w.Write([]byte(chunk("start-")))
+		w.Write([]byte(": keep-alive\n"))
+		w.Write([]byte("event: delta\n" + chunk(large)))
+		w.Write([]byte(chunk("-end")))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:       "gpt-4.1",
+		UserPrompt:  "Hello",
+		ExtraParams: map[string]interface{}{"user": "bench"},
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+
+	var content strings.Builder
+	for _, resp := range collectResponses(t, responseChan) {
+		if resp.Error != nil {
+			t.Fatalf("unexpected error: %v", resp.Error)
+		}
+		content.WriteString(resp.Content)
+	}
+
+	if want := "start-" + large + "-end"; content.String() != want {
+		t.Errorf("assembled %d bytes, want %d", content.Len(), len(want))
+	}
+}
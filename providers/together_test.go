@@ -0,0 +1,97 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewTogetherProvider(t *testing.T) {
+	if _, err := NewOpenAICompatibleProvider("together", &OpenAICompatibleConfig{}); err == nil {
+		t.Error("NewOpenAICompatibleProvider(\"together\") without an API key should fail")
+	}
+
+	provider, err := NewOpenAICompatibleProvider("together", &OpenAICompatibleConfig{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("NewOpenAICompatibleProvider(\"together\") error = %v", err)
+	}
+	if provider.Name() != "together" {
+		t.Errorf("Name() = %s, want 'together'", provider.Name())
+	}
+	if provider.config.BaseURL != "https://api.together.xyz/v1" {
+		t.Errorf("BaseURL = %s, want the default", provider.config.BaseURL)
+	}
+
+	// StreamChat rejects invalid requests before sending anything
+	if _, err := provider.StreamChat(context.Background(), ChatRequest{Model: "meta-llama/Llama-3.3-70B-Instruct-Turbo"}); err == nil {
+		t.Error("StreamChat() accepted a request without a prompt")
+	}
+}
+
+func TestTogetherProvider_StreamChat(t *testing.T) {
+	var gotBody compatChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("request path = %s, want /chat/completions", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q, want the API key as a bearer token", got)
+		}
+		json.NewDecoder(r.Body).Decode(&gotBody)
+
+		// Recorded from the API: usage arrives on the chunk that finishes the choice
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"8f3c1b2a9d","object":"chat.completion.chunk","created":1736900000,"model":"meta-llama/Llama-3.3-70B-Instruct-Turbo","choices":[{"index":0,"text":"Hello","logprobs":null,"finish_reason":null,"seed":null,"delta":{"token_id":9906,"role":"assistant","content":"Hello","tool_calls":null}}],"usage":null}` + "\n\n"))
+		w.Write([]byte(`data: {"id":"8f3c1b2a9d","object":"chat.completion.chunk","created":1736900000,"model":"meta-llama/Llama-3.3-70B-Instruct-Turbo","choices":[{"index":0,"text":"!","logprobs":null,"finish_reason":null,"seed":null,"delta":{"token_id":0,"role":"assistant","content":"!","tool_calls":null}}],"usage":null}` + "\n\n"))
+		w.Write([]byte(`data: {"id":"8f3c1b2a9d","object":"chat.completion.chunk","created":1736900000,"model":"meta-llama/Llama-3.3-70B-Instruct-Turbo","choices":[{"index":0,"text":"","logprobs":null,"finish_reason":"stop","seed":4815162342,"delta":{"token_id":128009,"role":"assistant","content":"","tool_calls":null}}],"usage":{"prompt_tokens":41,"completion_tokens":3,"total_tokens":44}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAICompatibleProvider("together", &OpenAICompatibleConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responseChan, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model:      "meta-llama/Llama-3.3-70B-Instruct-Turbo",
+		UserPrompt: "Say hello",
+		MaxTokens:  16,
+	})
+	if err != nil {
+		t.Fatalf("StreamChat() error = %v", err)
+	}
+	responses := collectResponses(t, responseChan)
+
+	if !gotBody.Stream || gotBody.MaxTokens == nil || *gotBody.MaxTokens != 16 {
+		t.Errorf("request body = %+v, want a stream with max_tokens 16", gotBody)
+	}
+
+	var content string
+	for _, response := range responses {
+		if response.Error != nil {
+			t.Fatalf("unexpected error: %v", response.Error)
+		}
+		content += response.Content
+	}
+	if content != "Hello!" {
+		t.Errorf("content = %q, want %q", content, "Hello!")
+	}
+
+	final := responses[len(responses)-1]
+	if !final.IsComplete || final.FinishReason != "stop" {
+		t.Errorf("final response = %+v, want complete with finish reason stop", final)
+	}
+	want := Usage{InputTokens: 41, OutputTokens: 3}
+	if final.Usage == nil || *final.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", final.Usage, want)
+	}
+	if input, output, total := provider.TokenCount(final); input != 41 || output != 3 || total != 44 {
+		t.Errorf("TokenCount() = %d, %d, %d, want 41, 3, 44", input, output, total)
+	}
+	if final.ResponseModel != "meta-llama/Llama-3.3-70B-Instruct-Turbo" {
+		t.Errorf("ResponseModel = %q, want the served model", final.ResponseModel)
+	}
+}
@@ -0,0 +1,146 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewXAIProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *OpenAICompatibleConfig
+		wantErr bool
+		wantURL string
+	}{
+		{
+			name:    "valid config",
+			config:  &OpenAICompatibleConfig{APIKey: "test-key", BaseURL: "https://example.test/v1"},
+			wantURL: "https://example.test/v1",
+		},
+		{
+			name:    "missing API key",
+			config:  &OpenAICompatibleConfig{BaseURL: "https://api.x.ai/v1"},
+			wantErr: true,
+		},
+		{
+			name:    "empty base URL (should use default)",
+			config:  &OpenAICompatibleConfig{APIKey: "test-key"},
+			wantURL: "https://api.x.ai/v1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewOpenAICompatibleProvider("xai", tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewOpenAICompatibleProvider(\"xai\") error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if provider.Name() != "xai" {
+				t.Errorf("Name() = %s, want 'xai'", provider.Name())
+			}
+			if provider.config.BaseURL != tt.wantURL {
+				t.Errorf("BaseURL = %s, want %s", provider.config.BaseURL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestXAIProvider_ValidateRequest(t *testing.T) {
+	provider, err := NewOpenAICompatibleProvider("xai", &OpenAICompatibleConfig{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		request ChatRequest
+		wantErr bool
+	}{
+		{"valid request", ChatRequest{Model: "grok-3", UserPrompt: "Hello"}, false},
+		{"conversation without user prompt", ChatRequest{Model: "grok-3", Messages: []Message{{Role: "user", Content: "Hello"}}}, false},
+		{"missing model", ChatRequest{UserPrompt: "Hello"}, true},
+		{"missing user prompt", ChatRequest{Model: "grok-3"}, true},
+		{"negative max tokens", ChatRequest{Model: "grok-3", UserPrompt: "Hello", MaxTokens: -1}, true},
+		{"invalid temperature", ChatRequest{Model: "grok-3", UserPrompt: "Hello", Temperature: float64Ptr(3)}, true},
+		{"invalid top_p", ChatRequest{Model: "grok-3", UserPrompt: "Hello", TopP: 1.5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := provider.ValidateRequest(tt.request); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// StreamChat rejects invalid requests before sending anything
+	if _, err := provider.StreamChat(context.Background(), ChatRequest{Model: "grok-3"}); err == nil {
+		t.Error("StreamChat() accepted a request without a prompt")
+	}
+}
+
+func TestXAIProvider_IsRetryableError(t *testing.T) {
+	provider, err := NewOpenAICompatibleProvider("xai", &OpenAICompatibleConfig{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil error", nil, false},
+		{"rate limit error", &RateLimitError{Provider: "xai"}, true},
+		{"server error", &ServerError{Provider: "xai", StatusCode: 503}, true},
+		{"timeout error", &TimeoutError{Operation: "streaming response", Duration: time.Second}, true},
+		{"deadline exceeded", fmt.Errorf("request: %w", context.DeadlineExceeded), true},
+		{"network error", &NetworkError{Provider: "xai", Cause: errors.New("connection refused")}, true},
+		{"wrapped rate limit error", &ProviderError{Provider: "xai", Message: "error in streaming response", Cause: &RateLimitError{Provider: "xai"}}, true},
+		{"auth error", &AuthError{Provider: "xai", StatusCode: 401}, false},
+		{"bad request", &ProviderError{Provider: "xai", Message: "HTTP error 400: model not found"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := provider.IsRetryableError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXAIProvider_GetRetryDelay(t *testing.T) {
+	provider, err := NewOpenAICompatibleProvider("xai", &OpenAICompatibleConfig{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		attempt int
+		err     error
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{"first attempt", 1, &ServerError{Provider: "xai", StatusCode: 500}, time.Second, time.Second},
+		{"second attempt", 2, &ServerError{Provider: "xai", StatusCode: 500}, 2 * time.Second, 2 * time.Second},
+		{"rate limit with a reset hint", 1, &RateLimitError{Provider: "xai", RetryAfter: 7 * time.Second}, 7 * time.Second, 7 * time.Second},
+		{"high attempt (should be capped)", 10, &TimeoutError{Operation: "streaming response"}, 30 * time.Second, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := provider.GetRetryDelay(tt.attempt, tt.err)
+			if got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("GetRetryDelay() = %v, want between %v and %v", got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}